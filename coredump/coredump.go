@@ -0,0 +1,259 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package coredump lists the crashes recorded by systemd-coredump and
+// extracts their core files.
+//
+// systemd-coredump logs a journal entry with the message ID MessageID for
+// every crash it handles, carrying the metadata of the crashed process in
+// COREDUMP_* fields. The core itself is either stored in the entry, or
+// written compressed to a file in Dir.
+//
+// See coredump.conf(5) and systemd-coredump(8).
+package coredump
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/internal/lz4"
+	"github.com/coreos/go-systemd/v22/internal/zstd"
+)
+
+const (
+	// MessageID is the MESSAGE_ID of the entries logged by
+	// systemd-coredump.
+	MessageID = "fc2e22bc6ee647b6b90729ab34a250b1"
+
+	// Dir is the directory where systemd-coredump stores core files.
+	Dir = "/var/lib/systemd/coredump"
+)
+
+var (
+	// ErrNoCore is returned by Open when the core of a crash was not
+	// stored, or has been removed since.
+	ErrNoCore = errors.New("core file not available")
+
+	// ErrUnsupportedCompression is returned by Open when the core file is
+	// compressed with a format that cannot be decompressed here.
+	ErrUnsupportedCompression = errors.New("unsupported core file compression")
+)
+
+// Reader is the part of a journal reader used by List. It is satisfied
+// by *sdjournal.Journal, *journalfile.Journal and *journalfile.Merged.
+type Reader interface {
+	Next() (uint64, error)
+	GetDataValue(field string) (string, error)
+	GetRealtimeUsec() (uint64, error)
+}
+
+// Coredump describes a crash recorded by systemd-coredump.
+type Coredump struct {
+	Time       time.Time // when the process crashed
+	PID        int
+	UID        int
+	GID        int
+	Signal     int
+	SignalName string // e.g. SIGSEGV
+	Exe        string // path of the executable
+	Comm       string
+	Cmdline    string
+	Unit       string // system unit of the process, if any
+	UserUnit   string // user unit of the process, if any
+	Hostname   string
+	BootID     string
+	Message    string // the human readable summary, with the backtrace if any
+
+	// Filename is the path of the core file in Dir, empty if the core was
+	// not stored in a file.
+	Filename string
+	// Truncated is set if the core was cut short because it exceeded the
+	// size limits of coredump.conf(5).
+	Truncated bool
+
+	core []byte // the core, when stored in the journal entry
+}
+
+// List returns the crashes recorded by systemd-coredump among the entries
+// r has after its current position, oldest first.
+//
+// With sdjournal, adding a match speeds up the search considerably:
+//
+//	j.AddMatch("MESSAGE_ID=" + coredump.MessageID)
+func List(r Reader) ([]*Coredump, error) {
+	var dumps []*Coredump
+	for {
+		n, err := r.Next()
+		if err != nil {
+			return dumps, err
+		}
+		if n == 0 {
+			return dumps, nil
+		}
+
+		if id, _ := r.GetDataValue("MESSAGE_ID"); id != MessageID {
+			continue
+		}
+		c, err := read(r)
+		if err != nil {
+			return dumps, err
+		}
+		dumps = append(dumps, c)
+	}
+}
+
+// read builds a Coredump from the current entry of r.
+func read(r Reader) (*Coredump, error) {
+	get := func(field string) string {
+		v, _ := r.GetDataValue(field)
+		return v
+	}
+	getInt := func(field string) int {
+		n, _ := strconv.Atoi(get(field))
+		return n
+	}
+
+	c := &Coredump{
+		PID:        getInt("COREDUMP_PID"),
+		UID:        getInt("COREDUMP_UID"),
+		GID:        getInt("COREDUMP_GID"),
+		Signal:     getInt("COREDUMP_SIGNAL"),
+		SignalName: get("COREDUMP_SIGNAL_NAME"),
+		Exe:        get("COREDUMP_EXE"),
+		Comm:       get("COREDUMP_COMM"),
+		Cmdline:    get("COREDUMP_CMDLINE"),
+		Unit:       get("COREDUMP_UNIT"),
+		UserUnit:   get("COREDUMP_USER_UNIT"),
+		Hostname:   get("COREDUMP_HOSTNAME"),
+		BootID:     get("_BOOT_ID"),
+		Message:    get("MESSAGE"),
+		Filename:   get("COREDUMP_FILENAME"),
+	}
+	c.Truncated, _ = strconv.ParseBool(get("COREDUMP_TRUNCATED"))
+	if core := get("COREDUMP"); core != "" {
+		c.core = []byte(core)
+	}
+
+	if usec, err := strconv.ParseUint(get("COREDUMP_TIMESTAMP"), 10, 64); err == nil {
+		c.Time = time.Unix(0, int64(usec)*int64(time.Microsecond))
+	} else {
+		usec, err := r.GetRealtimeUsec()
+		if err != nil {
+			return nil, err
+		}
+		c.Time = time.Unix(0, int64(usec)*int64(time.Microsecond))
+	}
+	return c, nil
+}
+
+// HasCore reports whether the core of the crash is available, either in the
+// journal entry or as a file.
+func (c *Coredump) HasCore() bool {
+	if c.core != nil {
+		return true
+	}
+	if c.Filename == "" {
+		return false
+	}
+	_, err := os.Stat(c.Filename)
+	return err == nil
+}
+
+// Open returns the uncompressed core of the crash. Files compressed with LZ4
+// or zstd are decompressed natively; xz needs the xz command.
+// The caller must close the returned reader.
+func (c *Coredump) Open() (io.ReadCloser, error) {
+	if c.core != nil {
+		return ioutil.NopCloser(strings.NewReader(string(c.core))), nil
+	}
+	if c.Filename == "" {
+		return nil, ErrNoCore
+	}
+
+	f, err := os.Open(c.Filename)
+	if os.IsNotExist(err) {
+		return nil, ErrNoCore
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch filepath.Ext(c.Filename) {
+	case ".lz4":
+		return &readCloser{lz4.NewReader(f), f.Close}, nil
+	case ".zst":
+		return &readCloser{zstd.NewReader(f), f.Close}, nil
+	case ".xz":
+		return decompressCommand(f, "xz")
+	default:
+		return f, nil
+	}
+}
+
+// WriteTo writes the uncompressed core of the crash to w.
+func (c *Coredump) WriteTo(w io.Writer) (int64, error) {
+	rc, err := c.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+	return io.Copy(w, rc)
+}
+
+// decompressCommand decompresses f with "name -dc".
+func decompressCommand(f *os.File, name string) (io.ReadCloser, error) {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		f.Close()
+		return nil, ErrUnsupportedCompression
+	}
+
+	cmd := exec.Command(path, "-dc")
+	cmd.Stdin = f
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &readCloser{out, func() error {
+		f.Close()
+		out.Close()
+		if err := cmd.Wait(); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		return nil
+	}}, nil
+}
+
+type readCloser struct {
+	io.Reader
+	close func() error
+}
+
+func (r *readCloser) Close() error {
+	return r.close()
+}
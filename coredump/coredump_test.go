@@ -0,0 +1,180 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coredump
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeReader is a Reader over in-memory entries.
+type fakeReader struct {
+	entries []map[string]string
+	pos     int
+}
+
+func (r *fakeReader) Next() (uint64, error) {
+	if r.pos >= len(r.entries) {
+		return 0, nil
+	}
+	r.pos++
+	return 1, nil
+}
+
+func (r *fakeReader) GetDataValue(field string) (string, error) {
+	v, ok := r.entries[r.pos-1][field]
+	if !ok {
+		return "", os.ErrNotExist
+	}
+	return v, nil
+}
+
+func (r *fakeReader) GetRealtimeUsec() (uint64, error) {
+	return 1600000000000000, nil
+}
+
+func TestList(t *testing.T) {
+	r := &fakeReader{entries: []map[string]string{
+		{"MESSAGE_ID": "other", "MESSAGE": "not a crash"},
+		{
+			"MESSAGE_ID":           MessageID,
+			"MESSAGE":              "Process 42 (crashy) of user 1000 dumped core.",
+			"COREDUMP_PID":         "42",
+			"COREDUMP_UID":         "1000",
+			"COREDUMP_GID":         "1000",
+			"COREDUMP_SIGNAL":      "11",
+			"COREDUMP_SIGNAL_NAME": "SIGSEGV",
+			"COREDUMP_EXE":         "/usr/bin/crashy",
+			"COREDUMP_COMM":        "crashy",
+			"COREDUMP_UNIT":        "crashy.service",
+			"COREDUMP_TIMESTAMP":   "1700000000123456",
+			"COREDUMP_FILENAME":    "/var/lib/systemd/coredump/core.crashy.lz4",
+			"_BOOT_ID":             "0123456789abcdef0123456789abcdef",
+		},
+		{
+			"MESSAGE_ID":         MessageID,
+			"COREDUMP_PID":       "7",
+			"COREDUMP_TRUNCATED": "1",
+			"COREDUMP":           "core",
+		},
+	}}
+
+	dumps, err := List(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dumps) != 2 {
+		t.Fatalf("got %d coredumps, want 2", len(dumps))
+	}
+
+	c := dumps[0]
+	if c.PID != 42 || c.UID != 1000 || c.GID != 1000 || c.Signal != 11 || c.SignalName != "SIGSEGV" {
+		t.Errorf("unexpected process metadata: %+v", c)
+	}
+	if c.Exe != "/usr/bin/crashy" || c.Comm != "crashy" || c.Unit != "crashy.service" {
+		t.Errorf("unexpected process metadata: %+v", c)
+	}
+	if want := time.Unix(1700000000, 123456000); !c.Time.Equal(want) {
+		t.Errorf("got time %v, want %v", c.Time, want)
+	}
+	if c.Truncated || c.HasCore() {
+		t.Errorf("unexpected core state: %+v", c)
+	}
+
+	c = dumps[1]
+	if want := time.Unix(1600000000, 0); !c.Time.Equal(want) {
+		t.Errorf("got time %v, want the entry time %v", c.Time, want)
+	}
+	if !c.Truncated || !c.HasCore() {
+		t.Errorf("unexpected core state: %+v", c)
+	}
+	var buf bytes.Buffer
+	if _, err := c.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "core" {
+		t.Errorf("got core %q, want %q", buf.String(), "core")
+	}
+}
+
+// lz4Frame returns an LZ4 frame storing data in an uncompressed block.
+func lz4Frame(data string) []byte {
+	var b bytes.Buffer
+	b.Write([]byte{0x04, 0x22, 0x4d, 0x18, 0x60, 0x40, 0}) // magic, FLG, BD, HC
+	binary.Write(&b, binary.LittleEndian, uint32(len(data))|1<<31)
+	b.WriteString(data)
+	b.Write([]byte{0, 0, 0, 0})
+	return b.Bytes()
+}
+
+func TestOpen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coredump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string][]byte{
+		"core.plain":     []byte("plain core"),
+		"core.plain.lz4": lz4Frame("lz4 core"),
+		// written by the zstd command line tool
+		"core.plain.zst": {
+			0x28, 0xb5, 0x2f, 0xfd, 0x04, 0x58, 0x49, 0x00, 0x00, 0x7a, 0x73, 0x74,
+			0x64, 0x20, 0x63, 0x6f, 0x72, 0x65, 0xd0, 0x28, 0x58, 0x96,
+		},
+	}
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for name, want := range map[string]string{
+		"core.plain":     "plain core",
+		"core.plain.lz4": "lz4 core",
+		"core.plain.zst": "zstd core",
+	} {
+		c := &Coredump{Filename: filepath.Join(dir, name)}
+		if !c.HasCore() {
+			t.Errorf("%s: expected a core", name)
+		}
+		rc, err := c.Open()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		got, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if string(got) != want {
+			t.Errorf("%s: got %q, want %q", name, got, want)
+		}
+	}
+
+	for _, c := range []*Coredump{{}, {Filename: filepath.Join(dir, "core.removed.zst")}} {
+		if c.HasCore() {
+			t.Errorf("%q: unexpected core", c.Filename)
+		}
+		if _, err := c.Open(); err != ErrNoCore {
+			t.Errorf("%q: got error %v, want ErrNoCore", c.Filename, err)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// BootTimes holds the time spent in each phase of the boot, as reported by
+// systemd-analyze time. Phases that did not happen, such as the firmware
+// and loader phases on machines whose boot loader does not report them, or
+// the initrd phase when booting without one, are 0.
+type BootTimes struct {
+	// KernelTimestamp is when the kernel started, the origin of all the
+	// monotonic timestamps below.
+	KernelTimestamp time.Time
+
+	// The monotonic timestamps of the phases, as reported by systemd.
+	// FirmwareTimestamp and LoaderTimestamp count backwards from the start
+	// of the kernel.
+	FirmwareTimestamp  time.Duration
+	LoaderTimestamp    time.Duration
+	InitRDTimestamp    time.Duration
+	UserspaceTimestamp time.Duration
+	FinishTimestamp    time.Duration
+
+	// The duration of each phase.
+	Firmware  time.Duration
+	Loader    time.Duration
+	Kernel    time.Duration
+	InitRD    time.Duration
+	Userspace time.Duration
+	Total     time.Duration
+
+	// Finished is set once the boot reached its default target. Until then,
+	// Userspace and Total are 0.
+	Finished bool
+}
+
+// GetBootTimes returns the time spent booting, like systemd-analyze time.
+func (c *Conn) GetBootTimes(ctx context.Context) (*BootTimes, error) {
+	props, err := c.getProperties(ctx, "/org/freedesktop/systemd1", "org.freedesktop.systemd1.Manager")
+	if err != nil {
+		return nil, err
+	}
+	return bootTimes(props), nil
+}
+
+func bootTimes(props map[string]interface{}) *BootTimes {
+	usec := func(name string) time.Duration {
+		v, _ := props[name].(uint64)
+		return time.Duration(v) * time.Microsecond
+	}
+
+	b := &BootTimes{
+		KernelTimestamp:    usecTime(props["KernelTimestamp"]),
+		FirmwareTimestamp:  usec("FirmwareTimestampMonotonic"),
+		LoaderTimestamp:    usec("LoaderTimestampMonotonic"),
+		InitRDTimestamp:    usec("InitRDTimestampMonotonic"),
+		UserspaceTimestamp: usec("UserspaceTimestampMonotonic"),
+		FinishTimestamp:    usec("FinishTimestampMonotonic"),
+	}
+
+	if b.FirmwareTimestamp > 0 {
+		b.Firmware = b.FirmwareTimestamp - b.LoaderTimestamp
+	}
+	b.Loader = b.LoaderTimestamp
+	if b.InitRDTimestamp > 0 {
+		b.Kernel = b.InitRDTimestamp
+		b.InitRD = b.UserspaceTimestamp - b.InitRDTimestamp
+	} else {
+		b.Kernel = b.UserspaceTimestamp
+	}
+	if b.FinishTimestamp > 0 {
+		b.Finished = true
+		b.Userspace = b.FinishTimestamp - b.UserspaceTimestamp
+		b.Total = b.Firmware + b.Loader + b.FinishTimestamp
+	}
+
+	return b
+}
+
+// UnitStartupTime is the time a unit took to start, as listed by
+// systemd-analyze blame.
+type UnitStartupTime struct {
+	Name string
+	// Activating and Activated are the monotonic timestamps of when the
+	// unit left the inactive state, and when it entered the active state.
+	Activating time.Duration
+	Activated  time.Duration
+	Duration   time.Duration // Activated - Activating
+}
+
+// ListUnitStartupTimes returns how long each unit took to start the last
+// time it was started, slowest first, like systemd-analyze blame. Units that
+// have not completed a start are left out.
+func (c *Conn) ListUnitStartupTimes(ctx context.Context) ([]UnitStartupTime, error) {
+	units, err := c.ListUnitsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	times := make([]UnitStartupTime, 0, len(units))
+	for _, u := range units {
+		t, err := c.unitStartupTime(ctx, u.Name)
+		if err != nil {
+			if ErrUnknownObject.Is(err) {
+				// the unit was unloaded in the meantime
+				continue
+			}
+			return nil, err
+		}
+		if t.Duration > 0 {
+			times = append(times, t)
+		}
+	}
+
+	sortStartupTimes(times)
+	return times, nil
+}
+
+func (c *Conn) unitStartupTime(ctx context.Context, name string) (UnitStartupTime, error) {
+	t := UnitStartupTime{Name: name}
+
+	usec := func(property string) (time.Duration, error) {
+		prop, err := c.getProperty(ctx, name, "org.freedesktop.systemd1.Unit", property)
+		if err != nil {
+			return 0, err
+		}
+		v, _ := prop.Value.Value().(uint64)
+		return time.Duration(v) * time.Microsecond, nil
+	}
+
+	var err error
+	if t.Activating, err = usec("InactiveExitTimestampMonotonic"); err != nil {
+		return t, err
+	}
+	if t.Activated, err = usec("ActiveEnterTimestampMonotonic"); err != nil {
+		return t, err
+	}
+	if t.Activating > 0 && t.Activated > t.Activating {
+		t.Duration = t.Activated - t.Activating
+	}
+	return t, nil
+}
+
+func sortStartupTimes(times []UnitStartupTime) {
+	sort.Slice(times, func(i, j int) bool {
+		if times[i].Duration != times[j].Duration {
+			return times[i].Duration > times[j].Duration
+		}
+		return times[i].Name < times[j].Name
+	})
+}
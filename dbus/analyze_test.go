@@ -0,0 +1,81 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBootTimes(t *testing.T) {
+	props := map[string]interface{}{
+		"FirmwareTimestampMonotonic":  uint64(5000000),
+		"LoaderTimestampMonotonic":    uint64(2000000),
+		"KernelTimestamp":             uint64(1600000000000000),
+		"InitRDTimestampMonotonic":    uint64(1500000),
+		"UserspaceTimestampMonotonic": uint64(4000000),
+		"FinishTimestampMonotonic":    uint64(10000000),
+	}
+
+	b := bootTimes(props)
+	for _, tt := range []struct {
+		name     string
+		got      time.Duration
+		expected time.Duration
+	}{
+		{"firmware", b.Firmware, 3 * time.Second},
+		{"loader", b.Loader, 2 * time.Second},
+		{"kernel", b.Kernel, 1500 * time.Millisecond},
+		{"initrd", b.InitRD, 2500 * time.Millisecond},
+		{"userspace", b.Userspace, 6 * time.Second},
+		{"total", b.Total, 15 * time.Second},
+	} {
+		if tt.got != tt.expected {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.expected)
+		}
+	}
+	if !b.Finished {
+		t.Error("boot should be finished")
+	}
+	if !b.KernelTimestamp.Equal(time.Unix(1600000000, 0)) {
+		t.Errorf("got kernel timestamp %v", b.KernelTimestamp)
+	}
+
+	// no firmware, loader or initrd information, boot still in progress
+	b = bootTimes(map[string]interface{}{
+		"UserspaceTimestampMonotonic": uint64(1000000),
+	})
+	if b.Kernel != time.Second || b.InitRD != 0 || b.Total != 0 || b.Finished {
+		t.Errorf("unexpected boot times for unfinished boot: %+v", b)
+	}
+}
+
+func TestSortStartupTimes(t *testing.T) {
+	times := []UnitStartupTime{
+		{Name: "b.service", Duration: time.Second},
+		{Name: "c.service", Duration: 3 * time.Second},
+		{Name: "a.service", Duration: time.Second},
+	}
+	sortStartupTimes(times)
+
+	var names []string
+	for _, st := range times {
+		names = append(names, st.Name)
+	}
+	if expected := []string{"c.service", "a.service", "b.service"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("got order %v, want %v", names, expected)
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Backend is the D-Bus connection a Conn talks to systemd through. The
+// constructors of this package use one implemented with
+// github.com/godbus/dbus by default. Other implementations, such as an
+// adapter to another D-Bus library or an in-process fake of systemd, can be
+// plugged in with DialOptions.Backend.
+//
+// The connection must be fully initialised: it must be authenticated and,
+// when connected to a bus, the org.freedesktop.DBus.Hello call must have
+// succeeded.
+type Backend interface {
+	// Call sends a method call and waits for its reply, returning the
+	// body of the reply. Error replies should be returned as *Error.
+	Call(ctx context.Context, call *BackendCall) ([]interface{}, error)
+	// Signal registers ch to receive the signals matched by the
+	// org.freedesktop.DBus.AddMatch calls made on the connection. When the
+	// connection is lost, ch must be closed.
+	Signal(ch chan<- *BackendSignal)
+	// Close closes the connection.
+	Close() error
+}
+
+// BackendCall is a method call sent through a Backend.
+type BackendCall struct {
+	Destination string          // The peer, e.g. org.freedesktop.systemd1
+	Path        dbus.ObjectPath // The object the method is called on
+	Method      string          // The interface and member, e.g. org.freedesktop.systemd1.Manager.StartUnit
+	Args        []interface{}   // The arguments of the call
+
+	// AllowInteractiveAuthorization is set if polkit may interactively ask
+	// the user for authorization, see SetInteractiveAuthorization.
+	AllowInteractiveAuthorization bool
+}
+
+// BackendSignal is a signal received through a Backend.
+type BackendSignal struct {
+	Sender string          // The unique name of the sender
+	Path   dbus.ObjectPath // The object emitting the signal
+	Name   string          // The interface and member, e.g. org.freedesktop.systemd1.Manager.JobRemoved
+	Body   []interface{}   // The arguments of the signal
+}
+
+// godbusBackend adapts a function dialing godbus connections to a function
+// dialing a Backend.
+func godbusBackend(dialBus func() (*dbus.Conn, error)) func() (Backend, error) {
+	return func() (Backend, error) {
+		conn, err := dialBus()
+		if err != nil {
+			return nil, err
+		}
+		return godbusConn{conn}, nil
+	}
+}
+
+// godbusConn is a Backend using a godbus connection.
+type godbusConn struct {
+	conn *dbus.Conn
+}
+
+func (c godbusConn) Call(ctx context.Context, call *BackendCall) ([]interface{}, error) {
+	var flags dbus.Flags
+	if call.AllowInteractiveAuthorization {
+		flags |= dbus.FlagAllowInteractiveAuthorization
+	}
+	reply := c.conn.Object(call.Destination, call.Path).CallWithContext(ctx, call.Method, flags, call.Args...)
+	return reply.Body, reply.Err
+}
+
+// Signal forwards the signals godbus receives to ch, and closes ch once
+// godbus closed its channel when the connection was lost.
+func (c godbusConn) Signal(ch chan<- *BackendSignal) {
+	signals := make(chan *dbus.Signal, cap(ch))
+	c.conn.Signal(signals)
+	go func() {
+		for s := range signals {
+			ch <- &BackendSignal{Sender: s.Sender, Path: s.Path, Name: s.Name, Body: s.Body}
+		}
+		close(ch)
+	}()
+}
+
+func (c godbusConn) Close() error {
+	return c.conn.Close()
+}
+
+var errMatchSignal = errors.New("dbus: AddMatchSignal and RemoveMatchSignal are not supported")
+
+// backendObject is the dbus.BusObject at path of the peer dest, called
+// through a Backend.
+type backendObject struct {
+	conn Backend
+	dest string
+	path dbus.ObjectPath
+}
+
+// busObject returns the org.freedesktop.DBus object of the bus conn.
+func busObject(conn Backend) backendObject {
+	return backendObject{conn: conn, dest: "org.freedesktop.DBus", path: "/org/freedesktop/DBus"}
+}
+
+func (o backendObject) newCall(method string, args []interface{}) *dbus.Call {
+	return &dbus.Call{Destination: o.dest, Path: o.path, Method: method, Args: args}
+}
+
+func (o backendObject) send(ctx context.Context, call *dbus.Call, flags dbus.Flags) {
+	call.Body, call.Err = o.conn.Call(ctx, &BackendCall{
+		Destination:                   o.dest,
+		Path:                          o.path,
+		Method:                        call.Method,
+		Args:                          call.Args,
+		AllowInteractiveAuthorization: flags&dbus.FlagAllowInteractiveAuthorization != 0,
+	})
+}
+
+func (o backendObject) Call(method string, flags dbus.Flags, args ...interface{}) *dbus.Call {
+	return o.CallWithContext(context.Background(), method, flags, args...)
+}
+
+func (o backendObject) CallWithContext(ctx context.Context, method string, flags dbus.Flags, args ...interface{}) *dbus.Call {
+	call := o.newCall(method, args)
+	o.send(ctx, call, flags)
+	return call
+}
+
+func (o backendObject) Go(method string, flags dbus.Flags, ch chan *dbus.Call, args ...interface{}) *dbus.Call {
+	return o.GoWithContext(context.Background(), method, flags, ch, args...)
+}
+
+func (o backendObject) GoWithContext(ctx context.Context, method string, flags dbus.Flags, ch chan *dbus.Call, args ...interface{}) *dbus.Call {
+	if ch == nil {
+		ch = make(chan *dbus.Call, 1)
+	}
+	call := o.newCall(method, args)
+	call.Done = ch
+	go func() {
+		o.send(ctx, call, flags)
+		ch <- call
+	}()
+	return call
+}
+
+// AddMatchSignal is not supported: the package calls
+// org.freedesktop.DBus.AddMatch with its own rules instead.
+func (o backendObject) AddMatchSignal(iface, member string, options ...dbus.MatchOption) *dbus.Call {
+	call := o.newCall("org.freedesktop.DBus.AddMatch", nil)
+	call.Err = errMatchSignal
+	return call
+}
+
+// RemoveMatchSignal is not supported, see AddMatchSignal.
+func (o backendObject) RemoveMatchSignal(iface, member string, options ...dbus.MatchOption) *dbus.Call {
+	call := o.newCall("org.freedesktop.DBus.RemoveMatch", nil)
+	call.Err = errMatchSignal
+	return call
+}
+
+// GetProperty returns the property p, in interface.member notation.
+func (o backendObject) GetProperty(p string) (dbus.Variant, error) {
+	var v dbus.Variant
+	iface, prop, err := splitProperty(p)
+	if err != nil {
+		return v, err
+	}
+	err = o.Call("org.freedesktop.DBus.Properties.Get", 0, iface, prop).Store(&v)
+	return v, err
+}
+
+// SetProperty sets the property p, in interface.member notation.
+func (o backendObject) SetProperty(p string, v interface{}) error {
+	iface, prop, err := splitProperty(p)
+	if err != nil {
+		return err
+	}
+	return o.Call("org.freedesktop.DBus.Properties.Set", 0, iface, prop, v).Err
+}
+
+func (o backendObject) Destination() string {
+	return o.dest
+}
+
+func (o backendObject) Path() dbus.ObjectPath {
+	return o.path
+}
+
+// splitProperty splits a property such as org.freedesktop.systemd1.Job.Id
+// into its interface and its name.
+func splitProperty(p string) (string, string, error) {
+	i := strings.LastIndex(p, ".")
+	if i < 0 || i == len(p)-1 {
+		return "", "", errors.New("dbus: invalid property " + p)
+	}
+	return p[:i], p[i+1:], nil
+}
@@ -0,0 +1,297 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// fakeBackend is an in-process Backend answering a few manager methods.
+type fakeBackend struct {
+	sync.Mutex
+	signals chan<- *BackendSignal
+	closed  bool
+}
+
+func (b *fakeBackend) Call(ctx context.Context, call *BackendCall) ([]interface{}, error) {
+	reply := fakeObject{path: call.Path}.CallWithContext(ctx, call.Method, 0, call.Args...)
+	return reply.Body, reply.Err
+}
+
+func (b *fakeBackend) Signal(ch chan<- *BackendSignal) {
+	b.Lock()
+	defer b.Unlock()
+	b.signals = ch
+}
+
+func (b *fakeBackend) Close() error {
+	b.Lock()
+	defer b.Unlock()
+	if !b.closed && b.signals != nil {
+		close(b.signals)
+	}
+	b.closed = true
+	return nil
+}
+
+func (b *fakeBackend) emit(name string, body ...interface{}) {
+	b.emitSignal(&BackendSignal{Name: name, Body: body})
+}
+
+func (b *fakeBackend) emitSignal(signal *BackendSignal) {
+	b.Lock()
+	defer b.Unlock()
+	b.signals <- signal
+}
+
+type fakeObject struct {
+	dbus.BusObject
+	path dbus.ObjectPath
+}
+
+func (o fakeObject) CallWithContext(ctx context.Context, method string, flags dbus.Flags, args ...interface{}) *dbus.Call {
+	call := &dbus.Call{Method: method}
+	switch method {
+	case "org.freedesktop.systemd1.Manager.GetUnitFileState":
+		call.Body = []interface{}{"enabled"}
+	case "org.freedesktop.systemd1.Manager.ListUnits":
+		var units [][]interface{}
+		for _, name := range []string{"foo.service", "bar.service", "baz.service"} {
+			units = append(units, []interface{}{name, "", "loaded", "active", "running", "", unitPath(name), uint32(0), "", dbus.ObjectPath("/")})
+		}
+		call.Body = []interface{}{units}
+	case "org.freedesktop.systemd1.Manager.StartUnit":
+		call.Body = []interface{}{jobPath(7)}
+	case "org.freedesktop.systemd1.Manager.EnqueueUnitJob":
+		call.Body = []interface{}{uint32(7), jobPath(7), "foo.service", unitPath("foo.service"), "start",
+			[][]interface{}{{uint32(8), jobPath(8), "bar.service", unitPath("bar.service"), "start"}}}
+	case "org.freedesktop.systemd1.Manager.EnqueueMarkedJobs":
+		call.Body = []interface{}{[]dbus.ObjectPath{jobPath(9), jobPath(10)}}
+	case "org.freedesktop.DBus.Properties.GetAll":
+		id := pathBusUnescape(strings.TrimPrefix(string(o.path), "/org/freedesktop/systemd1/unit/"))
+		call.Body = []interface{}{map[string]dbus.Variant{
+			"Id":          dbus.MakeVariant(id),
+			"ActiveState": dbus.MakeVariant("active"),
+		}}
+	case "org.freedesktop.DBus.Properties.Get":
+		if len(args) == 2 && args[1] == "ActiveState" {
+			call.Body = []interface{}{dbus.MakeVariant("active")}
+		} else {
+			// the Result property of failed units
+			call.Body = []interface{}{dbus.MakeVariant("start-limit-hit")}
+		}
+	}
+	return call
+}
+
+func (o fakeObject) Call(method string, flags dbus.Flags, args ...interface{}) *dbus.Call {
+	return o.CallWithContext(context.Background(), method, flags, args...)
+}
+
+// newFakeConn returns a Conn using fakeBackend connections, and the
+// connections: the first one is used for method calls, the second one for
+// signals.
+func newFakeConn(t *testing.T) (*Conn, []*fakeBackend) {
+	var backends []*fakeBackend
+	conn, err := NewConnectionWithOptions(context.Background(), DialOptions{
+		Backend: func(ctx context.Context) (Backend, error) {
+			b := &fakeBackend{}
+			backends = append(backends, b)
+			return b, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, backends
+}
+
+func TestBackend(t *testing.T) {
+	var backends []*fakeBackend
+	ctx := context.Background()
+	conn, err := NewConnectionWithOptions(ctx, DialOptions{
+		Backend: func(ctx context.Context) (Backend, error) {
+			b := &fakeBackend{}
+			backends = append(backends, b)
+			return b, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if len(backends) != 2 {
+		t.Fatalf("got %d connections, want 2", len(backends))
+	}
+
+	state, err := conn.GetUnitFileState(ctx, "foo.service")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state != "enabled" {
+		t.Errorf("got state %q, want enabled", state)
+	}
+
+	job, err := conn.StartUnitAsync(ctx, "foo.service", "replace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	backends[1].emit("org.freedesktop.systemd1.Manager.JobRemoved", uint32(7), jobPath(7), "foo.service", "done")
+	result, err := job.Wait(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != "done" {
+		t.Errorf("got result %q, want done", result)
+	}
+}
+
+func TestBackendEnqueueUnitJob(t *testing.T) {
+	conn, backends := newFakeConn(t)
+	defer conn.Close()
+	ctx := context.Background()
+
+	job, affected, err := conn.EnqueueUnitJob(ctx, "foo.service", "start", "replace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.ID != 7 || job.Unit != "foo.service" || job.Type != "start" {
+		t.Errorf("unexpected job %+v", job)
+	}
+	if len(affected) != 1 || affected[0].ID != 8 || affected[0].Unit != "bar.service" {
+		t.Fatalf("unexpected affected jobs %+v", affected)
+	}
+
+	backends[1].emit("org.freedesktop.systemd1.Manager.JobRemoved", uint32(8), jobPath(8), "bar.service", "done")
+	backends[1].emit("org.freedesktop.systemd1.Manager.JobRemoved", uint32(7), jobPath(7), "foo.service", "dependency")
+	results, err := WaitJobs(ctx, job.Job, affected[0].Job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(results, []string{"dependency", "done"}) {
+		t.Errorf("got results %v", results)
+	}
+}
+
+func TestBackendEnqueueMarkedJobs(t *testing.T) {
+	conn, backends := newFakeConn(t)
+	defer conn.Close()
+	ctx := context.Background()
+
+	jobs, err := conn.EnqueueMarkedJobs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 2 || jobs[0].ID != 9 || jobs[1].ID != 10 {
+		t.Fatalf("unexpected jobs %+v", jobs)
+	}
+
+	backends[1].emit("org.freedesktop.systemd1.Manager.JobRemoved", uint32(10), jobPath(10), "bar.service", "done")
+	backends[1].emit("org.freedesktop.systemd1.Manager.JobRemoved", uint32(9), jobPath(9), "foo.service", "done")
+	results, err := WaitJobs(ctx, jobs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(results, []string{"done", "done"}) {
+		t.Errorf("got results %v", results)
+	}
+}
+
+func TestBackendSetSubscribers(t *testing.T) {
+	conn, backends := newFakeConn(t)
+	defer conn.Close()
+
+	// run with -race: subscribers are set while signals are dispatched
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			conn.SetPropertiesSubscriber(make(chan *PropertiesUpdate, 1), make(chan error, 1))
+			conn.SetSubStateSubscriber(nil, nil)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		backends[1].emitSignal(&BackendSignal{
+			Path: unitPath("foo.service"),
+			Name: "org.freedesktop.DBus.Properties.PropertiesChanged",
+			Body: []interface{}{"org.freedesktop.systemd1.Unit", map[string]dbus.Variant{}, []string{}},
+		})
+	}
+	<-done
+}
+
+func TestBackendObject(t *testing.T) {
+	obj := backendObject{conn: &fakeBackend{}, dest: "org.freedesktop.systemd1", path: unitPath("foo.service")}
+	v, err := obj.GetProperty("org.freedesktop.systemd1.Unit.ActiveState")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Value() != "active" {
+		t.Errorf("got ActiveState %v, want active", v)
+	}
+	if _, err := obj.GetProperty("ActiveState"); err == nil {
+		t.Error("expected an error for a property without an interface")
+	}
+
+	call := <-obj.Go("org.freedesktop.systemd1.Manager.GetUnitFileState", 0, nil, "foo.service").Done
+	var state string
+	if err := call.Store(&state); err != nil || state != "enabled" {
+		t.Errorf("got state %q and error %v, want enabled", state, err)
+	}
+}
+
+func TestBackendSignalBuffer(t *testing.T) {
+	var mu sync.Mutex
+	var backends []*fakeBackend
+	conn, err := NewConnectionWithOptions(context.Background(), DialOptions{
+		Backend: func(ctx context.Context) (Backend, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			b := &fakeBackend{}
+			backends = append(backends, b)
+			return b, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	reconnected := make(chan *ReconnectUpdate, 1)
+	conn.SetReconnectSubscriber(reconnected)
+	conn.SetSignalBuffer(7)
+
+	// losing the signal connection makes conn dial new connections
+	backends[1].Close()
+	<-reconnected
+
+	mu.Lock()
+	defer mu.Unlock()
+	for i, want := range []int{signalBuffer, 7} {
+		b := backends[2*i+1]
+		b.Lock()
+		if got := cap(b.signals); got != want {
+			t.Errorf("signal connection %d: got buffer %d, want %d", i, got, want)
+		}
+		b.Unlock()
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"context"
+	"sync"
+)
+
+// unitsPropertiesConcurrency bounds the GetAll calls GetUnitsProperties has
+// in flight.
+const unitsPropertiesConcurrency = 16
+
+// GetUnitsProperties returns the properties of the org.freedesktop.systemd1.Unit
+// interface of each of the units names, keyed by unit name. If fields is not
+// empty, only the properties named in it are returned. The properties are
+// fetched with several calls in flight at once, which is much faster than
+// calling GetUnitPropertiesContext for each unit in turn when there are many
+// of them.
+//
+// The first error encountered is returned, and the remaining calls are
+// cancelled.
+func (c *Conn) GetUnitsProperties(ctx context.Context, names []string, fields []string) (map[string]map[string]interface{}, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	result := make(map[string]map[string]interface{}, len(names))
+	tokens := make(chan struct{}, unitsPropertiesConcurrency)
+
+	for _, name := range names {
+		select {
+		case tokens <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			defer func() { <-tokens }()
+
+			props, err := c.getProperties(ctx, unitPath(name), "org.freedesktop.systemd1.Unit")
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				return
+			}
+			result[name] = filterProperties(props, fields)
+		}(name)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// filterProperties returns the properties in props named in fields, or props
+// if fields is empty.
+func filterProperties(props map[string]interface{}, fields []string) map[string]interface{} {
+	if len(fields) == 0 {
+		return props
+	}
+	out := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		if v, ok := props[f]; ok {
+			out[f] = v
+		}
+	}
+	return out
+}
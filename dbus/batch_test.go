@@ -0,0 +1,64 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestGetUnitsProperties(t *testing.T) {
+	conn, _ := newFakeConn(t)
+	defer conn.Close()
+
+	var names []string
+	for i := 0; i < 3*unitsPropertiesConcurrency; i++ {
+		names = append(names, fmt.Sprintf("unit-%d.service", i))
+	}
+
+	props, err := conn.GetUnitsProperties(context.Background(), names, []string{"Id", "Missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(props) != len(names) {
+		t.Fatalf("got properties of %d units, want %d", len(props), len(names))
+	}
+	for _, name := range names {
+		if expected := map[string]interface{}{"Id": name}; !reflect.DeepEqual(props[name], expected) {
+			t.Errorf("got properties %v for %s, want %v", props[name], name, expected)
+		}
+	}
+
+	props, err = conn.GetUnitsProperties(context.Background(), names[:1], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(props[names[0]]) != 2 {
+		t.Errorf("got properties %v, want all properties", props[names[0]])
+	}
+}
+
+func TestGetUnitsPropertiesCancel(t *testing.T) {
+	conn, _ := newFakeConn(t)
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := conn.GetUnitsProperties(ctx, []string{"foo.service"}, nil); err != context.Canceled {
+		t.Fatalf("got error %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// GetUnitByControlGroup returns the object path of the unit owning the
+// control group cgroup, given relative to the root of the cgroup hierarchy,
+// e.g. "/system.slice/foo.service". Control groups nested below the one of a
+// unit, such as those delegated to a container manager, belong to that unit.
+// It requires systemd 250 or newer.
+func (c *Conn) GetUnitByControlGroup(ctx context.Context, cgroup string) (dbus.ObjectPath, error) {
+	var path dbus.ObjectPath
+	err := c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.GetUnitByControlGroup", 0, cgroup).Store(&path)
+	if err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// GetUnitNameByControlGroup returns the name of the unit owning the control
+// group cgroup, see GetUnitByControlGroup. Together with ParseProcCgroup, it
+// attributes a process to its unit from the contents of /proc/<pid>/cgroup:
+//
+//	data, err := ioutil.ReadFile("/proc/1234/cgroup")
+//	...
+//	cgroup, err := dbus.ParseProcCgroup(data)
+//	...
+//	name, err := conn.GetUnitNameByControlGroup(ctx, cgroup)
+func (c *Conn) GetUnitNameByControlGroup(ctx context.Context, cgroup string) (string, error) {
+	path, err := c.GetUnitByControlGroup(ctx, cgroup)
+	if err != nil {
+		return "", err
+	}
+	return c.unitID(ctx, path)
+}
+
+// ParseProcCgroup returns the control group path managed by systemd in data,
+// the contents of a /proc/<pid>/cgroup file, as described in cgroups(7). The
+// path of the unified (cgroup v2) hierarchy is preferred; on systems using
+// the legacy hierarchy only, the path of systemd's named hierarchy
+// (name=systemd) is returned.
+func ParseProcCgroup(data []byte) (string, error) {
+	var unified, named string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		// hierarchy-ID:controller-list:cgroup-path
+		fields := strings.SplitN(scanner.Text(), ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		switch {
+		case fields[0] == "0" && fields[1] == "":
+			unified = fields[2]
+		case fields[1] == "name=systemd":
+			named = fields[2]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	if unified != "" {
+		return unified, nil
+	}
+	if named != "" {
+		return named, nil
+	}
+	return "", errors.New("no systemd control group found")
+}
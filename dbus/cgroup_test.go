@@ -0,0 +1,48 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"testing"
+)
+
+func TestParseProcCgroup(t *testing.T) {
+	tests := []struct {
+		data     string
+		expected string
+	}{
+		{"0::/system.slice/foo.service\n", "/system.slice/foo.service"},
+		{
+			"12:cpu,cpuacct:/system.slice/foo.service\n1:name=systemd:/system.slice/foo.service\n",
+			"/system.slice/foo.service",
+		},
+		{
+			"1:name=systemd:/user.slice/user-1000.slice\n0::/system.slice/foo.service/payload\n",
+			"/system.slice/foo.service/payload",
+		},
+	}
+	for _, tt := range tests {
+		cgroup, err := ParseProcCgroup([]byte(tt.data))
+		if err != nil {
+			t.Errorf("ParseProcCgroup(%q): %v", tt.data, err)
+		} else if cgroup != tt.expected {
+			t.Errorf("ParseProcCgroup(%q) = %q, want %q", tt.data, cgroup, tt.expected)
+		}
+	}
+
+	if _, err := ParseProcCgroup([]byte("12:cpu,cpuacct:/\n")); err == nil {
+		t.Error("expected an error without a systemd control group")
+	}
+}
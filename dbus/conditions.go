@@ -0,0 +1,115 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"context"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// UnitCondition is a Condition...= or Assert...= setting of a unit, together
+// with the result of its last check.
+type UnitCondition struct {
+	Type      string // The setting, e.g. ConditionPathExists
+	Trigger   bool   // Whether the condition is a triggering one, prefixed with "|"
+	Negate    bool   // Whether the condition is negated, prefixed with "!"
+	Parameter string // The argument of the setting, e.g. a path
+	// State is 0 if the condition has not been checked yet, positive if
+	// it was satisfied and negative if it was not.
+	State int32
+}
+
+// Checked reports whether the condition has been checked.
+func (c UnitCondition) Checked() bool {
+	return c.State != 0
+}
+
+// Failed reports whether the condition was checked and not satisfied.
+func (c UnitCondition) Failed() bool {
+	return c.State < 0
+}
+
+// UnitConditions holds the conditions and asserts of a unit, and the results
+// of their last check, when the unit was last started.
+type UnitConditions struct {
+	Conditions         []UnitCondition
+	ConditionResult    bool
+	ConditionTimestamp time.Time // When the conditions were last checked, zero if never
+
+	Asserts         []UnitCondition
+	AssertResult    bool
+	AssertTimestamp time.Time // When the asserts were last checked, zero if never
+}
+
+// FailedConditions returns the conditions that were not satisfied, which
+// explain why a unit was skipped.
+func (u *UnitConditions) FailedConditions() []UnitCondition {
+	return failedConditions(u.Conditions)
+}
+
+// FailedAsserts returns the asserts that were not satisfied, which explain
+// why the start of a unit failed.
+func (u *UnitConditions) FailedAsserts() []UnitCondition {
+	return failedConditions(u.Asserts)
+}
+
+func failedConditions(conditions []UnitCondition) []UnitCondition {
+	var failed []UnitCondition
+	for _, c := range conditions {
+		if c.Failed() {
+			failed = append(failed, c)
+		}
+	}
+	return failed
+}
+
+// GetUnitConditions returns the conditions and asserts of the unit name, see
+// the Conditions, Asserts and related properties in org.freedesktop.systemd1(5).
+func (c *Conn) GetUnitConditions(ctx context.Context, name string) (*UnitConditions, error) {
+	props, err := c.getProperties(ctx, unitPath(name), "org.freedesktop.systemd1.Unit")
+	if err != nil {
+		return nil, err
+	}
+
+	u := &UnitConditions{}
+	if u.Conditions, err = parseConditions(props["Conditions"]); err != nil {
+		return nil, err
+	}
+	if u.Asserts, err = parseConditions(props["Asserts"]); err != nil {
+		return nil, err
+	}
+	u.ConditionResult, _ = props["ConditionResult"].(bool)
+	u.AssertResult, _ = props["AssertResult"].(bool)
+	u.ConditionTimestamp = usecTime(props["ConditionTimestamp"])
+	u.AssertTimestamp = usecTime(props["AssertTimestamp"])
+
+	return u, nil
+}
+
+// parseConditions converts the value of a property of type a(sbbsi).
+func parseConditions(v interface{}) ([]UnitCondition, error) {
+	result, _ := v.([][]interface{})
+
+	conditions := make([]UnitCondition, len(result))
+	for i, entry := range result {
+		c := &conditions[i]
+		if err := dbus.Store(entry, &c.Type, &c.Trigger, &c.Negate, &c.Parameter, &c.State); err != nil {
+			return nil, err
+		}
+	}
+	return conditions, nil
+}
@@ -0,0 +1,53 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseConditions(t *testing.T) {
+	raw := [][]interface{}{
+		{"ConditionPathExists", false, true, "/etc/foo", int32(-1)},
+		{"ConditionVirtualization", true, false, "container", int32(1)},
+		{"ConditionUser", false, false, "root", int32(0)},
+	}
+	conditions, err := parseConditions(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []UnitCondition{
+		{Type: "ConditionPathExists", Negate: true, Parameter: "/etc/foo", State: -1},
+		{Type: "ConditionVirtualization", Trigger: true, Parameter: "container", State: 1},
+		{Type: "ConditionUser", Parameter: "root"},
+	}
+	if !reflect.DeepEqual(conditions, expected) {
+		t.Fatalf("got %+v, want %+v", conditions, expected)
+	}
+
+	u := &UnitConditions{Conditions: conditions}
+	if failed := u.FailedConditions(); len(failed) != 1 || failed[0].Type != "ConditionPathExists" {
+		t.Errorf("unexpected failed conditions %+v", failed)
+	}
+	if conditions[2].Checked() {
+		t.Errorf("%s should not be checked", conditions[2].Type)
+	}
+
+	if _, err := parseConditions([][]interface{}{{"ConditionUser", "bogus"}}); err == nil {
+		t.Error("expected an error for a malformed condition")
+	}
+}
@@ -16,12 +16,17 @@
 package dbus
 
 import (
+	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/godbus/dbus/v5"
 )
@@ -31,6 +36,20 @@ const (
 	num          = `0123456789`
 	alphanum     = alpha + num
 	signalBuffer = 100
+
+	// systemdPrivateSocket is the address of systemd's private socket
+	systemdPrivateSocket = "unix:path=/run/systemd/private"
+
+	// closeTimeout bounds the calls made to tear down a connection
+	closeTimeout = time.Second
+)
+
+// Match rules for the signals the connection listens to
+const (
+	jobRemovedMatch        = "type='signal', interface='org.freedesktop.systemd1.Manager', member='JobRemoved'"
+	unitNewMatch           = "type='signal',interface='org.freedesktop.systemd1.Manager',member='UnitNew'"
+	unitRemovedMatch       = "type='signal',interface='org.freedesktop.systemd1.Manager',member='UnitRemoved'"
+	propertiesChangedMatch = "type='signal',interface='org.freedesktop.DBus.Properties',member='PropertiesChanged'"
 )
 
 // needsEscape checks whether a byte in a potential dbus ObjectPath needs to be escaped
@@ -82,23 +101,46 @@ func pathBusUnescape(path string) string {
 	return string(n)
 }
 
-// Conn is a connection to systemd's dbus endpoint.
+// Conn is a connection to systemd's dbus endpoint. It is safe for concurrent
+// use: method calls, signal dispatching and job tracking are synchronized
+// internally. See SetMaxConcurrentCalls and Pool for callers issuing many
+// calls at once.
 type Conn struct {
+	// connLock protects the connections below, which are replaced when
+	// the connection to the bus is re-established
+	connLock sync.RWMutex
+
 	// sysconn/sysobj are only used to call dbus methods
-	sysconn *dbus.Conn
-	sysobj  dbus.BusObject
+	sysconn Backend
+	sysobj  mappedObject
 
 	// sigconn/sigobj are only used to receive dbus signals
-	sigconn *dbus.Conn
-	sigobj  dbus.BusObject
+	sigconn Backend
+	sigobj  mappedObject
 
-	jobListener struct {
-		jobs map[dbus.ObjectPath]chan<- string
-		sync.Mutex
-	}
+	// subscribed records whether Subscribe has been called, so that the
+	// subscription can be restored after reconnecting. subscriptions counts
+	// the users of the systemd subscription: Subscribe, while subscribed,
+	// and each WatchUnitProperties call in progress. Both are guarded by
+	// subscriptionLock.
+	subscriptionLock sync.Mutex
+	subscribed       bool
+	subscriptions    int
+
+	// dialBus and ctx are kept to re-establish lost connections
+	dialBus func() (Backend, error)
+	ctx     context.Context
+
+	closeOnce  sync.Once
+	closed     chan struct{}
+	dispatched chan struct{} // closed when the dispatch goroutine exits
+
+	jobListener        *jobTracker
+	signals            *signalQueue
 	subStateSubscriber struct {
 		updateCh chan<- *SubStateUpdate
 		errCh    chan<- error
+		set      int32 // 1 if updateCh is set, accessed atomically
 		sync.Mutex
 		ignore      map[dbus.ObjectPath]int64
 		cleanIgnore int64
@@ -106,53 +148,205 @@ type Conn struct {
 	propertiesSubscriber struct {
 		updateCh chan<- *PropertiesUpdate
 		errCh    chan<- error
+		set      int32 // 1 if updateCh is set, accessed atomically
+		sync.Mutex
+	}
+	reconnectSubscriber struct {
+		updateCh chan<- *ReconnectUpdate
+		sync.Mutex
+	}
+	setSubscribers struct {
+		sets map[*SubscriptionSet]bool
 		sync.Mutex
 	}
+	propertiesWatches struct {
+		watches map[*propertiesWatch]bool
+		sync.Mutex
+	}
+
+	// interactiveAuth is set to 1 if method calls allow interactive
+	// authorization, accessed atomically
+	interactiveAuth int32
+
+	hooks atomic.Value // of hooksValue, see SetHooks
+
+	callLimit atomic.Value // of callLimitValue, see SetMaxConcurrentCalls
 }
 
 // New establishes a connection to any available bus and authenticates.
 // Callers should call Close() when done with the connection.
+//
+// Deprecated: use NewWithContext instead.
 func New() (*Conn, error) {
-	conn, err := NewSystemConnection()
+	return NewWithContext(context.Background())
+}
+
+// NewWithContext is like New, but the returned connection is bound to
+// ctx: cancelling ctx closes the connection.
+func NewWithContext(ctx context.Context) (*Conn, error) {
+	conn, err := NewSystemConnectionContext(ctx)
 	if err != nil && os.Geteuid() == 0 {
-		return NewSystemdConnection()
+		return NewSystemdConnectionContext(ctx)
 	}
 	return conn, err
 }
 
 // NewSystemConnection establishes a connection to the system bus and authenticates.
 // Callers should call Close() when done with the connection
+//
+// Deprecated: use NewSystemConnectionContext instead.
 func NewSystemConnection() (*Conn, error) {
-	return NewConnection(func() (*dbus.Conn, error) {
-		return dbusAuthHelloConnection(dbus.SystemBusPrivate)
+	return NewSystemConnectionContext(context.Background())
+}
+
+// NewSystemConnectionContext is like NewSystemConnection, but the returned connection is bound to
+// ctx: cancelling ctx closes the connection.
+func NewSystemConnectionContext(ctx context.Context) (*Conn, error) {
+	return newConnection(ctx, func() (*dbus.Conn, error) {
+		return dbusAuthHelloConnection(ctx, dbus.SystemBusPrivate)
 	})
 }
 
 // NewUserConnection establishes a connection to the session bus and
 // authenticates. This can be used to connect to systemd user instances.
+// If DBUS_SESSION_BUS_ADDRESS is not set, the user bus socket in
+// $XDG_RUNTIME_DIR is tried before falling back to the default session bus
+// discovery.
 // Callers should call Close() when done with the connection.
+//
+// Deprecated: use NewUserConnectionContext instead.
 func NewUserConnection() (*Conn, error) {
-	return NewConnection(func() (*dbus.Conn, error) {
-		return dbusAuthHelloConnection(dbus.SessionBusPrivate)
+	return NewUserConnectionContext(context.Background())
+}
+
+// NewUserConnectionContext is like NewUserConnection, but the returned connection is bound to
+// ctx: cancelling ctx closes the connection.
+func NewUserConnectionContext(ctx context.Context) (*Conn, error) {
+	return newConnection(ctx, func() (*dbus.Conn, error) {
+		return dbusAuthHelloConnection(ctx, sessionBusPrivate)
 	})
 }
 
+// userBusAddress returns the address of the user bus socket in
+// $XDG_RUNTIME_DIR, if the session bus address is not already configured
+// through the environment and the socket exists.
+func userBusAddress() (string, bool) {
+	if os.Getenv("DBUS_SESSION_BUS_ADDRESS") != "" {
+		return "", false
+	}
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		return "", false
+	}
+	bus := filepath.Join(dir, "bus")
+	if _, err := os.Stat(bus); err != nil {
+		return "", false
+	}
+	return "unix:path=" + bus, true
+}
+
+func sessionBusPrivate(opts ...dbus.ConnOption) (*dbus.Conn, error) {
+	if address, ok := userBusAddress(); ok {
+		return dbus.Dial(address, opts...)
+	}
+	return dbus.SessionBusPrivate(opts...)
+}
+
 // NewSystemdConnection establishes a private, direct connection to systemd.
-// This can be used for communicating with systemd without a dbus daemon.
+// This can be used for communicating with systemd without a dbus daemon,
+// e.g. during early boot or from a container's init. Only root may connect
+// to the private socket.
 // Callers should call Close() when done with the connection.
+//
+// Deprecated: use NewSystemdConnectionContext instead.
 func NewSystemdConnection() (*Conn, error) {
-	return NewConnection(func() (*dbus.Conn, error) {
+	return NewSystemdConnectionContext(context.Background())
+}
+
+// NewSystemdConnectionContext is like NewSystemdConnection, but the returned connection is bound to
+// ctx: cancelling ctx closes the connection.
+func NewSystemdConnectionContext(ctx context.Context) (*Conn, error) {
+	return newConnection(ctx, func() (*dbus.Conn, error) {
 		// We skip Hello when talking directly to systemd.
-		return dbusAuthConnection(func(opts ...dbus.ConnOption) (*dbus.Conn, error) {
-			return dbus.Dial("unix:path=/run/systemd/private")
+		return dbusAuthConnection(ctx, func(opts ...dbus.ConnOption) (*dbus.Conn, error) {
+			return dbus.Dial(systemdPrivateSocket, opts...)
 		})
 	})
 }
 
-// Close closes an established connection
+// Close closes an established connection. The connection is unsubscribed
+// from systemd events, the signal dispatching goroutine and the goroutines
+// started by SubscribeUnits are stopped, and the channels returned by
+// SubscribeUnits are closed. Channels supplied by the caller are left open.
+// Close may be called more than once.
 func (c *Conn) Close() {
-	c.sysconn.Close()
-	c.sigconn.Close()
+	c.closeOnce.Do(func() {
+		close(c.closed)
+
+		ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+		defer cancel()
+
+		c.subscriptionLock.Lock()
+		c.connLock.Lock()
+		c.closeSubscription(ctx)
+		c.bus(c.sigconn).CallWithContext(ctx, "org.freedesktop.DBus.RemoveMatch", 0, jobRemovedMatch)
+		c.sysconn.Close()
+		c.sigconn.Close()
+		c.connLock.Unlock()
+		c.subscriptionLock.Unlock()
+
+		<-c.dispatched
+	})
+}
+
+// DialOptions configures how NewConnectionWithOptions connects to systemd.
+type DialOptions struct {
+	// Address is the D-Bus address to connect to, for example
+	// "unix:path=/run/dbus/system_bus_socket" or
+	// "tcp:host=localhost,port=5555".
+	Address string
+	// Direct is set if Address is a direct connection to systemd, such as
+	// "unix:path=/run/systemd/private", rather than a bus.
+	Direct bool
+	// Auth lists the authentication methods to try. By default, only
+	// EXTERNAL with the uid of the process is used.
+	Auth []dbus.Auth
+	// ConnOptions are passed on to dbus.Dial.
+	ConnOptions []dbus.ConnOption
+	// Backend, if set, is used to establish the connections instead of
+	// dialing Address with godbus; the other options are ignored then. It
+	// is called again to re-establish lost connections, and must return
+	// independent connections.
+	Backend func(ctx context.Context) (Backend, error)
+}
+
+// NewConnectionWithOptions establishes a connection to systemd as described
+// by opts, for example through a socket mounted into a container. The
+// returned connection is bound to ctx: cancelling ctx closes the connection.
+func NewConnectionWithOptions(ctx context.Context, opts DialOptions) (*Conn, error) {
+	if opts.Backend != nil {
+		return newBackendConnection(ctx, func() (Backend, error) {
+			return opts.Backend(ctx)
+		})
+	}
+	if opts.Address == "" {
+		return nil, errors.New("no D-Bus address given")
+	}
+
+	createBus := func(connOpts ...dbus.ConnOption) (*dbus.Conn, error) {
+		return dbus.Dial(opts.Address, append(connOpts, opts.ConnOptions...)...)
+	}
+	return newConnection(ctx, func() (*dbus.Conn, error) {
+		conn, err := dbusAuthConnectionWith(ctx, createBus, opts.Auth)
+		if err != nil || opts.Direct {
+			return conn, err
+		}
+		if err = conn.Hello(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return conn, nil
+	})
 }
 
 // NewConnection establishes a connection to a bus using a caller-supplied function.
@@ -160,7 +354,18 @@ func (c *Conn) Close() {
 // The supplied function may be called multiple times, and should return independent connections.
 // The returned connection must be fully initialised: the org.freedesktop.DBus.Hello call must have succeeded,
 // and any authentication should be handled by the function.
+//
+// If the connection to the bus is lost, e.g. because dbus-daemon was
+// restarted, dialBus is used to re-establish it. See SetReconnectSubscriber.
 func NewConnection(dialBus func() (*dbus.Conn, error)) (*Conn, error) {
+	return newConnection(context.Background(), dialBus)
+}
+
+func newConnection(ctx context.Context, dialBus func() (*dbus.Conn, error)) (*Conn, error) {
+	return newBackendConnection(ctx, godbusBackend(dialBus))
+}
+
+func newBackendConnection(ctx context.Context, dialBus func() (Backend, error)) (*Conn, error) {
 	sysconn, err := dialBus()
 	if err != nil {
 		return nil, err
@@ -177,40 +382,142 @@ func NewConnection(dialBus func() (*dbus.Conn, error)) (*Conn, error) {
 		sysobj:  systemdObject(sysconn),
 		sigconn: sigconn,
 		sigobj:  systemdObject(sigconn),
+		dialBus: dialBus,
+		ctx:     ctx,
+		closed:  make(chan struct{}),
+
+		dispatched: make(chan struct{}),
 	}
 
 	c.subStateSubscriber.ignore = make(map[dbus.ObjectPath]int64)
-	c.jobListener.jobs = make(map[dbus.ObjectPath]chan<- string)
+	c.jobListener = newJobTracker(c.closed)
+	c.signals = newSignalQueue(signalBuffer)
+
+	ch := c.newSignalChannel()
+	c.sigconn.Signal(ch)
 
 	// Setup the listeners on jobs so that we can get completions
-	c.sigconn.BusObject().Call("org.freedesktop.DBus.AddMatch", 0,
-		"type='signal', interface='org.freedesktop.systemd1.Manager', member='JobRemoved'")
+	c.addJobMatch(c.sigconn)
 
-	c.dispatch()
+	c.dispatch(ch)
 	return c, nil
 }
 
+func (c *Conn) addJobMatch(sigconn Backend) error {
+	return c.bus(sigconn).Call("org.freedesktop.DBus.AddMatch", 0, jobRemovedMatch).Store()
+}
+
+// signalConn returns the connection used to receive signals, and the systemd
+// manager object on it.
+func (c *Conn) signalConn() (Backend, dbus.BusObject) {
+	c.connLock.RLock()
+	defer c.connLock.RUnlock()
+	return c.sigconn, c.hooked(c.sigobj)
+}
+
+// bus returns the org.freedesktop.DBus object of conn, through which the
+// match rules of the signals are set.
+func (c *Conn) bus(conn Backend) dbus.BusObject {
+	return c.hooked(mappedObject{BusObject: busObject(conn)})
+}
+
+// manager returns the systemd manager object used for method calls.
+func (c *Conn) manager() dbus.BusObject {
+	c.connLock.RLock()
+	defer c.connLock.RUnlock()
+	return c.hooked(c.sysobj)
+}
+
+// object returns the systemd object at path, such as a unit or a job.
+func (c *Conn) object(path dbus.ObjectPath) dbus.BusObject {
+	c.connLock.RLock()
+	defer c.connLock.RUnlock()
+	return c.hooked(mappedObject{BusObject: backendObject{conn: c.sysconn, dest: "org.freedesktop.systemd1", path: path}})
+}
+
+// hooked returns obj with the call flags, hooks and call limit currently
+// set on c.
+func (c *Conn) hooked(obj mappedObject) mappedObject {
+	obj.flags = c.callFlags()
+	obj.hooks = c.loadHooks()
+	obj.limit = c.loadCallLimit()
+	return obj
+}
+
+// SetInteractiveAuthorization sets whether method calls made on c allow
+// polkit to interactively ask the user for authorization, for example with a
+// password prompt on the desktop, instead of failing with ErrAccessDenied or
+// ErrInteractiveAuthorizationRequired. Such calls may block until the user
+// answered the prompt. It is off by default; use WithInteractiveAuthorization
+// to allow it for single calls only.
+func (c *Conn) SetInteractiveAuthorization(allow bool) {
+	var v int32
+	if allow {
+		v = 1
+	}
+	atomic.StoreInt32(&c.interactiveAuth, v)
+}
+
+func (c *Conn) callFlags() dbus.Flags {
+	if atomic.LoadInt32(&c.interactiveAuth) != 0 {
+		return dbus.FlagAllowInteractiveAuthorization
+	}
+	return 0
+}
+
+type interactiveAuthKey struct{}
+
+// WithInteractiveAuthorization returns a copy of ctx which allows interactive
+// authorization, as described for SetInteractiveAuthorization, for the method
+// calls it is passed to.
+func WithInteractiveAuthorization(ctx context.Context) context.Context {
+	return context.WithValue(ctx, interactiveAuthKey{}, true)
+}
+
+func contextFlags(ctx context.Context) dbus.Flags {
+	if allow, _ := ctx.Value(interactiveAuthKey{}).(bool); allow {
+		return dbus.FlagAllowInteractiveAuthorization
+	}
+	return 0
+}
+
 // GetManagerProperty returns the value of a property on the org.freedesktop.systemd1.Manager
 // interface. The value is returned in its string representation, as defined at
 // https://developer.gnome.org/glib/unstable/gvariant-text.html
+//
+// Deprecated: use GetManagerPropertyContext instead.
 func (c *Conn) GetManagerProperty(prop string) (string, error) {
-	variant, err := c.sysobj.GetProperty("org.freedesktop.systemd1.Manager." + prop)
+	return c.GetManagerPropertyContext(context.Background(), prop)
+}
+
+// GetManagerPropertyContext is like GetManagerProperty, but the call can be
+// cancelled through ctx.
+func (c *Conn) GetManagerPropertyContext(ctx context.Context, prop string) (string, error) {
+	var variant dbus.Variant
+	err := c.manager().CallWithContext(ctx, "org.freedesktop.DBus.Properties.Get", 0,
+		"org.freedesktop.systemd1.Manager", prop).Store(&variant)
 	if err != nil {
 		return "", err
 	}
 	return variant.String(), nil
 }
 
-func dbusAuthConnection(createBus func(opts ...dbus.ConnOption) (*dbus.Conn, error)) (*dbus.Conn, error) {
-	conn, err := createBus()
+func dbusAuthConnection(ctx context.Context, createBus func(opts ...dbus.ConnOption) (*dbus.Conn, error)) (*dbus.Conn, error) {
+	return dbusAuthConnectionWith(ctx, createBus, nil)
+}
+
+func dbusAuthConnectionWith(ctx context.Context, createBus func(opts ...dbus.ConnOption) (*dbus.Conn, error), methods []dbus.Auth) (*dbus.Conn, error) {
+	conn, err := createBus(dbus.WithContext(ctx))
 	if err != nil {
 		return nil, err
 	}
 
-	// Only use EXTERNAL method, and hardcode the uid (not username)
-	// to avoid a username lookup (which requires a dynamically linked
-	// libc)
-	methods := []dbus.Auth{dbus.AuthExternal(strconv.Itoa(os.Getuid()))}
+	if methods == nil {
+		// Only use EXTERNAL method, and hardcode the uid (not username)
+		// to avoid a username lookup (which requires a dynamically linked
+		// libc)
+		methods = []dbus.Auth{dbus.AuthExternal(strconv.Itoa(os.Getuid()))}
+	}
 
 	err = conn.Auth(methods)
 	if err != nil {
@@ -221,8 +528,8 @@ func dbusAuthConnection(createBus func(opts ...dbus.ConnOption) (*dbus.Conn, err
 	return conn, nil
 }
 
-func dbusAuthHelloConnection(createBus func(opts ...dbus.ConnOption) (*dbus.Conn, error)) (*dbus.Conn, error) {
-	conn, err := dbusAuthConnection(createBus)
+func dbusAuthHelloConnection(ctx context.Context, createBus func(opts ...dbus.ConnOption) (*dbus.Conn, error)) (*dbus.Conn, error) {
+	conn, err := dbusAuthConnection(ctx, createBus)
 	if err != nil {
 		return nil, err
 	}
@@ -235,6 +542,6 @@ func dbusAuthHelloConnection(createBus func(opts ...dbus.ConnOption) (*dbus.Conn
 	return conn, nil
 }
 
-func systemdObject(conn *dbus.Conn) dbus.BusObject {
-	return conn.Object("org.freedesktop.systemd1", dbus.ObjectPath("/org/freedesktop/systemd1"))
+func systemdObject(conn Backend) mappedObject {
+	return mappedObject{BusObject: backendObject{conn: conn, dest: "org.freedesktop.systemd1", path: "/org/freedesktop/systemd1"}}
 }
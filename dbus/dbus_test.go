@@ -15,7 +15,13 @@
 package dbus
 
 import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/godbus/dbus/v5"
 )
 
 func TestNeedsEscape(t *testing.T) {
@@ -94,3 +100,93 @@ func TestNew(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestUserBusAddress(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-systemd-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer os.Setenv("DBUS_SESSION_BUS_ADDRESS", os.Getenv("DBUS_SESSION_BUS_ADDRESS"))
+	defer os.Setenv("XDG_RUNTIME_DIR", os.Getenv("XDG_RUNTIME_DIR"))
+
+	os.Unsetenv("DBUS_SESSION_BUS_ADDRESS")
+	os.Setenv("XDG_RUNTIME_DIR", dir)
+	if _, ok := userBusAddress(); ok {
+		t.Fatal("expected no address without a bus socket")
+	}
+
+	bus := filepath.Join(dir, "bus")
+	if err := ioutil.WriteFile(bus, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	address, ok := userBusAddress()
+	if !ok {
+		t.Fatal("expected an address for the bus socket")
+	}
+	if want := "unix:path=" + bus; address != want {
+		t.Errorf("bad address: got %q, want %q", address, want)
+	}
+
+	os.Setenv("DBUS_SESSION_BUS_ADDRESS", "unix:path=/nonexistent")
+	if _, ok := userBusAddress(); ok {
+		t.Error("expected DBUS_SESSION_BUS_ADDRESS to take precedence")
+	}
+}
+
+func TestNewConnectionWithOptionsErrors(t *testing.T) {
+	ctx := context.Background()
+	if _, err := NewConnectionWithOptions(ctx, DialOptions{}); err == nil {
+		t.Error("expected an error without an address")
+	}
+
+	dir, err := ioutil.TempDir("", "go-systemd-dial")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	opts := DialOptions{Address: "unix:path=" + filepath.Join(dir, "missing"), Direct: true}
+	if _, err := NewConnectionWithOptions(ctx, opts); err == nil {
+		t.Error("expected an error for a missing socket")
+	}
+}
+
+// flagsObject is a dbus.BusObject that records the flags of the last call.
+type flagsObject struct {
+	dbus.BusObject
+	flags dbus.Flags
+}
+
+func (o *flagsObject) CallWithContext(ctx context.Context, method string, flags dbus.Flags, args ...interface{}) *dbus.Call {
+	o.flags = flags
+	return &dbus.Call{Method: method}
+}
+
+func TestInteractiveAuthorization(t *testing.T) {
+	obj := &flagsObject{}
+	c := &Conn{sysobj: mappedObject{BusObject: obj}}
+	ctx := context.Background()
+
+	call := func(ctx context.Context) dbus.Flags {
+		c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.StartUnit", 0)
+		return obj.flags
+	}
+
+	if flags := call(ctx); flags != 0 {
+		t.Errorf("default flags: got %v, want 0", flags)
+	}
+	if flags := call(WithInteractiveAuthorization(ctx)); flags != dbus.FlagAllowInteractiveAuthorization {
+		t.Errorf("per-call flags: got %v, want %v", flags, dbus.FlagAllowInteractiveAuthorization)
+	}
+
+	c.SetInteractiveAuthorization(true)
+	if flags := call(ctx); flags != dbus.FlagAllowInteractiveAuthorization {
+		t.Errorf("per-connection flags: got %v, want %v", flags, dbus.FlagAllowInteractiveAuthorization)
+	}
+	c.SetInteractiveAuthorization(false)
+	if flags := call(ctx); flags != 0 {
+		t.Errorf("flags after disabling: got %v, want 0", flags)
+	}
+}
@@ -0,0 +1,384 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package dbustest provides an in-memory implementation of the Manager
+// interface of the dbus package, for testing code that manages systemd units
+// without a running systemd or bus.
+package dbustest
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	sd_dbus "github.com/coreos/go-systemd/v22/dbus"
+	"github.com/godbus/dbus/v5"
+)
+
+// Manager is an in-memory sd_dbus.Manager. Its units are set up with
+// SetUnit, and the jobs enqueued for them complete immediately, with the
+// result set with SetJobResult or "done". Jobs that complete with "done"
+// update the state of their unit as systemd would; other results leave a
+// started unit failed.
+//
+// The zero value is an empty Manager ready to use.
+type Manager struct {
+	mu      sync.Mutex
+	units   map[string]sd_dbus.UnitStatus
+	results map[string]string
+	jobs    []Job
+	closed  bool
+}
+
+// Job records a job enqueued on a Manager.
+type Job struct {
+	ID     int
+	Type   string // The method that enqueued the job, e.g. "StartUnit"
+	Unit   string
+	Mode   string
+	Result string
+}
+
+var _ sd_dbus.Manager = (*Manager)(nil)
+
+// NewManager returns a Manager knowing about the given units.
+func NewManager(units ...sd_dbus.UnitStatus) *Manager {
+	m := &Manager{}
+	for _, u := range units {
+		m.SetUnit(u)
+	}
+	return m
+}
+
+// SetUnit adds the unit described by status, or replaces its state. The
+// load state defaults to "loaded", the active state to "inactive" and the
+// sub state to "dead".
+func (m *Manager) SetUnit(status sd_dbus.UnitStatus) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if status.LoadState == "" {
+		status.LoadState = "loaded"
+	}
+	if status.ActiveState == "" {
+		status.ActiveState = "inactive"
+	}
+	if status.SubState == "" {
+		status.SubState = "dead"
+	}
+	if m.units == nil {
+		m.units = make(map[string]sd_dbus.UnitStatus)
+	}
+	m.units[status.Name] = status
+}
+
+// RemoveUnit forgets about the unit name.
+func (m *Manager) RemoveUnit(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.units, name)
+}
+
+// Unit returns the state of the unit name, and whether it is known.
+func (m *Manager) Unit(name string) (sd_dbus.UnitStatus, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, ok := m.units[name]
+	return u, ok
+}
+
+// SetJobResult sets the result of the jobs subsequently enqueued for the unit
+// name, such as "failed", "timeout" or "dependency". An empty result restores
+// the default, "done".
+func (m *Manager) SetJobResult(name string, result string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if result == "" {
+		delete(m.results, name)
+		return
+	}
+	if m.results == nil {
+		m.results = make(map[string]string)
+	}
+	m.results[name] = result
+}
+
+// Jobs returns the jobs enqueued so far, oldest first.
+func (m *Manager) Jobs() []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Job(nil), m.jobs...)
+}
+
+// Closed reports whether Close has been called.
+func (m *Manager) Closed() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.closed
+}
+
+func noSuchUnit(name string) error {
+	return &sd_dbus.Error{Name: sd_dbus.ErrNoSuchUnit.Name, Message: "Unit " + name + " not found."}
+}
+
+// runJob enqueues and completes a job for the unit name. If the job succeeds,
+// update is applied to the unit.
+func (m *Manager) runJob(ctx context.Context, typ string, name string, mode string, ch chan<- string, update func(*sd_dbus.UnitStatus)) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	u, ok := m.units[name]
+	if !ok {
+		m.mu.Unlock()
+		return 0, noSuchUnit(name)
+	}
+
+	result := "done"
+	if r, ok := m.results[name]; ok {
+		result = r
+	}
+	if result == "done" {
+		update(&u)
+	} else if typ != "StopUnit" {
+		u.ActiveState, u.SubState = "failed", "failed"
+	}
+	m.units[name] = u
+
+	id := len(m.jobs) + 1
+	m.jobs = append(m.jobs, Job{ID: id, Type: typ, Unit: name, Mode: mode, Result: result})
+	m.mu.Unlock()
+
+	if ch != nil {
+		go func() {
+			ch <- result
+		}()
+	}
+	return id, nil
+}
+
+func start(u *sd_dbus.UnitStatus) {
+	u.ActiveState, u.SubState = "active", "running"
+}
+
+func stop(u *sd_dbus.UnitStatus) {
+	u.ActiveState, u.SubState = "inactive", "dead"
+}
+
+func keep(u *sd_dbus.UnitStatus) {}
+
+// StartUnitContext starts the unit name, see sd_dbus.Conn.StartUnitContext.
+func (m *Manager) StartUnitContext(ctx context.Context, name string, mode string, ch chan<- string) (int, error) {
+	return m.runJob(ctx, "StartUnit", name, mode, ch, start)
+}
+
+// StopUnitContext stops the unit name.
+func (m *Manager) StopUnitContext(ctx context.Context, name string, mode string, ch chan<- string) (int, error) {
+	return m.runJob(ctx, "StopUnit", name, mode, ch, stop)
+}
+
+// ReloadUnitContext reloads the unit name; its state is left unchanged.
+func (m *Manager) ReloadUnitContext(ctx context.Context, name string, mode string, ch chan<- string) (int, error) {
+	return m.runJob(ctx, "ReloadUnit", name, mode, ch, keep)
+}
+
+// RestartUnitContext restarts the unit name, starting it if it is not
+// running.
+func (m *Manager) RestartUnitContext(ctx context.Context, name string, mode string, ch chan<- string) (int, error) {
+	return m.runJob(ctx, "RestartUnit", name, mode, ch, start)
+}
+
+// TryRestartUnitContext restarts the unit name if it is running.
+func (m *Manager) TryRestartUnitContext(ctx context.Context, name string, mode string, ch chan<- string) (int, error) {
+	return m.runJob(ctx, "TryRestartUnit", name, mode, ch, keep)
+}
+
+// ReloadOrRestartUnitContext reloads the unit name, starting it if it is not
+// running.
+func (m *Manager) ReloadOrRestartUnitContext(ctx context.Context, name string, mode string, ch chan<- string) (int, error) {
+	return m.runJob(ctx, "ReloadOrRestartUnit", name, mode, ch, start)
+}
+
+// KillUnitContext fails with sd_dbus.ErrNoSuchUnit if the unit name is not
+// known, and does nothing otherwise.
+func (m *Manager) KillUnitContext(ctx context.Context, name string, signal int32) error {
+	_, err := m.unit(ctx, name)
+	return err
+}
+
+// ResetFailedUnitContext resets a failed unit name to inactive.
+func (m *Manager) ResetFailedUnitContext(ctx context.Context, name string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, ok := m.units[name]
+	if !ok {
+		return noSuchUnit(name)
+	}
+	if u.ActiveState == "failed" {
+		stop(&u)
+		m.units[name] = u
+	}
+	return nil
+}
+
+func (m *Manager) unit(ctx context.Context, name string) (sd_dbus.UnitStatus, error) {
+	if err := ctx.Err(); err != nil {
+		return sd_dbus.UnitStatus{}, err
+	}
+	u, ok := m.Unit(name)
+	if !ok {
+		return u, noSuchUnit(name)
+	}
+	return u, nil
+}
+
+// list returns the units for which keep returns true, sorted by name.
+func (m *Manager) list(ctx context.Context, keep func(sd_dbus.UnitStatus) bool) ([]sd_dbus.UnitStatus, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	units := make([]sd_dbus.UnitStatus, 0, len(m.units))
+	for _, u := range m.units {
+		if keep(u) {
+			units = append(units, u)
+		}
+	}
+	sort.Slice(units, func(i, j int) bool { return units[i].Name < units[j].Name })
+	return units, nil
+}
+
+func matchStates(u sd_dbus.UnitStatus, states []string) bool {
+	if len(states) == 0 {
+		return true
+	}
+	for _, s := range states {
+		if s == u.LoadState || s == u.ActiveState || s == u.SubState {
+			return true
+		}
+	}
+	return false
+}
+
+func matchPatterns(u sd_dbus.UnitStatus, patterns []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, u.Name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ListUnitsContext returns all known units, sorted by name.
+func (m *Manager) ListUnitsContext(ctx context.Context) ([]sd_dbus.UnitStatus, error) {
+	return m.list(ctx, func(sd_dbus.UnitStatus) bool { return true })
+}
+
+// ListUnitsFilteredContext returns the units in one of the given load,
+// active or sub states.
+func (m *Manager) ListUnitsFilteredContext(ctx context.Context, states []string) ([]sd_dbus.UnitStatus, error) {
+	return m.list(ctx, func(u sd_dbus.UnitStatus) bool {
+		return matchStates(u, states)
+	})
+}
+
+// ListUnitsByPatternsContext returns the units in one of the given states
+// whose name matches one of the given shell patterns.
+func (m *Manager) ListUnitsByPatternsContext(ctx context.Context, states []string, patterns []string) ([]sd_dbus.UnitStatus, error) {
+	return m.list(ctx, func(u sd_dbus.UnitStatus) bool {
+		return matchStates(u, states) && matchPatterns(u, patterns)
+	})
+}
+
+// ListUnitsByNamesContext returns the given units in order. Like systemd, it
+// reports unknown units as not found rather than failing.
+func (m *Manager) ListUnitsByNamesContext(ctx context.Context, units []string) ([]sd_dbus.UnitStatus, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	statuses := make([]sd_dbus.UnitStatus, 0, len(units))
+	for _, name := range units {
+		u, ok := m.units[name]
+		if !ok {
+			u = sd_dbus.UnitStatus{Name: name, LoadState: "not-found", ActiveState: "inactive", SubState: "dead"}
+		}
+		statuses = append(statuses, u)
+	}
+	return statuses, nil
+}
+
+func unitProperties(u sd_dbus.UnitStatus) map[string]interface{} {
+	return map[string]interface{}{
+		"Id":          u.Name,
+		"Description": u.Description,
+		"LoadState":   u.LoadState,
+		"ActiveState": u.ActiveState,
+		"SubState":    u.SubState,
+		"Following":   u.Followed,
+	}
+}
+
+// GetUnitPropertiesContext returns the Id, Description, LoadState,
+// ActiveState, SubState and Following properties of the unit.
+func (m *Manager) GetUnitPropertiesContext(ctx context.Context, unit string) (map[string]interface{}, error) {
+	u, err := m.unit(ctx, unit)
+	if err != nil {
+		return nil, err
+	}
+	return unitProperties(u), nil
+}
+
+// GetUnitPropertyContext returns one of the properties returned by
+// GetUnitPropertiesContext.
+func (m *Manager) GetUnitPropertyContext(ctx context.Context, unit string, propertyName string) (*sd_dbus.Property, error) {
+	u, err := m.unit(ctx, unit)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := unitProperties(u)[propertyName]
+	if !ok {
+		return nil, &sd_dbus.Error{Name: "org.freedesktop.DBus.Error.UnknownProperty", Message: "Unknown property " + propertyName}
+	}
+	return &sd_dbus.Property{Name: propertyName, Value: dbus.MakeVariant(v)}, nil
+}
+
+// ReloadContext does nothing.
+func (m *Manager) ReloadContext(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// Close marks the Manager closed, see Closed.
+func (m *Manager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+}
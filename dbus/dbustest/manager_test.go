@@ -0,0 +1,127 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbustest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	sd_dbus "github.com/coreos/go-systemd/v22/dbus"
+)
+
+func TestManagerJobs(t *testing.T) {
+	m := NewManager(
+		sd_dbus.UnitStatus{Name: "foo.service"},
+		sd_dbus.UnitStatus{Name: "bar.service"},
+	)
+	m.SetJobResult("bar.service", "failed")
+	ctx := context.Background()
+
+	ch := make(chan string)
+	if _, err := m.StartUnitContext(ctx, "foo.service", "replace", ch); err != nil {
+		t.Fatal(err)
+	}
+	if result := <-ch; result != "done" {
+		t.Errorf("foo.service: got result %q, want done", result)
+	}
+	if u, _ := m.Unit("foo.service"); u.ActiveState != "active" {
+		t.Errorf("foo.service: got state %q, want active", u.ActiveState)
+	}
+
+	if _, err := m.StartUnitContext(ctx, "bar.service", "replace", ch); err != nil {
+		t.Fatal(err)
+	}
+	if result := <-ch; result != "failed" {
+		t.Errorf("bar.service: got result %q, want failed", result)
+	}
+	failed, err := m.ListUnitsFilteredContext(ctx, []string{"failed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 1 || failed[0].Name != "bar.service" {
+		t.Errorf("got failed units %v, want bar.service", failed)
+	}
+	if err := m.ResetFailedUnitContext(ctx, "bar.service"); err != nil {
+		t.Fatal(err)
+	}
+	if u, _ := m.Unit("bar.service"); u.ActiveState != "inactive" {
+		t.Errorf("bar.service: got state %q after reset, want inactive", u.ActiveState)
+	}
+
+	_, err = m.StopUnitContext(ctx, "baz.service", "replace", nil)
+	if !errors.Is(err, sd_dbus.ErrNoSuchUnit) {
+		t.Errorf("baz.service: got error %v, want ErrNoSuchUnit", err)
+	}
+
+	jobs := m.Jobs()
+	expected := []Job{
+		{ID: 1, Type: "StartUnit", Unit: "foo.service", Mode: "replace", Result: "done"},
+		{ID: 2, Type: "StartUnit", Unit: "bar.service", Mode: "replace", Result: "failed"},
+	}
+	if !reflect.DeepEqual(jobs, expected) {
+		t.Errorf("got jobs %v, want %v", jobs, expected)
+	}
+}
+
+func TestManagerListUnits(t *testing.T) {
+	m := NewManager(
+		sd_dbus.UnitStatus{Name: "b.service", ActiveState: "active", SubState: "running"},
+		sd_dbus.UnitStatus{Name: "a.socket"},
+		sd_dbus.UnitStatus{Name: "a.service"},
+	)
+	ctx := context.Background()
+
+	names := func(units []sd_dbus.UnitStatus) []string {
+		var names []string
+		for _, u := range units {
+			names = append(names, u.Name)
+		}
+		return names
+	}
+
+	units, err := m.ListUnitsContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := names(units), []string{"a.service", "a.socket", "b.service"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListUnits: got %v, want %v", got, want)
+	}
+
+	units, err = m.ListUnitsByPatternsContext(ctx, []string{"inactive"}, []string{"*.service"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := names(units), []string{"a.service"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListUnitsByPatterns: got %v, want %v", got, want)
+	}
+
+	units, err = m.ListUnitsByNamesContext(ctx, []string{"b.service", "c.service"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(units) != 2 || units[0].ActiveState != "active" || units[1].LoadState != "not-found" {
+		t.Errorf("ListUnitsByNames: got %v", units)
+	}
+
+	prop, err := m.GetUnitPropertyContext(ctx, "b.service", "SubState")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := prop.Value.Value().(string); s != "running" {
+		t.Errorf("got SubState %v, want running", prop.Value)
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// DependencyOptions selects the dependencies followed by ListDependencies.
+// By default, the requirement dependencies are followed, like
+// `systemctl list-dependencies` does.
+type DependencyOptions struct {
+	// Reverse follows the units that depend on the unit instead, like
+	// `systemctl list-dependencies --reverse`.
+	Reverse bool
+	// Order follows the ordering dependencies instead: After, or Before if
+	// Reverse is set, like `systemctl list-dependencies --after/--before`.
+	Order bool
+	// MaxDepth limits the depth of the returned tree; 0 means unlimited.
+	MaxDepth int
+	// All expands the dependencies of all units, like
+	// `systemctl list-dependencies --all`. By default, only target units
+	// are expanded below the root.
+	All bool
+}
+
+func (o *DependencyOptions) properties() []string {
+	switch {
+	case o.Order && !o.Reverse:
+		return []string{"After"}
+	case o.Order && o.Reverse:
+		return []string{"Before"}
+	case o.Reverse:
+		return []string{"RequiredBy", "RequisiteOf", "WantedBy", "PartOf", "BoundBy"}
+	default:
+		return []string{"Requires", "Requisite", "Wants", "ConsistsOf", "BindsTo"}
+	}
+}
+
+// DependencyNode is a unit in the tree returned by ListDependencies.
+type DependencyNode struct {
+	Name         string            // The unit name
+	Dependencies []*DependencyNode // The dependencies of the unit, sorted by name
+}
+
+// ListDependencies returns the tree of the dependencies of the (unescaped)
+// unit name, similar to `systemctl list-dependencies`. Unless opts.All is
+// set, only target units are expanded below the root. Each unit is expanded
+// once only: where it appears again, such as basic.target below every target
+// or in a dependency cycle, it is a leaf.
+func (c *Conn) ListDependencies(ctx context.Context, name string, opts DependencyOptions) (*DependencyNode, error) {
+	l := &dependencyLister{
+		conn:       c,
+		properties: opts.properties(),
+		maxDepth:   opts.MaxDepth,
+		all:        opts.All,
+		deps:       make(map[string][]string),
+		expanded:   make(map[string]bool),
+	}
+	return l.list(ctx, name, 0)
+}
+
+type dependencyLister struct {
+	conn       *Conn
+	properties []string
+	maxDepth   int
+	all        bool
+
+	// deps caches the dependencies of the units seen so far, expanded
+	// records the units already expanded in the tree
+	deps     map[string][]string
+	expanded map[string]bool
+}
+
+func (l *dependencyLister) list(ctx context.Context, name string, depth int) (*DependencyNode, error) {
+	node := &DependencyNode{Name: name}
+	if l.expanded[name] || (l.maxDepth > 0 && depth >= l.maxDepth) {
+		return node, nil
+	}
+	if depth > 0 && !l.all && !strings.HasSuffix(name, ".target") {
+		return node, nil
+	}
+
+	deps, err := l.dependencies(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	l.expanded[name] = true
+
+	for _, dep := range deps {
+		child, err := l.list(ctx, dep, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		node.Dependencies = append(node.Dependencies, child)
+	}
+	return node, nil
+}
+
+func (l *dependencyLister) dependencies(ctx context.Context, name string) ([]string, error) {
+	if deps, ok := l.deps[name]; ok {
+		return deps, nil
+	}
+
+	props, err := l.conn.GetUnitPropertiesContext(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var deps []string
+	for _, p := range l.properties {
+		units, _ := props[p].([]string)
+		for _, u := range units {
+			if !seen[u] {
+				seen[u] = true
+				deps = append(deps, u)
+			}
+		}
+	}
+	sort.Strings(deps)
+
+	l.deps[name] = deps
+	return deps, nil
+}
@@ -0,0 +1,132 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func TestListDependencies(t *testing.T) {
+	conn := setupConn(t)
+	defer conn.Close()
+
+	tree, err := conn.ListDependencies(context.Background(), "sysinit.target", DependencyOptions{MaxDepth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree.Name != "sysinit.target" || len(tree.Dependencies) == 0 {
+		t.Fatalf("expected dependencies of sysinit.target, got %+v", tree)
+	}
+	for _, dep := range tree.Dependencies {
+		if len(dep.Dependencies) != 0 {
+			t.Fatalf("MaxDepth was not honored for %s", dep.Name)
+		}
+	}
+
+	reverse, err := conn.ListDependencies(context.Background(), "sysinit.target", DependencyOptions{Reverse: true, MaxDepth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, dep := range reverse.Dependencies {
+		if dep.Name == "basic.target" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected basic.target to depend on sysinit.target, got %+v", reverse.Dependencies)
+	}
+}
+
+func TestDependencyOptionsProperties(t *testing.T) {
+	cases := []struct {
+		opts DependencyOptions
+		want string
+	}{
+		{DependencyOptions{}, "Requires"},
+		{DependencyOptions{Reverse: true}, "RequiredBy"},
+		{DependencyOptions{Order: true}, "After"},
+		{DependencyOptions{Order: true, Reverse: true}, "Before"},
+	}
+	for _, tt := range cases {
+		if got := tt.opts.properties()[0]; got != tt.want {
+			t.Errorf("%+v: got %s, want %s", tt.opts, got, tt.want)
+		}
+	}
+}
+
+// dependencyBackend is a Backend serving the Requires property of units.
+type dependencyBackend struct {
+	fakeBackend
+	requires map[string][]string
+}
+
+func (b *dependencyBackend) Call(ctx context.Context, call *BackendCall) ([]interface{}, error) {
+	name := pathBusUnescape(strings.TrimPrefix(string(call.Path), "/org/freedesktop/systemd1/unit/"))
+	return []interface{}{map[string]dbus.Variant{"Requires": dbus.MakeVariant(b.requires[name])}}, nil
+}
+
+// formatTree formats a dependency tree as name(dependencies...).
+func formatTree(node *DependencyNode) string {
+	if len(node.Dependencies) == 0 {
+		return node.Name
+	}
+	var deps []string
+	for _, dep := range node.Dependencies {
+		deps = append(deps, formatTree(dep))
+	}
+	return node.Name + "(" + strings.Join(deps, " ") + ")"
+}
+
+func TestListDependenciesShared(t *testing.T) {
+	c := &Conn{sysconn: &dependencyBackend{requires: map[string][]string{
+		"multi-user.target": {"a.service", "basic.target", "foo.target"},
+		"foo.target":        {"basic.target"},
+		"basic.target":      {"sysinit.target", "b.service"},
+		"sysinit.target":    {"basic.target"},
+		"a.service":         {"basic.target", "c.service"},
+		"b.service":         {"c.service"},
+	}}}
+
+	for _, tt := range []struct {
+		opts DependencyOptions
+		want string
+	}{
+		{DependencyOptions{}, "multi-user.target(a.service basic.target(b.service sysinit.target(basic.target)) foo.target(basic.target))"},
+		{DependencyOptions{All: true}, "multi-user.target(a.service(basic.target(b.service(c.service) sysinit.target(basic.target)) c.service) basic.target foo.target(basic.target))"},
+		{DependencyOptions{MaxDepth: 1}, "multi-user.target(a.service basic.target foo.target)"},
+	} {
+		tree, err := c.ListDependencies(context.Background(), "multi-user.target", tt.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := formatTree(tree); got != tt.want {
+			t.Errorf("%+v: got %s, want %s", tt.opts, got, tt.want)
+		}
+	}
+
+	// the root is expanded whatever its type
+	tree, err := c.ListDependencies(context.Background(), "b.service", DependencyOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := formatTree(tree); got != "b.service(c.service)" {
+		t.Errorf("got %s, want b.service(c.service)", got)
+	}
+}
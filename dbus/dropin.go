@@ -0,0 +1,174 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/coreos/go-systemd/v22/unit"
+)
+
+// Directories drop-ins are placed in, see DropIn.
+const (
+	persistentUnitDir = "/etc/systemd/system"
+	runtimeUnitDir    = "/run/systemd/system"
+)
+
+// DropIn describes an override drop-in for a unit, such as
+// /etc/systemd/system/foo.service.d/50-go.conf.
+type DropIn struct {
+	Unit    string             // The unit to override, e.g. foo.service
+	Name    string             // The file name without .conf suffix, e.g. 50-go
+	Options []*unit.UnitOption // The directives to set
+
+	// Runtime places the drop-in below /run/systemd/system, so that it is
+	// lost on reboot, rather than below /etc/systemd/system.
+	Runtime bool
+	// Dir, if set, overrides the directory the drop-in directory of the
+	// unit is created in, e.g. ~/.config/systemd/user for a user manager.
+	Dir string
+}
+
+// Path returns the path of the drop-in file.
+func (d *DropIn) Path() string {
+	dir := d.Dir
+	if dir == "" {
+		dir = persistentUnitDir
+		if d.Runtime {
+			dir = runtimeUnitDir
+		}
+	}
+	return filepath.Join(dir, d.Unit+".d", d.Name+".conf")
+}
+
+func (d *DropIn) validate() error {
+	if d.Unit == "" || strings.ContainsRune(d.Unit, '/') || !strings.ContainsRune(d.Unit, '.') {
+		return fmt.Errorf("invalid unit name %q", d.Unit)
+	}
+	if d.Name == "" || strings.ContainsRune(d.Name, '/') {
+		return fmt.Errorf("invalid drop-in name %q", d.Name)
+	}
+	for _, opt := range d.Options {
+		if !validDirectiveName(opt.Section) {
+			return fmt.Errorf("invalid section name %q", opt.Section)
+		}
+		if !validDirectiveName(opt.Name) {
+			return fmt.Errorf("invalid directive name %q in section %s", opt.Name, opt.Section)
+		}
+		if strings.ContainsAny(opt.Value, "\r\n") {
+			return fmt.Errorf("value of %s.%s contains a newline", opt.Section, opt.Name)
+		}
+	}
+	return nil
+}
+
+// validDirectiveName reports whether s may be used as a section or directive
+// name in a unit file: it must start with a letter, and consist of ASCII
+// letters, digits, dashes and underscores only.
+func validDirectiveName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case i > 0 && (r >= '0' && r <= '9' || r == '-' || r == '_'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// WriteDropIn writes the drop-in d, replacing any previous version, and has
+// systemd reload its configuration so that it takes effect. The path of the
+// drop-in file is returned. Like with systemctl edit, a running unit needs to
+// be restarted to pick up most changes.
+func (c *Conn) WriteDropIn(ctx context.Context, d DropIn) (string, error) {
+	if err := d.validate(); err != nil {
+		return "", err
+	}
+	if len(d.Options) == 0 {
+		return "", errors.New("no directives given for drop-in " + d.Name)
+	}
+
+	path := d.Path()
+	if err := writeFileAtomic(path, unit.Serialize(d.Options)); err != nil {
+		return "", err
+	}
+	return path, c.ReloadContext(ctx)
+}
+
+// RemoveDropIn removes the drop-in d, written by WriteDropIn, and has systemd
+// reload its configuration. The drop-in directory of the unit is removed too
+// if it is empty then. Only the Unit, Name, Runtime and Dir fields of d are
+// used. Removing a drop-in which does not exist is not an error.
+func (c *Conn) RemoveDropIn(ctx context.Context, d DropIn) error {
+	if err := d.validate(); err != nil {
+		return err
+	}
+
+	path := d.Path()
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	// fails if other drop-ins are left, which is fine
+	os.Remove(filepath.Dir(path))
+
+	return c.ReloadContext(ctx)
+}
+
+// writeFileAtomic writes the contents of r to path, creating its directory
+// if needed, so that readers see either the old or the new contents.
+func writeFileAtomic(path string, r io.Reader) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(dir, "."+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,109 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/coreos/go-systemd/v22/unit"
+)
+
+func TestDropInPath(t *testing.T) {
+	d := DropIn{Unit: "foo.service", Name: "50-go"}
+	if p := d.Path(); p != "/etc/systemd/system/foo.service.d/50-go.conf" {
+		t.Errorf("persistent: got %s", p)
+	}
+	d.Runtime = true
+	if p := d.Path(); p != "/run/systemd/system/foo.service.d/50-go.conf" {
+		t.Errorf("runtime: got %s", p)
+	}
+}
+
+func TestDropInValidate(t *testing.T) {
+	for _, d := range []DropIn{
+		{Unit: "../foo.service", Name: "50-go"},
+		{Unit: "foo", Name: "50-go"},
+		{Unit: "foo.service", Name: ""},
+		{Unit: "foo.service", Name: "50-go", Options: []*unit.UnitOption{unit.NewUnitOption("Service", "Nice=1\nUser", "root")}},
+		{Unit: "foo.service", Name: "50-go", Options: []*unit.UnitOption{unit.NewUnitOption("Service]", "Nice", "1")}},
+		{Unit: "foo.service", Name: "50-go", Options: []*unit.UnitOption{unit.NewUnitOption("Service", "Environment", "A=1\nB=2")}},
+	} {
+		if err := d.validate(); err == nil {
+			t.Errorf("%+v: want error, got nil", d)
+		}
+	}
+
+	d := DropIn{Unit: "foo.service", Name: "50-go", Options: []*unit.UnitOption{
+		unit.NewUnitOption("Service", "MemoryMax", "1G"),
+		unit.NewUnitOption("X-Fleet", "Conflicts_With", "bar.service"),
+	}}
+	if err := d.validate(); err != nil {
+		t.Errorf("valid drop-in: %v", err)
+	}
+}
+
+func TestWriteDropIn(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dropin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	h := &recordingHooks{}
+	c := &Conn{sysobj: mappedObject{BusObject: fakeObject{}}}
+	c.SetHooks(h)
+	ctx := context.Background()
+
+	d := DropIn{Unit: "foo.service", Name: "50-go", Dir: dir, Options: []*unit.UnitOption{
+		unit.NewUnitOption("Service", "Nice", "5"),
+	}}
+	path, err := c.WriteDropIn(ctx, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != filepath.Join(dir, "foo.service.d", "50-go.conf") {
+		t.Errorf("got path %s", path)
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "[Service]\nNice=5\n" {
+		t.Errorf("got content %q", content)
+	}
+
+	if err := c.RemoveDropIn(ctx, d); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Dir(path)); !os.IsNotExist(err) {
+		t.Errorf("drop-in directory not removed: %v", err)
+	}
+	if err := c.RemoveDropIn(ctx, d); err != nil {
+		t.Errorf("removing again: %v", err)
+	}
+
+	expected := []string{
+		"call org.freedesktop.systemd1.Manager.Reload",
+		"call org.freedesktop.systemd1.Manager.Reload",
+	}
+	if !reflect.DeepEqual(h.events, expected) {
+		t.Errorf("got calls %v, want %v", h.events, expected)
+	}
+}
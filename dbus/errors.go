@@ -0,0 +1,167 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"context"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Error is an error reply from systemd or the bus. Errors returned by Conn
+// methods can be matched against the Err* values below with errors.Is, or
+// inspected with errors.As:
+//
+//	if errors.Is(err, dbus.ErrNoSuchUnit) { ... }
+type Error struct {
+	Name    string // The D-Bus error name, e.g. org.freedesktop.systemd1.NoSuchUnit
+	Message string // The human readable message sent along with the error, if any
+
+	err error
+}
+
+// Errors sent by systemd, see
+// https://www.freedesktop.org/wiki/Software/systemd/dbus/ and
+// src/libsystemd/sd-bus/bus-common-errors.h in the systemd sources.
+var (
+	ErrNoSuchUnit                 = &Error{Name: "org.freedesktop.systemd1.NoSuchUnit"}
+	ErrNoUnitForPID               = &Error{Name: "org.freedesktop.systemd1.NoUnitForPID"}
+	ErrNoUnitForInvocationID      = &Error{Name: "org.freedesktop.systemd1.NoUnitForInvocationID"}
+	ErrUnitExists                 = &Error{Name: "org.freedesktop.systemd1.UnitExists"}
+	ErrLoadFailed                 = &Error{Name: "org.freedesktop.systemd1.LoadFailed"}
+	ErrBadUnitSetting             = &Error{Name: "org.freedesktop.systemd1.BadUnitSetting"}
+	ErrJobFailed                  = &Error{Name: "org.freedesktop.systemd1.JobFailed"}
+	ErrNoSuchJob                  = &Error{Name: "org.freedesktop.systemd1.NoSuchJob"}
+	ErrNotSubscribed              = &Error{Name: "org.freedesktop.systemd1.NotSubscribed"}
+	ErrAlreadySubscribed          = &Error{Name: "org.freedesktop.systemd1.AlreadySubscribed"}
+	ErrOnlyByDependency           = &Error{Name: "org.freedesktop.systemd1.OnlyByDependency"}
+	ErrTransactionJobsConflicting = &Error{Name: "org.freedesktop.systemd1.TransactionJobsConflicting"}
+	ErrTransactionOrderIsCyclic   = &Error{Name: "org.freedesktop.systemd1.TransactionOrderIsCyclic"}
+	ErrTransactionIsDestructive   = &Error{Name: "org.freedesktop.systemd1.TransactionIsDestructive"}
+	ErrUnitMasked                 = &Error{Name: "org.freedesktop.systemd1.UnitMasked"}
+	ErrUnitGenerated              = &Error{Name: "org.freedesktop.systemd1.UnitGenerated"}
+	ErrUnitLinked                 = &Error{Name: "org.freedesktop.systemd1.UnitLinked"}
+	ErrJobTypeNotApplicable       = &Error{Name: "org.freedesktop.systemd1.JobTypeNotApplicable"}
+	ErrNoIsolation                = &Error{Name: "org.freedesktop.systemd1.NoIsolation"}
+	ErrShuttingDown               = &Error{Name: "org.freedesktop.systemd1.ShuttingDown"}
+	ErrScopeNotRunning            = &Error{Name: "org.freedesktop.systemd1.ScopeNotRunning"}
+	ErrNoSuchDynamicUser          = &Error{Name: "org.freedesktop.systemd1.NoSuchDynamicUser"}
+	ErrNoSuchProcess              = &Error{Name: "org.freedesktop.systemd1.NoSuchProcess"}
+	ErrNotReferenced              = &Error{Name: "org.freedesktop.systemd1.NotReferenced"}
+	ErrDiskFull                   = &Error{Name: "org.freedesktop.systemd1.DiskFull"}
+	ErrUnitInactive               = &Error{Name: "org.freedesktop.systemd1.UnitInactive"}
+	ErrUnitBusy                   = &Error{Name: "org.freedesktop.systemd1.UnitBusy"}
+)
+
+// Errors sent by the bus itself, most notably when polkit denies a call.
+var (
+	ErrAccessDenied                     = &Error{Name: "org.freedesktop.DBus.Error.AccessDenied"}
+	ErrInteractiveAuthorizationRequired = &Error{Name: "org.freedesktop.DBus.Error.InteractiveAuthorizationRequired"}
+	ErrUnknownObject                    = &Error{Name: "org.freedesktop.DBus.Error.UnknownObject"}
+	ErrUnknownMethod                    = &Error{Name: "org.freedesktop.DBus.Error.UnknownMethod"}
+	ErrServiceUnknown                   = &Error{Name: "org.freedesktop.DBus.Error.ServiceUnknown"}
+	ErrNoReply                          = &Error{Name: "org.freedesktop.DBus.Error.NoReply"}
+)
+
+func (e *Error) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return e.Name
+}
+
+// Is reports whether target is an *Error with the same name.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Name == e.Name
+}
+
+// Unwrap returns the underlying dbus.Error.
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// mapError converts D-Bus error replies to *Error and returns any other
+// error unchanged.
+func mapError(err error) error {
+	var dbusErr dbus.Error
+	switch e := err.(type) {
+	case dbus.Error:
+		dbusErr = e
+	case *dbus.Error:
+		dbusErr = *e
+	default:
+		return err
+	}
+
+	var msg string
+	if len(dbusErr.Body) >= 1 {
+		msg, _ = dbusErr.Body[0].(string)
+	}
+	return &Error{Name: dbusErr.Name, Message: msg, err: dbusErr}
+}
+
+// mappedObject is a dbus.BusObject whose method calls return *Error for
+// D-Bus error replies.
+type mappedObject struct {
+	dbus.BusObject
+	flags dbus.Flags    // added to the flags of every method call
+	hooks Hooks         // notified of every method call, if set
+	limit chan struct{} // bounds the calls in flight, see SetMaxConcurrentCalls
+}
+
+func (o mappedObject) Call(method string, flags dbus.Flags, args ...interface{}) *dbus.Call {
+	start := time.Now()
+	release, _ := acquireCall(context.Background(), o.limit)
+	defer release()
+	call := o.BusObject.Call(method, flags|o.flags, args...)
+	call.Err = mapError(call.Err)
+	observeCall(o.hooks, method, start, call.Err)
+	return call
+}
+
+func (o mappedObject) CallWithContext(ctx context.Context, method string, flags dbus.Flags, args ...interface{}) *dbus.Call {
+	start := time.Now()
+	release, err := acquireCall(ctx, o.limit)
+	if err != nil {
+		observeCall(o.hooks, method, start, err)
+		return &dbus.Call{Method: method, Args: args, Err: err}
+	}
+	defer release()
+	call := o.BusObject.CallWithContext(ctx, method, flags|o.flags|contextFlags(ctx), args...)
+	call.Err = mapError(call.Err)
+	observeCall(o.hooks, method, start, call.Err)
+	return call
+}
+
+func (o mappedObject) GetProperty(p string) (dbus.Variant, error) {
+	start := time.Now()
+	release, _ := acquireCall(context.Background(), o.limit)
+	defer release()
+	v, err := o.BusObject.GetProperty(p)
+	err = mapError(err)
+	observeCall(o.hooks, "org.freedesktop.DBus.Properties.Get", start, err)
+	return v, err
+}
+
+func (o mappedObject) SetProperty(p string, v interface{}) error {
+	start := time.Now()
+	release, _ := acquireCall(context.Background(), o.limit)
+	defer release()
+	err := mapError(o.BusObject.SetProperty(p, v))
+	observeCall(o.hooks, "org.freedesktop.DBus.Properties.Set", start, err)
+	return err
+}
@@ -0,0 +1,66 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func TestMapError(t *testing.T) {
+	raw := dbus.Error{
+		Name: "org.freedesktop.systemd1.NoSuchUnit",
+		Body: []interface{}{"Unit foo.service not loaded."},
+	}
+
+	for _, in := range []error{raw, &raw} {
+		err := mapError(in)
+		if !errors.Is(err, ErrNoSuchUnit) {
+			t.Errorf("expected %v to match ErrNoSuchUnit", err)
+		}
+		if errors.Is(err, ErrUnitMasked) {
+			t.Errorf("expected %v not to match ErrUnitMasked", err)
+		}
+		if err.Error() != raw.Error() {
+			t.Errorf("bad message: got %q, want %q", err.Error(), raw.Error())
+		}
+
+		var e *Error
+		if !errors.As(err, &e) {
+			t.Fatalf("expected %v to be an *Error", err)
+		}
+		if e.Name != raw.Name {
+			t.Errorf("bad name: got %q, want %q", e.Name, raw.Name)
+		}
+
+		var d dbus.Error
+		if !errors.As(err, &d) || d.Name != raw.Name {
+			t.Errorf("expected %v to unwrap to the dbus.Error", err)
+		}
+	}
+
+	if err := mapError(nil); err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+	if err := mapError(io.EOF); err != io.EOF {
+		t.Errorf("expected io.EOF to pass through, got %v", err)
+	}
+	if msg := ErrAccessDenied.Error(); msg != ErrAccessDenied.Name {
+		t.Errorf("bad message for sentinel: got %q", msg)
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"time"
+)
+
+// Hooks is notified of the interactions of a Conn with systemd, for example
+// to export metrics or traces, see SetHooks. The methods are called
+// synchronously, possibly from several goroutines at once, and must not
+// block.
+type Hooks interface {
+	// OnCall is called when a method call to systemd or to the bus
+	// returned, whether it succeeded or not. method is the full D-Bus
+	// method name, such as org.freedesktop.systemd1.Manager.StartUnit, or
+	// org.freedesktop.DBus.AddMatch when subscribing to signals.
+	OnCall(method string, duration time.Duration)
+	// OnError is called after OnCall if the method call failed.
+	OnError(method string, duration time.Duration, err error)
+	// OnSignal is called for each signal received from the bus, such as
+	// org.freedesktop.systemd1.Manager.JobRemoved.
+	OnSignal(name string)
+}
+
+type hooksValue struct {
+	hooks Hooks
+}
+
+// SetHooks sets the hooks notified of method calls and signals, replacing
+// any previously set hooks. A nil h removes them.
+func (c *Conn) SetHooks(h Hooks) {
+	c.hooks.Store(hooksValue{h})
+}
+
+func (c *Conn) loadHooks() Hooks {
+	v, _ := c.hooks.Load().(hooksValue)
+	return v.hooks
+}
+
+// observeCall notifies h of a method call started at start.
+func observeCall(h Hooks, method string, start time.Time, err error) {
+	if h == nil {
+		return
+	}
+	d := time.Since(start)
+	h.OnCall(method, d)
+	if err != nil {
+		h.OnError(method, d, err)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+type recordingHooks struct {
+	sync.Mutex
+	events  []string
+	signals chan string
+}
+
+func (h *recordingHooks) OnCall(method string, duration time.Duration) {
+	h.Lock()
+	defer h.Unlock()
+	h.events = append(h.events, "call "+method)
+}
+
+func (h *recordingHooks) OnError(method string, duration time.Duration, err error) {
+	h.Lock()
+	defer h.Unlock()
+	h.events = append(h.events, "error "+method+": "+err.Error())
+}
+
+func (h *recordingHooks) OnSignal(name string) {
+	h.signals <- name
+}
+
+// failingObject is a dbus.BusObject whose method calls fail with
+// NoSuchUnit.
+type failingObject struct {
+	dbus.BusObject
+}
+
+func (failingObject) CallWithContext(ctx context.Context, method string, flags dbus.Flags, args ...interface{}) *dbus.Call {
+	return &dbus.Call{Method: method, Err: dbus.Error{Name: ErrNoSuchUnit.Name, Body: []interface{}{"Unit foo.service not found."}}}
+}
+
+func TestHooks(t *testing.T) {
+	h := &recordingHooks{signals: make(chan string, 1)}
+	c := &Conn{sysobj: mappedObject{BusObject: failingObject{}}}
+	c.SetHooks(h)
+
+	err := c.KillUnitContext(context.Background(), "foo.service", 15)
+	if !errors.Is(err, ErrNoSuchUnit) {
+		t.Fatalf("got error %v, want ErrNoSuchUnit", err)
+	}
+
+	c.SetHooks(nil)
+	c.KillUnitContext(context.Background(), "foo.service", 15)
+
+	expected := []string{
+		"call org.freedesktop.systemd1.Manager.KillUnit",
+		"error org.freedesktop.systemd1.Manager.KillUnit: Unit foo.service not found.",
+	}
+	if !reflect.DeepEqual(h.events, expected) {
+		t.Errorf("got events %q, want %q", h.events, expected)
+	}
+}
+
+func TestHooksSignal(t *testing.T) {
+	conn, backends := newFakeConn(t)
+	defer conn.Close()
+
+	h := &recordingHooks{signals: make(chan string, 1)}
+	conn.SetHooks(h)
+
+	backends[1].emit("org.freedesktop.systemd1.Manager.UnitNew", "foo.service", unitPath("foo.service"))
+	select {
+	case name := <-h.signals:
+		if name != "org.freedesktop.systemd1.Manager.UnitNew" {
+			t.Errorf("got signal %s, want UnitNew", name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for OnSignal")
+	}
+}
+
+func TestHooksSubscribe(t *testing.T) {
+	conn, _ := newFakeConn(t)
+	defer conn.Close()
+
+	h := &recordingHooks{}
+	conn.SetHooks(h)
+	if err := conn.Subscribe(); err != nil {
+		t.Fatal(err)
+	}
+
+	h.Lock()
+	defer h.Unlock()
+	expected := []string{
+		"call org.freedesktop.DBus.AddMatch",
+		"call org.freedesktop.DBus.AddMatch",
+		"call org.freedesktop.DBus.AddMatch",
+		"call org.freedesktop.systemd1.Manager.Subscribe",
+	}
+	if !reflect.DeepEqual(h.events, expected) {
+		t.Errorf("got events %q, want %q", h.events, expected)
+	}
+}
@@ -0,0 +1,534 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// jobResultTTL bounds how long the result of a job nobody waits for yet is
+// kept around.
+const jobResultTTL = 10 * time.Second
+
+// ErrJobTimeout is returned by RunJob when the job did not complete in time.
+var ErrJobTimeout = errors.New("timed out waiting for job completion")
+
+// ErrStartLimitHit is matched by the *JobError of a job that failed because
+// its unit was started too often, see StartLimitIntervalSec= in
+// systemd.unit(5). The unit will not start again until the limit interval
+// passed or ResetFailedUnitContext is called, so supervisors should back off.
+var ErrStartLimitHit = errors.New("start request repeated too quickly")
+
+// JobError is returned by the helpers that wait for a job, such as NewScope,
+// when the job did not complete successfully.
+type JobError struct {
+	Unit   string // The unit the job was enqueued for
+	Result string // The job result, e.g. "failed", "canceled" or "timeout"
+	// UnitResult is the Result property of the unit once the job failed,
+	// e.g. "exit-code" or "start-limit-hit", if its unit type has one.
+	UnitResult string
+}
+
+func (e *JobError) Error() string {
+	if e.UnitResult == "start-limit-hit" {
+		return "job for " + e.Unit + " failed: " + ErrStartLimitHit.Error()
+	}
+	if e.UnitResult != "" && e.UnitResult != "success" {
+		return "job for " + e.Unit + " finished with result " + e.Result + " (unit result " + e.UnitResult + ")"
+	}
+	return "job for " + e.Unit + " finished with result " + e.Result
+}
+
+// Is reports whether target is ErrStartLimitHit and the job failed because
+// its unit hit the start limit.
+func (e *JobError) Is(target error) bool {
+	return target == ErrStartLimitHit && e.UnitResult == "start-limit-hit"
+}
+
+// jobError returns nil if result, the result of a job for the unit name, is
+// "done", and a *JobError describing the failure otherwise.
+func (c *Conn) jobError(ctx context.Context, name string, result string) error {
+	if result == "done" {
+		return nil
+	}
+
+	e := &JobError{Unit: name, Result: result}
+	if result == "failed" {
+		if i := strings.LastIndexByte(name, '.'); i >= 0 && i < len(name)-1 {
+			unitType := strings.ToUpper(name[i+1:i+2]) + name[i+2:]
+			// not all unit types have a Result property
+			if prop, err := c.getProperty(ctx, name, "org.freedesktop.systemd1."+unitType, "Result"); err == nil {
+				e.UnitResult, _ = prop.Value.Value().(string)
+			}
+		}
+	}
+	return e
+}
+
+type jobResult struct {
+	result  string
+	removed time.Time
+}
+
+// jobTracker delivers the results carried by JobRemoved signals to the
+// channels waiting for them. systemd merges requests for the same job, so
+// several channels may wait for the same job path; all of them receive the
+// result, and the job is forgotten once it has been delivered.
+//
+// systemd may emit JobRemoved for a fast job before the reply to the call
+// that enqueued it has been processed, that is, before the job path is known
+// to the caller. While any such call is in flight, results of jobs nobody
+// waits for are therefore kept, and handed over once the caller registers.
+type jobTracker struct {
+	sync.Mutex
+	jobs    map[dbus.ObjectPath][]chan<- string
+	results map[dbus.ObjectPath]jobResult
+
+	// enqueuing is the number of calls waiting for a job path from systemd
+	enqueuing int
+
+	timeout time.Duration
+	closed  <-chan struct{}
+}
+
+func newJobTracker(closed <-chan struct{}) *jobTracker {
+	return &jobTracker{
+		jobs:    make(map[dbus.ObjectPath][]chan<- string),
+		results: make(map[dbus.ObjectPath]jobResult),
+		closed:  closed,
+	}
+}
+
+// begin must be called before a call that enqueues a job is made.
+func (t *jobTracker) begin() {
+	t.Lock()
+	defer t.Unlock()
+	t.enqueuing++
+}
+
+// end must be called once the call that enqueues a job returned. If ch is
+// not nil, the result of the job at path is sent to it.
+func (t *jobTracker) end(path dbus.ObjectPath, ch chan<- string) {
+	t.Lock()
+
+	t.enqueuing--
+	r, early := t.results[path]
+	if ch != nil && path != "" {
+		if early {
+			delete(t.results, path)
+		} else {
+			t.jobs[path] = append(t.jobs[path], ch)
+		}
+	}
+	if t.enqueuing == 0 {
+		// nobody can be waiting for the remaining results anymore
+		t.results = make(map[dbus.ObjectPath]jobResult)
+	}
+
+	t.Unlock()
+
+	if ch != nil && early {
+		t.deliver(ch, r.result)
+	}
+}
+
+// register sends the result of the job at path to ch, for jobs enqueued along
+// with the one returned by a call, see EnqueueUnitJob. It must be called
+// before end is called for that call.
+func (t *jobTracker) register(path dbus.ObjectPath, ch chan<- string) {
+	t.Lock()
+	r, early := t.results[path]
+	if early {
+		delete(t.results, path)
+	} else {
+		t.jobs[path] = append(t.jobs[path], ch)
+	}
+	t.Unlock()
+
+	if early {
+		t.deliver(ch, r.result)
+	}
+}
+
+// complete records the result of the job at path.
+func (t *jobTracker) complete(path dbus.ObjectPath, result string) {
+	t.Lock()
+
+	if chans, ok := t.jobs[path]; ok {
+		delete(t.jobs, path)
+		t.Unlock()
+
+		for _, ch := range chans {
+			t.deliver(ch, result)
+		}
+		return
+	}
+	defer t.Unlock()
+
+	if t.enqueuing > 0 {
+		now := time.Now()
+		t.results[path] = jobResult{result, now}
+		for p, r := range t.results {
+			if now.Sub(r.removed) > jobResultTTL {
+				delete(t.results, p)
+			}
+		}
+	}
+}
+
+// deliver sends result to ch without blocking: if ch is not ready, which
+// is usually the case for unbuffered channels, the result is handed over by
+// a separate goroutine that gives up once the connection is closed. This way
+// a slow consumer never holds up the processing of other signals.
+func (t *jobTracker) deliver(ch chan<- string, result string) {
+	select {
+	case ch <- result:
+	default:
+		go func() {
+			select {
+			case ch <- result:
+			case <-t.closed:
+			}
+		}()
+	}
+}
+
+// forget stops sending the result of the job at path to ch. Other channels
+// waiting for the same job are not affected.
+func (t *jobTracker) forget(path dbus.ObjectPath, ch chan<- string) {
+	t.Lock()
+	defer t.Unlock()
+
+	chans := t.jobs[path]
+	for i := range chans {
+		if chans[i] == ch {
+			chans = append(chans[:i], chans[i+1:]...)
+			break
+		}
+	}
+	if len(chans) == 0 {
+		delete(t.jobs, path)
+	} else {
+		t.jobs[path] = chans
+	}
+}
+
+// pending returns the paths of the jobs being waited for.
+func (t *jobTracker) pending() []dbus.ObjectPath {
+	t.Lock()
+	defer t.Unlock()
+	paths := make([]dbus.ObjectPath, 0, len(t.jobs))
+	for p := range t.jobs {
+		paths = append(paths, p)
+	}
+	return paths
+}
+
+// drop stops waiting for the job at path, and reports whether it was being
+// waited for.
+func (t *jobTracker) drop(path dbus.ObjectPath) bool {
+	t.Lock()
+	defer t.Unlock()
+	_, ok := t.jobs[path]
+	delete(t.jobs, path)
+	return ok
+}
+
+func (c *Conn) jobComplete(signal *BackendSignal) {
+	var id uint32
+	var job dbus.ObjectPath
+	var unit string
+	var result string
+	dbus.Store(signal.Body, &id, &job, &unit, &result)
+	c.jobListener.complete(job, result)
+}
+
+func (c *Conn) startJob(ctx context.Context, ch chan<- string, job string, args ...interface{}) (int, error) {
+	return c.startObjectJob(ctx, c.manager(), ch, job, args...)
+}
+
+// startObjectJob is like startJob, but calls the method enqueuing the job on
+// obj instead of the manager.
+func (c *Conn) startObjectJob(ctx context.Context, obj dbus.BusObject, ch chan<- string, job string, args ...interface{}) (int, error) {
+	c.jobListener.begin()
+
+	var p dbus.ObjectPath
+	err := obj.CallWithContext(ctx, job, 0, args...).Store(&p)
+	if err != nil {
+		c.jobListener.end("", nil)
+		return 0, err
+	}
+	c.jobListener.end(p, ch)
+
+	// ignore error since 0 is fine if conversion fails
+	jobID, _ := strconv.Atoi(path.Base(string(p)))
+
+	return jobID, nil
+}
+
+// SetJobTimeout sets the default timeout used by RunJob when no per-job
+// timeout is given. A timeout of 0, the default, means that RunJob waits
+// until its context is done.
+func (c *Conn) SetJobTimeout(timeout time.Duration) {
+	c.jobListener.Lock()
+	defer c.jobListener.Unlock()
+	c.jobListener.timeout = timeout
+}
+
+// RunJob enqueues a job and waits for its completion. start must enqueue the
+// job by calling one of the job methods, such as StartUnitContext or
+// StopUnitContext, with the channel it is given:
+//
+//	result, err := conn.RunJob(ctx, time.Minute, func(ch chan<- string) (int, error) {
+//		return conn.StartUnitContext(ctx, "foo.service", "replace", ch)
+//	})
+//
+// The job result is returned as described for StartUnitContext. If the job
+// does not complete within timeout, or the default set with SetJobTimeout if
+// timeout is 0, ErrJobTimeout is returned. If ctx is done first, its error is
+// returned.
+func (c *Conn) RunJob(ctx context.Context, timeout time.Duration, start func(chan<- string) (int, error)) (string, error) {
+	// Buffered, so that a result arriving after we gave up does not block
+	// the signal dispatching.
+	ch := make(chan string, 1)
+	jobID, err := start(ch)
+	if err != nil {
+		return "", err
+	}
+
+	if timeout == 0 {
+		c.jobListener.Lock()
+		timeout = c.jobListener.timeout
+		c.jobListener.Unlock()
+	}
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case result := <-ch:
+		return result, nil
+	case <-expired:
+		err = ErrJobTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	c.jobListener.forget(jobPath(jobID), ch)
+	return "", err
+}
+
+// Job is a handle to a job enqueued by one of the ...Async methods, such as
+// StartUnitAsync.
+type Job struct {
+	ID   int             // The numeric job id
+	Path dbus.ObjectPath // The job object path
+
+	// Result receives the job result, as described for StartUnitContext,
+	// once the job completed. It is buffered, so the result is kept until
+	// it is read.
+	Result <-chan string
+}
+
+// Wait waits for the job to complete and returns its result. If ctx is done
+// first, its error is returned; Wait may then be called again.
+func (j *Job) Wait(ctx context.Context) (string, error) {
+	select {
+	case result := <-j.Result:
+		return result, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func (c *Conn) startJobAsync(ctx context.Context, job string, args ...interface{}) (*Job, error) {
+	return c.startObjectJobAsync(ctx, c.manager(), job, args...)
+}
+
+func (c *Conn) startObjectJobAsync(ctx context.Context, obj dbus.BusObject, job string, args ...interface{}) (*Job, error) {
+	ch := make(chan string, 1)
+	id, err := c.startObjectJob(ctx, obj, ch, job, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &Job{ID: id, Path: jobPath(id), Result: ch}, nil
+}
+
+// StartUnitAsync is like StartUnitContext, but returns a handle to the job
+// instead of taking a channel, so that many jobs can be enqueued and their
+// results collected later.
+func (c *Conn) StartUnitAsync(ctx context.Context, name string, mode string) (*Job, error) {
+	return c.startJobAsync(ctx, "org.freedesktop.systemd1.Manager.StartUnit", name, mode)
+}
+
+// StopUnitAsync is like StopUnitContext, but returns a handle to the job.
+func (c *Conn) StopUnitAsync(ctx context.Context, name string, mode string) (*Job, error) {
+	return c.startJobAsync(ctx, "org.freedesktop.systemd1.Manager.StopUnit", name, mode)
+}
+
+// ReloadUnitAsync is like ReloadUnitContext, but returns a handle to the job.
+func (c *Conn) ReloadUnitAsync(ctx context.Context, name string, mode string) (*Job, error) {
+	return c.startJobAsync(ctx, "org.freedesktop.systemd1.Manager.ReloadUnit", name, mode)
+}
+
+// RestartUnitAsync is like RestartUnitContext, but returns a handle to the
+// job.
+func (c *Conn) RestartUnitAsync(ctx context.Context, name string, mode string) (*Job, error) {
+	return c.startJobAsync(ctx, "org.freedesktop.systemd1.Manager.RestartUnit", name, mode)
+}
+
+// TryRestartUnitAsync is like TryRestartUnitContext, but returns a handle to
+// the job.
+func (c *Conn) TryRestartUnitAsync(ctx context.Context, name string, mode string) (*Job, error) {
+	return c.startJobAsync(ctx, "org.freedesktop.systemd1.Manager.TryRestartUnit", name, mode)
+}
+
+// ReloadOrRestartUnitAsync is like ReloadOrRestartUnitContext, but returns a
+// handle to the job.
+func (c *Conn) ReloadOrRestartUnitAsync(ctx context.Context, name string, mode string) (*Job, error) {
+	return c.startJobAsync(ctx, "org.freedesktop.systemd1.Manager.ReloadOrRestartUnit", name, mode)
+}
+
+// ReloadOrTryRestartUnitAsync is like ReloadOrTryRestartUnitContext, but
+// returns a handle to the job.
+func (c *Conn) ReloadOrTryRestartUnitAsync(ctx context.Context, name string, mode string) (*Job, error) {
+	return c.startJobAsync(ctx, "org.freedesktop.systemd1.Manager.ReloadOrTryRestartUnit", name, mode)
+}
+
+// EnqueuedJob is a job enqueued by EnqueueUnitJob.
+type EnqueuedJob struct {
+	*Job
+	Unit     string          // The unit the job is for
+	UnitPath dbus.ObjectPath // The unit object path
+	Type     string          // The job type, e.g. start or stop
+}
+
+// EnqueueUnitJob enqueues a job of type jobType, such as "start", "stop",
+// "restart" or "reload", for the unit name, like StartUnitContext and
+// friends; see StartUnitContext for the possible modes. Besides the requested
+// job, it returns the other jobs systemd enqueued or changed to satisfy the
+// dependencies of the unit, so that callers can learn, and wait for,
+// everything the request set in motion, for example with WaitJobs.
+//
+// EnqueueUnitJob requires systemd 242 or later.
+func (c *Conn) EnqueueUnitJob(ctx context.Context, name string, jobType string, mode string) (*EnqueuedJob, []*EnqueuedJob, error) {
+	c.jobListener.begin()
+
+	var id uint32
+	var path, unitPath dbus.ObjectPath
+	var unitID, typ string
+	result := make([][]interface{}, 0)
+	err := c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.EnqueueUnitJob", 0, name, jobType, mode).
+		Store(&id, &path, &unitID, &unitPath, &typ, &result)
+	if err != nil {
+		c.jobListener.end("", nil)
+		return nil, nil, err
+	}
+
+	affected := make([]*EnqueuedJob, 0, len(result))
+	chans := make([]chan string, 0, len(result))
+	for _, entry := range result {
+		var j EnqueuedJob
+		var jobID uint32
+		var p dbus.ObjectPath
+		if err := dbus.Store(entry, &jobID, &p, &j.Unit, &j.UnitPath, &j.Type); err != nil {
+			c.jobListener.end("", nil)
+			return nil, nil, err
+		}
+		if p == path {
+			continue
+		}
+		ch := make(chan string, 1)
+		j.Job = &Job{ID: int(jobID), Path: p, Result: ch}
+		affected = append(affected, &j)
+		chans = append(chans, ch)
+	}
+	for i, j := range affected {
+		c.jobListener.register(j.Path, chans[i])
+	}
+
+	ch := make(chan string, 1)
+	c.jobListener.end(path, ch)
+	job := &EnqueuedJob{
+		Job:      &Job{ID: int(id), Path: path, Result: ch},
+		Unit:     unitID,
+		UnitPath: unitPath,
+		Type:     typ,
+	}
+
+	return job, affected, nil
+}
+
+// EnqueueMarkedJobs enqueues a restart or reload job for every unit marked
+// with PropMarkers, like systemctl reload-or-restart --marked, and returns the
+// jobs. A package manager would mark the services it updated:
+//
+//	err := conn.SetUnitPropertiesContext(ctx, "foo.service", true, dbus.PropMarkers("needs-restart"))
+//
+// and restart them all at once when done. The markers are cleared once the
+// jobs complete. EnqueueMarkedJobs requires systemd 248 or later.
+func (c *Conn) EnqueueMarkedJobs(ctx context.Context) ([]*Job, error) {
+	c.jobListener.begin()
+
+	var paths []dbus.ObjectPath
+	err := c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.EnqueueMarkedJobs", 0).Store(&paths)
+	if err != nil {
+		c.jobListener.end("", nil)
+		return nil, err
+	}
+
+	jobs := make([]*Job, 0, len(paths))
+	for _, p := range paths {
+		ch := make(chan string, 1)
+		c.jobListener.register(p, ch)
+		// ignore error since 0 is fine if conversion fails
+		id, _ := strconv.Atoi(path.Base(string(p)))
+		jobs = append(jobs, &Job{ID: id, Path: p, Result: ch})
+	}
+	c.jobListener.end("", nil)
+
+	return jobs, nil
+}
+
+// WaitJobs waits for all jobs to complete, and returns their results in
+// order. If ctx is done first, its error is returned along with the results
+// collected so far.
+func WaitJobs(ctx context.Context, jobs ...*Job) ([]string, error) {
+	results := make([]string, 0, len(jobs))
+	for _, j := range jobs {
+		result, err := j.Wait(ctx)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func jobPath(id int) dbus.ObjectPath {
+	return dbus.ObjectPath("/org/freedesktop/systemd1/job/" + strconv.Itoa(id))
+}
@@ -0,0 +1,277 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestJobConn() *Conn {
+	c := &Conn{closed: make(chan struct{})}
+	c.jobListener = newJobTracker(c.closed)
+	return c
+}
+
+func fakeJobStart(c *Conn, id int) func(chan<- string) (int, error) {
+	return func(ch chan<- string) (int, error) {
+		c.jobListener.begin()
+		c.jobListener.end(jobPath(id), ch)
+		return id, nil
+	}
+}
+
+func jobRemovedSignal(id int, result string) *BackendSignal {
+	return &BackendSignal{
+		Name: "org.freedesktop.systemd1.Manager.JobRemoved",
+		Body: []interface{}{uint32(id), jobPath(id), "foo.service", result},
+	}
+}
+
+func TestJobTrackerComplete(t *testing.T) {
+	tr := newJobTracker(make(chan struct{}))
+	ch := make(chan string, 1)
+
+	tr.begin()
+	tr.end(jobPath(1), ch)
+	tr.complete(jobPath(1), "done")
+
+	if result := <-ch; result != "done" {
+		t.Errorf("bad result: got %q, want %q", result, "done")
+	}
+	if len(tr.jobs) != 0 {
+		t.Errorf("completed job was not removed: %v", tr.jobs)
+	}
+}
+
+func TestJobTrackerEarlyResult(t *testing.T) {
+	tr := newJobTracker(make(chan struct{}))
+	ch := make(chan string, 1)
+
+	// JobRemoved arrives before the reply carrying the job path
+	tr.begin()
+	tr.complete(jobPath(1), "failed")
+	tr.end(jobPath(1), ch)
+
+	select {
+	case result := <-ch:
+		if result != "failed" {
+			t.Errorf("bad result: got %q, want %q", result, "failed")
+		}
+	default:
+		t.Fatal("early result was lost")
+	}
+	if len(tr.jobs) != 0 || len(tr.results) != 0 {
+		t.Errorf("job was not cleaned up: %v %v", tr.jobs, tr.results)
+	}
+}
+
+func TestJobTrackerUnrelatedResults(t *testing.T) {
+	tr := newJobTracker(make(chan struct{}))
+
+	// results are not kept when no job is being enqueued
+	tr.complete(jobPath(1), "done")
+	if len(tr.results) != 0 {
+		t.Errorf("unexpected results kept: %v", tr.results)
+	}
+
+	// and are dropped once no job is being enqueued anymore
+	tr.begin()
+	tr.complete(jobPath(2), "done")
+	tr.end(jobPath(3), make(chan string, 1))
+	if len(tr.results) != 0 {
+		t.Errorf("unexpected results kept: %v", tr.results)
+	}
+
+	// and expire while long calls are in flight
+	tr.begin()
+	tr.results[jobPath(4)] = jobResult{"done", time.Now().Add(-2 * jobResultTTL)}
+	tr.complete(jobPath(5), "done")
+	if _, ok := tr.results[jobPath(4)]; ok {
+		t.Error("expired result was kept")
+	}
+	tr.end("", nil)
+}
+
+func TestJobTrackerForget(t *testing.T) {
+	tr := newJobTracker(make(chan struct{}))
+	ch := make(chan string, 1)
+	other := make(chan string, 1)
+
+	tr.begin()
+	tr.end(jobPath(1), ch)
+
+	tr.forget(jobPath(1), other)
+	if len(tr.pending()) != 1 {
+		t.Fatal("job forgotten for the wrong channel")
+	}
+	tr.forget(jobPath(1), ch)
+	if len(tr.pending()) != 0 {
+		t.Fatal("job was not forgotten")
+	}
+	if tr.drop(jobPath(1)) {
+		t.Error("dropped a job that was not pending")
+	}
+}
+
+func TestJobTrackerMultipleWaiters(t *testing.T) {
+	tr := newJobTracker(make(chan struct{}))
+	first := make(chan string, 1)
+	second := make(chan string, 1)
+	third := make(chan string, 1)
+
+	for _, ch := range []chan string{first, second, third} {
+		tr.begin()
+		tr.end(jobPath(1), ch)
+	}
+	tr.forget(jobPath(1), third)
+	tr.complete(jobPath(1), "done")
+
+	for i, ch := range []chan string{first, second} {
+		select {
+		case result := <-ch:
+			if result != "done" {
+				t.Errorf("bad result for waiter %d: got %q, want %q", i, result, "done")
+			}
+		default:
+			t.Errorf("waiter %d did not receive the result", i)
+		}
+	}
+	select {
+	case <-third:
+		t.Error("forgotten waiter received the result")
+	default:
+	}
+	if len(tr.pending()) != 0 {
+		t.Errorf("completed job was not removed: %v", tr.jobs)
+	}
+}
+
+func TestJobTrackerSlowConsumer(t *testing.T) {
+	closed := make(chan struct{})
+	tr := newJobTracker(closed)
+	slow := make(chan string)
+	fast := make(chan string, 1)
+
+	tr.begin()
+	tr.end(jobPath(1), slow)
+	tr.begin()
+	tr.end(jobPath(2), fast)
+
+	// must not block although nobody reads from slow
+	tr.complete(jobPath(1), "done")
+	tr.complete(jobPath(2), "done")
+
+	if result := <-fast; result != "done" {
+		t.Errorf("bad result: got %q, want %q", result, "done")
+	}
+	select {
+	case result := <-slow:
+		if result != "done" {
+			t.Errorf("bad result: got %q, want %q", result, "done")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("result for the slow consumer was lost")
+	}
+
+	// pending deliveries are abandoned once the connection is closed
+	tr.begin()
+	tr.end(jobPath(3), slow)
+	tr.complete(jobPath(3), "done")
+	close(closed)
+}
+
+func TestRunJob(t *testing.T) {
+	c := newTestJobConn()
+
+	start := func(ch chan<- string) (int, error) {
+		c.jobListener.begin()
+		c.jobComplete(jobRemovedSignal(1, "done"))
+		c.jobListener.end(jobPath(1), ch)
+		return 1, nil
+	}
+
+	result, err := c.RunJob(context.Background(), time.Minute, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != "done" {
+		t.Errorf("bad result: got %q, want %q", result, "done")
+	}
+}
+
+func TestRunJobTimeout(t *testing.T) {
+	c := newTestJobConn()
+	c.SetJobTimeout(10 * time.Millisecond)
+
+	_, err := c.RunJob(context.Background(), 0, fakeJobStart(c, 2))
+	if err != ErrJobTimeout {
+		t.Fatalf("expected ErrJobTimeout, got %v", err)
+	}
+	if len(c.jobListener.pending()) != 0 {
+		t.Error("timed out job was not forgotten")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = c.RunJob(ctx, time.Minute, fakeJobStart(c, 3))
+	if err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestJobWait(t *testing.T) {
+	ch := make(chan string, 1)
+	job := &Job{ID: 1, Path: jobPath(1), Result: ch}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := job.Wait(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("bad error: got %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	ch <- "done"
+	result, err := job.Wait(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != "done" {
+		t.Errorf("bad result: got %q, want %q", result, "done")
+	}
+}
+
+func TestJobError(t *testing.T) {
+	c := &Conn{sysconn: &fakeBackend{}}
+	ctx := context.Background()
+
+	if err := c.jobError(ctx, "foo.service", "done"); err != nil {
+		t.Errorf("done: got error %v", err)
+	}
+
+	err := c.jobError(ctx, "foo.service", "failed")
+	if !errors.Is(err, ErrStartLimitHit) {
+		t.Errorf("got error %v, want ErrStartLimitHit", err)
+	}
+	if jobErr, ok := err.(*JobError); !ok || jobErr.UnitResult != "start-limit-hit" {
+		t.Errorf("got error %#v, want *JobError with unit result start-limit-hit", err)
+	}
+
+	err = c.jobError(ctx, "foo.service", "canceled")
+	if errors.Is(err, ErrStartLimitHit) {
+		t.Errorf("canceled job should not match ErrStartLimitHit: %v", err)
+	}
+}
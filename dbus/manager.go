@@ -0,0 +1,45 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"context"
+)
+
+// Manager is the subset of the methods of Conn used to manage units at
+// runtime. Applications can depend on Manager rather than *Conn, and use the
+// in-memory implementation of the dbustest package in their tests.
+type Manager interface {
+	StartUnitContext(ctx context.Context, name string, mode string, ch chan<- string) (int, error)
+	StopUnitContext(ctx context.Context, name string, mode string, ch chan<- string) (int, error)
+	ReloadUnitContext(ctx context.Context, name string, mode string, ch chan<- string) (int, error)
+	RestartUnitContext(ctx context.Context, name string, mode string, ch chan<- string) (int, error)
+	TryRestartUnitContext(ctx context.Context, name string, mode string, ch chan<- string) (int, error)
+	ReloadOrRestartUnitContext(ctx context.Context, name string, mode string, ch chan<- string) (int, error)
+	KillUnitContext(ctx context.Context, name string, signal int32) error
+	ResetFailedUnitContext(ctx context.Context, name string) error
+
+	ListUnitsContext(ctx context.Context) ([]UnitStatus, error)
+	ListUnitsFilteredContext(ctx context.Context, states []string) ([]UnitStatus, error)
+	ListUnitsByPatternsContext(ctx context.Context, states []string, patterns []string) ([]UnitStatus, error)
+	ListUnitsByNamesContext(ctx context.Context, units []string) ([]UnitStatus, error)
+	GetUnitPropertiesContext(ctx context.Context, unit string) (map[string]interface{}, error)
+	GetUnitPropertyContext(ctx context.Context, unit string, propertyName string) (*Property, error)
+
+	ReloadContext(ctx context.Context) error
+	Close()
+}
+
+var _ Manager = (*Conn)(nil)
@@ -15,52 +15,25 @@
 package dbus
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"path"
-	"strconv"
+	"strings"
 
 	"github.com/godbus/dbus/v5"
 )
 
-func (c *Conn) jobComplete(signal *dbus.Signal) {
-	var id uint32
-	var job dbus.ObjectPath
-	var unit string
-	var result string
-	dbus.Store(signal.Body, &id, &job, &unit, &result)
-	c.jobListener.Lock()
-	out, ok := c.jobListener.jobs[job]
-	if ok {
-		out <- result
-		delete(c.jobListener.jobs, job)
-	}
-	c.jobListener.Unlock()
-}
-
-func (c *Conn) startJob(ch chan<- string, job string, args ...interface{}) (int, error) {
-	if ch != nil {
-		c.jobListener.Lock()
-		defer c.jobListener.Unlock()
-	}
-
-	var p dbus.ObjectPath
-	err := c.sysobj.Call(job, 0, args...).Store(&p)
-	if err != nil {
-		return 0, err
-	}
-
-	if ch != nil {
-		c.jobListener.jobs[p] = ch
-	}
-
-	// ignore error since 0 is fine if conversion fails
-	jobID, _ := strconv.Atoi(path.Base(string(p)))
-
-	return jobID, nil
+// StartUnit is a wrapper around StartUnitContext.
+//
+// Deprecated: use StartUnitContext instead.
+func (c *Conn) StartUnit(name string, mode string, ch chan<- string) (int, error) {
+	return c.StartUnitContext(context.Background(), name, mode, ch)
 }
 
-// StartUnit enqueues a start job and depending jobs, if any (unless otherwise
+// StartUnitContext enqueues a start job and depending jobs, if any (unless otherwise
 // specified by the mode string).
 //
 // Takes the unit to activate, plus a mode string. The mode needs to be one of
@@ -90,72 +63,231 @@ func (c *Conn) startJob(ch chan<- string, job string, args ...interface{}) (int,
 // should not be considered authoritative.
 //
 // If an error does occur, it will be returned to the user alongside a job ID of 0.
-func (c *Conn) StartUnit(name string, mode string, ch chan<- string) (int, error) {
-	return c.startJob(ch, "org.freedesktop.systemd1.Manager.StartUnit", name, mode)
+//
+// The method call is abandoned if ctx is done before systemd replies. The
+// job result is delivered asynchronously, so callers that want to bound the
+// wait for it should select on ctx.Done() alongside the channel.
+func (c *Conn) StartUnitContext(ctx context.Context, name string, mode string, ch chan<- string) (int, error) {
+	return c.startJob(ctx, ch, "org.freedesktop.systemd1.Manager.StartUnit", name, mode)
 }
 
-// StopUnit is similar to StartUnit but stops the specified unit rather
-// than starting it.
+// StartUnitAndWait starts the unit name like StartUnitContext, and waits
+// for the job to complete. Failures are classified, so that supervisors can
+// react to them: if the unit is masked, the error matches ErrUnitMasked; if it
+// was started too often, the error matches ErrStartLimitHit. Any other job
+// failure is returned as a *JobError.
+func (c *Conn) StartUnitAndWait(ctx context.Context, name string, mode string) error {
+	result, err := c.RunJob(ctx, 0, func(ch chan<- string) (int, error) {
+		return c.StartUnitContext(ctx, name, mode, ch)
+	})
+	if err != nil {
+		return err
+	}
+	return c.jobError(ctx, name, result)
+}
+
+// StopUnit is a wrapper around StopUnitContext.
+//
+// Deprecated: use StopUnitContext instead.
 func (c *Conn) StopUnit(name string, mode string, ch chan<- string) (int, error) {
-	return c.startJob(ch, "org.freedesktop.systemd1.Manager.StopUnit", name, mode)
+	return c.StopUnitContext(context.Background(), name, mode, ch)
+}
+
+// StopUnitContext is similar to StartUnit but stops the specified unit rather
+// than starting it.
+func (c *Conn) StopUnitContext(ctx context.Context, name string, mode string, ch chan<- string) (int, error) {
+	return c.startJob(ctx, ch, "org.freedesktop.systemd1.Manager.StopUnit", name, mode)
 }
 
-// ReloadUnit reloads a unit.  Reloading is done only if the unit is already running and fails otherwise.
+// ReloadUnit is a wrapper around ReloadUnitContext.
+//
+// Deprecated: use ReloadUnitContext instead.
 func (c *Conn) ReloadUnit(name string, mode string, ch chan<- string) (int, error) {
-	return c.startJob(ch, "org.freedesktop.systemd1.Manager.ReloadUnit", name, mode)
+	return c.ReloadUnitContext(context.Background(), name, mode, ch)
 }
 
-// RestartUnit restarts a service.  If a service is restarted that isn't
-// running it will be started.
+// ReloadUnitContext reloads a unit.  Reloading is done only if the unit is already running and fails otherwise.
+func (c *Conn) ReloadUnitContext(ctx context.Context, name string, mode string, ch chan<- string) (int, error) {
+	return c.startJob(ctx, ch, "org.freedesktop.systemd1.Manager.ReloadUnit", name, mode)
+}
+
+// RestartUnit is a wrapper around RestartUnitContext.
+//
+// Deprecated: use RestartUnitContext instead.
 func (c *Conn) RestartUnit(name string, mode string, ch chan<- string) (int, error) {
-	return c.startJob(ch, "org.freedesktop.systemd1.Manager.RestartUnit", name, mode)
+	return c.RestartUnitContext(context.Background(), name, mode, ch)
 }
 
-// TryRestartUnit is like RestartUnit, except that a service that isn't running
-// is not affected by the restart.
+// RestartUnitContext restarts a service.  If a service is restarted that isn't
+// running it will be started.
+func (c *Conn) RestartUnitContext(ctx context.Context, name string, mode string, ch chan<- string) (int, error) {
+	return c.startJob(ctx, ch, "org.freedesktop.systemd1.Manager.RestartUnit", name, mode)
+}
+
+// TryRestartUnit is a wrapper around TryRestartUnitContext.
+//
+// Deprecated: use TryRestartUnitContext instead.
 func (c *Conn) TryRestartUnit(name string, mode string, ch chan<- string) (int, error) {
-	return c.startJob(ch, "org.freedesktop.systemd1.Manager.TryRestartUnit", name, mode)
+	return c.TryRestartUnitContext(context.Background(), name, mode, ch)
 }
 
-// ReloadOrRestartUnit attempts a reload if the unit supports it and use a restart
-// otherwise.
+// TryRestartUnitContext is like RestartUnit, except that a service that isn't running
+// is not affected by the restart.
+func (c *Conn) TryRestartUnitContext(ctx context.Context, name string, mode string, ch chan<- string) (int, error) {
+	return c.startJob(ctx, ch, "org.freedesktop.systemd1.Manager.TryRestartUnit", name, mode)
+}
+
+// ReloadOrRestartUnit is a wrapper around ReloadOrRestartUnitContext.
+//
+// Deprecated: use ReloadOrRestartUnitContext instead.
 func (c *Conn) ReloadOrRestartUnit(name string, mode string, ch chan<- string) (int, error) {
-	return c.startJob(ch, "org.freedesktop.systemd1.Manager.ReloadOrRestartUnit", name, mode)
+	return c.ReloadOrRestartUnitContext(context.Background(), name, mode, ch)
 }
 
-// ReloadOrTryRestartUnit attempts a reload if the unit supports it and use a "Try"
-// flavored restart otherwise.
+// ReloadOrRestartUnitContext attempts a reload if the unit supports it and use a restart
+// otherwise.
+func (c *Conn) ReloadOrRestartUnitContext(ctx context.Context, name string, mode string, ch chan<- string) (int, error) {
+	return c.startJob(ctx, ch, "org.freedesktop.systemd1.Manager.ReloadOrRestartUnit", name, mode)
+}
+
+// ReloadOrTryRestartUnit is a wrapper around ReloadOrTryRestartUnitContext.
+//
+// Deprecated: use ReloadOrTryRestartUnitContext instead.
 func (c *Conn) ReloadOrTryRestartUnit(name string, mode string, ch chan<- string) (int, error) {
-	return c.startJob(ch, "org.freedesktop.systemd1.Manager.ReloadOrTryRestartUnit", name, mode)
+	return c.ReloadOrTryRestartUnitContext(context.Background(), name, mode, ch)
+}
+
+// ReloadOrTryRestartUnitContext attempts a reload if the unit supports it and use a "Try"
+// flavored restart otherwise.
+func (c *Conn) ReloadOrTryRestartUnitContext(ctx context.Context, name string, mode string, ch chan<- string) (int, error) {
+	return c.startJob(ctx, ch, "org.freedesktop.systemd1.Manager.ReloadOrTryRestartUnit", name, mode)
 }
 
-// StartTransientUnit() may be used to create and start a transient unit, which
+// StartTransientUnit is a wrapper around StartTransientUnitContext.
+//
+// Deprecated: use StartTransientUnitContext instead.
+func (c *Conn) StartTransientUnit(name string, mode string, properties []Property, ch chan<- string) (int, error) {
+	return c.StartTransientUnitContext(context.Background(), name, mode, properties, ch)
+}
+
+// StartTransientUnitContext may be used to create and start a transient unit, which
 // will be released as soon as it is not running or referenced anymore or the
 // system is rebooted. name is the unit name including suffix, and must be
 // unique. mode is the same as in StartUnit(), properties contains properties
 // of the unit.
-func (c *Conn) StartTransientUnit(name string, mode string, properties []Property, ch chan<- string) (int, error) {
-	return c.startJob(ch, "org.freedesktop.systemd1.Manager.StartTransientUnit", name, mode, properties, make([]PropertyCollection, 0))
+func (c *Conn) StartTransientUnitContext(ctx context.Context, name string, mode string, properties []Property, ch chan<- string) (int, error) {
+	return c.startJob(ctx, ch, "org.freedesktop.systemd1.Manager.StartTransientUnit", name, mode, properties, make([]PropertyCollection, 0))
+}
+
+// Who can be used to specify which process to kill in the unit via the KillUnitWithTarget API
+type Who string
+
+const (
+	// All sends the signal to all processes in the unit
+	All Who = "all"
+	// Main sends the signal to the main process of the unit
+	Main Who = "main"
+	// Control sends the signal to the control process of the unit
+	Control Who = "control"
+)
+
+// KillUnit is a wrapper around KillUnitContext.
+//
+// Deprecated: use KillUnitContext instead.
+func (c *Conn) KillUnit(name string, signal int32) error {
+	return c.KillUnitContext(context.Background(), name, signal)
 }
 
-// KillUnit takes the unit name and a UNIX signal number to send.  All of the unit's
+// KillUnitContext takes the unit name and a UNIX signal number to send.  All of the unit's
 // processes are killed.
-func (c *Conn) KillUnit(name string, signal int32) {
-	c.sysobj.Call("org.freedesktop.systemd1.Manager.KillUnit", 0, name, "all", signal).Store()
+func (c *Conn) KillUnitContext(ctx context.Context, name string, signal int32) error {
+	return c.KillUnitWithTarget(ctx, name, All, signal)
+}
+
+// KillUnitWithTarget is like KillUnitContext, but allows you to specify which
+// process within the unit to send the signal to: its main process, its
+// control process, or all of its processes.
+func (c *Conn) KillUnitWithTarget(ctx context.Context, name string, target Who, signal int32) error {
+	return c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.KillUnit", 0, name, string(target), signal).Store()
+}
+
+// QueueSignalUnit is like KillUnitWithTarget, but queues the realtime signal
+// signal, between SIGRTMIN and SIGRTMAX, along with value, via sigqueue(3).
+// Processes receive value in the si_value field of their siginfo_t, which
+// some daemons use for reexec or log rotation protocols. It requires
+// systemd 254 or newer.
+func (c *Conn) QueueSignalUnit(ctx context.Context, name string, target Who, signal int32, value int32) error {
+	return c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.QueueSignalUnit", 0, name, string(target), signal, value).Store()
 }
 
-// ResetFailedUnit resets the "failed" state of a specific unit.
+// ResetFailedUnit is a wrapper around ResetFailedUnitContext.
+//
+// Deprecated: use ResetFailedUnitContext instead.
 func (c *Conn) ResetFailedUnit(name string) error {
-	return c.sysobj.Call("org.freedesktop.systemd1.Manager.ResetFailedUnit", 0, name).Store()
+	return c.ResetFailedUnitContext(context.Background(), name)
 }
 
-// SystemState returns the systemd state. Equivalent to `systemctl is-system-running`.
+// ResetFailedUnitContext resets the "failed" state of a specific unit.
+func (c *Conn) ResetFailedUnitContext(ctx context.Context, name string) error {
+	return c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.ResetFailedUnit", 0, name).Store()
+}
+
+// FreezeUnit freezes all processes of the unit using the cgroup freezer,
+// like `systemctl freeze`. Note that FreezeUnit and ThawUnit are only
+// supported on systems running with the unified cgroup hierarchy (cgroup v2).
+func (c *Conn) FreezeUnit(ctx context.Context, unit string) error {
+	return c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.FreezeUnit", 0, unit).Store()
+}
+
+// ThawUnit resumes the processes of a unit frozen with FreezeUnit.
+func (c *Conn) ThawUnit(ctx context.Context, unit string) error {
+	return c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.ThawUnit", 0, unit).Store()
+}
+
+// CleanUnit removes the configuration, state, cache, log and runtime
+// directories of the unit, like `systemctl clean`. mask selects the kinds of
+// resources to remove: any of "configuration", "state", "cache", "logs",
+// "runtime" and "fdstore", or "all". The unit must not be running.
+func (c *Conn) CleanUnit(ctx context.Context, unit string, mask []string) error {
+	return c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.CleanUnit", 0, unit, mask).Store()
+}
+
+// RefUnit adds a reference to the unit held by this connection, which keeps
+// systemd from unloading it, for example when a transient unit becomes
+// inactive. The reference is released with UnrefUnit, and when the bus
+// connection is closed or lost.
+func (c *Conn) RefUnit(ctx context.Context, unit string) error {
+	return c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.RefUnit", 0, unit).Store()
+}
+
+// UnrefUnit releases a reference added with RefUnit.
+func (c *Conn) UnrefUnit(ctx context.Context, unit string) error {
+	return c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.UnrefUnit", 0, unit).Store()
+}
+
+// AttachProcessesToUnit moves existing processes, identified by pids, into an
+// existing systemd unit's cgroup. The unit must be a service or scope with
+// Delegate=yes. subcgroup is a path relative to the unit's cgroup, or empty
+// for the unit's cgroup itself; the processes must be owned by the caller or
+// already be part of the unit's delegated subtree.
+func (c *Conn) AttachProcessesToUnit(ctx context.Context, unit, subcgroup string, pids []uint32) error {
+	return c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.AttachProcessesToUnit", 0, unit, subcgroup, pids).Store()
+}
+
+// SystemState is a wrapper around SystemStateContext.
+//
+// Deprecated: use SystemStateContext instead.
 func (c *Conn) SystemState() (*Property, error) {
+	return c.SystemStateContext(context.Background())
+}
+
+// SystemStateContext returns the systemd state. Equivalent to `systemctl is-system-running`.
+func (c *Conn) SystemStateContext(ctx context.Context) (*Property, error) {
 	var err error
 	var prop dbus.Variant
 
-	obj := c.sysconn.Object("org.freedesktop.systemd1", "/org/freedesktop/systemd1")
-	err = obj.Call("org.freedesktop.DBus.Properties.Get", 0, "org.freedesktop.systemd1.Manager", "SystemState").Store(&prop)
+	obj := c.object("/org/freedesktop/systemd1")
+	err = obj.CallWithContext(ctx, "org.freedesktop.DBus.Properties.Get", 0, "org.freedesktop.systemd1.Manager", "SystemState").Store(&prop)
 	if err != nil {
 		return nil, err
 	}
@@ -163,8 +295,39 @@ func (c *Conn) SystemState() (*Property, error) {
 	return &Property{Name: "SystemState", Value: prop}, nil
 }
 
+// ManagerStatus summarizes the state of the service manager.
+type ManagerStatus struct {
+	Version      string // The systemd version, e.g. "245"
+	Features     string // The compile-time features, e.g. "+PAM +AUDIT -SELINUX"
+	Architecture string // The architecture systemd runs on, e.g. "x86-64"
+	SystemState  string // The system state, as reported by `systemctl is-system-running`
+	NNames       uint32 // The number of unit names currently loaded
+	NJobs        uint32 // The number of jobs currently queued
+	NFailedUnits uint32 // The number of units currently in the failed state
+}
+
+// GetManagerStatus returns typed values of a selection of the properties of
+// the org.freedesktop.systemd1.Manager interface.
+func (c *Conn) GetManagerStatus(ctx context.Context) (*ManagerStatus, error) {
+	props, err := c.getProperties(ctx, "/org/freedesktop/systemd1", "org.freedesktop.systemd1.Manager")
+	if err != nil {
+		return nil, err
+	}
+
+	status := &ManagerStatus{}
+	status.Version, _ = props["Version"].(string)
+	status.Features, _ = props["Features"].(string)
+	status.Architecture, _ = props["Architecture"].(string)
+	status.SystemState, _ = props["SystemState"].(string)
+	status.NNames, _ = props["NNames"].(uint32)
+	status.NJobs, _ = props["NJobs"].(uint32)
+	status.NFailedUnits, _ = props["NFailedUnits"].(uint32)
+
+	return status, nil
+}
+
 // getProperties takes the unit path and returns all of its dbus object properties, for the given dbus interface
-func (c *Conn) getProperties(path dbus.ObjectPath, dbusInterface string) (map[string]interface{}, error) {
+func (c *Conn) getProperties(ctx context.Context, path dbus.ObjectPath, dbusInterface string) (map[string]interface{}, error) {
 	var err error
 	var props map[string]dbus.Variant
 
@@ -172,8 +335,8 @@ func (c *Conn) getProperties(path dbus.ObjectPath, dbusInterface string) (map[st
 		return nil, fmt.Errorf("invalid unit name: %v", path)
 	}
 
-	obj := c.sysconn.Object("org.freedesktop.systemd1", path)
-	err = obj.Call("org.freedesktop.DBus.Properties.GetAll", 0, dbusInterface).Store(&props)
+	obj := c.object(path)
+	err = obj.CallWithContext(ctx, "org.freedesktop.DBus.Properties.GetAll", 0, dbusInterface).Store(&props)
 	if err != nil {
 		return nil, err
 	}
@@ -186,24 +349,45 @@ func (c *Conn) getProperties(path dbus.ObjectPath, dbusInterface string) (map[st
 	return out, nil
 }
 
-// GetUnitProperties takes the (unescaped) unit name and returns all of its dbus object properties.
+// GetUnitProperties is a wrapper around GetUnitPropertiesContext.
+//
+// Deprecated: use GetUnitPropertiesContext instead.
 func (c *Conn) GetUnitProperties(unit string) (map[string]interface{}, error) {
+	return c.GetUnitPropertiesContext(context.Background(), unit)
+}
+
+// GetUnitPropertiesContext takes the (unescaped) unit name and returns all of its dbus object properties.
+func (c *Conn) GetUnitPropertiesContext(ctx context.Context, unit string) (map[string]interface{}, error) {
 	path := unitPath(unit)
-	return c.getProperties(path, "org.freedesktop.systemd1.Unit")
+	return c.getProperties(ctx, path, "org.freedesktop.systemd1.Unit")
 }
 
-// GetUnitPathProperties takes the (escaped) unit path and returns all of its dbus object properties.
+// GetUnitPathProperties is a wrapper around GetUnitPathPropertiesContext.
+//
+// Deprecated: use GetUnitPathPropertiesContext instead.
 func (c *Conn) GetUnitPathProperties(path dbus.ObjectPath) (map[string]interface{}, error) {
-	return c.getProperties(path, "org.freedesktop.systemd1.Unit")
+	return c.GetUnitPathPropertiesContext(context.Background(), path)
+}
+
+// GetUnitPathPropertiesContext takes the (escaped) unit path and returns all of its dbus object properties.
+func (c *Conn) GetUnitPathPropertiesContext(ctx context.Context, path dbus.ObjectPath) (map[string]interface{}, error) {
+	return c.getProperties(ctx, path, "org.freedesktop.systemd1.Unit")
 }
 
-// GetAllProperties takes the (unescaped) unit name and returns all of its dbus object properties.
+// GetAllProperties is a wrapper around GetAllPropertiesContext.
+//
+// Deprecated: use GetAllPropertiesContext instead.
 func (c *Conn) GetAllProperties(unit string) (map[string]interface{}, error) {
+	return c.GetAllPropertiesContext(context.Background(), unit)
+}
+
+// GetAllPropertiesContext takes the (unescaped) unit name and returns all of its dbus object properties.
+func (c *Conn) GetAllPropertiesContext(ctx context.Context, unit string) (map[string]interface{}, error) {
 	path := unitPath(unit)
-	return c.getProperties(path, "")
+	return c.getProperties(ctx, path, "")
 }
 
-func (c *Conn) getProperty(unit string, dbusInterface string, propertyName string) (*Property, error) {
+func (c *Conn) getProperty(ctx context.Context, unit string, dbusInterface string, propertyName string) (*Property, error) {
 	var err error
 	var prop dbus.Variant
 
@@ -212,8 +396,8 @@ func (c *Conn) getProperty(unit string, dbusInterface string, propertyName strin
 		return nil, errors.New("invalid unit name: " + unit)
 	}
 
-	obj := c.sysconn.Object("org.freedesktop.systemd1", path)
-	err = obj.Call("org.freedesktop.DBus.Properties.Get", 0, dbusInterface, propertyName).Store(&prop)
+	obj := c.object(path)
+	err = obj.CallWithContext(ctx, "org.freedesktop.DBus.Properties.Get", 0, dbusInterface, propertyName).Store(&prop)
 	if err != nil {
 		return nil, err
 	}
@@ -221,36 +405,75 @@ func (c *Conn) getProperty(unit string, dbusInterface string, propertyName strin
 	return &Property{Name: propertyName, Value: prop}, nil
 }
 
+// GetUnitProperty is a wrapper around GetUnitPropertyContext.
+//
+// Deprecated: use GetUnitPropertyContext instead.
 func (c *Conn) GetUnitProperty(unit string, propertyName string) (*Property, error) {
-	return c.getProperty(unit, "org.freedesktop.systemd1.Unit", propertyName)
+	return c.GetUnitPropertyContext(context.Background(), unit, propertyName)
+}
+
+// GetUnitPropertyContext takes an (unescaped) unit name, and a property name,
+// and returns the property value.
+func (c *Conn) GetUnitPropertyContext(ctx context.Context, unit string, propertyName string) (*Property, error) {
+	return c.getProperty(ctx, unit, "org.freedesktop.systemd1.Unit", propertyName)
 }
 
-// GetServiceProperty returns property for given service name and property name
+// GetServiceProperty is a wrapper around GetServicePropertyContext.
+//
+// Deprecated: use GetServicePropertyContext instead.
 func (c *Conn) GetServiceProperty(service string, propertyName string) (*Property, error) {
-	return c.getProperty(service, "org.freedesktop.systemd1.Service", propertyName)
+	return c.GetServicePropertyContext(context.Background(), service, propertyName)
 }
 
-// GetUnitTypeProperties returns the extra properties for a unit, specific to the unit type.
+// GetServicePropertyContext returns property for given service name and property name
+func (c *Conn) GetServicePropertyContext(ctx context.Context, service string, propertyName string) (*Property, error) {
+	return c.getProperty(ctx, service, "org.freedesktop.systemd1.Service", propertyName)
+}
+
+// GetUnitTypeProperties is a wrapper around GetUnitTypePropertiesContext.
+//
+// Deprecated: use GetUnitTypePropertiesContext instead.
+func (c *Conn) GetUnitTypeProperties(unit string, unitType string) (map[string]interface{}, error) {
+	return c.GetUnitTypePropertiesContext(context.Background(), unit, unitType)
+}
+
+// GetUnitTypePropertiesContext returns the extra properties for a unit, specific to the unit type.
 // Valid values for unitType: Service, Socket, Target, Device, Mount, Automount, Snapshot, Timer, Swap, Path, Slice, Scope
 // return "dbus.Error: Unknown interface" if the unitType is not the correct type of the unit
-func (c *Conn) GetUnitTypeProperties(unit string, unitType string) (map[string]interface{}, error) {
+func (c *Conn) GetUnitTypePropertiesContext(ctx context.Context, unit string, unitType string) (map[string]interface{}, error) {
 	path := unitPath(unit)
-	return c.getProperties(path, "org.freedesktop.systemd1."+unitType)
+	return c.getProperties(ctx, path, "org.freedesktop.systemd1."+unitType)
+}
+
+// SetUnitProperties is a wrapper around SetUnitPropertiesContext.
+//
+// Deprecated: use SetUnitPropertiesContext instead.
+func (c *Conn) SetUnitProperties(name string, runtime bool, properties ...Property) error {
+	return c.SetUnitPropertiesContext(context.Background(), name, runtime, properties...)
 }
 
-// SetUnitProperties() may be used to modify certain unit properties at runtime.
+// SetUnitPropertiesContext may be used to modify certain unit properties at runtime.
 // Not all properties may be changed at runtime, but many resource management
 // settings (primarily those in systemd.cgroup(5)) may. The changes are applied
 // instantly, and stored on disk for future boots, unless runtime is true, in which
 // case the settings only apply until the next reboot. name is the name of the unit
 // to modify. properties are the settings to set, encoded as an array of property
 // name and value pairs.
-func (c *Conn) SetUnitProperties(name string, runtime bool, properties ...Property) error {
-	return c.sysobj.Call("org.freedesktop.systemd1.Manager.SetUnitProperties", 0, name, runtime, properties).Store()
+func (c *Conn) SetUnitPropertiesContext(ctx context.Context, name string, runtime bool, properties ...Property) error {
+	return c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.SetUnitProperties", 0, name, runtime, properties).Store()
 }
 
+// GetUnitTypeProperty is a wrapper around GetUnitTypePropertyContext.
+//
+// Deprecated: use GetUnitTypePropertyContext instead.
 func (c *Conn) GetUnitTypeProperty(unit string, unitType string, propertyName string) (*Property, error) {
-	return c.getProperty(unit, "org.freedesktop.systemd1."+unitType, propertyName)
+	return c.GetUnitTypePropertyContext(context.Background(), unit, unitType, propertyName)
+}
+
+// GetUnitTypePropertyContext takes a property name, a unit name, and a unit type,
+// and returns a property value. For valid values of unitType, see GetUnitTypePropertiesContext.
+func (c *Conn) GetUnitTypePropertyContext(ctx context.Context, unit string, unitType string, propertyName string) (*Property, error) {
+	return c.getProperty(ctx, unit, "org.freedesktop.systemd1."+unitType, propertyName)
 }
 
 type UnitStatus struct {
@@ -294,98 +517,133 @@ func (c *Conn) listUnitsInternal(f storeFunc) ([]UnitStatus, error) {
 	return status, nil
 }
 
-// ListUnits returns an array with all currently loaded units. Note that
+// ListUnits is a wrapper around ListUnitsContext.
+//
+// Deprecated: use ListUnitsContext instead.
+func (c *Conn) ListUnits() ([]UnitStatus, error) {
+	return c.ListUnitsContext(context.Background())
+}
+
+// ListUnitsContext returns an array with all currently loaded units. Note that
 // units may be known by multiple names at the same time, and hence there might
 // be more unit names loaded than actual units behind them.
 // Also note that a unit is only loaded if it is active and/or enabled.
 // Units that are both disabled and inactive will thus not be returned.
-func (c *Conn) ListUnits() ([]UnitStatus, error) {
-	return c.listUnitsInternal(c.sysobj.Call("org.freedesktop.systemd1.Manager.ListUnits", 0).Store)
+func (c *Conn) ListUnitsContext(ctx context.Context) ([]UnitStatus, error) {
+	return c.listUnitsInternal(c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.ListUnits", 0).Store)
 }
 
-// ListUnitsFiltered returns an array with units filtered by state.
-// It takes a list of units' statuses to filter.
+// ListUnitsFiltered is a wrapper around ListUnitsFilteredContext.
+//
+// Deprecated: use ListUnitsFilteredContext instead.
 func (c *Conn) ListUnitsFiltered(states []string) ([]UnitStatus, error) {
-	return c.listUnitsInternal(c.sysobj.Call("org.freedesktop.systemd1.Manager.ListUnitsFiltered", 0, states).Store)
+	return c.ListUnitsFilteredContext(context.Background(), states)
+}
+
+// ListUnitsFilteredContext returns an array with units filtered by state.
+// It takes a list of units' statuses to filter.
+func (c *Conn) ListUnitsFilteredContext(ctx context.Context, states []string) ([]UnitStatus, error) {
+	return c.listUnitsInternal(c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.ListUnitsFiltered", 0, states).Store)
+}
+
+// ListUnitsByPatterns is a wrapper around ListUnitsByPatternsContext.
+//
+// Deprecated: use ListUnitsByPatternsContext instead.
+func (c *Conn) ListUnitsByPatterns(states []string, patterns []string) ([]UnitStatus, error) {
+	return c.ListUnitsByPatternsContext(context.Background(), states, patterns)
 }
 
-// ListUnitsByPatterns returns an array with units.
+// ListUnitsByPatternsContext returns an array with units.
 // It takes a list of units' statuses and names to filter.
 // Note that units may be known by multiple names at the same time,
 // and hence there might be more unit names loaded than actual units behind them.
-func (c *Conn) ListUnitsByPatterns(states []string, patterns []string) ([]UnitStatus, error) {
-	return c.listUnitsInternal(c.sysobj.Call("org.freedesktop.systemd1.Manager.ListUnitsByPatterns", 0, states, patterns).Store)
+func (c *Conn) ListUnitsByPatternsContext(ctx context.Context, states []string, patterns []string) ([]UnitStatus, error) {
+	return c.listUnitsInternal(c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.ListUnitsByPatterns", 0, states, patterns).Store)
+}
+
+// ListUnitsByNames is a wrapper around ListUnitsByNamesContext.
+//
+// Deprecated: use ListUnitsByNamesContext instead.
+func (c *Conn) ListUnitsByNames(units []string) ([]UnitStatus, error) {
+	return c.ListUnitsByNamesContext(context.Background(), units)
 }
 
-// ListUnitsByNames returns an array with units. It takes a list of units'
+// ListUnitsByNamesContext returns an array with units. It takes a list of units'
 // names and returns an UnitStatus array. Comparing to ListUnitsByPatterns
 // method, this method returns statuses even for inactive or non-existing
 // units. Input array should contain exact unit names, but not patterns.
 // Note: Requires systemd v230 or higher
-func (c *Conn) ListUnitsByNames(units []string) ([]UnitStatus, error) {
-	return c.listUnitsInternal(c.sysobj.Call("org.freedesktop.systemd1.Manager.ListUnitsByNames", 0, units).Store)
+func (c *Conn) ListUnitsByNamesContext(ctx context.Context, units []string) ([]UnitStatus, error) {
+	return c.listUnitsInternal(c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.ListUnitsByNames", 0, units).Store)
 }
 
-type UnitFile struct {
-	Path string
-	Type string
+// GetUnitByPID returns the object path of the unit the process with the
+// given PID belongs to. If the process does not belong to any unit, an error
+// matching ErrNoUnitForPID is returned.
+func (c *Conn) GetUnitByPID(ctx context.Context, pid uint32) (dbus.ObjectPath, error) {
+	var path dbus.ObjectPath
+	err := c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.GetUnitByPID", 0, pid).Store(&path)
+	if err != nil {
+		return "", err
+	}
+	return path, nil
 }
 
-func (c *Conn) listUnitFilesInternal(f storeFunc) ([]UnitFile, error) {
-	result := make([][]interface{}, 0)
-	err := f(&result)
+// GetUnitNameByPID returns the name of the unit the process with the given
+// PID belongs to.
+func (c *Conn) GetUnitNameByPID(ctx context.Context, pid uint32) (string, error) {
+	path, err := c.GetUnitByPID(ctx, pid)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
+	return c.unitID(ctx, path)
+}
 
-	resultInterface := make([]interface{}, len(result))
-	for i := range result {
-		resultInterface[i] = result[i]
+// unitID returns the Id property, the primary name, of the unit at path.
+func (c *Conn) unitID(ctx context.Context, path dbus.ObjectPath) (string, error) {
+	var prop dbus.Variant
+	err := c.object(path).CallWithContext(ctx, "org.freedesktop.DBus.Properties.Get", 0, "org.freedesktop.systemd1.Unit", "Id").Store(&prop)
+	if err != nil {
+		return "", err
 	}
 
-	files := make([]UnitFile, len(result))
-	fileInterface := make([]interface{}, len(files))
-	for i := range files {
-		fileInterface[i] = &files[i]
+	name, ok := prop.Value().(string)
+	if !ok {
+		return "", fmt.Errorf("unexpected type for unit Id: %s", prop.Signature())
 	}
+	return name, nil
+}
 
-	err = dbus.Store(resultInterface, fileInterface...)
+// GetUnitStatusByPID returns the status of the unit the process with the
+// given PID belongs to.
+func (c *Conn) GetUnitStatusByPID(ctx context.Context, pid uint32) (*UnitStatus, error) {
+	name, err := c.GetUnitNameByPID(ctx, pid)
 	if err != nil {
 		return nil, err
 	}
 
-	return files, nil
-}
-
-// ListUnitFiles returns an array of all available units on disk.
-func (c *Conn) ListUnitFiles() ([]UnitFile, error) {
-	return c.listUnitFilesInternal(c.sysobj.Call("org.freedesktop.systemd1.Manager.ListUnitFiles", 0).Store)
+	units, err := c.ListUnitsByNamesContext(ctx, []string{name})
+	if err != nil {
+		return nil, err
+	}
+	if len(units) != 1 {
+		return nil, fmt.Errorf("unit %s not found", name)
+	}
+	return &units[0], nil
 }
 
-// ListUnitFilesByPatterns returns an array of all available units on disk matched the patterns.
-func (c *Conn) ListUnitFilesByPatterns(states []string, patterns []string) ([]UnitFile, error) {
-	return c.listUnitFilesInternal(c.sysobj.Call("org.freedesktop.systemd1.Manager.ListUnitFilesByPatterns", 0, states, patterns).Store)
+// UnitProcess is a process belonging to a unit.
+type UnitProcess struct {
+	Path    string // The cgroup of the process, relative to the cgroup root
+	PID     uint32 // The process ID
+	Command string // The command line of the process
 }
 
-type LinkUnitFileChange EnableUnitFileChange
-
-// LinkUnitFiles() links unit files (that are located outside of the
-// usual unit search paths) into the unit search path.
-//
-// It takes a list of absolute paths to unit files to link and two
-// booleans. The first boolean controls whether the unit shall be
-// enabled for runtime only (true, /run), or persistently (false,
-// /etc).
-// The second controls whether symlinks pointing to other units shall
-// be replaced if necessary.
-//
-// This call returns a list of the changes made. The list consists of
-// structures with three strings: the type of the change (one of symlink
-// or unlink), the file name of the symlink and the destination of the
-// symlink.
-func (c *Conn) LinkUnitFiles(files []string, runtime bool, force bool) ([]LinkUnitFileChange, error) {
+// GetUnitProcesses returns the processes belonging to the (unescaped) unit
+// name, including those of its sub-cgroups, like `systemctl status` shows.
+func (c *Conn) GetUnitProcesses(ctx context.Context, name string) ([]UnitProcess, error) {
 	result := make([][]interface{}, 0)
-	err := c.sysobj.Call("org.freedesktop.systemd1.Manager.LinkUnitFiles", 0, files, runtime, force).Store(&result)
+	err := c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.GetUnitProcesses", 0, name).Store(&result)
 	if err != nil {
 		return nil, err
 	}
@@ -395,43 +653,36 @@ func (c *Conn) LinkUnitFiles(files []string, runtime bool, force bool) ([]LinkUn
 		resultInterface[i] = result[i]
 	}
 
-	changes := make([]LinkUnitFileChange, len(result))
-	changesInterface := make([]interface{}, len(changes))
-	for i := range changes {
-		changesInterface[i] = &changes[i]
+	processes := make([]UnitProcess, len(result))
+	processesInterface := make([]interface{}, len(processes))
+	for i := range processes {
+		processesInterface[i] = &processes[i]
 	}
 
-	err = dbus.Store(resultInterface, changesInterface...)
+	err = dbus.Store(resultInterface, processesInterface...)
 	if err != nil {
 		return nil, err
 	}
 
-	return changes, nil
+	return processes, nil
 }
 
-// EnableUnitFiles() may be used to enable one or more units in the system (by
-// creating symlinks to them in /etc or /run).
-//
-// It takes a list of unit files to enable (either just file names or full
-// absolute paths if the unit files are residing outside the usual unit
-// search paths), and two booleans: the first controls whether the unit shall
-// be enabled for runtime only (true, /run), or persistently (false, /etc).
-// The second one controls whether symlinks pointing to other units shall
-// be replaced if necessary.
-//
-// This call returns one boolean and an array with the changes made. The
-// boolean signals whether the unit files contained any enablement
-// information (i.e. an [Install]) section. The changes list consists of
-// structures with three strings: the type of the change (one of symlink
-// or unlink), the file name of the symlink and the destination of the
-// symlink.
-func (c *Conn) EnableUnitFiles(files []string, runtime bool, force bool) (bool, []EnableUnitFileChange, error) {
-	var carries_install_info bool
+// JobStatus represents a job queued in systemd.
+type JobStatus struct {
+	Id       uint32          // The numeric job id
+	Unit     string          // The primary unit name for this job
+	JobType  string          // The job type as string
+	Status   string          // The job state as string
+	JobPath  dbus.ObjectPath // The job object path
+	UnitPath dbus.ObjectPath // The unit object path
+}
 
+// ListJobs returns an array with all currently queued jobs.
+func (c *Conn) ListJobs(ctx context.Context) ([]JobStatus, error) {
 	result := make([][]interface{}, 0)
-	err := c.sysobj.Call("org.freedesktop.systemd1.Manager.EnableUnitFiles", 0, files, runtime, force).Store(&carries_install_info, &result)
+	err := c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.ListJobs", 0).Store(&result)
 	if err != nil {
-		return false, nil, err
+		return nil, err
 	}
 
 	resultInterface := make([]interface{}, len(result))
@@ -439,62 +690,327 @@ func (c *Conn) EnableUnitFiles(files []string, runtime bool, force bool) (bool,
 		resultInterface[i] = result[i]
 	}
 
-	changes := make([]EnableUnitFileChange, len(result))
-	changesInterface := make([]interface{}, len(changes))
-	for i := range changes {
-		changesInterface[i] = &changes[i]
+	status := make([]JobStatus, len(result))
+	statusInterface := make([]interface{}, len(status))
+	for i := range status {
+		statusInterface[i] = &status[i]
 	}
 
-	err = dbus.Store(resultInterface, changesInterface...)
+	err = dbus.Store(resultInterface, statusInterface...)
 	if err != nil {
-		return false, nil, err
+		return nil, err
 	}
 
-	return carries_install_info, changes, nil
-}
-
-type EnableUnitFileChange struct {
-	Type        string // Type of the change (one of symlink or unlink)
-	Filename    string // File name of the symlink
-	Destination string // Destination of the symlink
+	return status, nil
 }
 
-// DisableUnitFiles() may be used to disable one or more units in the system (by
-// removing symlinks to them from /etc or /run).
-//
-// It takes a list of unit files to disable (either just file names or full
-// absolute paths if the unit files are residing outside the usual unit
-// search paths), and one boolean: whether the unit was enabled for runtime
-// only (true, /run), or persistently (false, /etc).
-//
-// This call returns an array with the changes made. The changes list
-// consists of structures with three strings: the type of the change (one of
-// symlink or unlink), the file name of the symlink and the destination of the
-// symlink.
-func (c *Conn) DisableUnitFiles(files []string, runtime bool) ([]DisableUnitFileChange, error) {
-	result := make([][]interface{}, 0)
-	err := c.sysobj.Call("org.freedesktop.systemd1.Manager.DisableUnitFiles", 0, files, runtime).Store(&result)
+// GetJob returns the queued job with the given id. If there is no such job,
+// an error matching ErrNoSuchJob is returned.
+func (c *Conn) GetJob(ctx context.Context, id int) (*JobStatus, error) {
+	var path dbus.ObjectPath
+	err := c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.GetJob", 0, uint32(id)).Store(&path)
 	if err != nil {
 		return nil, err
 	}
 
-	resultInterface := make([]interface{}, len(result))
-	for i := range result {
-		resultInterface[i] = result[i]
-	}
-
-	changes := make([]DisableUnitFileChange, len(result))
-	changesInterface := make([]interface{}, len(changes))
-	for i := range changes {
-		changesInterface[i] = &changes[i]
-	}
-
-	err = dbus.Store(resultInterface, changesInterface...)
+	props, err := c.getProperties(ctx, path, "org.freedesktop.systemd1.Job")
 	if err != nil {
 		return nil, err
 	}
 
-	return changes, nil
+	status := &JobStatus{JobPath: path}
+	status.Id, _ = props["Id"].(uint32)
+	status.JobType, _ = props["JobType"].(string)
+	status.Status, _ = props["State"].(string)
+	// Unit is a (so) struct holding the unit name and path
+	if unit, ok := props["Unit"].([]interface{}); ok && len(unit) == 2 {
+		status.Unit, _ = unit[0].(string)
+		status.UnitPath, _ = unit[1].(dbus.ObjectPath)
+	}
+
+	return status, nil
+}
+
+// CancelJob cancels the queued job with the given id.
+func (c *Conn) CancelJob(ctx context.Context, id int) error {
+	return c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.CancelJob", 0, uint32(id)).Store()
+}
+
+type UnitFile struct {
+	Path string
+	Type string
+}
+
+func (c *Conn) listUnitFilesInternal(f storeFunc) ([]UnitFile, error) {
+	result := make([][]interface{}, 0)
+	err := f(&result)
+	if err != nil {
+		return nil, err
+	}
+
+	resultInterface := make([]interface{}, len(result))
+	for i := range result {
+		resultInterface[i] = result[i]
+	}
+
+	files := make([]UnitFile, len(result))
+	fileInterface := make([]interface{}, len(files))
+	for i := range files {
+		fileInterface[i] = &files[i]
+	}
+
+	err = dbus.Store(resultInterface, fileInterface...)
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// ListUnitFiles is a wrapper around ListUnitFilesContext.
+//
+// Deprecated: use ListUnitFilesContext instead.
+func (c *Conn) ListUnitFiles() ([]UnitFile, error) {
+	return c.ListUnitFilesContext(context.Background())
+}
+
+// ListUnitFilesContext returns an array of all available units on disk.
+func (c *Conn) ListUnitFilesContext(ctx context.Context) ([]UnitFile, error) {
+	return c.listUnitFilesInternal(c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.ListUnitFiles", 0).Store)
+}
+
+// ListUnitFilesByPatterns is a wrapper around ListUnitFilesByPatternsContext.
+//
+// Deprecated: use ListUnitFilesByPatternsContext instead.
+func (c *Conn) ListUnitFilesByPatterns(states []string, patterns []string) ([]UnitFile, error) {
+	return c.ListUnitFilesByPatternsContext(context.Background(), states, patterns)
+}
+
+// ListUnitFilesByPatternsContext returns an array of all available units on disk matched the patterns.
+func (c *Conn) ListUnitFilesByPatternsContext(ctx context.Context, states []string, patterns []string) ([]UnitFile, error) {
+	return c.listUnitFilesInternal(c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.ListUnitFilesByPatterns", 0, states, patterns).Store)
+}
+
+// GetUnitFileState returns the install state of the unit file name, such as
+// "enabled", "disabled", "static" or "masked", like `systemctl is-enabled`.
+func (c *Conn) GetUnitFileState(ctx context.Context, name string) (string, error) {
+	var state string
+	err := c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.GetUnitFileState", 0, name).Store(&state)
+	if err != nil {
+		return "", err
+	}
+	return state, nil
+}
+
+// UnitFileContent is a file making up the configuration of a unit.
+type UnitFileContent struct {
+	Path    string // The path of the unit file or drop-in
+	Content string // The contents of the file
+}
+
+// GetUnitFileContent returns the files the configuration of the unit name is
+// loaded from, like systemctl cat: the unit file first, followed by its
+// drop-ins in the order they are applied. The files are read from the local
+// file system, so this is only meaningful for connections to the local
+// machine. Use FormatUnitFileContent to show the result.
+func (c *Conn) GetUnitFileContent(ctx context.Context, name string) ([]UnitFileContent, error) {
+	fragment, err := c.GetUnitPropertyContext(ctx, name, "FragmentPath")
+	if err != nil {
+		return nil, err
+	}
+	dropIns, err := c.GetUnitPropertyContext(ctx, name, "DropInPaths")
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	if path, ok := fragment.Value.Value().(string); ok && path != "" {
+		paths = append(paths, path)
+	}
+	if p, ok := dropIns.Value.Value().([]string); ok {
+		paths = append(paths, p...)
+	}
+	if len(paths) == 0 {
+		return nil, errors.New("no files found for " + name)
+	}
+
+	files := make([]UnitFileContent, 0, len(paths))
+	for _, path := range paths {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, UnitFileContent{Path: path, Content: string(content)})
+	}
+	return files, nil
+}
+
+// FormatUnitFileContent concatenates files, each preceded by a comment
+// naming its path, as systemctl cat does.
+func FormatUnitFileContent(files []UnitFileContent) string {
+	var b strings.Builder
+	for i, f := range files {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("# " + f.Path + "\n")
+		b.WriteString(f.Content)
+		if f.Content != "" && !strings.HasSuffix(f.Content, "\n") {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+type LinkUnitFileChange EnableUnitFileChange
+
+// LinkUnitFiles is a wrapper around LinkUnitFilesContext.
+//
+// Deprecated: use LinkUnitFilesContext instead.
+func (c *Conn) LinkUnitFiles(files []string, runtime bool, force bool) ([]LinkUnitFileChange, error) {
+	return c.LinkUnitFilesContext(context.Background(), files, runtime, force)
+}
+
+// LinkUnitFilesContext links unit files (that are located outside of the
+// usual unit search paths) into the unit search path.
+//
+// It takes a list of absolute paths to unit files to link and two
+// booleans. The first boolean controls whether the unit shall be
+// enabled for runtime only (true, /run), or persistently (false,
+// /etc).
+// The second controls whether symlinks pointing to other units shall
+// be replaced if necessary.
+//
+// This call returns a list of the changes made. The list consists of
+// structures with three strings: the type of the change (one of symlink
+// or unlink), the file name of the symlink and the destination of the
+// symlink.
+func (c *Conn) LinkUnitFilesContext(ctx context.Context, files []string, runtime bool, force bool) ([]LinkUnitFileChange, error) {
+	result := make([][]interface{}, 0)
+	err := c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.LinkUnitFiles", 0, files, runtime, force).Store(&result)
+	if err != nil {
+		return nil, err
+	}
+
+	resultInterface := make([]interface{}, len(result))
+	for i := range result {
+		resultInterface[i] = result[i]
+	}
+
+	changes := make([]LinkUnitFileChange, len(result))
+	changesInterface := make([]interface{}, len(changes))
+	for i := range changes {
+		changesInterface[i] = &changes[i]
+	}
+
+	err = dbus.Store(resultInterface, changesInterface...)
+	if err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// EnableUnitFiles is a wrapper around EnableUnitFilesContext.
+//
+// Deprecated: use EnableUnitFilesContext instead.
+func (c *Conn) EnableUnitFiles(files []string, runtime bool, force bool) (bool, []EnableUnitFileChange, error) {
+	return c.EnableUnitFilesContext(context.Background(), files, runtime, force)
+}
+
+// EnableUnitFilesContext may be used to enable one or more units in the system (by
+// creating symlinks to them in /etc or /run).
+//
+// It takes a list of unit files to enable (either just file names or full
+// absolute paths if the unit files are residing outside the usual unit
+// search paths), and two booleans: the first controls whether the unit shall
+// be enabled for runtime only (true, /run), or persistently (false, /etc).
+// The second one controls whether symlinks pointing to other units shall
+// be replaced if necessary.
+//
+// This call returns one boolean and an array with the changes made. The
+// boolean signals whether the unit files contained any enablement
+// information (i.e. an [Install]) section. The changes list consists of
+// structures with three strings: the type of the change (one of symlink
+// or unlink), the file name of the symlink and the destination of the
+// symlink.
+func (c *Conn) EnableUnitFilesContext(ctx context.Context, files []string, runtime bool, force bool) (bool, []EnableUnitFileChange, error) {
+	var carries_install_info bool
+
+	result := make([][]interface{}, 0)
+	err := c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.EnableUnitFiles", 0, files, runtime, force).Store(&carries_install_info, &result)
+	if err != nil {
+		return false, nil, err
+	}
+
+	resultInterface := make([]interface{}, len(result))
+	for i := range result {
+		resultInterface[i] = result[i]
+	}
+
+	changes := make([]EnableUnitFileChange, len(result))
+	changesInterface := make([]interface{}, len(changes))
+	for i := range changes {
+		changesInterface[i] = &changes[i]
+	}
+
+	err = dbus.Store(resultInterface, changesInterface...)
+	if err != nil {
+		return false, nil, err
+	}
+
+	return carries_install_info, changes, nil
+}
+
+type EnableUnitFileChange struct {
+	Type        string // Type of the change (one of symlink or unlink)
+	Filename    string // File name of the symlink
+	Destination string // Destination of the symlink
+}
+
+// DisableUnitFiles is a wrapper around DisableUnitFilesContext.
+//
+// Deprecated: use DisableUnitFilesContext instead.
+func (c *Conn) DisableUnitFiles(files []string, runtime bool) ([]DisableUnitFileChange, error) {
+	return c.DisableUnitFilesContext(context.Background(), files, runtime)
+}
+
+// DisableUnitFilesContext may be used to disable one or more units in the system (by
+// removing symlinks to them from /etc or /run).
+//
+// It takes a list of unit files to disable (either just file names or full
+// absolute paths if the unit files are residing outside the usual unit
+// search paths), and one boolean: whether the unit was enabled for runtime
+// only (true, /run), or persistently (false, /etc).
+//
+// This call returns an array with the changes made. The changes list
+// consists of structures with three strings: the type of the change (one of
+// symlink or unlink), the file name of the symlink and the destination of the
+// symlink.
+func (c *Conn) DisableUnitFilesContext(ctx context.Context, files []string, runtime bool) ([]DisableUnitFileChange, error) {
+	result := make([][]interface{}, 0)
+	err := c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.DisableUnitFiles", 0, files, runtime).Store(&result)
+	if err != nil {
+		return nil, err
+	}
+
+	resultInterface := make([]interface{}, len(result))
+	for i := range result {
+		resultInterface[i] = result[i]
+	}
+
+	changes := make([]DisableUnitFileChange, len(result))
+	changesInterface := make([]interface{}, len(changes))
+	for i := range changes {
+		changesInterface[i] = &changes[i]
+	}
+
+	err = dbus.Store(resultInterface, changesInterface...)
+	if err != nil {
+		return nil, err
+	}
+
+	return changes, nil
 }
 
 type DisableUnitFileChange struct {
@@ -503,7 +1019,14 @@ type DisableUnitFileChange struct {
 	Destination string // Destination of the symlink
 }
 
-// MaskUnitFiles masks one or more units in the system
+// MaskUnitFiles is a wrapper around MaskUnitFilesContext.
+//
+// Deprecated: use MaskUnitFilesContext instead.
+func (c *Conn) MaskUnitFiles(files []string, runtime bool, force bool) ([]MaskUnitFileChange, error) {
+	return c.MaskUnitFilesContext(context.Background(), files, runtime, force)
+}
+
+// MaskUnitFilesContext masks one or more units in the system
 //
 // It takes three arguments:
 //   * list of units to mask (either just file names or full
@@ -512,9 +1035,9 @@ type DisableUnitFileChange struct {
 //   * runtime to specify whether the unit was enabled for runtime
 //     only (true, /run/systemd/..), or persistently (false, /etc/systemd/..)
 //   * force flag
-func (c *Conn) MaskUnitFiles(files []string, runtime bool, force bool) ([]MaskUnitFileChange, error) {
+func (c *Conn) MaskUnitFilesContext(ctx context.Context, files []string, runtime bool, force bool) ([]MaskUnitFileChange, error) {
 	result := make([][]interface{}, 0)
-	err := c.sysobj.Call("org.freedesktop.systemd1.Manager.MaskUnitFiles", 0, files, runtime, force).Store(&result)
+	err := c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.MaskUnitFiles", 0, files, runtime, force).Store(&result)
 	if err != nil {
 		return nil, err
 	}
@@ -544,7 +1067,14 @@ type MaskUnitFileChange struct {
 	Destination string // Destination of the symlink
 }
 
-// UnmaskUnitFiles unmasks one or more units in the system
+// UnmaskUnitFiles is a wrapper around UnmaskUnitFilesContext.
+//
+// Deprecated: use UnmaskUnitFilesContext instead.
+func (c *Conn) UnmaskUnitFiles(files []string, runtime bool) ([]UnmaskUnitFileChange, error) {
+	return c.UnmaskUnitFilesContext(context.Background(), files, runtime)
+}
+
+// UnmaskUnitFilesContext unmasks one or more units in the system
 //
 // It takes two arguments:
 //   * list of unit files to mask (either just file names or full
@@ -552,9 +1082,9 @@ type MaskUnitFileChange struct {
 //     the usual unit search paths)
 //   * runtime to specify whether the unit was enabled for runtime
 //     only (true, /run/systemd/..), or persistently (false, /etc/systemd/..)
-func (c *Conn) UnmaskUnitFiles(files []string, runtime bool) ([]UnmaskUnitFileChange, error) {
+func (c *Conn) UnmaskUnitFilesContext(ctx context.Context, files []string, runtime bool) ([]UnmaskUnitFileChange, error) {
 	result := make([][]interface{}, 0)
-	err := c.sysobj.Call("org.freedesktop.systemd1.Manager.UnmaskUnitFiles", 0, files, runtime).Store(&result)
+	err := c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.UnmaskUnitFiles", 0, files, runtime).Store(&result)
 	if err != nil {
 		return nil, err
 	}
@@ -584,10 +1114,510 @@ type UnmaskUnitFileChange struct {
 	Destination string // Destination of the symlink
 }
 
-// Reload instructs systemd to scan for and reload unit files. This is
-// equivalent to a 'systemctl daemon-reload'.
+type PresetUnitFileChange EnableUnitFileChange
+
+// PresetUnitFiles enables or disables one or more unit files according to
+// the preset policy (see systemd.preset(5)), like `systemctl preset`.
+//
+// It takes a list of unit files (either just file names or full absolute
+// paths if the unit files are residing outside the usual unit search paths),
+// and two booleans: the first controls whether the unit shall be enabled or
+// disabled for runtime only (true, /run), or persistently (false, /etc). The
+// second one controls whether symlinks pointing to other units shall be
+// replaced if necessary.
+//
+// This call returns one boolean and an array with the changes made, just
+// like EnableUnitFilesContext.
+func (c *Conn) PresetUnitFiles(ctx context.Context, files []string, runtime bool, force bool) (bool, []PresetUnitFileChange, error) {
+	return c.presetUnitFiles(ctx, "org.freedesktop.systemd1.Manager.PresetUnitFiles", files, runtime, force)
+}
+
+// PresetUnitFilesWithMode is like PresetUnitFiles, but only applies the
+// changes permitted by mode, which is one of "full" (enable and disable),
+// "enable-only" or "disable-only".
+func (c *Conn) PresetUnitFilesWithMode(ctx context.Context, files []string, mode string, runtime bool, force bool) (bool, []PresetUnitFileChange, error) {
+	return c.presetUnitFiles(ctx, "org.freedesktop.systemd1.Manager.PresetUnitFilesWithMode", files, mode, runtime, force)
+}
+
+func (c *Conn) presetUnitFiles(ctx context.Context, method string, args ...interface{}) (bool, []PresetUnitFileChange, error) {
+	var carriesInstallInfo bool
+
+	result := make([][]interface{}, 0)
+	err := c.manager().CallWithContext(ctx, method, 0, args...).Store(&carriesInstallInfo, &result)
+	if err != nil {
+		return false, nil, err
+	}
+
+	resultInterface := make([]interface{}, len(result))
+	for i := range result {
+		resultInterface[i] = result[i]
+	}
+
+	changes := make([]PresetUnitFileChange, len(result))
+	changesInterface := make([]interface{}, len(changes))
+	for i := range changes {
+		changesInterface[i] = &changes[i]
+	}
+
+	err = dbus.Store(resultInterface, changesInterface...)
+	if err != nil {
+		return false, nil, err
+	}
+
+	return carriesInstallInfo, changes, nil
+}
+
+// PresetAllUnitFiles applies the preset policy to all installed unit files,
+// like `systemctl preset-all`. mode, runtime and force are as for
+// PresetUnitFilesWithMode.
+func (c *Conn) PresetAllUnitFiles(ctx context.Context, mode string, runtime bool, force bool) ([]PresetUnitFileChange, error) {
+	result := make([][]interface{}, 0)
+	err := c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.PresetAllUnitFiles", 0, mode, runtime, force).Store(&result)
+	if err != nil {
+		return nil, err
+	}
+
+	resultInterface := make([]interface{}, len(result))
+	for i := range result {
+		resultInterface[i] = result[i]
+	}
+
+	changes := make([]PresetUnitFileChange, len(result))
+	changesInterface := make([]interface{}, len(changes))
+	for i := range changes {
+		changesInterface[i] = &changes[i]
+	}
+
+	err = dbus.Store(resultInterface, changesInterface...)
+	if err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// ReenableUnitFiles disables and re-enables one or more unit files according
+// to the current [Install] section of each, like `systemctl reenable`.
+// Arguments and return values are the same as for EnableUnitFilesContext.
+func (c *Conn) ReenableUnitFiles(ctx context.Context, files []string, runtime bool, force bool) (bool, []EnableUnitFileChange, error) {
+	var carriesInstallInfo bool
+
+	result := make([][]interface{}, 0)
+	err := c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.ReenableUnitFiles", 0, files, runtime, force).Store(&carriesInstallInfo, &result)
+	if err != nil {
+		return false, nil, err
+	}
+
+	resultInterface := make([]interface{}, len(result))
+	for i := range result {
+		resultInterface[i] = result[i]
+	}
+
+	changes := make([]EnableUnitFileChange, len(result))
+	changesInterface := make([]interface{}, len(changes))
+	for i := range changes {
+		changesInterface[i] = &changes[i]
+	}
+
+	err = dbus.Store(resultInterface, changesInterface...)
+	if err != nil {
+		return false, nil, err
+	}
+
+	return carriesInstallInfo, changes, nil
+}
+
+type RevertUnitFileChange EnableUnitFileChange
+
+// RevertUnitFiles reverts one or more units to their vendor version, like
+// `systemctl revert`: drop-ins and overriding unit files in /etc and /run are
+// removed and the units are unmasked. It takes a list of unit names, and
+// returns an array with the changes made.
+func (c *Conn) RevertUnitFiles(ctx context.Context, names []string) ([]RevertUnitFileChange, error) {
+	result := make([][]interface{}, 0)
+	err := c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.RevertUnitFiles", 0, names).Store(&result)
+	if err != nil {
+		return nil, err
+	}
+
+	resultInterface := make([]interface{}, len(result))
+	for i := range result {
+		resultInterface[i] = result[i]
+	}
+
+	changes := make([]RevertUnitFileChange, len(result))
+	changesInterface := make([]interface{}, len(changes))
+	for i := range changes {
+		changesInterface[i] = &changes[i]
+	}
+
+	err = dbus.Store(resultInterface, changesInterface...)
+	if err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// GetDefaultTarget returns the name of the default target, the unit the
+// system boots into, such as "multi-user.target" or "graphical.target".
+func (c *Conn) GetDefaultTarget(ctx context.Context) (string, error) {
+	var name string
+	err := c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.GetDefaultTarget", 0).Store(&name)
+	if err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+type SetDefaultTargetChange EnableUnitFileChange
+
+// SetDefaultTarget makes name the default target by updating the
+// default.target symlink, like `systemctl set-default`. If force is set, an
+// existing symlink is replaced even if it does not point to a target. It
+// returns the changes made to the symlinks.
+func (c *Conn) SetDefaultTarget(ctx context.Context, name string, force bool) ([]SetDefaultTargetChange, error) {
+	result := make([][]interface{}, 0)
+	err := c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.SetDefaultTarget", 0, name, force).Store(&result)
+	if err != nil {
+		return nil, err
+	}
+
+	resultInterface := make([]interface{}, len(result))
+	for i := range result {
+		resultInterface[i] = result[i]
+	}
+
+	changes := make([]SetDefaultTargetChange, len(result))
+	changesInterface := make([]interface{}, len(changes))
+	for i := range changes {
+		changesInterface[i] = &changes[i]
+	}
+
+	err = dbus.Store(resultInterface, changesInterface...)
+	if err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+type AddDependencyUnitFileChange EnableUnitFileChange
+
+// AddDependencyUnitFiles adds a dependency of type depType, "Wants" or
+// "Requires", on the units files to the unit target, like `systemctl
+// add-wants` and `systemctl add-requires`: symlinks are created in the
+// .wants/ or .requires/ directory of target. If runtime is set, the symlinks
+// are placed below /run, so that they are lost on reboot. If force is set,
+// conflicting symlinks are replaced. It returns the changes made.
+func (c *Conn) AddDependencyUnitFiles(ctx context.Context, files []string, target string, depType string, runtime bool, force bool) ([]AddDependencyUnitFileChange, error) {
+	result := make([][]interface{}, 0)
+	err := c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.AddDependencyUnitFiles", 0, files, target, depType, runtime, force).Store(&result)
+	if err != nil {
+		return nil, err
+	}
+
+	resultInterface := make([]interface{}, len(result))
+	for i := range result {
+		resultInterface[i] = result[i]
+	}
+
+	changes := make([]AddDependencyUnitFileChange, len(result))
+	changesInterface := make([]interface{}, len(changes))
+	for i := range changes {
+		changesInterface[i] = &changes[i]
+	}
+
+	err = dbus.Store(resultInterface, changesInterface...)
+	if err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// GetUnitFileLinks returns the symlinks pointing to the unit file name, such
+// as those created when enabling it, below /etc, or below /run if runtime is
+// set.
+func (c *Conn) GetUnitFileLinks(ctx context.Context, name string, runtime bool) ([]string, error) {
+	var links []string
+	err := c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.GetUnitFileLinks", 0, name, runtime).Store(&links)
+	if err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// DynamicUser is a user allocated by systemd for a service with
+// DynamicUser=yes.
+type DynamicUser struct {
+	UID  uint32
+	Name string
+}
+
+// LookupDynamicUserByName returns the UID of the dynamic user name. If there
+// is no such user, the error matches ErrNoSuchDynamicUser.
+func (c *Conn) LookupDynamicUserByName(ctx context.Context, name string) (uint32, error) {
+	var uid uint32
+	err := c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.LookupDynamicUserByName", 0, name).Store(&uid)
+	if err != nil {
+		return 0, err
+	}
+	return uid, nil
+}
+
+// LookupDynamicUserByUID returns the name of the dynamic user with the given
+// UID. If there is no such user, the error matches ErrNoSuchDynamicUser.
+func (c *Conn) LookupDynamicUserByUID(ctx context.Context, uid uint32) (string, error) {
+	var name string
+	err := c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.LookupDynamicUserByUID", 0, uid).Store(&name)
+	if err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// GetDynamicUsers returns the dynamic users currently allocated.
+func (c *Conn) GetDynamicUsers(ctx context.Context) ([]DynamicUser, error) {
+	result := make([][]interface{}, 0)
+	err := c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.GetDynamicUsers", 0).Store(&result)
+	if err != nil {
+		return nil, err
+	}
+
+	resultInterface := make([]interface{}, len(result))
+	for i := range result {
+		resultInterface[i] = result[i]
+	}
+
+	users := make([]DynamicUser, len(result))
+	usersInterface := make([]interface{}, len(users))
+	for i := range users {
+		usersInterface[i] = &users[i]
+	}
+
+	err = dbus.Store(resultInterface, usersInterface...)
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// Reload is a wrapper around ReloadContext.
+//
+// Deprecated: use ReloadContext instead.
 func (c *Conn) Reload() error {
-	return c.sysobj.Call("org.freedesktop.systemd1.Manager.Reload", 0).Store()
+	return c.ReloadContext(context.Background())
+}
+
+// ReloadContext instructs systemd to scan for and reload unit files. This is
+// equivalent to a 'systemctl daemon-reload'.
+func (c *Conn) ReloadContext(ctx context.Context) error {
+	return c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.Reload", 0).Store()
+}
+
+// Reexecute instructs systemd to serialize its state, re-execute itself and
+// deserialize the state again, like 'systemctl daemon-reexec'.
+//
+// systemd does not reply to this call; instead, it drops its bus connections
+// when re-executing. Reexecute therefore treats the call being cut off as
+// success. If c talks to systemd directly, as done by NewSystemdConnection,
+// c reconnects in the background, see SetReconnectSubscriber.
+func (c *Conn) Reexecute(ctx context.Context) error {
+	return ignoreCutOff(c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.Reexecute", 0).Store())
+}
+
+// ignoreCutOff returns nil if err reports that systemd went away before
+// replying to a call, which is expected for the calls that make it
+// re-execute or shut down.
+func ignoreCutOff(err error) error {
+	if err == dbus.ErrClosed || ErrNoReply.Is(err) {
+		return nil
+	}
+	return err
+}
+
+// Reboot asks systemd to immediately reboot the system, without stopping any
+// units first. Like PowerOff, Halt and KExec, it is meant to be used as the
+// last step of a shutdown; to shut down the system cleanly, use the login1
+// package instead, or start reboot.target with mode "replace-irreversibly".
+func (c *Conn) Reboot(ctx context.Context) error {
+	return ignoreCutOff(c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.Reboot", 0).Store())
+}
+
+// PowerOff immediately powers off the system, see Reboot.
+func (c *Conn) PowerOff(ctx context.Context) error {
+	return ignoreCutOff(c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.PowerOff", 0).Store())
+}
+
+// Halt immediately halts the system, see Reboot.
+func (c *Conn) Halt(ctx context.Context) error {
+	return ignoreCutOff(c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.Halt", 0).Store())
+}
+
+// KExec immediately reboots the system into the kernel loaded with kexec,
+// see Reboot.
+func (c *Conn) KExec(ctx context.Context) error {
+	return ignoreCutOff(c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.KExec", 0).Store())
+}
+
+// SoftReboot immediately restarts the userspace of the system without
+// rebooting the kernel, like `systemctl soft-reboot`. If newRoot is not
+// empty, the system switches into it. Requires systemd 254 or newer.
+func (c *Conn) SoftReboot(ctx context.Context, newRoot string) error {
+	return ignoreCutOff(c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.SoftReboot", 0, newRoot).Store())
+}
+
+// SwitchRoot makes the system switch into the root directory newRoot and
+// execute init there, like `systemctl switch-root`. If init is empty, systemd
+// is re-executed in newRoot. This is normally only used in the initrd.
+func (c *Conn) SwitchRoot(ctx context.Context, newRoot string, init string) error {
+	return ignoreCutOff(c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.SwitchRoot", 0, newRoot, init).Store())
+}
+
+// GetEnvironment returns the environment of the service manager, which is
+// passed to all processes it spawns, as "NAME=value" assignments.
+func (c *Conn) GetEnvironment(ctx context.Context) ([]string, error) {
+	var prop dbus.Variant
+	err := c.manager().CallWithContext(ctx, "org.freedesktop.DBus.Properties.Get", 0, "org.freedesktop.systemd1.Manager", "Environment").Store(&prop)
+	if err != nil {
+		return nil, err
+	}
+
+	env, ok := prop.Value().([]string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type for Environment: %s", prop.Signature())
+	}
+	return env, nil
+}
+
+// SetEnvironment adds or replaces variables in the environment of the service
+// manager, like `systemctl set-environment`. assignments are of the form
+// "NAME=value".
+func (c *Conn) SetEnvironment(ctx context.Context, assignments []string) error {
+	return c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.SetEnvironment", 0, assignments).Store()
+}
+
+// UnsetEnvironment removes variables from the environment of the service
+// manager, like `systemctl unset-environment`. names may be plain variable
+// names, or "NAME=value" assignments to only remove variables with that
+// value.
+func (c *Conn) UnsetEnvironment(ctx context.Context, names []string) error {
+	return c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.UnsetEnvironment", 0, names).Store()
+}
+
+// UnsetAndSetEnvironment atomically removes the variables names and then sets
+// assignments in the environment of the service manager.
+func (c *Conn) UnsetAndSetEnvironment(ctx context.Context, names []string, assignments []string) error {
+	return c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.UnsetAndSetEnvironment", 0, names, assignments).Store()
+}
+
+// getManagerString returns the string property name of the manager.
+func (c *Conn) getManagerString(ctx context.Context, name string) (string, error) {
+	var prop dbus.Variant
+	err := c.manager().CallWithContext(ctx, "org.freedesktop.DBus.Properties.Get", 0, "org.freedesktop.systemd1.Manager", name).Store(&prop)
+	if err != nil {
+		return "", err
+	}
+
+	value, ok := prop.Value().(string)
+	if !ok {
+		return "", fmt.Errorf("unexpected type for manager property %s: %s", name, prop.Signature())
+	}
+	return value, nil
+}
+
+func (c *Conn) setManagerProperty(ctx context.Context, name string, value interface{}) error {
+	return c.manager().CallWithContext(ctx, "org.freedesktop.DBus.Properties.Set", 0, "org.freedesktop.systemd1.Manager", name, dbus.MakeVariant(value)).Store()
+}
+
+// GetLogLevel returns the maximum log level of the manager, such as "info" or
+// "debug".
+func (c *Conn) GetLogLevel(ctx context.Context) (string, error) {
+	return c.getManagerString(ctx, "LogLevel")
+}
+
+// SetLogLevel sets the maximum log level of the manager, like
+// `systemd-analyze log-level`. level is a syslog level name, such as "debug",
+// or number.
+func (c *Conn) SetLogLevel(ctx context.Context, level string) error {
+	return c.setManagerProperty(ctx, "LogLevel", level)
+}
+
+// GetLogTarget returns where the manager logs to, such as "journal-or-kmsg"
+// or "console".
+func (c *Conn) GetLogTarget(ctx context.Context) (string, error) {
+	return c.getManagerString(ctx, "LogTarget")
+}
+
+// SetLogTarget sets where the manager logs to, like `systemd-analyze
+// log-target`; one of "console", "kmsg", "journal", "journal-or-kmsg",
+// "syslog", "syslog-or-kmsg" or "null".
+func (c *Conn) SetLogTarget(ctx context.Context, target string) error {
+	return c.setManagerProperty(ctx, "LogTarget", target)
+}
+
+// SetShowStatus sets whether the manager shows status messages on the
+// console, like ShowStatus= in systemd-system.conf(5): mode is one of "yes",
+// "no", "auto", "error" or "", which restores the configured value. It
+// requires systemd 246 or later.
+func (c *Conn) SetShowStatus(ctx context.Context, mode string) error {
+	return c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.SetShowStatus", 0, mode).Store()
+}
+
+// Dump returns a human readable dump of the internal state of the manager,
+// like `systemd-analyze dump`. Its format is not stable. Large dumps may
+// exceed the D-Bus message size limits; DumpByFileDescriptor avoids those.
+func (c *Conn) Dump(ctx context.Context) (string, error) {
+	var dump string
+	err := c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.Dump", 0).Store(&dump)
+	if err != nil {
+		return "", err
+	}
+	return dump, nil
+}
+
+// DumpByFileDescriptor is like Dump, but systemd passes the dump through a
+// file descriptor rather than in the reply. The connection must support
+// passing file descriptors, that is it must use a unix socket.
+func (c *Conn) DumpByFileDescriptor(ctx context.Context) (string, error) {
+	return c.dumpByFileDescriptor(ctx, "org.freedesktop.systemd1.Manager.DumpByFileDescriptor")
+}
+
+// DumpUnitsMatchingPatterns is like Dump, but only dumps the units whose
+// names match one of the shell patterns, such as "*.socket". It requires
+// systemd 252 or later.
+func (c *Conn) DumpUnitsMatchingPatterns(ctx context.Context, patterns []string) (string, error) {
+	var dump string
+	err := c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.DumpUnitsMatchingPatterns", 0, patterns).Store(&dump)
+	if err != nil {
+		return "", err
+	}
+	return dump, nil
+}
+
+// DumpUnitsMatchingPatternsByFileDescriptor is like
+// DumpUnitsMatchingPatterns, but the dump is passed as for
+// DumpByFileDescriptor.
+func (c *Conn) DumpUnitsMatchingPatternsByFileDescriptor(ctx context.Context, patterns []string) (string, error) {
+	return c.dumpByFileDescriptor(ctx, "org.freedesktop.systemd1.Manager.DumpUnitsMatchingPatternsByFileDescriptor", patterns)
+}
+
+func (c *Conn) dumpByFileDescriptor(ctx context.Context, method string, args ...interface{}) (string, error) {
+	var fd dbus.UnixFD
+	err := c.manager().CallWithContext(ctx, method, 0, args...).Store(&fd)
+	if err != nil {
+		return "", err
+	}
+
+	f := os.NewFile(uintptr(fd), "dump")
+	defer f.Close()
+	dump, err := ioutil.ReadAll(f)
+	if err != nil {
+		return "", err
+	}
+	return string(dump), nil
 }
 
 func unitPath(name string) dbus.ObjectPath {
@@ -15,12 +15,15 @@
 package dbus
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"syscall"
 	"testing"
 	"time"
@@ -597,6 +600,64 @@ func TestListUnitFiles(t *testing.T) {
 	}
 }
 
+func TestGetUnitFileState(t *testing.T) {
+	conn := setupConn(t)
+	defer conn.Close()
+
+	state, err := conn.GetUnitFileState(context.Background(), "systemd-journald.service")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state != "static" {
+		t.Fatalf("systemd-journald.service unit file should be static, got %q", state)
+	}
+
+	if _, err := conn.GetUnitFileState(context.Background(), "nonexistent-unit-file.service"); err == nil {
+		t.Fatal("expected an error for a nonexistent unit file")
+	}
+}
+
+func TestGetUnitFileContent(t *testing.T) {
+	conn := setupConn(t)
+	defer conn.Close()
+
+	files, err := conn.GetUnitFileContent(context.Background(), "systemd-journald.service")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 || !strings.HasSuffix(files[0].Path, "/systemd-journald.service") {
+		t.Fatalf("unexpected files for systemd-journald.service: %v", files)
+	}
+	if !strings.Contains(files[0].Content, "[Service]") {
+		t.Errorf("unexpected content of %s: %q", files[0].Path, files[0].Content)
+	}
+}
+
+func TestFormatUnitFileContent(t *testing.T) {
+	files := []UnitFileContent{
+		{Path: "/usr/lib/systemd/system/foo.service", Content: "[Service]\nExecStart=/bin/true\n"},
+		{Path: "/etc/systemd/system/foo.service.d/50-go.conf", Content: "[Service]\nNice=5"},
+	}
+	expected := "# /usr/lib/systemd/system/foo.service\n[Service]\nExecStart=/bin/true\n\n" +
+		"# /etc/systemd/system/foo.service.d/50-go.conf\n[Service]\nNice=5\n"
+	if out := FormatUnitFileContent(files); out != expected {
+		t.Errorf("got %q, want %q", out, expected)
+	}
+}
+
+func TestGetDefaultTarget(t *testing.T) {
+	conn := setupConn(t)
+	defer conn.Close()
+
+	target, err := conn.GetDefaultTarget(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(target, ".target") {
+		t.Fatalf("default target should be a target, got %q", target)
+	}
+}
+
 // Enables a unit and then immediately tears it down
 func TestEnableDisableUnit(t *testing.T) {
 	target := "enable-disable.service"
@@ -642,6 +703,105 @@ func TestEnableDisableUnit(t *testing.T) {
 	}
 }
 
+// Adds a unit to the wants of a target, then removes it again
+func TestAddDependencyUnitFiles(t *testing.T) {
+	target := "enable-disable.service"
+	conn := setupConn(t)
+	defer conn.Close()
+
+	setupUnit(target, conn, t)
+	linkUnit(target, conn, t)
+	defer conn.DisableUnitFiles([]string{target}, true)
+
+	ctx := context.Background()
+	wantsPath := filepath.Join("/run/systemd/system/multi-user.target.wants", target)
+	changes, err := conn.AddDependencyUnitFiles(ctx, []string{target}, "multi-user.target", "Wants", true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Filename != wantsPath {
+		t.Fatalf("unexpected changes %+v", changes)
+	}
+
+	links, err := conn.GetUnitFileLinks(ctx, target, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, l := range links {
+		if l == wantsPath {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("%s not among the links of %s: %v", wantsPath, target, links)
+	}
+}
+
+func TestDynamicUsers(t *testing.T) {
+	conn := setupConn(t)
+	defer conn.Close()
+
+	ctx := context.Background()
+	users, err := conn.GetDynamicUsers(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range users {
+		name, err := conn.LookupDynamicUserByUID(ctx, u.UID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name != u.Name {
+			t.Errorf("UID %d: got name %q, want %q", u.UID, name, u.Name)
+		}
+	}
+
+	_, err = conn.LookupDynamicUserByName(ctx, "nonexistent-dynamic-user")
+	if !errors.Is(err, ErrNoSuchDynamicUser) {
+		t.Errorf("expected ErrNoSuchDynamicUser, got %v", err)
+	}
+}
+
+func TestLogLevel(t *testing.T) {
+	conn := setupConn(t)
+	defer conn.Close()
+
+	ctx := context.Background()
+	level, err := conn.GetLogLevel(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.SetLogLevel(ctx, "debug"); err != nil {
+		t.Fatal(err)
+	}
+	defer conn.SetLogLevel(ctx, level)
+
+	if got, err := conn.GetLogLevel(ctx); err != nil || got != "debug" {
+		t.Fatalf("expected log level debug, got %q, %v", got, err)
+	}
+}
+
+func TestDump(t *testing.T) {
+	conn := setupConn(t)
+	defer conn.Close()
+
+	ctx := context.Background()
+	dump, err := conn.Dump(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fdDump, err := conn.DumpByFileDescriptor(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []string{dump, fdDump} {
+		if !strings.Contains(d, "-> Unit ") {
+			t.Errorf("dump does not list any unit: %.200q", d)
+		}
+	}
+}
+
 // TestSystemState tests if system state is one of the valid states
 func TestSystemState(t *testing.T) {
 	conn := setupConn(t)
@@ -674,6 +834,126 @@ func TestSystemState(t *testing.T) {
 	}
 }
 
+func TestGetManagerStatus(t *testing.T) {
+	conn := setupConn(t)
+	defer conn.Close()
+
+	status, err := conn.GetManagerStatus(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status.Version == "" {
+		t.Fatal("expected a non-empty systemd version")
+	}
+	if status.SystemState == "" {
+		t.Fatal("expected a non-empty system state")
+	}
+	if status.NNames == 0 {
+		t.Fatal("expected at least one loaded unit name")
+	}
+}
+
+func TestGetUnitByPID(t *testing.T) {
+	conn := setupConn(t)
+	defer conn.Close()
+
+	name, err := conn.GetUnitNameByPID(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "init.scope" {
+		t.Fatalf("expected PID 1 to belong to init.scope, got %q", name)
+	}
+
+	status, err := conn.GetUnitStatusByPID(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Name != name {
+		t.Fatalf("unexpected unit status %+v", status)
+	}
+}
+
+func TestGetUnitProcesses(t *testing.T) {
+	conn := setupConn(t)
+	defer conn.Close()
+
+	processes, err := conn.GetUnitProcesses(context.Background(), "init.scope")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := false
+	for _, p := range processes {
+		if p.PID == 1 {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("PID 1 not found in init.scope: %v", processes)
+	}
+}
+
+func TestManagerEnvironment(t *testing.T) {
+	conn := setupConn(t)
+	defer conn.Close()
+
+	ctx := context.Background()
+	assignment := "GO_SYSTEMD_TEST_ENV=1"
+
+	if err := conn.SetEnvironment(ctx, []string{assignment}); err != nil {
+		t.Fatal(err)
+	}
+	defer conn.UnsetEnvironment(ctx, []string{"GO_SYSTEMD_TEST_ENV"})
+
+	contains := func() bool {
+		env, err := conn.GetEnvironment(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range env {
+			if e == assignment {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !contains() {
+		t.Fatalf("%s not found in the manager environment", assignment)
+	}
+
+	if err := conn.UnsetEnvironment(ctx, []string{"GO_SYSTEMD_TEST_ENV"}); err != nil {
+		t.Fatal(err)
+	}
+	if contains() {
+		t.Fatalf("%s still found in the manager environment", assignment)
+	}
+}
+
+func TestListJobs(t *testing.T) {
+	conn := setupConn(t)
+	defer conn.Close()
+
+	jobs, err := conn.ListJobs(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, job := range jobs {
+		if !job.JobPath.IsValid() {
+			t.Fatalf("invalid job path %q for job %d", job.JobPath, job.Id)
+		}
+	}
+
+	// job ids are allocated sequentially, so this one is not in use
+	_, err = conn.GetJob(context.Background(), 1<<31-1)
+	if err == nil {
+		t.Fatal("expected an error for a nonexistent job")
+	}
+}
+
 // TestGetUnitProperties reads the `-.mount` which should exist on all systemd
 // systems and ensures that one of its properties is valid.
 func TestGetUnitProperties(t *testing.T) {
@@ -1341,7 +1621,10 @@ func TestKillUnit(t *testing.T) {
 	}
 
 	// send SIGTERM
-	conn.KillUnit(target, int32(syscall.SIGTERM))
+	err = conn.KillUnit(target, int32(syscall.SIGTERM))
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	timeout := make(chan bool, 1)
 	go func() {
@@ -1539,3 +1822,15 @@ func TestUnitName(t *testing.T) {
 		}
 	}
 }
+
+func TestQueueSignalUnit(t *testing.T) {
+	obj := &recordingObject{}
+	c := &Conn{sysobj: mappedObject{BusObject: obj}}
+
+	if err := c.QueueSignalUnit(context.Background(), "foo.service", Main, 35, 1); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"org.freedesktop.systemd1.Manager.QueueSignalUnit"}; !reflect.DeepEqual(obj.calls, expected) {
+		t.Errorf("got calls %v, want %v", obj.calls, expected)
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+type callLimitValue struct {
+	tokens chan struct{}
+}
+
+// SetMaxConcurrentCalls limits the number of method calls c has in flight
+// to n; further calls wait for one of them to return, or for their context
+// to be done. With n set to 1, calls are serialized. A zero n, the default,
+// removes the limit. Calls already waiting are not affected by a change.
+//
+// The bus limits the number of calls awaiting a reply on a connection
+// (max_replies_per_connection in dbus-daemon(1), 128 on the system bus by
+// default) and fails the calls beyond it, so callers issuing many calls at
+// once, such as starting thousands of jobs, should set a limit below it, and
+// may spread the calls across several connections with a Pool.
+func (c *Conn) SetMaxConcurrentCalls(n int) {
+	var v callLimitValue
+	if n > 0 {
+		v.tokens = make(chan struct{}, n)
+	}
+	c.callLimit.Store(v)
+}
+
+func (c *Conn) loadCallLimit() chan struct{} {
+	v, _ := c.callLimit.Load().(callLimitValue)
+	return v.tokens
+}
+
+// acquireCall waits for a free slot in tokens, and returns the function
+// releasing it. A nil tokens is unlimited.
+func acquireCall(ctx context.Context, tokens chan struct{}) (func(), error) {
+	if tokens == nil {
+		return func() {}, nil
+	}
+	select {
+	case tokens <- struct{}{}:
+		return func() { <-tokens }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Pool is a fixed set of connections to systemd used in turn, for callers
+// whose concurrent method calls exceed what a single connection handles
+// well. Each call to Conn returns the next connection:
+//
+//	pool, err := dbus.NewPool(ctx, 4, dbus.NewSystemConnectionContext)
+//	...
+//	defer pool.Close()
+//	for _, name := range units {
+//		go func(name string) {
+//			job, err := pool.Conn().StartUnitAsync(ctx, name, "replace")
+//			...
+//			result, err := job.Wait(ctx)
+//		}(name)
+//	}
+//
+// Jobs are tracked by the connection that enqueued them, so their results
+// are available from that connection only, as with the Job returned by
+// StartUnitAsync.
+type Pool struct {
+	conns []*Conn
+	next  uint32
+}
+
+// NewPool establishes size connections with dial.
+func NewPool(ctx context.Context, size int, dial func(ctx context.Context) (*Conn, error)) (*Pool, error) {
+	if size < 1 {
+		return nil, errors.New("pool size must be positive")
+	}
+
+	p := &Pool{conns: make([]*Conn, 0, size)}
+	for i := 0; i < size; i++ {
+		conn, err := dial(ctx)
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		p.conns = append(p.conns, conn)
+	}
+	return p, nil
+}
+
+// Conn returns the next connection of the pool, in round-robin order. It
+// must not be closed by the caller.
+func (p *Pool) Conn() *Conn {
+	i := atomic.AddUint32(&p.next, 1) - 1
+	return p.conns[i%uint32(len(p.conns))]
+}
+
+// Conns returns all connections of the pool, for example to subscribe to
+// signals on each of them or to set their options.
+func (p *Pool) Conns() []*Conn {
+	return append([]*Conn(nil), p.conns...)
+}
+
+// Close closes all connections of the pool.
+func (p *Pool) Close() {
+	for _, conn := range p.conns {
+		conn.Close()
+	}
+}
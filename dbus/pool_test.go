@@ -0,0 +1,153 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMaxConcurrentCalls(t *testing.T) {
+	conn, _ := newFakeConn(t)
+	defer conn.Close()
+
+	conn.SetMaxConcurrentCalls(1)
+	tokens := conn.loadCallLimit()
+	// occupy the only slot, as a call in flight would
+	tokens <- struct{}{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := conn.GetUnitFileState(ctx, "foo.service"); err != context.DeadlineExceeded {
+		t.Fatalf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	<-tokens
+	if _, err := conn.GetUnitFileState(context.Background(), "foo.service"); err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 0 {
+		t.Error("call did not release its slot")
+	}
+
+	conn.SetMaxConcurrentCalls(0)
+	if conn.loadCallLimit() != nil {
+		t.Error("limit was not removed")
+	}
+}
+
+func TestConcurrentUse(t *testing.T) {
+	conn, backends := newFakeConn(t)
+	defer conn.Close()
+	ctx := context.Background()
+
+	const n = 100
+	jobs := make(chan *Job, n)
+	errs := make(chan error, 2*n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			switch i % 4 {
+			case 0:
+				conn.SetMaxConcurrentCalls(i % 8)
+			case 1:
+				conn.SetHooks(&recordingHooks{signals: make(chan string, n)})
+			case 2:
+				conn.SetInteractiveAuthorization(i%8 == 2)
+			}
+			if _, err := conn.GetUnitFileState(ctx, "foo.service"); err != nil {
+				errs <- err
+			}
+			job, err := conn.StartUnitAsync(ctx, "foo.service", "replace")
+			if err != nil {
+				errs <- err
+				return
+			}
+			jobs <- job
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	backends[1].emit("org.freedesktop.systemd1.Manager.JobRemoved", uint32(7), jobPath(7), "foo.service", "done")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	for i := 0; i < n; i++ {
+		result, err := (<-jobs).Wait(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result != "done" {
+			t.Errorf("got result %q, want done", result)
+		}
+	}
+}
+
+func TestPool(t *testing.T) {
+	var conns []*Conn
+	dial := func(ctx context.Context) (*Conn, error) {
+		conn, _ := newFakeConn(t)
+		conns = append(conns, conn)
+		return conn, nil
+	}
+
+	pool, err := NewPool(context.Background(), 3, dial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	if len(pool.Conns()) != 3 {
+		t.Fatalf("got %d connections, want 3", len(pool.Conns()))
+	}
+	for i := 0; i < 6; i++ {
+		if conn := pool.Conn(); conn != conns[i%3] {
+			t.Errorf("call %d returned connection %p, want %p", i, conn, conns[i%3])
+		}
+	}
+
+	if _, err := NewPool(context.Background(), 0, dial); err == nil {
+		t.Error("expected an error for an empty pool")
+	}
+}
+
+func TestPoolDialError(t *testing.T) {
+	dialErr := errors.New("dial failed")
+	var first *Conn
+	dial := func(ctx context.Context) (*Conn, error) {
+		if first != nil {
+			return nil, dialErr
+		}
+		first, _ = newFakeConn(t)
+		return first, nil
+	}
+
+	if _, err := NewPool(context.Background(), 2, dial); err != dialErr {
+		t.Fatalf("got error %v, want %v", err, dialErr)
+	}
+	select {
+	case <-first.closed:
+	default:
+		t.Error("connection was not closed")
+	}
+}
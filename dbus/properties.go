@@ -15,6 +15,8 @@
 package dbus
 
 import (
+	"time"
+
 	"github.com/godbus/dbus/v5"
 )
 
@@ -235,3 +237,61 @@ func PropPids(pids ...uint32) Property {
 		Value: dbus.MakeVariant(pids),
 	}
 }
+
+// PropDelegate sets the Delegate unit property, turning over control of the
+// unit's cgroup subtree to its processes. See
+// http://www.freedesktop.org/software/systemd/man/systemd.resource-control.html#Delegate=
+func PropDelegate(b bool) Property {
+	return Property{
+		Name:  "Delegate",
+		Value: dbus.MakeVariant(b),
+	}
+}
+
+// PropMarkers sets the Markers unit property, flagging the unit for a restart
+// or reload by EnqueueMarkedJobs. Markers are "needs-restart" and
+// "needs-reload"; they are only kept at runtime, so the property must be set
+// with runtime true. See the Markers property in org.freedesktop.systemd1(5).
+func PropMarkers(markers ...string) Property {
+	return Property{
+		Name:  "Markers",
+		Value: dbus.MakeVariant(markers),
+	}
+}
+
+// PropOnCalendar sets the OnCalendar timer property to a calendar event
+// expression, such as "daily" or "Mon *-*-* 09:00:00". See
+// http://www.freedesktop.org/software/systemd/man/systemd.timer.html#OnCalendar=
+func PropOnCalendar(spec string) Property {
+	return Property{
+		Name:  "OnCalendar",
+		Value: dbus.MakeVariant(spec),
+	}
+}
+
+func propTimerUSec(name string, d time.Duration) Property {
+	return Property{
+		Name:  name,
+		Value: dbus.MakeVariant(uint64(d / time.Microsecond)),
+	}
+}
+
+// PropOnActiveSec sets the OnActiveSec timer property, which makes the timer
+// elapse d after it has been started. See
+// http://www.freedesktop.org/software/systemd/man/systemd.timer.html#OnActiveSec=
+func PropOnActiveSec(d time.Duration) Property {
+	return propTimerUSec("OnActiveSec", d)
+}
+
+// PropOnBootSec sets the OnBootSec timer property.  See
+// http://www.freedesktop.org/software/systemd/man/systemd.timer.html#OnBootSec=
+func PropOnBootSec(d time.Duration) Property {
+	return propTimerUSec("OnBootSec", d)
+}
+
+// PropOnUnitActiveSec sets the OnUnitActiveSec timer property, which makes
+// the timer elapse again d after the unit it activates was last activated.
+// See http://www.freedesktop.org/software/systemd/man/systemd.timer.html#OnUnitActiveSec=
+func PropOnUnitActiveSec(d time.Duration) Property {
+	return propTimerUSec("OnUnitActiveSec", d)
+}
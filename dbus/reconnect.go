@@ -0,0 +1,150 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	reconnectMinInterval = 100 * time.Millisecond
+	reconnectMaxInterval = 30 * time.Second
+)
+
+// ReconnectUpdate is sent after the connection to the bus was lost and has
+// been re-established.
+type ReconnectUpdate struct {
+	// LostJobs holds the jobs that were being waited for but no longer
+	// exist in systemd. Their results were emitted while disconnected and
+	// will never be sent to the channels passed when starting them.
+	LostJobs []dbus.ObjectPath
+	// Err is set if the subscription to systemd events, or one of the
+	// watches set up with WatchUnitProperties, could not be restored on the
+	// new connection.
+	Err error
+}
+
+// SetReconnectSubscriber writes to updateCh whenever the connection to the
+// bus has been re-established, for example after dbus-daemon was restarted.
+// Updates are written with non-blocking writes: if updateCh is full, the
+// update is dropped.
+func (c *Conn) SetReconnectSubscriber(updateCh chan<- *ReconnectUpdate) {
+	c.reconnectSubscriber.Lock()
+	defer c.reconnectSubscriber.Unlock()
+	c.reconnectSubscriber.updateCh = updateCh
+}
+
+func (c *Conn) sendReconnectUpdate(update *ReconnectUpdate) {
+	c.reconnectSubscriber.Lock()
+	defer c.reconnectSubscriber.Unlock()
+
+	if c.reconnectSubscriber.updateCh == nil {
+		return
+	}
+
+	select {
+	case c.reconnectSubscriber.updateCh <- update:
+	default:
+	}
+}
+
+// reconnect re-establishes the bus connections after the signal connection
+// has been lost, retrying with an exponential backoff. It returns the signal
+// channel of the new connection, or nil if the Conn was closed or its context
+// is done.
+func (c *Conn) reconnect() chan *BackendSignal {
+	interval := reconnectMinInterval
+	for {
+		select {
+		case <-c.closed:
+			return nil
+		case <-c.ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+
+		ch, err := c.redial()
+		if err == nil {
+			return ch
+		}
+
+		interval *= 2
+		if interval > reconnectMaxInterval {
+			interval = reconnectMaxInterval
+		}
+	}
+}
+
+func (c *Conn) redial() (chan *BackendSignal, error) {
+	sysconn, err := c.dialBus()
+	if err != nil {
+		return nil, err
+	}
+
+	sigconn, err := c.dialBus()
+	if err != nil {
+		sysconn.Close()
+		return nil, err
+	}
+
+	ch := c.newSignalChannel()
+	sigconn.Signal(ch)
+
+	if err := c.addJobMatch(sigconn); err != nil {
+		sysconn.Close()
+		sigconn.Close()
+		return nil, err
+	}
+
+	c.connLock.Lock()
+	select {
+	case <-c.closed:
+		c.connLock.Unlock()
+		sysconn.Close()
+		sigconn.Close()
+		return nil, nil
+	default:
+	}
+	oldSysconn, oldSigconn := c.sysconn, c.sigconn
+	c.sysconn, c.sysobj = sysconn, systemdObject(sysconn)
+	c.sigconn, c.sigobj = sigconn, systemdObject(sigconn)
+	c.connLock.Unlock()
+
+	oldSysconn.Close()
+	oldSigconn.Close()
+
+	update := &ReconnectUpdate{}
+	update.Err = c.restoreSubscription(sigconn, c.hooked(systemdObject(sigconn)))
+	update.LostJobs = c.dropLostJobs()
+	c.sendReconnectUpdate(update)
+
+	return ch, nil
+}
+
+// dropLostJobs forgets about the jobs being waited for which systemd no
+// longer knows about, and returns their paths.
+func (c *Conn) dropLostJobs() []dbus.ObjectPath {
+	var lost []dbus.ObjectPath
+	for _, path := range c.jobListener.pending() {
+		if _, err := c.object(path).GetProperty("org.freedesktop.systemd1.Job.Id"); err != nil {
+			if c.jobListener.drop(path) {
+				lost = append(lost, path)
+			}
+		}
+	}
+	return lost
+}
@@ -0,0 +1,205 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// ResourceControl builds the cgroup resource control properties of a unit,
+// as described in systemd.resource-control(5). The properties can be passed
+// to both StartTransientUnitContext and SetUnitPropertiesContext:
+//
+//	props, err := dbus.NewResourceControl().
+//		CPUQuota("150%").
+//		MemoryMax("512M").
+//		TasksMax("100").
+//		Properties()
+//
+// Values are given in the syntax of unit files. The first invalid value is
+// reported by Properties.
+type ResourceControl struct {
+	props []Property
+	err   error
+}
+
+// NewResourceControl returns an empty ResourceControl.
+func NewResourceControl() *ResourceControl {
+	return &ResourceControl{}
+}
+
+// Properties returns the properties set so far, or the error for the first
+// invalid value.
+func (r *ResourceControl) Properties() ([]Property, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.props, nil
+}
+
+func (r *ResourceControl) add(name string, value interface{}) *ResourceControl {
+	r.props = append(r.props, Property{Name: name, Value: dbus.MakeVariant(value)})
+	return r
+}
+
+func (r *ResourceControl) fail(name string, value string, err error) *ResourceControl {
+	if r.err == nil {
+		r.err = fmt.Errorf("invalid %s value %q: %v", name, value, err)
+	}
+	return r
+}
+
+// CPUQuota sets the CPUQuotaPerSecUSec property from a percentage of the time
+// of a single CPU, such as "150%", or "infinity" for no limit. See
+// http://www.freedesktop.org/software/systemd/man/systemd.resource-control.html#CPUQuota=
+func (r *ResourceControl) CPUQuota(quota string) *ResourceControl {
+	if quota == "infinity" {
+		return r.add("CPUQuotaPerSecUSec", uint64(math.MaxUint64))
+	}
+	if !strings.HasSuffix(quota, "%") {
+		return r.fail("CPUQuota", quota, fmt.Errorf("not a percentage"))
+	}
+	pct, err := strconv.ParseFloat(strings.TrimSuffix(quota, "%"), 64)
+	if err != nil || pct <= 0 {
+		return r.fail("CPUQuota", quota, fmt.Errorf("not a positive percentage"))
+	}
+	return r.add("CPUQuotaPerSecUSec", uint64(pct*10000))
+}
+
+// CPUWeight sets the CPUWeight property, between 1 and 10000. See
+// http://www.freedesktop.org/software/systemd/man/systemd.resource-control.html#CPUWeight=
+func (r *ResourceControl) CPUWeight(weight uint64) *ResourceControl {
+	if weight < 1 || weight > 10000 {
+		return r.fail("CPUWeight", strconv.FormatUint(weight, 10), fmt.Errorf("out of range 1-10000"))
+	}
+	return r.add("CPUWeight", weight)
+}
+
+// IOWeight sets the IOWeight property, between 1 and 10000. See
+// http://www.freedesktop.org/software/systemd/man/systemd.resource-control.html#IOWeight=
+func (r *ResourceControl) IOWeight(weight uint64) *ResourceControl {
+	if weight < 1 || weight > 10000 {
+		return r.fail("IOWeight", strconv.FormatUint(weight, 10), fmt.Errorf("out of range 1-10000"))
+	}
+	return r.add("IOWeight", weight)
+}
+
+// MemoryMax sets the MemoryMax property from a size in bytes, optionally
+// suffixed with K, M, G, T, P or E (base 1024), a percentage of the physical
+// memory, such as "50%", or "infinity" for no limit. See
+// http://www.freedesktop.org/software/systemd/man/systemd.resource-control.html#MemoryMax=bytes
+func (r *ResourceControl) MemoryMax(size string) *ResourceControl {
+	return r.limit("MemoryMax", size, parseSize)
+}
+
+// MemoryHigh sets the MemoryHigh property. The size is given as for
+// MemoryMax.
+func (r *ResourceControl) MemoryHigh(size string) *ResourceControl {
+	return r.limit("MemoryHigh", size, parseSize)
+}
+
+// MemoryLow sets the MemoryLow property. The size is given as for MemoryMax.
+func (r *ResourceControl) MemoryLow(size string) *ResourceControl {
+	return r.limit("MemoryLow", size, parseSize)
+}
+
+// TasksMax sets the TasksMax property from a number of tasks, a percentage of
+// the system-wide limit, such as "10%", or "infinity" for no limit. See
+// http://www.freedesktop.org/software/systemd/man/systemd.resource-control.html#TasksMax=N
+func (r *ResourceControl) TasksMax(tasks string) *ResourceControl {
+	return r.limit("TasksMax", tasks, func(s string) (uint64, error) {
+		return strconv.ParseUint(s, 10, 64)
+	})
+}
+
+// limit sets the property name, or its Scale variant if value is a
+// percentage.
+func (r *ResourceControl) limit(name string, value string, parse func(string) (uint64, error)) *ResourceControl {
+	if value == "infinity" {
+		return r.add(name, uint64(math.MaxUint64))
+	}
+	if strings.HasSuffix(value, "%") {
+		pct, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		if err != nil || pct < 0 || pct > 100 {
+			return r.fail(name, value, fmt.Errorf("not a percentage between 0 and 100"))
+		}
+		// the Scale properties express the fraction in units of 1/(2^32-1)
+		return r.add(name+"Scale", uint32(math.Round(pct/100*math.MaxUint32)))
+	}
+	n, err := parse(value)
+	if err != nil {
+		return r.fail(name, value, err)
+	}
+	return r.add(name, n)
+}
+
+// DevicePolicy sets the DevicePolicy property to "auto", "closed" or
+// "strict". See
+// http://www.freedesktop.org/software/systemd/man/systemd.resource-control.html#DevicePolicy=auto%7Cclosed%7Cstrict
+func (r *ResourceControl) DevicePolicy(policy string) *ResourceControl {
+	switch policy {
+	case "auto", "closed", "strict":
+		return r.add("DevicePolicy", policy)
+	}
+	return r.fail("DevicePolicy", policy, fmt.Errorf("must be auto, closed or strict"))
+}
+
+type deviceAllow struct {
+	Path        string // the device node path, or a device group such as "char-pts"
+	Permissions string // any combination of "r", "w" and "m"
+}
+
+// DeviceAllow adds a DeviceAllow entry granting the permissions, any
+// combination of "r", "w" and "m", to the device node at path. See
+// http://www.freedesktop.org/software/systemd/man/systemd.resource-control.html#DeviceAllow=
+func (r *ResourceControl) DeviceAllow(path string, permissions string) *ResourceControl {
+	if strings.Trim(permissions, "rwm") != "" {
+		return r.fail("DeviceAllow", permissions, fmt.Errorf("permissions must be a combination of r, w and m"))
+	}
+	return r.add("DeviceAllow", []deviceAllow{{path, permissions}})
+}
+
+// Delegate sets the Delegate property, turning over control of the unit's
+// cgroup subtree to its processes. See
+// http://www.freedesktop.org/software/systemd/man/systemd.resource-control.html#Delegate=
+func (r *ResourceControl) Delegate(delegate bool) *ResourceControl {
+	return r.add("Delegate", delegate)
+}
+
+// parseSize parses a size in bytes with an optional base 1024 suffix.
+func parseSize(s string) (uint64, error) {
+	factor := uint64(1)
+	if s != "" {
+		shift := strings.IndexByte("KMGTPE", s[len(s)-1])
+		if shift >= 0 {
+			factor = 1 << (10 * uint(shift+1))
+			s = s[:len(s)-1]
+		}
+	}
+
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if n > math.MaxUint64/factor {
+		return 0, fmt.Errorf("size too large")
+	}
+	return n * factor, nil
+}
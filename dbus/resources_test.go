@@ -0,0 +1,96 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func TestResourceControl(t *testing.T) {
+	props, err := NewResourceControl().
+		CPUQuota("150%").
+		CPUWeight(200).
+		MemoryMax("512M").
+		MemoryHigh("50%").
+		TasksMax("infinity").
+		DevicePolicy("closed").
+		DeviceAllow("/dev/null", "rw").
+		Delegate(true).
+		Properties()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Property{
+		{"CPUQuotaPerSecUSec", dbus.MakeVariant(uint64(1500000))},
+		{"CPUWeight", dbus.MakeVariant(uint64(200))},
+		{"MemoryMax", dbus.MakeVariant(uint64(512 << 20))},
+		{"MemoryHighScale", dbus.MakeVariant(uint32(math.MaxUint32/2 + 1))},
+		{"TasksMax", dbus.MakeVariant(uint64(math.MaxUint64))},
+		{"DevicePolicy", dbus.MakeVariant("closed")},
+		{"DeviceAllow", dbus.MakeVariant([]deviceAllow{{"/dev/null", "rw"}})},
+		{"Delegate", dbus.MakeVariant(true)},
+	}
+	if !reflect.DeepEqual(props, expected) {
+		t.Errorf("bad properties:\ngot  %v\nwant %v", props, expected)
+	}
+}
+
+func TestResourceControlInvalid(t *testing.T) {
+	cases := []*ResourceControl{
+		NewResourceControl().CPUQuota("1.5"),
+		NewResourceControl().CPUWeight(0),
+		NewResourceControl().IOWeight(10001),
+		NewResourceControl().MemoryMax("512X"),
+		NewResourceControl().MemoryLow("150%"),
+		NewResourceControl().TasksMax("many"),
+		NewResourceControl().DevicePolicy("open"),
+		NewResourceControl().DeviceAllow("/dev/null", "x"),
+	}
+	for i, r := range cases {
+		if _, err := r.Properties(); err == nil {
+			t.Errorf("case %d: expected an error", i)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	cases := map[string]uint64{
+		"0":    0,
+		"1024": 1024,
+		"4K":   4 << 10,
+		"512M": 512 << 20,
+		"2G":   2 << 30,
+		"1E":   1 << 60,
+	}
+	for in, want := range cases {
+		got, err := parseSize(in)
+		if err != nil {
+			t.Errorf("%q: %v", in, err)
+		} else if got != want {
+			t.Errorf("%q: got %d, want %d", in, got, want)
+		}
+	}
+
+	for _, in := range []string{"", "M", "-1", "16E"} {
+		if _, err := parseSize(in); err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"sync"
+)
+
+// signalQueue holds the signals waiting to be processed for the
+// subscribers. Once limit signals are queued, further signals are dropped.
+type signalQueue struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	signals []*BackendSignal
+	limit   int
+	dropped uint64
+	closed  bool
+}
+
+func newSignalQueue(limit int) *signalQueue {
+	q := &signalQueue{limit: limit}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// push queues signal, and reports whether there was room for it.
+func (q *signalQueue) push(signal *BackendSignal) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return false
+	}
+	if len(q.signals) >= q.limit {
+		q.dropped++
+		return false
+	}
+	q.signals = append(q.signals, signal)
+	q.cond.Signal()
+	return true
+}
+
+// pop waits for a signal to be queued. It returns false once the queue has
+// been closed.
+func (q *signalQueue) pop() (*BackendSignal, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.signals) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if q.closed {
+		return nil, false
+	}
+	signal := q.signals[0]
+	q.signals[0] = nil
+	q.signals = q.signals[1:]
+	return signal, true
+}
+
+// close discards the queued signals and wakes up pop.
+func (q *signalQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.signals = nil
+	q.cond.Broadcast()
+}
+
+func (q *signalQueue) setLimit(limit int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.limit = limit
+}
+
+func (q *signalQueue) getLimit() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.limit
+}
+
+func (q *signalQueue) droppedCount() uint64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.dropped
+}
+
+// SetSignalBuffer sets how many signals may be waiting to be processed for
+// the subscribers set with SetSubStateSubscriber and SetPropertiesSubscriber
+// before further signals are dropped. The default is 100. Job completions
+// are not subject to this limit. The channel receiving the signals of the
+// bus connection is sized to match when the connection is re-established.
+func (c *Conn) SetSignalBuffer(size int) {
+	c.signals.setLimit(size)
+}
+
+// newSignalChannel returns the channel receiving the signals of a new bus
+// connection, buffered as configured with SetSignalBuffer.
+func (c *Conn) newSignalChannel() chan *BackendSignal {
+	size := c.signals.getLimit()
+	if size < 0 {
+		size = 0
+	}
+	return make(chan *BackendSignal, size)
+}
+
+// DroppedSignals returns the number of signals dropped so far because the
+// signal buffer was full. A growing count means that the subscribers may
+// have missed unit state changes, and should resynchronize, e.g. using
+// ListUnits.
+func (c *Conn) DroppedSignals() uint64 {
+	return c.signals.droppedCount()
+}
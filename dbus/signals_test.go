@@ -0,0 +1,61 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import "testing"
+
+func TestSignalQueue(t *testing.T) {
+	q := newSignalQueue(2)
+
+	for i, name := range []string{"a", "b", "c"} {
+		ok := q.push(&BackendSignal{Name: name})
+		if want := i < 2; ok != want {
+			t.Errorf("push(%s) returned %t, want %t", name, ok, want)
+		}
+	}
+	if n := q.droppedCount(); n != 1 {
+		t.Errorf("bad dropped count: got %d, want 1", n)
+	}
+
+	for _, want := range []string{"a", "b"} {
+		signal, ok := q.pop()
+		if !ok || signal.Name != want {
+			t.Errorf("pop returned %v, %t, want %s", signal, ok, want)
+		}
+	}
+
+	q.setLimit(3)
+	for _, name := range []string{"d", "e", "f"} {
+		if !q.push(&BackendSignal{Name: name}) {
+			t.Errorf("push(%s) failed after raising the limit", name)
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			if _, ok := q.pop(); !ok {
+				return
+			}
+		}
+	}()
+	q.close()
+	<-done
+
+	if q.push(&BackendSignal{Name: "g"}) {
+		t.Error("push succeeded on a closed queue")
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/coreos/go-systemd/v22/unit"
+	"github.com/godbus/dbus/v5"
+)
+
+// RootSlice is the name of the root of the slice hierarchy.
+const RootSlice = "-.slice"
+
+// sliceMemberPatterns match the unit types which can be placed in a slice,
+// that is, the types with a Slice property.
+var sliceMemberPatterns = []string{"*.service", "*.socket", "*.mount", "*.swap", "*.scope", "*.slice"}
+
+// SliceName returns the name of the slice at the path parts in the slice
+// hierarchy, as used by systemd-run --slice: SliceName("machine", "payload")
+// is "machine-payload.slice", a child of "machine.slice". Dashes and other
+// special characters in parts are escaped. With no parts, SliceName returns
+// RootSlice.
+func SliceName(parts ...string) (string, error) {
+	if len(parts) == 0 {
+		return RootSlice, nil
+	}
+
+	escaped := make([]string, len(parts))
+	for i, p := range parts {
+		if p == "" {
+			return "", errors.New("empty slice name component")
+		}
+		escaped[i] = unit.UnitNameEscape(p)
+	}
+	return strings.Join(escaped, "-") + ".slice", nil
+}
+
+// SliceParent returns the name of the parent of the slice name, e.g.
+// "machine.slice" for "machine-payload.slice" and RootSlice for
+// "machine.slice". The root slice has no parent, and SliceParent returns an
+// empty string for it. An error is returned if name is not a valid slice name.
+func SliceParent(name string) (string, error) {
+	if name == RootSlice {
+		return "", nil
+	}
+	if err := validateSliceName(name); err != nil {
+		return "", err
+	}
+
+	prefix := strings.TrimSuffix(name, ".slice")
+	i := strings.LastIndexByte(prefix, '-')
+	if i < 0 {
+		return RootSlice, nil
+	}
+	return prefix[:i] + ".slice", nil
+}
+
+// validateSliceName checks name against the rules systemd applies to slice
+// names: a non-empty prefix without leading, trailing or doubled dashes,
+// followed by ".slice".
+func validateSliceName(name string) error {
+	prefix := strings.TrimSuffix(name, ".slice")
+	if prefix == name || prefix == "" ||
+		strings.HasPrefix(prefix, "-") || strings.HasSuffix(prefix, "-") ||
+		strings.Contains(prefix, "--") ||
+		!unitPath(name).IsValid() {
+		return errors.New("invalid slice name: " + name)
+	}
+	return nil
+}
+
+// NewSlice creates and starts the transient slice unit name, which must be a
+// valid slice name such as one returned by SliceName. Its parent slices are
+// created implicitly by systemd if they don't exist. Resource limits can be
+// passed along as properties, for example built with ResourceControl:
+//
+//	props, err := dbus.NewResourceControl().
+//		CPUWeight(200).
+//		MemoryMax("4G").
+//		Properties()
+//	...
+//	err = conn.NewSlice(ctx, "machine-payload.slice", props...)
+//
+// Limits of an existing slice are changed with SetUnitPropertiesContext.
+// NewSlice waits for the slice to be started. If a unit with this name
+// already exists, an error matching ErrUnitExists is returned; if the job
+// fails, the error is a *JobError.
+func (c *Conn) NewSlice(ctx context.Context, name string, properties ...Property) error {
+	if err := validateSliceName(name); err != nil {
+		return err
+	}
+	return c.startTransient(ctx, name, properties, nil)
+}
+
+// ListSliceUnits returns the units loaded in systemd that are placed
+// directly in slice according to their Slice property, including its child
+// slices, sorted by name. Units in the children of those slices are not
+// included.
+func (c *Conn) ListSliceUnits(ctx context.Context, slice string) ([]UnitStatus, error) {
+	if slice != RootSlice {
+		if err := validateSliceName(slice); err != nil {
+			return nil, err
+		}
+	}
+
+	units, err := c.ListUnitsByPatternsContext(ctx, nil, sliceMemberPatterns)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]UnitStatus, 0)
+	for _, u := range units {
+		parent, err := c.unitSlice(ctx, u)
+		if err != nil {
+			if ErrUnknownObject.Is(err) {
+				// the unit was unloaded in the meantime
+				continue
+			}
+			return nil, err
+		}
+		if parent == slice {
+			members = append(members, u)
+		}
+	}
+
+	sort.Slice(members, func(i, j int) bool { return members[i].Name < members[j].Name })
+	return members, nil
+}
+
+// unitSlice returns the Slice property of the unit u.
+func (c *Conn) unitSlice(ctx context.Context, u UnitStatus) (string, error) {
+	i := strings.LastIndexByte(u.Name, '.')
+	if i < 0 || i == len(u.Name)-1 {
+		return "", errors.New("invalid unit name: " + u.Name)
+	}
+	unitType := strings.ToUpper(u.Name[i+1:i+2]) + u.Name[i+2:]
+
+	var prop dbus.Variant
+	err := c.object(u.Path).CallWithContext(ctx, "org.freedesktop.DBus.Properties.Get", 0, "org.freedesktop.systemd1."+unitType, "Slice").Store(&prop)
+	if err != nil {
+		return "", err
+	}
+	slice, _ := prop.Value().(string)
+	return slice, nil
+}
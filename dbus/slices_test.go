@@ -0,0 +1,69 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"testing"
+)
+
+func TestSliceName(t *testing.T) {
+	tests := []struct {
+		parts    []string
+		expected string
+	}{
+		{nil, RootSlice},
+		{[]string{"machine"}, "machine.slice"},
+		{[]string{"machine", "payload"}, "machine-payload.slice"},
+		{[]string{"user", "app-1"}, `user-app\x2d1.slice`},
+	}
+	for _, tt := range tests {
+		name, err := SliceName(tt.parts...)
+		if err != nil {
+			t.Errorf("SliceName(%q): %v", tt.parts, err)
+		} else if name != tt.expected {
+			t.Errorf("SliceName(%q) = %q, want %q", tt.parts, name, tt.expected)
+		}
+	}
+
+	if _, err := SliceName("machine", ""); err == nil {
+		t.Error("expected an error for an empty component")
+	}
+}
+
+func TestSliceParent(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{RootSlice, ""},
+		{"machine.slice", RootSlice},
+		{"machine-payload.slice", "machine.slice"},
+		{"a-b-c.slice", "a-b.slice"},
+	}
+	for _, tt := range tests {
+		parent, err := SliceParent(tt.name)
+		if err != nil {
+			t.Errorf("SliceParent(%q): %v", tt.name, err)
+		} else if parent != tt.expected {
+			t.Errorf("SliceParent(%q) = %q, want %q", tt.name, parent, tt.expected)
+		}
+	}
+
+	for _, name := range []string{"machine", "machine.service", ".slice", "-machine.slice", "machine-.slice", "a--b.slice"} {
+		if _, err := SliceParent(name); err == nil {
+			t.Errorf("SliceParent(%q): expected an error", name)
+		}
+	}
+}
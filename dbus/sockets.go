@@ -0,0 +1,95 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"context"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// SocketListen is an address a socket unit listens on.
+type SocketListen struct {
+	Type    string // The kind of listener, e.g. Stream, Datagram, FIFO or Netlink
+	Address string // The address, e.g. /run/foo.sock or [::]:80
+}
+
+// SocketStatus describes a socket unit, as listed by systemctl list-sockets.
+type SocketStatus struct {
+	Name        string         // The socket unit name, e.g. sshd.socket
+	ActiveState string         // The active state of the socket
+	Listen      []SocketListen // The addresses the socket listens on
+	Triggers    []string       // The units the socket activates, usually one service
+}
+
+// ListSockets returns the socket units loaded in systemd, like systemctl
+// list-sockets --all, in name order.
+func (c *Conn) ListSockets(ctx context.Context) ([]SocketStatus, error) {
+	units, err := c.ListUnitsByPatternsContext(ctx, nil, []string{"*.socket"})
+	if err != nil {
+		return nil, err
+	}
+
+	sockets := make([]SocketStatus, 0, len(units))
+	for _, u := range units {
+		s, err := c.socketStatus(ctx, u)
+		if err != nil {
+			if ErrUnknownObject.Is(err) {
+				// the socket was unloaded in the meantime
+				continue
+			}
+			return nil, err
+		}
+		sockets = append(sockets, s)
+	}
+
+	return sockets, nil
+}
+
+func (c *Conn) socketStatus(ctx context.Context, u UnitStatus) (SocketStatus, error) {
+	s := SocketStatus{Name: u.Name, ActiveState: u.ActiveState}
+
+	listen, err := c.getProperty(ctx, u.Name, "org.freedesktop.systemd1.Socket", "Listen")
+	if err != nil {
+		return s, err
+	}
+	if s.Listen, err = parseSocketListen(listen.Value); err != nil {
+		return s, err
+	}
+
+	triggers, err := c.getProperty(ctx, u.Name, "org.freedesktop.systemd1.Unit", "Triggers")
+	if err != nil {
+		return s, err
+	}
+	s.Triggers, _ = triggers.Value.Value().([]string)
+
+	return s, nil
+}
+
+// parseSocketListen converts the value of the Listen property, of type a(ss).
+func parseSocketListen(v dbus.Variant) ([]SocketListen, error) {
+	result := make([][]interface{}, 0)
+	if err := dbus.Store([]interface{}{v.Value()}, &result); err != nil {
+		return nil, err
+	}
+
+	listen := make([]SocketListen, len(result))
+	for i, entry := range result {
+		if err := dbus.Store(entry, &listen[i].Type, &listen[i].Address); err != nil {
+			return nil, err
+		}
+	}
+	return listen, nil
+}
@@ -0,0 +1,45 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func TestParseSocketListen(t *testing.T) {
+	v := dbus.MakeVariant([][]interface{}{
+		{"Stream", "/run/foo.sock"},
+		{"Datagram", "[::]:53"},
+	})
+	listen, err := parseSocketListen(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []SocketListen{
+		{Type: "Stream", Address: "/run/foo.sock"},
+		{Type: "Datagram", Address: "[::]:53"},
+	}
+	if !reflect.DeepEqual(listen, expected) {
+		t.Errorf("got %+v, want %+v", listen, expected)
+	}
+
+	if _, err := parseSocketListen(dbus.MakeVariant([][]interface{}{{"Stream"}})); err == nil {
+		t.Error("expected an error for a malformed listen entry")
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"context"
+)
+
+// UnitState summarizes the state of a unit, see GetUnitState.
+type UnitState struct {
+	LoadState     string // e.g. loaded, not-found or masked
+	ActiveState   string // e.g. active, inactive or failed
+	SubState      string // e.g. running or exited for a service
+	UnitFileState string // e.g. enabled, disabled or static; empty if the unit has no unit file
+}
+
+// Active reports whether the unit is active, like systemctl is-active. A
+// unit being reloaded is active too.
+func (s *UnitState) Active() bool {
+	return s.ActiveState == "active" || s.ActiveState == "reloading"
+}
+
+// Running reports whether the unit is active and, for units with processes,
+// its processes are running: a oneshot service that exited successfully with
+// RemainAfterExit=yes is active, but not running.
+func (s *UnitState) Running() bool {
+	if !s.Active() {
+		return false
+	}
+	switch s.SubState {
+	case "exited", "elapsed", "waiting":
+		return false
+	}
+	return true
+}
+
+// Failed reports whether the unit failed, like systemctl is-failed.
+func (s *UnitState) Failed() bool {
+	return s.ActiveState == "failed"
+}
+
+// Enabled reports whether the unit is started at boot or on demand, like
+// systemctl is-enabled: besides enabled units, this includes static, indirect,
+// generated and alias units, as well as transient ones.
+func (s *UnitState) Enabled() bool {
+	switch s.UnitFileState {
+	case "enabled", "enabled-runtime", "static", "indirect", "generated", "transient", "alias":
+		return true
+	}
+	return false
+}
+
+// GetUnitState returns the state of the unit name. Units that are not loaded
+// are reported with LoadState "not-found" rather than an error.
+func (c *Conn) GetUnitState(ctx context.Context, name string) (*UnitState, error) {
+	props, err := c.GetUnitPropertiesContext(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &UnitState{}
+	s.LoadState, _ = props["LoadState"].(string)
+	s.ActiveState, _ = props["ActiveState"].(string)
+	s.SubState, _ = props["SubState"].(string)
+	s.UnitFileState, _ = props["UnitFileState"].(string)
+	return s, nil
+}
+
+// IsUnitActive reports whether the unit name is active, see UnitState.Active.
+func (c *Conn) IsUnitActive(ctx context.Context, name string) (bool, error) {
+	s, err := c.GetUnitState(ctx, name)
+	if err != nil {
+		return false, err
+	}
+	return s.Active(), nil
+}
+
+// IsUnitFailed reports whether the unit name failed, see UnitState.Failed.
+func (c *Conn) IsUnitFailed(ctx context.Context, name string) (bool, error) {
+	s, err := c.GetUnitState(ctx, name)
+	if err != nil {
+		return false, err
+	}
+	return s.Failed(), nil
+}
+
+// IsUnitEnabled reports whether the unit name is enabled, see
+// UnitState.Enabled.
+func (c *Conn) IsUnitEnabled(ctx context.Context, name string) (bool, error) {
+	s, err := c.GetUnitState(ctx, name)
+	if err != nil {
+		return false, err
+	}
+	return s.Enabled(), nil
+}
@@ -0,0 +1,37 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"testing"
+)
+
+func TestUnitState(t *testing.T) {
+	for _, tt := range []struct {
+		state                            UnitState
+		active, running, failed, enabled bool
+	}{
+		{UnitState{ActiveState: "active", SubState: "running", UnitFileState: "enabled"}, true, true, false, true},
+		{UnitState{ActiveState: "active", SubState: "exited", UnitFileState: "static"}, true, false, false, true},
+		{UnitState{ActiveState: "reloading", SubState: "reload", UnitFileState: "linked"}, true, true, false, false},
+		{UnitState{ActiveState: "failed", SubState: "failed", UnitFileState: "disabled"}, false, false, true, false},
+		{UnitState{LoadState: "not-found", ActiveState: "inactive", SubState: "dead"}, false, false, false, false},
+	} {
+		s := tt.state
+		if s.Active() != tt.active || s.Running() != tt.running || s.Failed() != tt.failed || s.Enabled() != tt.enabled {
+			t.Errorf("%+v: got active %v, running %v, failed %v, enabled %v", s, s.Active(), s.Running(), s.Failed(), s.Enabled())
+		}
+	}
+}
@@ -15,8 +15,10 @@
 package dbus
 
 import (
+	"context"
 	"errors"
 	"log"
+	"sync/atomic"
 	"time"
 
 	"github.com/godbus/dbus/v5"
@@ -30,89 +32,284 @@ const (
 // Subscribe sets up this connection to subscribe to all systemd dbus events.
 // This is required before calling SubscribeUnits. When the connection closes
 // systemd will automatically stop sending signals so there is no need to
-// explicitly call Unsubscribe().
+// explicitly call Unsubscribe(). Calling Subscribe again has no effect.
 func (c *Conn) Subscribe() error {
-	c.sigconn.BusObject().Call("org.freedesktop.DBus.AddMatch", 0,
-		"type='signal',interface='org.freedesktop.systemd1.Manager',member='UnitNew'")
-	c.sigconn.BusObject().Call("org.freedesktop.DBus.AddMatch", 0,
-		"type='signal',interface='org.freedesktop.DBus.Properties',member='PropertiesChanged'")
+	c.subscriptionLock.Lock()
+	defer c.subscriptionLock.Unlock()
 
-	return c.sigobj.Call("org.freedesktop.systemd1.Manager.Subscribe", 0).Store()
+	if c.subscribed {
+		return nil
+	}
+
+	ctx := context.Background()
+	sigconn, _ := c.signalConn()
+	c.addSubscriptionMatches(ctx, sigconn)
+	if err := c.acquireSubscription(ctx); err != nil {
+		c.removeSubscriptionMatches(ctx, sigconn)
+		return err
+	}
+	c.subscribed = true
+	return nil
 }
 
-// Unsubscribe this connection from systemd dbus events.
+// Unsubscribe this connection from systemd dbus events. systemd keeps sending
+// the signals needed by the WatchUnitProperties calls in progress.
 func (c *Conn) Unsubscribe() error {
-	return c.sigobj.Call("org.freedesktop.systemd1.Manager.Unsubscribe", 0).Store()
+	c.subscriptionLock.Lock()
+	defer c.subscriptionLock.Unlock()
+
+	if !c.subscribed {
+		return nil
+	}
+
+	ctx := context.Background()
+	sigconn, _ := c.signalConn()
+	c.removeSubscriptionMatches(ctx, sigconn)
+	c.subscribed = false
+	return c.releaseSubscription(ctx)
 }
 
-func (c *Conn) dispatch() {
-	ch := make(chan *dbus.Signal, signalBuffer)
+// acquireSubscription adds a user of the systemd subscription, asking
+// systemd to emit signals for the first one. c.subscriptionLock must be
+// held.
+func (c *Conn) acquireSubscription(ctx context.Context) error {
+	if c.subscriptions == 0 {
+		_, sigobj := c.signalConn()
+		err := sigobj.CallWithContext(ctx, "org.freedesktop.systemd1.Manager.Subscribe", 0).Store()
+		if err != nil && !ErrAlreadySubscribed.Is(err) {
+			return err
+		}
+	}
+	c.subscriptions++
+	return nil
+}
 
-	c.sigconn.Signal(ch)
+// releaseSubscription removes a user of the systemd subscription, telling
+// systemd to stop emitting signals once there are none left.
+// c.subscriptionLock must be held.
+func (c *Conn) releaseSubscription(ctx context.Context) error {
+	c.subscriptions--
+	if c.subscriptions > 0 {
+		return nil
+	}
+	_, sigobj := c.signalConn()
+	return sigobj.CallWithContext(ctx, "org.freedesktop.systemd1.Manager.Unsubscribe", 0).Store()
+}
+
+// restoreSubscription re-establishes the subscription and the match rules
+// on a new signal connection.
+func (c *Conn) restoreSubscription(sigconn Backend, sigobj dbus.BusObject) error {
+	c.subscriptionLock.Lock()
+	defer c.subscriptionLock.Unlock()
+
+	ctx, cancel := context.WithTimeout(c.ctx, closeTimeout)
+	defer cancel()
+
+	var err error
+	if c.subscriptions > 0 {
+		err = sigobj.CallWithContext(ctx, "org.freedesktop.systemd1.Manager.Subscribe", 0).Store()
+		if ErrAlreadySubscribed.Is(err) {
+			err = nil
+		}
+	}
+	if c.subscribed {
+		c.addSubscriptionMatches(ctx, sigconn)
+	}
+	for _, w := range c.watches() {
+		if werr := c.addWatchMatch(ctx, sigconn, w); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	return err
+}
+
+// closeSubscription tears down the subscription when closing c.
+// c.subscriptionLock and c.connLock must be held.
+func (c *Conn) closeSubscription(ctx context.Context) {
+	if c.subscriptions > 0 {
+		c.hooked(c.sigobj).CallWithContext(ctx, "org.freedesktop.systemd1.Manager.Unsubscribe", 0).Store()
+	}
+	if c.subscribed {
+		c.removeSubscriptionMatches(ctx, c.sigconn)
+	}
+	c.subscribed = false
+	c.subscriptions = 0
+}
+
+func (c *Conn) addSubscriptionMatches(ctx context.Context, sigconn Backend) {
+	bus := c.bus(sigconn)
+	bus.CallWithContext(ctx, "org.freedesktop.DBus.AddMatch", 0, unitNewMatch)
+	bus.CallWithContext(ctx, "org.freedesktop.DBus.AddMatch", 0, unitRemovedMatch)
+	bus.CallWithContext(ctx, "org.freedesktop.DBus.AddMatch", 0, propertiesChangedMatch)
+}
+
+func (c *Conn) removeSubscriptionMatches(ctx context.Context, sigconn Backend) {
+	bus := c.bus(sigconn)
+	bus.CallWithContext(ctx, "org.freedesktop.DBus.RemoveMatch", 0, unitNewMatch)
+	bus.CallWithContext(ctx, "org.freedesktop.DBus.RemoveMatch", 0, unitRemovedMatch)
+	bus.CallWithContext(ctx, "org.freedesktop.DBus.RemoveMatch", 0, propertiesChangedMatch)
+}
+
+// dispatch processes the signals received on ch. Job completions are handled
+// as they are received, everything else is queued and processed for the
+// subscribers by a second goroutine, so that slow subscribers cannot hold up
+// the reception of signals.
+func (c *Conn) dispatch(ch chan *BackendSignal) {
+	done := make(chan struct{})
 
 	go func() {
+		defer close(done)
+
 		for {
-			signal, ok := <-ch
+			signal, ok := c.signals.pop()
 			if !ok {
 				return
 			}
+			c.processSignal(signal)
+		}
+	}()
 
-			if signal.Name == "org.freedesktop.systemd1.Manager.JobRemoved" {
-				c.jobComplete(signal)
-			}
+	go func() {
+		defer close(c.dispatched)
+		defer func() { <-done }()
+		defer c.signals.close()
 
-			if c.subStateSubscriber.updateCh == nil &&
-				c.propertiesSubscriber.updateCh == nil {
+		for {
+			signal, ok := <-ch
+			if !ok {
+				// The signal connection was lost or closed.
+				if ch = c.reconnect(); ch == nil {
+					return
+				}
 				continue
 			}
 
-			var unitPath dbus.ObjectPath
-			switch signal.Name {
-			case "org.freedesktop.systemd1.Manager.JobRemoved":
-				unitName := signal.Body[2].(string)
-				c.sysobj.Call("org.freedesktop.systemd1.Manager.GetUnit", 0, unitName).Store(&unitPath)
-			case "org.freedesktop.systemd1.Manager.UnitNew":
-				unitPath = signal.Body[1].(dbus.ObjectPath)
-			case "org.freedesktop.DBus.Properties.PropertiesChanged":
-				if signal.Body[0].(string) == "org.freedesktop.systemd1.Unit" {
-					unitPath = signal.Path
-
-					if len(signal.Body) >= 2 {
-						if changed, ok := signal.Body[1].(map[string]dbus.Variant); ok {
-							c.sendPropertiesUpdate(unitPath, changed)
-						}
-					}
-				}
+			if h := c.loadHooks(); h != nil {
+				h.OnSignal(signal.Name)
+			}
+
+			if signal.Name == "org.freedesktop.systemd1.Manager.JobRemoved" {
+				c.jobComplete(signal)
 			}
+			c.routeWatchSignal(signal)
 
-			if unitPath == dbus.ObjectPath("") {
+			if !c.hasSubscribers() {
 				continue
 			}
 
-			c.sendSubStateUpdate(unitPath)
+			c.signals.push(signal)
 		}
 	}()
 }
 
-// SubscribeUnits returns two unbuffered channels which will receive all changed units every
-// interval.  Deleted units are sent as nil.
+// hasSubscribers reports whether signals need to be queued for processing.
+// The substate and properties subscribers are checked with flags rather than
+// under their locks, which are held while updates are sent.
+func (c *Conn) hasSubscribers() bool {
+	return atomic.LoadInt32(&c.subStateSubscriber.set) != 0 ||
+		atomic.LoadInt32(&c.propertiesSubscriber.set) != 0 ||
+		c.hasSetSubscribers()
+}
+
+func boolInt32(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (c *Conn) processSignal(signal *BackendSignal) {
+	c.sendSetUpdates(signal)
+
+	var unitPath dbus.ObjectPath
+	switch signal.Name {
+	case "org.freedesktop.systemd1.Manager.JobRemoved":
+		unitName := signal.Body[2].(string)
+		c.manager().Call("org.freedesktop.systemd1.Manager.GetUnit", 0, unitName).Store(&unitPath)
+	case "org.freedesktop.systemd1.Manager.UnitNew":
+		unitPath = signal.Body[1].(dbus.ObjectPath)
+	case "org.freedesktop.DBus.Properties.PropertiesChanged":
+		if signal.Body[0].(string) == "org.freedesktop.systemd1.Unit" {
+			unitPath = signal.Path
+
+			if len(signal.Body) >= 2 {
+				if changed, ok := signal.Body[1].(map[string]dbus.Variant); ok {
+					c.sendPropertiesUpdate(unitPath, changed)
+				}
+			}
+		}
+	}
+
+	if unitPath == dbus.ObjectPath("") {
+		return
+	}
+
+	c.sendSubStateUpdate(unitPath)
+}
+
+// SubscribeUnits is a wrapper around SubscribeUnitsContext.
+//
+// Deprecated: use SubscribeUnitsContext instead.
 func (c *Conn) SubscribeUnits(interval time.Duration) (<-chan map[string]*UnitStatus, <-chan error) {
-	return c.SubscribeUnitsCustom(interval, 0, func(u1, u2 *UnitStatus) bool { return *u1 != *u2 }, nil)
+	return c.SubscribeUnitsContext(context.Background(), interval)
+}
+
+// SubscribeUnitsContext returns two unbuffered channels which will receive all changed units every
+// interval.  Deleted units are sent as nil. Both channels are closed once ctx
+// is done or the connection is closed.
+func (c *Conn) SubscribeUnitsContext(ctx context.Context, interval time.Duration) (<-chan map[string]*UnitStatus, <-chan error) {
+	return c.SubscribeUnitsCustomContext(ctx, interval, 0, func(u1, u2 *UnitStatus) bool { return *u1 != *u2 }, nil)
+}
+
+// SubscribeUnitsCustom is a wrapper around SubscribeUnitsCustomContext.
+//
+// Deprecated: use SubscribeUnitsCustomContext instead.
+func (c *Conn) SubscribeUnitsCustom(interval time.Duration, buffer int, isChanged func(*UnitStatus, *UnitStatus) bool, filterUnit func(string) bool) (<-chan map[string]*UnitStatus, <-chan error) {
+	return c.SubscribeUnitsCustomContext(context.Background(), interval, buffer, isChanged, filterUnit)
 }
 
-// SubscribeUnitsCustom is like SubscribeUnits but lets you specify the buffer
+// SubscribeUnitsCustomContext is like SubscribeUnitsContext but lets you specify the buffer
 // size of the channels, the comparison function for detecting changes and a filter
 // function for cutting down on the noise that your channel receives.
-func (c *Conn) SubscribeUnitsCustom(interval time.Duration, buffer int, isChanged func(*UnitStatus, *UnitStatus) bool, filterUnit func(string) bool) (<-chan map[string]*UnitStatus, <-chan error) {
+// Both channels are closed once ctx is done or the connection is closed.
+func (c *Conn) SubscribeUnitsCustomContext(ctx context.Context, interval time.Duration, buffer int, isChanged func(*UnitStatus, *UnitStatus) bool, filterUnit func(string) bool) (<-chan map[string]*UnitStatus, <-chan error) {
+	return c.subscribeUnits(ctx, interval, buffer, isChanged, filterUnit, func() ([]UnitStatus, error) {
+		return c.ListUnitsContext(ctx)
+	})
+}
+
+// SubscribeUnitsByNames is like SubscribeUnitsCustomContext, but only asks
+// systemd for the units with the given names on every interval, which is much
+// cheaper than listing all units. Units that are not loaded are reported with
+// LoadState "not-found" instead of being absent.
+func (c *Conn) SubscribeUnitsByNames(ctx context.Context, interval time.Duration, buffer int, isChanged func(*UnitStatus, *UnitStatus) bool, names []string) (<-chan map[string]*UnitStatus, <-chan error) {
+	return c.subscribeUnits(ctx, interval, buffer, isChanged, nil, func() ([]UnitStatus, error) {
+		return c.ListUnitsByNamesContext(ctx, names)
+	})
+}
+
+// SubscribeUnitsByPatterns is like SubscribeUnitsCustomContext, but only asks
+// systemd for the loaded units matching one of the given glob patterns, such
+// as "docker-*.scope", on every interval.
+func (c *Conn) SubscribeUnitsByPatterns(ctx context.Context, interval time.Duration, buffer int, isChanged func(*UnitStatus, *UnitStatus) bool, patterns []string) (<-chan map[string]*UnitStatus, <-chan error) {
+	return c.subscribeUnits(ctx, interval, buffer, isChanged, nil, func() ([]UnitStatus, error) {
+		return c.ListUnitsByPatternsContext(ctx, []string{}, patterns)
+	})
+}
+
+func (c *Conn) subscribeUnits(ctx context.Context, interval time.Duration, buffer int, isChanged func(*UnitStatus, *UnitStatus) bool, filterUnit func(string) bool, listUnits func() ([]UnitStatus, error)) (<-chan map[string]*UnitStatus, <-chan error) {
 	old := make(map[string]*UnitStatus)
 	statusChan := make(chan map[string]*UnitStatus, buffer)
 	errChan := make(chan error, buffer)
 
 	go func() {
+		defer close(statusChan)
+		defer close(errChan)
+
 		for {
 			timerChan := time.After(interval)
 
-			units, err := c.ListUnits()
+			units, err := listUnits()
 			if err == nil {
 				cur := make(map[string]*UnitStatus)
 				for i := range units {
@@ -139,13 +336,31 @@ func (c *Conn) SubscribeUnitsCustom(interval time.Duration, buffer int, isChange
 				old = cur
 
 				if len(changed) != 0 {
-					statusChan <- changed
+					select {
+					case statusChan <- changed:
+					case <-c.closed:
+						return
+					case <-ctx.Done():
+						return
+					}
+				}
+			} else if ctx.Err() == nil {
+				select {
+				case errChan <- err:
+				case <-c.closed:
+					return
+				case <-ctx.Done():
+					return
 				}
-			} else {
-				errChan <- err
 			}
 
-			<-timerChan
+			select {
+			case <-timerChan:
+			case <-c.closed:
+				return
+			case <-ctx.Done():
+				return
+			}
 		}
 	}()
 
@@ -181,6 +396,7 @@ func (c *Conn) SetSubStateSubscriber(updateCh chan<- *SubStateUpdate, errCh chan
 	defer c.subStateSubscriber.Unlock()
 	c.subStateSubscriber.updateCh = updateCh
 	c.subStateSubscriber.errCh = errCh
+	atomic.StoreInt32(&c.subStateSubscriber.set, boolInt32(updateCh != nil))
 }
 
 func (c *Conn) sendSubStateUpdate(unitPath dbus.ObjectPath) {
@@ -197,7 +413,7 @@ func (c *Conn) sendSubStateUpdate(unitPath dbus.ObjectPath) {
 		return
 	}
 
-	info, err := c.GetUnitPathProperties(unitPath)
+	info, err := c.GetUnitPathPropertiesContext(context.Background(), unitPath)
 	if err != nil {
 		select {
 		case c.subStateSubscriber.errCh <- err:
@@ -305,6 +521,7 @@ func (c *Conn) SetPropertiesSubscriber(updateCh chan<- *PropertiesUpdate, errCh
 	defer c.propertiesSubscriber.Unlock()
 	c.propertiesSubscriber.updateCh = updateCh
 	c.propertiesSubscriber.errCh = errCh
+	atomic.StoreInt32(&c.propertiesSubscriber.set, boolInt32(updateCh != nil))
 }
 
 // we don't need to worry about shouldIgnore() here because
@@ -15,7 +15,13 @@
 package dbus
 
 import (
+	"context"
+	"errors"
+	"log"
+	"sync"
 	"time"
+
+	"github.com/godbus/dbus/v5"
 )
 
 // SubscriptionSet returns a subscription set which is like conn.Subscribe but
@@ -23,17 +29,34 @@ import (
 type SubscriptionSet struct {
 	*set
 	conn *Conn
+
+	subscriber struct {
+		updateCh chan<- map[string]*UnitStatus
+		errCh    chan<- error
+		sync.Mutex
+		// last holds the status last sent for each unit, nil for units
+		// that have been removed
+		last map[string]*UnitStatus
+	}
 }
 
 func (s *SubscriptionSet) filter(unit string) bool {
 	return !s.Contains(unit)
 }
 
-// Subscribe starts listening for dbus events for all of the units in the set.
-// Returns channels identical to conn.SubscribeUnits.
+// Subscribe is a wrapper around SubscribeContext.
+//
+// Deprecated: use SubscribeContext instead.
 func (s *SubscriptionSet) Subscribe() (<-chan map[string]*UnitStatus, <-chan error) {
-	// TODO: Make fully evented by using systemd 209 with properties changed values
-	return s.conn.SubscribeUnitsCustom(time.Second, 0,
+	return s.SubscribeContext(context.Background())
+}
+
+// SubscribeContext starts listening for dbus events for all of the units in the set.
+// Returns channels identical to conn.SubscribeUnitsContext. SubscribeContext
+// polls the status of all units every second; see SetSubscriber for an
+// alternative driven by the signals systemd emits.
+func (s *SubscriptionSet) SubscribeContext(ctx context.Context) (<-chan map[string]*UnitStatus, <-chan error) {
+	return s.conn.SubscribeUnitsCustomContext(ctx, time.Second, 0,
 		mismatchUnitStatus,
 		func(unit string) bool { return s.filter(unit) },
 	)
@@ -41,7 +64,180 @@ func (s *SubscriptionSet) Subscribe() (<-chan map[string]*UnitStatus, <-chan err
 
 // NewSubscriptionSet returns a new subscription set.
 func (conn *Conn) NewSubscriptionSet() *SubscriptionSet {
-	return &SubscriptionSet{newSet(), conn}
+	return &SubscriptionSet{set: newSet(), conn: conn}
+}
+
+// SetSubscriber writes the status of the units in the set to updateCh whenever
+// systemd signals a change to one of them, without periodically listing all
+// units. Each update holds a single unit; units that have been unloaded are
+// sent as nil. The current status of the units in the set is written first.
+// conn.Subscribe must be called for systemd to emit the signals.
+//
+// Like SetSubStateSubscriber, the reported status may be more recent than the
+// change that generated it, and updates are written with non-blocking writes:
+// if updateCh is full, an error is written to errCh; if errCh is full, the
+// error passes silently. Passing a nil updateCh stops the updates.
+func (s *SubscriptionSet) SetSubscriber(updateCh chan<- map[string]*UnitStatus, errCh chan<- error) {
+	s.subscriber.Lock()
+	s.subscriber.updateCh = updateCh
+	s.subscriber.errCh = errCh
+	s.subscriber.last = make(map[string]*UnitStatus)
+	s.subscriber.Unlock()
+
+	s.conn.setSubscribers.Lock()
+	if updateCh == nil {
+		delete(s.conn.setSubscribers.sets, s)
+	} else {
+		if s.conn.setSubscribers.sets == nil {
+			s.conn.setSubscribers.sets = make(map[*SubscriptionSet]bool)
+		}
+		s.conn.setSubscribers.sets[s] = true
+	}
+	s.conn.setSubscribers.Unlock()
+
+	if updateCh == nil {
+		return
+	}
+
+	s.subscriber.Lock()
+	defer s.subscriber.Unlock()
+
+	units, err := s.conn.ListUnitsByNamesContext(context.Background(), s.Values())
+	if err != nil {
+		s.sendError(err)
+		return
+	}
+	for i := range units {
+		s.sendUpdate(units[i].Name, &units[i])
+	}
+}
+
+// sendError must be called with s.subscriber locked.
+func (s *SubscriptionSet) sendError(err error) {
+	select {
+	case s.subscriber.errCh <- err:
+	default:
+		log.Printf("full error channel while reporting: %s\n", err)
+	}
+}
+
+// sendUpdate sends status if it differs from the status last sent for name.
+// It must be called with s.subscriber locked.
+func (s *SubscriptionSet) sendUpdate(name string, status *UnitStatus) {
+	if last, known := s.subscriber.last[name]; known {
+		if last == nil && status == nil {
+			return
+		}
+		if last != nil && status != nil && !mismatchUnitStatus(last, status) {
+			return
+		}
+	}
+	s.subscriber.last[name] = status
+
+	select {
+	case s.subscriber.updateCh <- map[string]*UnitStatus{name: status}:
+	default:
+		s.sendError(errors.New("update channel is full"))
+	}
+}
+
+// processSignal updates the subscriber of s about the unit signal refers to,
+// if that unit is in the set.
+func (s *SubscriptionSet) processSignal(signal *BackendSignal) {
+	var name string
+	removed := false
+	switch signal.Name {
+	case "org.freedesktop.systemd1.Manager.UnitNew":
+		name, _ = signal.Body[0].(string)
+	case "org.freedesktop.systemd1.Manager.UnitRemoved":
+		name, _ = signal.Body[0].(string)
+		removed = true
+	case "org.freedesktop.DBus.Properties.PropertiesChanged":
+		if iface, _ := signal.Body[0].(string); iface == "org.freedesktop.systemd1.Unit" {
+			name = unitName(signal.Path)
+		}
+	}
+
+	if name == "" || !s.Contains(name) {
+		return
+	}
+
+	s.subscriber.Lock()
+	defer s.subscriber.Unlock()
+
+	if s.subscriber.updateCh == nil {
+		return
+	}
+
+	if removed {
+		s.sendUpdate(name, nil)
+		return
+	}
+
+	// Requesting the properties of a unit that is not loaded makes systemd
+	// load it, emitting UnitNew and UnitRemoved again, see the ignore
+	// functions in subscription.go. Only look at UnitNew for units we have
+	// not reported on yet, later changes are signalled by PropertiesChanged.
+	if _, known := s.subscriber.last[name]; known && signal.Name == "org.freedesktop.systemd1.Manager.UnitNew" {
+		return
+	}
+
+	status, err := s.conn.unitStatus(context.Background(), unitPath(name))
+	if err != nil {
+		s.sendError(err)
+		return
+	}
+	s.sendUpdate(name, status)
+}
+
+func (c *Conn) hasSetSubscribers() bool {
+	c.setSubscribers.Lock()
+	defer c.setSubscribers.Unlock()
+	return len(c.setSubscribers.sets) != 0
+}
+
+func (c *Conn) sendSetUpdates(signal *BackendSignal) {
+	c.setSubscribers.Lock()
+	sets := make([]*SubscriptionSet, 0, len(c.setSubscribers.sets))
+	for s := range c.setSubscribers.sets {
+		sets = append(sets, s)
+	}
+	c.setSubscribers.Unlock()
+
+	for _, s := range sets {
+		s.processSignal(signal)
+	}
+}
+
+// unitStatus returns the status of the unit at path, as it would be reported
+// by ListUnits.
+func (c *Conn) unitStatus(ctx context.Context, path dbus.ObjectPath) (*UnitStatus, error) {
+	props, err := c.GetUnitPathPropertiesContext(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &UnitStatus{Path: path}
+	status.Name, _ = props["Id"].(string)
+	status.Description, _ = props["Description"].(string)
+	status.LoadState, _ = props["LoadState"].(string)
+	status.ActiveState, _ = props["ActiveState"].(string)
+	status.SubState, _ = props["SubState"].(string)
+	status.Followed, _ = props["Following"].(string)
+	// Job is a (uo) struct holding the job id and path
+	if job, ok := props["Job"].([]interface{}); ok && len(job) == 2 {
+		status.JobId, _ = job[0].(uint32)
+		status.JobPath, _ = job[1].(dbus.ObjectPath)
+	}
+	if status.JobId != 0 {
+		var jobType dbus.Variant
+		err := c.object(status.JobPath).CallWithContext(ctx, "org.freedesktop.DBus.Properties.Get", 0, "org.freedesktop.systemd1.Job", "JobType").Store(&jobType)
+		if err == nil {
+			status.JobType, _ = jobType.Value().(string)
+		}
+	}
+
+	return status, nil
 }
 
 // mismatchUnitStatus returns true if the provided UnitStatus objects
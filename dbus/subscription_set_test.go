@@ -80,3 +80,46 @@ func TestSubscriptionSetUnit(t *testing.T) {
 success:
 	return
 }
+
+func TestSubscriptionSetProcessSignal(t *testing.T) {
+	target := "subscribe-events-set.service"
+
+	subSet := (&Conn{}).NewSubscriptionSet()
+	subSet.Add(target)
+
+	updateCh := make(chan map[string]*UnitStatus, 10)
+	errCh := make(chan error, 10)
+	subSet.subscriber.updateCh = updateCh
+	subSet.subscriber.errCh = errCh
+	subSet.subscriber.last = map[string]*UnitStatus{
+		target: {Name: target, ActiveState: "active"},
+	}
+
+	removed := func(name string) *BackendSignal {
+		return &BackendSignal{
+			Name: "org.freedesktop.systemd1.Manager.UnitRemoved",
+			Body: []interface{}{name, unitPath(name)},
+		}
+	}
+
+	subSet.processSignal(removed("other.service"))
+	subSet.processSignal(removed(target))
+	subSet.processSignal(removed(target))
+
+	select {
+	case update := <-updateCh:
+		if status, ok := update[target]; !ok || status != nil || len(update) != 1 {
+			t.Fatalf("unexpected update: %v", update)
+		}
+	default:
+		t.Fatal("no update for removed unit")
+	}
+
+	select {
+	case update := <-updateCh:
+		t.Fatalf("unexpected update: %v", update)
+	case err := <-errCh:
+		t.Fatal(err)
+	default:
+	}
+}
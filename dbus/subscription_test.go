@@ -15,8 +15,12 @@
 package dbus
 
 import (
+	"context"
+	"reflect"
 	"testing"
 	"time"
+
+	"github.com/godbus/dbus/v5"
 )
 
 // TestSubscribe exercises the basics of subscription
@@ -98,6 +102,129 @@ success:
 	return
 }
 
+func TestSubscribeUnitsByNames(t *testing.T) {
+	target := "subscribe-events.service"
+
+	conn, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	evChan, errChan := conn.SubscribeUnitsByNames(context.Background(), time.Second, 0,
+		func(u1, u2 *UnitStatus) bool { return *u1 != *u2 }, []string{target})
+
+	setupUnit(target, conn, t)
+	linkUnit(target, conn, t)
+
+	reschan := make(chan string)
+	_, err = conn.StartUnit(target, "replace", reschan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job := <-reschan
+	if job != "done" {
+		t.Fatal("Couldn't start", target)
+	}
+
+	for {
+		select {
+		case changes := <-evChan:
+			for name := range changes {
+				if name != target {
+					t.Fatal("Unexpected event:", changes)
+				}
+			}
+
+			if tCh := changes[target]; tCh != nil && tCh.ActiveState == "active" {
+				return
+			}
+		case err = <-errChan:
+			t.Fatal(err)
+		case <-time.After(10 * time.Second):
+			t.Fatal("Reached timeout")
+		}
+	}
+}
+
+func TestSubscribeUnitsCancel(t *testing.T) {
+	c := &Conn{closed: make(chan struct{})}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	units := []UnitStatus{{Name: "foo.service", ActiveState: "active"}}
+	evChan, errChan := c.subscribeUnits(ctx, time.Millisecond, 0,
+		func(u1, u2 *UnitStatus) bool { return *u1 != *u2 }, nil,
+		func() ([]UnitStatus, error) { return units, nil })
+
+	select {
+	case changes := <-evChan:
+		if changes["foo.service"] == nil {
+			t.Fatal("Unexpected event:", changes)
+		}
+	case err := <-errChan:
+		t.Fatal(err)
+	case <-time.After(time.Second):
+		t.Fatal("Reached timeout")
+	}
+
+	cancel()
+
+	for evChan != nil || errChan != nil {
+		select {
+		case _, ok := <-evChan:
+			if !ok {
+				evChan = nil
+			}
+		case _, ok := <-errChan:
+			if !ok {
+				errChan = nil
+			}
+		case <-time.After(time.Second):
+			t.Fatal("channels were not closed after cancellation")
+		}
+	}
+}
+
+// recordingObject is a dbus.BusObject that records the methods called on it.
+type recordingObject struct {
+	dbus.BusObject
+	calls []string
+}
+
+func (o *recordingObject) CallWithContext(ctx context.Context, method string, flags dbus.Flags, args ...interface{}) *dbus.Call {
+	o.calls = append(o.calls, method)
+	return &dbus.Call{Method: method}
+}
+
+func TestSubscriptionRefCount(t *testing.T) {
+	obj := &recordingObject{}
+	c := &Conn{sigobj: mappedObject{BusObject: obj}}
+	ctx := context.Background()
+
+	c.subscriptionLock.Lock()
+	defer c.subscriptionLock.Unlock()
+
+	for i := 0; i < 2; i++ {
+		if err := c.acquireSubscription(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if err := c.releaseSubscription(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := []string{
+		"org.freedesktop.systemd1.Manager.Subscribe",
+		"org.freedesktop.systemd1.Manager.Unsubscribe",
+	}
+	if !reflect.DeepEqual(obj.calls, expected) {
+		t.Errorf("bad calls: got %v, want %v", obj.calls, expected)
+	}
+}
+
 // TestSubStateSubscription exercises the basics of sub-state event subscriptions
 func TestSubStateSubscription(t *testing.T) {
 	target := "subscribe-events.service"
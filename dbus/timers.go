@@ -0,0 +1,110 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+)
+
+// TimerStatus describes a timer unit, as listed by systemctl list-timers.
+type TimerStatus struct {
+	Name        string // The timer unit name, e.g. logrotate.timer
+	Unit        string // The unit the timer activates, e.g. logrotate.service
+	ActiveState string // The active state of the timer
+
+	// NextElapse is when the timer elapses next, the earlier of its
+	// calendar time and its monotonic time converted to wallclock time,
+	// zero if it has neither.
+	NextElapse time.Time
+	// NextElapseMonotonic is the CLOCK_MONOTONIC time the timer elapses
+	// next based on its monotonic settings, such as OnBootSec=, 0 if it has
+	// none.
+	NextElapseMonotonic time.Duration
+	// LastTrigger is when the timer last elapsed, zero if it never has.
+	LastTrigger time.Time
+}
+
+// ListTimers returns the timer units loaded in systemd, like systemctl
+// list-timers --all. Timers with a next elapse come first, those elapsing
+// soonest first; the others follow in name order.
+func (c *Conn) ListTimers(ctx context.Context) ([]TimerStatus, error) {
+	units, err := c.ListUnitsByPatternsContext(ctx, nil, []string{"*.timer"})
+	if err != nil {
+		return nil, err
+	}
+
+	boot := bootTime()
+	timers := make([]TimerStatus, 0, len(units))
+	for _, u := range units {
+		props, err := c.getProperties(ctx, u.Path, "org.freedesktop.systemd1.Timer")
+		if err != nil {
+			if ErrUnknownObject.Is(err) {
+				// the timer was unloaded in the meantime
+				continue
+			}
+			return nil, err
+		}
+		timers = append(timers, timerStatus(u, props, boot))
+	}
+
+	sortTimers(timers)
+	return timers, nil
+}
+
+// bootTime returns the wallclock time at which CLOCK_MONOTONIC was zero, or
+// the zero time if it is unknown.
+func bootTime() time.Time {
+	mono, ok := monotonicNow()
+	if !ok {
+		return time.Time{}
+	}
+	return time.Now().Round(0).Add(-mono)
+}
+
+// timerStatus builds the status of timer unit u from its properties. The
+// monotonic next elapse is converted to wallclock time with boot, as
+// returned by bootTime, like systemctl list-timers does.
+func timerStatus(u UnitStatus, props map[string]interface{}, boot time.Time) TimerStatus {
+	t := TimerStatus{Name: u.Name, ActiveState: u.ActiveState}
+	t.Unit, _ = props["Unit"].(string)
+	t.NextElapse = usecTime(props["NextElapseUSecRealtime"])
+	if usec, ok := props["NextElapseUSecMonotonic"].(uint64); ok && usec != 0 && usec != math.MaxUint64 {
+		t.NextElapseMonotonic = time.Duration(usec) * time.Microsecond
+		if !boot.IsZero() {
+			next := boot.Add(t.NextElapseMonotonic)
+			if t.NextElapse.IsZero() || next.Before(t.NextElapse) {
+				t.NextElapse = next
+			}
+		}
+	}
+	t.LastTrigger = usecTime(props["LastTriggerUSec"])
+	return t
+}
+
+func sortTimers(timers []TimerStatus) {
+	sort.Slice(timers, func(i, j int) bool {
+		a, b := timers[i].NextElapse, timers[j].NextElapse
+		if a.IsZero() != b.IsZero() {
+			return !a.IsZero()
+		}
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+		return timers[i].Name < timers[j].Name
+	})
+}
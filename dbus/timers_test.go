@@ -0,0 +1,112 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTimerStatus(t *testing.T) {
+	u := UnitStatus{Name: "logrotate.timer", ActiveState: "active"}
+	props := map[string]interface{}{
+		"Unit":                    "logrotate.service",
+		"NextElapseUSecRealtime":  uint64(1600000000000000),
+		"NextElapseUSecMonotonic": uint64(0),
+		"LastTriggerUSec":         uint64(1500000000000000),
+	}
+
+	expected := TimerStatus{
+		Name:        "logrotate.timer",
+		Unit:        "logrotate.service",
+		ActiveState: "active",
+		NextElapse:  time.Unix(1600000000, 0),
+		LastTrigger: time.Unix(1500000000, 0),
+	}
+	if status := timerStatus(u, props, time.Unix(1000, 0)); !reflect.DeepEqual(status, expected) {
+		t.Errorf("got %+v, want %+v", status, expected)
+	}
+
+	// timers without a next elapse report USEC_INFINITY
+	props["NextElapseUSecRealtime"] = uint64(math.MaxUint64)
+	props["NextElapseUSecMonotonic"] = uint64(math.MaxUint64)
+	expected.NextElapse = time.Time{}
+	if status := timerStatus(u, props, time.Unix(1000, 0)); !reflect.DeepEqual(status, expected) {
+		t.Errorf("got %+v, want %+v", status, expected)
+	}
+}
+
+func TestTimerStatusMonotonic(t *testing.T) {
+	boot := time.Unix(1600000000, 0)
+	u := UnitStatus{Name: "boot.timer", ActiveState: "active"}
+	props := map[string]interface{}{
+		"Unit":                    "boot.service",
+		"NextElapseUSecRealtime":  uint64(0),
+		"NextElapseUSecMonotonic": uint64(15 * 60 * 1000000),
+	}
+
+	// a monotonic-only timer elapses its monotonic time after boot
+	status := timerStatus(u, props, boot)
+	if expected := boot.Add(15 * time.Minute); !status.NextElapse.Equal(expected) {
+		t.Errorf("got next elapse %v, want %v", status.NextElapse, expected)
+	}
+	if status.NextElapseMonotonic != 15*time.Minute {
+		t.Errorf("got monotonic next elapse %v, want %v", status.NextElapseMonotonic, 15*time.Minute)
+	}
+
+	// with both, the earlier one is next
+	props["NextElapseUSecRealtime"] = uint64(1600000000+10*60) * 1000000
+	if status := timerStatus(u, props, boot); !status.NextElapse.Equal(boot.Add(10 * time.Minute)) {
+		t.Errorf("got next elapse %v, want the calendar time", status.NextElapse)
+	}
+	props["NextElapseUSecRealtime"] = uint64(1600000000+20*60) * 1000000
+	if status := timerStatus(u, props, boot); !status.NextElapse.Equal(boot.Add(15 * time.Minute)) {
+		t.Errorf("got next elapse %v, want the monotonic time", status.NextElapse)
+	}
+
+	// without a boot time, only the calendar time is known
+	if status := timerStatus(u, props, time.Time{}); !status.NextElapse.Equal(boot.Add(20 * time.Minute)) {
+		t.Errorf("got next elapse %v, want the calendar time", status.NextElapse)
+	}
+}
+
+func TestBootTime(t *testing.T) {
+	if _, ok := monotonicNow(); !ok {
+		t.Skip("CLOCK_MONOTONIC is not available")
+	}
+	if boot := bootTime(); boot.IsZero() || boot.After(time.Now()) {
+		t.Errorf("got boot time %v", boot)
+	}
+}
+
+func TestSortTimers(t *testing.T) {
+	timers := []TimerStatus{
+		{Name: "c.timer"},
+		{Name: "b.timer", NextElapse: time.Unix(2000, 0)},
+		{Name: "a.timer"},
+		{Name: "d.timer", NextElapse: time.Unix(1000, 0)},
+	}
+	sortTimers(timers)
+
+	var names []string
+	for _, timer := range timers {
+		names = append(names, timer.Name)
+	}
+	if expected := []string{"d.timer", "b.timer", "a.timer", "c.timer"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("got order %v, want %v", names, expected)
+	}
+}
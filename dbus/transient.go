@@ -0,0 +1,237 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/unit"
+	"github.com/godbus/dbus/v5"
+)
+
+// Values of ExecMainCode, see waitid(2)
+const (
+	cldExited = 1
+	cldKilled = 2
+	cldDumped = 3
+)
+
+// startTransient starts the transient unit name, creating the auxiliary units
+// aux along with it, and waits for the job to complete.
+func (c *Conn) startTransient(ctx context.Context, name string, properties []Property, aux []PropertyCollection) error {
+	if aux == nil {
+		aux = make([]PropertyCollection, 0)
+	}
+	result, err := c.RunJob(ctx, 0, func(ch chan<- string) (int, error) {
+		return c.startJob(ctx, ch, "org.freedesktop.systemd1.Manager.StartTransientUnit", name, "fail", properties, aux)
+	})
+	if err != nil {
+		return err
+	}
+	return c.jobError(ctx, name, result)
+}
+
+// NewScope creates and starts the transient scope unit name, which must end
+// in ".scope", and moves the already running processes pids into it. The
+// slice and delegation of the scope can be set with PropSlice and
+// PropDelegate, along with any other properties:
+//
+//	err := conn.NewScope(ctx, "container-foo.scope", []uint32{pid},
+//		dbus.PropSlice("machine.slice"), dbus.PropDelegate(true))
+//
+// NewScope waits for the scope to be started. If a unit with this name
+// already exists, an error matching ErrUnitExists is returned; if the job
+// fails, the error is a *JobError.
+func (c *Conn) NewScope(ctx context.Context, name string, pids []uint32, properties ...Property) error {
+	if !strings.HasSuffix(name, ".scope") {
+		return errors.New("invalid scope name: " + name)
+	}
+	if len(pids) == 0 {
+		return errors.New("no processes given for scope " + name)
+	}
+
+	props := append([]Property{PropPids(pids...)}, properties...)
+	return c.startTransient(ctx, name, props, nil)
+}
+
+// NewTimer creates and starts the transient timer unit name, which must end
+// in ".timer", together with the transient service it activates, like
+// `systemd-run --on-calendar`. The service is named after the timer, e.g.
+// "backup.service" for "backup.timer", and is only started when the timer
+// elapses:
+//
+//	err := conn.NewTimer(ctx, "backup.timer",
+//		[]dbus.Property{dbus.PropOnCalendar("daily")},
+//		[]dbus.Property{dbus.PropExecStart([]string{"/usr/bin/backup"}, false)})
+//
+// timerProperties must include at least one trigger, such as PropOnCalendar
+// or PropOnActiveSec. NewTimer waits for the timer to be started. If a unit
+// with either name already exists, an error matching ErrUnitExists is
+// returned.
+func (c *Conn) NewTimer(ctx context.Context, name string, timerProperties []Property, serviceProperties []Property) error {
+	if !strings.HasSuffix(name, ".timer") {
+		return errors.New("invalid timer name: " + name)
+	}
+	service := strings.TrimSuffix(name, ".timer") + ".service"
+
+	aux := []PropertyCollection{{Name: service, Properties: serviceProperties}}
+	return c.startTransient(ctx, name, timerProperties, aux)
+}
+
+// CommandResult describes how a command run with RunCommand finished.
+type CommandResult struct {
+	ExitCode  int       // The exit status of the command, or -1 if it was killed by a signal
+	Signal    int       // The signal that killed the command, or 0
+	StartTime time.Time // When the command was started (ExecMainStartTimestamp)
+	ExitTime  time.Time // When the command exited (ExecMainExitTimestamp)
+	Result    string    // The result of the service, e.g. "success", "exit-code" or "signal"
+}
+
+// RunCommand runs argv in the transient service unit name, which must end in
+// ".service", and waits for it to finish, like `systemd-run --wait`. Further
+// properties, for example sandboxing settings or resource limits, may be
+// given; the service type is "oneshot".
+//
+// A command that fails is not an error: its exit status is reported in the
+// CommandResult. If ctx is done before the command finished, the service is
+// stopped and ctx's error is returned.
+func (c *Conn) RunCommand(ctx context.Context, name string, argv []string, properties ...Property) (*CommandResult, error) {
+	if !strings.HasSuffix(name, ".service") {
+		return nil, errors.New("invalid service name: " + name)
+	}
+	if len(argv) == 0 {
+		return nil, errors.New("no command given for " + name)
+	}
+
+	props := []Property{
+		PropExecStart(argv, true),
+		PropType("oneshot"),
+		// keep the unit loaded after it finished, until UnrefUnit
+		{Name: "AddRef", Value: dbus.MakeVariant(true)},
+	}
+	props = append(props, properties...)
+
+	if err := c.startTransient(ctx, name, props, nil); err != nil {
+		if _, failed := err.(*JobError); !failed {
+			if ctx.Err() != nil {
+				// waiting for the command was cut short
+				c.cleanupCommand(name, true)
+			}
+			return nil, err
+		}
+		// the command failed, which is reported in the result
+	}
+
+	service, err := c.getProperties(ctx, unitPath(name), "org.freedesktop.systemd1.Service")
+	c.cleanupCommand(name, false)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CommandResult{ExitCode: -1}
+	code, _ := service["ExecMainCode"].(int32)
+	status, _ := service["ExecMainStatus"].(int32)
+	switch code {
+	case cldExited:
+		result.ExitCode = int(status)
+	case cldKilled, cldDumped:
+		result.Signal = int(status)
+	}
+	result.StartTime = usecTime(service["ExecMainStartTimestamp"])
+	result.ExitTime = usecTime(service["ExecMainExitTimestamp"])
+	result.Result, _ = service["Result"].(string)
+
+	return result, nil
+}
+
+// cleanupCommand releases the unit of RunCommand, stopping it first if it is
+// still running.
+func (c *Conn) cleanupCommand(name string, stop bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+
+	if stop {
+		c.StopUnitContext(ctx, name, "replace", nil)
+	}
+	c.UnrefUnit(ctx, name)
+	c.ResetFailedUnitContext(ctx, name)
+}
+
+// usecTime converts a timestamp property in microseconds since the epoch.
+// Unset timestamps, reported by systemd as 0 or USEC_INFINITY, are converted
+// to the zero time.
+func usecTime(v interface{}) time.Time {
+	usec, ok := v.(uint64)
+	if !ok || usec == 0 || usec == math.MaxUint64 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(usec)*int64(time.Microsecond))
+}
+
+// MountUnitName returns the name of the mount unit for the mount point where,
+// e.g. "mnt-data.mount" for "/mnt/data".
+func MountUnitName(where string) string {
+	return unit.UnitNamePathEscape(where) + ".mount"
+}
+
+func mountProperties(what, where, fsType, options string) []Property {
+	props := []Property{
+		{Name: "What", Value: dbus.MakeVariant(what)},
+		{Name: "Where", Value: dbus.MakeVariant(where)},
+	}
+	if fsType != "" {
+		props = append(props, Property{Name: "Type", Value: dbus.MakeVariant(fsType)})
+	}
+	if options != "" {
+		props = append(props, Property{Name: "Options", Value: dbus.MakeVariant(options)})
+	}
+	return props
+}
+
+// NewMount mounts what on the absolute path where through a transient mount
+// unit, like `systemd-mount`. fsType and options are the file
+// system type and the comma-separated mount options, and may be empty.
+// NewMount waits for the mount to complete and returns the name of the unit,
+// which can be stopped to unmount it again.
+func (c *Conn) NewMount(ctx context.Context, what, where, fsType, options string, properties ...Property) (string, error) {
+	if !strings.HasPrefix(where, "/") {
+		return "", errors.New("mount point must be an absolute path: " + where)
+	}
+
+	name := MountUnitName(where)
+	props := append(mountProperties(what, where, fsType, options), properties...)
+	return name, c.startTransient(ctx, name, props, nil)
+}
+
+// NewAutomount is like NewMount, but sets up a transient automount unit for
+// where instead, so that what is only mounted once where is accessed.
+// properties are applied to the automount unit, for example TimeoutIdleUSec
+// to unmount what again when it is idle. It returns the name of the automount
+// unit.
+func (c *Conn) NewAutomount(ctx context.Context, what, where, fsType, options string, properties ...Property) (string, error) {
+	if !strings.HasPrefix(where, "/") {
+		return "", errors.New("mount point must be an absolute path: " + where)
+	}
+
+	mount := MountUnitName(where)
+	name := strings.TrimSuffix(mount, ".mount") + ".automount"
+	props := append([]Property{{Name: "Where", Value: dbus.MakeVariant(where)}}, properties...)
+	aux := []PropertyCollection{{Name: mount, Properties: mountProperties(what, where, fsType, options)}}
+	return name, c.startTransient(ctx, name, props, aux)
+}
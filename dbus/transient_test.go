@@ -0,0 +1,138 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestNewScope(t *testing.T) {
+	conn := setupConn(t)
+	defer conn.Close()
+
+	cmd := exec.Command("/bin/sleep", "400")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Process.Kill()
+
+	pid := uint32(cmd.Process.Pid)
+	target := fmt.Sprintf("testing-new-scope-%d.scope", pid)
+
+	err := conn.NewScope(context.Background(), target, []uint32{pid}, PropDelegate(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name, err := conn.GetUnitNameByPID(context.Background(), pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != target {
+		t.Fatalf("process was not moved into %s, but %s", target, name)
+	}
+
+	err = conn.NewScope(context.Background(), target, []uint32{pid})
+	if !errors.Is(err, ErrUnitExists) {
+		t.Fatalf("expected ErrUnitExists, got %v", err)
+	}
+}
+
+func TestNewScopeInvalid(t *testing.T) {
+	conn := &Conn{}
+	if err := conn.NewScope(context.Background(), "foo.service", []uint32{1}); err == nil {
+		t.Error("expected an error for a non-scope unit name")
+	}
+	if err := conn.NewScope(context.Background(), "foo.scope", nil); err == nil {
+		t.Error("expected an error for a scope without processes")
+	}
+}
+
+func TestNewTimer(t *testing.T) {
+	conn := setupConn(t)
+	defer conn.Close()
+
+	target := fmt.Sprintf("testing-new-timer-%d.timer", time.Now().UnixNano())
+	err := conn.NewTimer(context.Background(), target,
+		[]Property{PropOnActiveSec(time.Hour)},
+		[]Property{PropExecStart([]string{"/bin/true"}, false)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.StopUnit(target, "replace", nil)
+
+	units, err := conn.ListUnitsByNamesContext(context.Background(), []string{target})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(units) != 1 || units[0].ActiveState != "active" {
+		t.Fatalf("timer %s is not active: %v", target, units)
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	conn := setupConn(t)
+	defer conn.Close()
+
+	target := fmt.Sprintf("testing-run-command-%d.service", time.Now().UnixNano())
+	result, err := conn.RunCommand(context.Background(), target, []string{"/bin/sh", "-c", "exit 3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.ExitCode != 3 || result.Signal != 0 {
+		t.Errorf("bad exit status: %+v", result)
+	}
+	if result.Result != "exit-code" {
+		t.Errorf("bad result: got %q, want %q", result.Result, "exit-code")
+	}
+	if result.StartTime.IsZero() || result.ExitTime.Before(result.StartTime) {
+		t.Errorf("bad timestamps: %+v", result)
+	}
+}
+
+func TestUsecTime(t *testing.T) {
+	if ts := usecTime(uint64(1500000)); !ts.Equal(time.Unix(1, 500000000)) {
+		t.Errorf("bad time: %v", ts)
+	}
+	if ts := usecTime(uint64(0)); !ts.IsZero() {
+		t.Errorf("expected the zero time, got %v", ts)
+	}
+	if ts := usecTime(uint64(math.MaxUint64)); !ts.IsZero() {
+		t.Errorf("expected the zero time, got %v", ts)
+	}
+	if ts := usecTime(nil); !ts.IsZero() {
+		t.Errorf("expected the zero time, got %v", ts)
+	}
+}
+
+func TestMountUnitName(t *testing.T) {
+	cases := map[string]string{
+		"/":              "-.mount",
+		"/mnt/data":      "mnt-data.mount",
+		"/var/lib/my-db": `var-lib-my\x2ddb.mount`,
+	}
+	for where, want := range cases {
+		if got := MountUnitName(where); got != want {
+			t.Errorf("%q: got %q, want %q", where, got, want)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+)
+
+// UnitProperties holds common properties of the org.freedesktop.systemd1.Unit
+// interface, see DecodeProperties.
+type UnitProperties struct {
+	Id           string
+	Description  string
+	LoadState    string
+	ActiveState  string
+	SubState     string
+	FragmentPath string
+	Names        []string
+	Requires     []string
+	Wants        []string
+	Before       []string
+	After        []string
+
+	ActiveEnterTimestamp   time.Time
+	ActiveExitTimestamp    time.Time
+	InactiveEnterTimestamp time.Time
+	InactiveExitTimestamp  time.Time
+	StateChangeTimestamp   time.Time
+}
+
+// ServiceProperties holds common properties of the
+// org.freedesktop.systemd1.Service interface. Resource counters, such as
+// MemoryCurrent, are math.MaxUint64 when accounting is disabled.
+type ServiceProperties struct {
+	Type         string
+	Restart      string
+	Result       string
+	StatusText   string
+	ControlGroup string
+
+	MainPID        uint32
+	ControlPID     uint32
+	NRestarts      uint32
+	ExecMainStatus int32
+
+	ExecMainStartTimestamp time.Time
+	ExecMainExitTimestamp  time.Time
+
+	MemoryCurrent uint64
+	CPUUsageNSec  uint64
+	TasksCurrent  uint64
+
+	TimeoutStart time.Duration `systemd:"TimeoutStartUSec"`
+	TimeoutStop  time.Duration `systemd:"TimeoutStopUSec"`
+	RestartDelay time.Duration `systemd:"RestartUSec"`
+}
+
+// SocketProperties holds common properties of the
+// org.freedesktop.systemd1.Socket interface.
+type SocketProperties struct {
+	Result       string
+	ControlGroup string
+	Accept       bool
+	NConnections uint32
+	NAccepted    uint32
+	NRefused     uint32
+}
+
+// TimerProperties holds common properties of the
+// org.freedesktop.systemd1.Timer interface.
+type TimerProperties struct {
+	Unit       string
+	Result     string
+	Persistent bool
+
+	NextElapse          time.Time     `systemd:"NextElapseUSecRealtime"`
+	NextElapseMonotonic time.Duration `systemd:"NextElapseUSecMonotonic"`
+	LastTrigger         time.Time     `systemd:"LastTriggerUSec"`
+	Accuracy            time.Duration `systemd:"AccuracyUSec"`
+	RandomizedDelay     time.Duration `systemd:"RandomizedDelayUSec"`
+}
+
+// MountProperties holds common properties of the
+// org.freedesktop.systemd1.Mount interface.
+type MountProperties struct {
+	What       string
+	Where      string
+	Type       string
+	Options    string
+	Result     string
+	ControlPID uint32
+
+	Timeout time.Duration `systemd:"TimeoutUSec"`
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// DecodeProperties stores the properties returned by GetUnitPropertiesContext
+// or GetUnitTypePropertiesContext into the struct v points to, such as a
+// ServiceProperties. Each field is set from the property with the name given
+// by its systemd tag, or its own name; properties without a field and fields
+// without a property are ignored. Properties counting microseconds, that is
+// timestamps and durations, may be decoded into time.Time and time.Duration
+// fields; an infinite duration becomes math.MaxInt64. Other fields must have
+// the type of their property.
+func DecodeProperties(props map[string]interface{}, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("DecodeProperties: v must be a pointer to a struct")
+	}
+	rv = rv.Elem()
+
+	for i := 0; i < rv.NumField(); i++ {
+		field := rv.Type().Field(i)
+		if field.PkgPath != "" {
+			// unexported
+			continue
+		}
+		name := field.Tag.Get("systemd")
+		if name == "" {
+			name = field.Name
+		}
+		value, ok := props[name]
+		if !ok {
+			continue
+		}
+
+		if err := decodeProperty(rv.Field(i), value); err != nil {
+			return fmt.Errorf("property %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+func decodeProperty(field reflect.Value, value interface{}) error {
+	switch field.Type() {
+	case timeType:
+		if _, ok := value.(uint64); !ok {
+			return fmt.Errorf("cannot decode %T into time.Time", value)
+		}
+		field.Set(reflect.ValueOf(usecTime(value)))
+		return nil
+	case durationType:
+		usec, ok := value.(uint64)
+		if !ok {
+			return fmt.Errorf("cannot decode %T into time.Duration", value)
+		}
+		d := time.Duration(math.MaxInt64)
+		if usec < uint64(math.MaxInt64/int64(time.Microsecond)) {
+			d = time.Duration(usec) * time.Microsecond
+		}
+		field.Set(reflect.ValueOf(d))
+		return nil
+	}
+
+	rv := reflect.ValueOf(value)
+	if !rv.IsValid() || !rv.Type().AssignableTo(field.Type()) {
+		return fmt.Errorf("cannot decode %T into %s", value, field.Type())
+	}
+	field.Set(rv)
+	return nil
+}
+
+// DecodeUnitProperties retrieves the properties of the unit name for unitType,
+// like GetUnitTypePropertiesContext, and decodes them into v with
+// DecodeProperties. unitType "Unit" selects the properties common to all unit
+// types.
+func (c *Conn) DecodeUnitProperties(ctx context.Context, name string, unitType string, v interface{}) error {
+	props, err := c.GetUnitTypePropertiesContext(ctx, name, unitType)
+	if err != nil {
+		return err
+	}
+	return DecodeProperties(props, v)
+}
+
+// GetServiceProperties returns the common properties of the service name.
+func (c *Conn) GetServiceProperties(ctx context.Context, name string) (*ServiceProperties, error) {
+	p := &ServiceProperties{}
+	if err := c.DecodeUnitProperties(ctx, name, "Service", p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// GetSocketProperties returns the common properties of the socket name.
+func (c *Conn) GetSocketProperties(ctx context.Context, name string) (*SocketProperties, error) {
+	p := &SocketProperties{}
+	if err := c.DecodeUnitProperties(ctx, name, "Socket", p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// GetTimerProperties returns the common properties of the timer name.
+func (c *Conn) GetTimerProperties(ctx context.Context, name string) (*TimerProperties, error) {
+	p := &TimerProperties{}
+	if err := c.DecodeUnitProperties(ctx, name, "Timer", p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// GetMountProperties returns the common properties of the mount name.
+func (c *Conn) GetMountProperties(ctx context.Context, name string) (*MountProperties, error) {
+	p := &MountProperties{}
+	if err := c.DecodeUnitProperties(ctx, name, "Mount", p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
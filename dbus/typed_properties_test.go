@@ -0,0 +1,66 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDecodeProperties(t *testing.T) {
+	props := map[string]interface{}{
+		"Type":                   "notify",
+		"MainPID":                uint32(42),
+		"ExecMainStatus":         int32(1),
+		"ExecMainStartTimestamp": uint64(1600000000000000),
+		"ExecMainExitTimestamp":  uint64(math.MaxUint64),
+		"MemoryCurrent":          uint64(1 << 20),
+		"TimeoutStartUSec":       uint64(90000000),
+		"TimeoutStopUSec":        uint64(math.MaxUint64),
+		"Unknown":                "ignored",
+	}
+
+	var p ServiceProperties
+	if err := DecodeProperties(props, &p); err != nil {
+		t.Fatal(err)
+	}
+	expected := ServiceProperties{
+		Type:                   "notify",
+		MainPID:                42,
+		ExecMainStatus:         1,
+		ExecMainStartTimestamp: time.Unix(1600000000, 0),
+		MemoryCurrent:          1 << 20,
+		TimeoutStart:           90 * time.Second,
+		TimeoutStop:            time.Duration(math.MaxInt64),
+	}
+	if !reflect.DeepEqual(p, expected) {
+		t.Errorf("got %+v, want %+v", p, expected)
+	}
+}
+
+func TestDecodePropertiesErrors(t *testing.T) {
+	var p ServiceProperties
+	if err := DecodeProperties(map[string]interface{}{"MainPID": "42"}, &p); err == nil {
+		t.Error("expected an error for a mistyped property")
+	}
+	if err := DecodeProperties(map[string]interface{}{"TimeoutStartUSec": "90s"}, &p); err == nil {
+		t.Error("expected an error for a mistyped duration")
+	}
+	if err := DecodeProperties(nil, p); err == nil {
+		t.Error("expected an error for a non-pointer")
+	}
+}
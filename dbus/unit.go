@@ -0,0 +1,101 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"context"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Unit is a handle to a unit loaded in systemd, bound to the unit's object
+// path. Its methods call the org.freedesktop.systemd1.Unit interface of
+// that object directly.
+type Unit struct {
+	Name string          // The primary unit name
+	Path dbus.ObjectPath // The unit object path
+
+	conn *Conn
+}
+
+// GetUnit returns a handle to the (unescaped) unit name. If the unit is not
+// loaded, systemd loads it; units without a unit file are reported with
+// LoadState "not-found" by Properties.
+func (c *Conn) GetUnit(ctx context.Context, name string) (*Unit, error) {
+	var path dbus.ObjectPath
+	err := c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.LoadUnit", 0, name).Store(&path)
+	if err != nil {
+		return nil, err
+	}
+	return &Unit{Name: name, Path: path, conn: c}, nil
+}
+
+func (u *Unit) object() dbus.BusObject {
+	return u.conn.object(u.Path)
+}
+
+// Start enqueues a start job for the unit. mode is as for StartUnitContext.
+func (u *Unit) Start(ctx context.Context, mode string) (*Job, error) {
+	return u.conn.startObjectJobAsync(ctx, u.object(), "org.freedesktop.systemd1.Unit.Start", mode)
+}
+
+// Stop enqueues a stop job for the unit.
+func (u *Unit) Stop(ctx context.Context, mode string) (*Job, error) {
+	return u.conn.startObjectJobAsync(ctx, u.object(), "org.freedesktop.systemd1.Unit.Stop", mode)
+}
+
+// Restart enqueues a restart job for the unit.
+func (u *Unit) Restart(ctx context.Context, mode string) (*Job, error) {
+	return u.conn.startObjectJobAsync(ctx, u.object(), "org.freedesktop.systemd1.Unit.Restart", mode)
+}
+
+// Reload enqueues a reload job for the unit.
+func (u *Unit) Reload(ctx context.Context, mode string) (*Job, error) {
+	return u.conn.startObjectJobAsync(ctx, u.object(), "org.freedesktop.systemd1.Unit.Reload", mode)
+}
+
+// Kill sends signal to the processes of the unit selected by target.
+func (u *Unit) Kill(ctx context.Context, target Who, signal int32) error {
+	return u.object().CallWithContext(ctx, "org.freedesktop.systemd1.Unit.Kill", 0, string(target), signal).Store()
+}
+
+// ResetFailed resets the "failed" state of the unit.
+func (u *Unit) ResetFailed(ctx context.Context) error {
+	return u.object().CallWithContext(ctx, "org.freedesktop.systemd1.Unit.ResetFailed", 0).Store()
+}
+
+// Properties returns the properties of the org.freedesktop.systemd1.Unit
+// interface of the unit.
+func (u *Unit) Properties(ctx context.Context) (map[string]interface{}, error) {
+	return u.conn.getProperties(ctx, u.Path, "org.freedesktop.systemd1.Unit")
+}
+
+// TypeProperties returns the properties specific to the unit type, see
+// GetUnitTypePropertiesContext for the valid values of unitType.
+func (u *Unit) TypeProperties(ctx context.Context, unitType string) (map[string]interface{}, error) {
+	return u.conn.getProperties(ctx, u.Path, "org.freedesktop.systemd1."+unitType)
+}
+
+// SetProperties modifies unit properties at runtime, see
+// SetUnitPropertiesContext.
+func (u *Unit) SetProperties(ctx context.Context, runtime bool, properties ...Property) error {
+	return u.object().CallWithContext(ctx, "org.freedesktop.systemd1.Unit.SetProperties", 0, runtime, properties).Store()
+}
+
+// Watch streams the changes to the properties of the unit on the D-Bus
+// interface iface, see WatchUnitProperties.
+func (u *Unit) Watch(ctx context.Context, iface string) (<-chan map[string]dbus.Variant, error) {
+	return u.conn.watchProperties(ctx, u.Path, iface)
+}
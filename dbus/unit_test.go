@@ -0,0 +1,62 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"context"
+	"testing"
+)
+
+func TestUnitHandle(t *testing.T) {
+	target := "start-stop.service"
+	conn := setupConn(t)
+	defer conn.Close()
+
+	setupUnit(target, conn, t)
+	linkUnit(target, conn, t)
+
+	ctx := context.Background()
+	u, err := conn.GetUnit(ctx, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Path != unitPath(target) {
+		t.Fatalf("bad unit path: %s", u.Path)
+	}
+
+	job, err := u.Start(ctx, "replace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result, err := job.Wait(ctx); err != nil || result != "done" {
+		t.Fatalf("start job: %q, %v", result, err)
+	}
+
+	props, err := u.Properties(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if props["ActiveState"] != "active" {
+		t.Fatalf("unit is not active: %v", props["ActiveState"])
+	}
+
+	job, err = u.Stop(ctx, "replace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result, err := job.Wait(ctx); err != nil || result != "done" {
+		t.Fatalf("stop job: %q, %v", result, err)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"context"
+	"errors"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// WalkUnits calls fn for each unit currently loaded, as returned by
+// ListUnitsContext, until fn returns false. Unlike ListUnitsContext, it
+// decodes the units one at a time into a single UnitStatus instead of
+// building intermediate copies of the whole list, which reduces allocations
+// on hosts with thousands of units. The reply is still received from the bus
+// as a whole. fn must not keep a pointer to its argument.
+func (c *Conn) WalkUnits(ctx context.Context, fn func(*UnitStatus) bool) error {
+	return walkUnits(c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.ListUnits", 0), fn)
+}
+
+// WalkUnitsByPatterns is like WalkUnits, but only walks the units matching
+// states and patterns, as returned by ListUnitsByPatternsContext.
+func (c *Conn) WalkUnitsByPatterns(ctx context.Context, states []string, patterns []string, fn func(*UnitStatus) bool) error {
+	return walkUnits(c.manager().CallWithContext(ctx, "org.freedesktop.systemd1.Manager.ListUnitsByPatterns", 0, states, patterns), fn)
+}
+
+func walkUnits(call *dbus.Call, fn func(*UnitStatus) bool) error {
+	if call.Err != nil {
+		return call.Err
+	}
+	if len(call.Body) != 1 {
+		return errors.New("unexpected reply to ListUnits")
+	}
+	entries, ok := call.Body[0].([][]interface{})
+	if !ok {
+		return errors.New("unexpected reply to ListUnits")
+	}
+
+	var status UnitStatus
+	src := make([]interface{}, 1)
+	for _, entry := range entries {
+		src[0] = entry
+		if err := dbus.Store(src, &status); err != nil {
+			return err
+		}
+		if !fn(&status) {
+			return nil
+		}
+	}
+	return nil
+}
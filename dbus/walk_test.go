@@ -0,0 +1,62 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestWalkUnits(t *testing.T) {
+	conn, _ := newFakeConn(t)
+	defer conn.Close()
+	ctx := context.Background()
+
+	var names []string
+	err := conn.WalkUnits(ctx, func(u *UnitStatus) bool {
+		if u.ActiveState != "active" || u.Path != unitPath(u.Name) {
+			t.Errorf("unexpected unit %+v", u)
+		}
+		names = append(names, u.Name)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"foo.service", "bar.service", "baz.service"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("got units %v, want %v", names, expected)
+	}
+
+	names = nil
+	err = conn.WalkUnits(ctx, func(u *UnitStatus) bool {
+		names = append(names, u.Name)
+		return len(names) < 2
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"foo.service", "bar.service"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("got units %v after stopping, want %v", names, expected)
+	}
+
+	units, err := conn.ListUnitsContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(units) != 3 {
+		t.Errorf("got %d units from ListUnitsContext, want 3", len(units))
+	}
+}
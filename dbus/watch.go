@@ -0,0 +1,270 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// propertiesWatch is a WatchUnitProperties call in progress.
+type propertiesWatch struct {
+	path  dbus.ObjectPath
+	iface string
+	match string
+	queue *signalQueue
+}
+
+func (w *propertiesWatch) matches(signal *BackendSignal) bool {
+	if signal.Name != "org.freedesktop.DBus.Properties.PropertiesChanged" || signal.Path != w.path {
+		return false
+	}
+	iface, _ := signal.Body[0].(string)
+	return w.iface == "" || iface == w.iface
+}
+
+// WatchUnitProperties streams the properties of the (unescaped) unit name
+// that change on the D-Bus interface iface, for example
+// "org.freedesktop.systemd1.Unit" for ActiveState or
+// "org.freedesktop.systemd1.Service" for MainPID. If iface is empty, changes
+// on all interfaces of the unit are streamed.
+//
+// Unlike Subscribe, only the signals for this unit are requested from the
+// bus. Each value received holds the changed properties along with their new
+// values; properties systemd merely invalidates are not included. At most 100
+// changes are buffered, further changes are dropped until the channel is
+// read. The channel is closed once ctx is done or the connection is closed.
+func (c *Conn) WatchUnitProperties(ctx context.Context, name string, iface string) (<-chan map[string]dbus.Variant, error) {
+	path := unitPath(name)
+	if !path.IsValid() {
+		return nil, errors.New("invalid unit name: " + name)
+	}
+	return c.watchProperties(ctx, path, iface)
+}
+
+// unitStatePollInterval is how often WaitForUnitActiveState checks the state
+// of the unit, in case a change was not signalled.
+const unitStatePollInterval = time.Second
+
+// WaitForUnitActiveState waits until the unit name reaches one of the given
+// active states, such as "active", "inactive" or "failed", and returns the
+// state reached. Unlike waiting for a job, this tells when a service is
+// actually up, e.g. a Type=notify service has reported readiness, or when it
+// failed after starting.
+//
+// The state is followed through the PropertiesChanged signals of the unit,
+// and polled periodically in case a change is missed, for example while the
+// connection is re-established. If ctx is done first, its error is returned.
+func (c *Conn) WaitForUnitActiveState(ctx context.Context, name string, states ...string) (string, error) {
+	if len(states) == 0 {
+		return "", errors.New("no states to wait for given")
+	}
+	path := unitPath(name)
+	if !path.IsValid() {
+		return "", errors.New("invalid unit name: " + name)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Subscribe before checking the state, so that no change is missed. If
+	// watching fails, polling alone will do.
+	changes, err := c.watchProperties(ctx, path, "org.freedesktop.systemd1.Unit")
+	if err != nil {
+		changes = nil
+	}
+
+	ticker := time.NewTicker(unitStatePollInterval)
+	defer ticker.Stop()
+
+	wanted := func(state string) bool {
+		for _, s := range states {
+			if s == state {
+				return true
+			}
+		}
+		return false
+	}
+
+	for {
+		prop, err := c.getProperty(ctx, name, "org.freedesktop.systemd1.Unit", "ActiveState")
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
+			return "", err
+		}
+		if state, _ := prop.Value.Value().(string); wanted(state) {
+			return state, nil
+		}
+
+	wait:
+		for {
+			select {
+			case changed, ok := <-changes:
+				if !ok {
+					changes = nil
+					break wait
+				}
+				if v, ok := changed["ActiveState"]; ok {
+					if state, _ := v.Value().(string); wanted(state) {
+						return state, nil
+					}
+				}
+			case <-ticker.C:
+				break wait
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+		}
+	}
+}
+
+func (c *Conn) watchProperties(ctx context.Context, path dbus.ObjectPath, iface string) (<-chan map[string]dbus.Variant, error) {
+	match := propertiesChangedMatch + ",path='" + string(path) + "'"
+	if iface != "" {
+		match += ",arg0='" + iface + "'"
+	}
+	w := &propertiesWatch{path: path, iface: iface, match: match, queue: newSignalQueue(signalBuffer)}
+
+	if err := c.addWatch(ctx, w); err != nil {
+		return nil, err
+	}
+
+	out := make(chan map[string]dbus.Variant)
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-c.closed:
+		case <-stop:
+		}
+		w.queue.close()
+	}()
+
+	go func() {
+		defer close(out)
+		defer close(stop)
+		defer c.removeWatch(w)
+
+		for {
+			signal, ok := w.queue.pop()
+			if !ok {
+				return
+			}
+			if len(signal.Body) < 2 {
+				continue
+			}
+			changed, ok := signal.Body[1].(map[string]dbus.Variant)
+			if !ok || len(changed) == 0 {
+				continue
+			}
+
+			select {
+			case out <- changed:
+			case <-ctx.Done():
+				return
+			case <-c.closed:
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+func (c *Conn) addWatchMatch(ctx context.Context, sigconn Backend, w *propertiesWatch) error {
+	return c.bus(sigconn).CallWithContext(ctx, "org.freedesktop.DBus.AddMatch", 0, w.match).Store()
+}
+
+// addWatch registers w and asks the bus for the signals it watches. systemd
+// only emits signals while subscribed, so w holds on to the subscription
+// until it is removed; unlike Subscribe, this does not add match rules for
+// all units.
+func (c *Conn) addWatch(ctx context.Context, w *propertiesWatch) error {
+	c.subscriptionLock.Lock()
+	defer c.subscriptionLock.Unlock()
+
+	if err := c.acquireSubscription(ctx); err != nil {
+		return err
+	}
+
+	c.propertiesWatches.Lock()
+	if c.propertiesWatches.watches == nil {
+		c.propertiesWatches.watches = make(map[*propertiesWatch]bool)
+	}
+	c.propertiesWatches.watches[w] = true
+	c.propertiesWatches.Unlock()
+
+	sigconn, _ := c.signalConn()
+	if err := c.addWatchMatch(ctx, sigconn, w); err != nil {
+		c.propertiesWatches.Lock()
+		delete(c.propertiesWatches.watches, w)
+		c.propertiesWatches.Unlock()
+		c.releaseSubscription(ctx)
+		return err
+	}
+	return nil
+}
+
+// removeWatch forgets about w, removes its match rule and releases its hold
+// on the subscription, unless the connection has been closed.
+func (c *Conn) removeWatch(w *propertiesWatch) {
+	c.subscriptionLock.Lock()
+	defer c.subscriptionLock.Unlock()
+
+	c.propertiesWatches.Lock()
+	delete(c.propertiesWatches.watches, w)
+	c.propertiesWatches.Unlock()
+
+	select {
+	case <-c.closed:
+		return
+	default:
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+
+	sigconn, _ := c.signalConn()
+	c.bus(sigconn).CallWithContext(ctx, "org.freedesktop.DBus.RemoveMatch", 0, w.match)
+	c.releaseSubscription(ctx)
+}
+
+// watches returns the WatchUnitProperties calls in progress.
+func (c *Conn) watches() []*propertiesWatch {
+	c.propertiesWatches.Lock()
+	defer c.propertiesWatches.Unlock()
+	watches := make([]*propertiesWatch, 0, len(c.propertiesWatches.watches))
+	for w := range c.propertiesWatches.watches {
+		watches = append(watches, w)
+	}
+	return watches
+}
+
+// routeWatchSignal queues signal for the watches it matches.
+func (c *Conn) routeWatchSignal(signal *BackendSignal) {
+	if signal.Name != "org.freedesktop.DBus.Properties.PropertiesChanged" || len(signal.Body) < 1 {
+		return
+	}
+	for _, w := range c.watches() {
+		if w.matches(signal) {
+			w.queue.push(signal)
+		}
+	}
+}
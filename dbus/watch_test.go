@@ -0,0 +1,102 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func TestRouteWatchSignal(t *testing.T) {
+	c := &Conn{closed: make(chan struct{})}
+
+	unitWatch := &propertiesWatch{path: unitPath("foo.service"), iface: "org.freedesktop.systemd1.Unit", queue: newSignalQueue(10)}
+	allWatch := &propertiesWatch{path: unitPath("foo.service"), queue: newSignalQueue(10)}
+	c.propertiesWatches.watches = map[*propertiesWatch]bool{unitWatch: true, allWatch: true}
+
+	changed := func(name, iface string) *BackendSignal {
+		return &BackendSignal{
+			Path: unitPath(name),
+			Name: "org.freedesktop.DBus.Properties.PropertiesChanged",
+			Body: []interface{}{iface, map[string]dbus.Variant{"ActiveState": dbus.MakeVariant("active")}, []string{}},
+		}
+	}
+
+	c.routeWatchSignal(changed("foo.service", "org.freedesktop.systemd1.Unit"))
+	c.routeWatchSignal(changed("foo.service", "org.freedesktop.systemd1.Service"))
+	c.routeWatchSignal(changed("bar.service", "org.freedesktop.systemd1.Unit"))
+
+	if n := len(unitWatch.queue.signals); n != 1 {
+		t.Errorf("interface watch got %d signals, want 1", n)
+	}
+	if n := len(allWatch.queue.signals); n != 2 {
+		t.Errorf("unit watch got %d signals, want 2", n)
+	}
+}
+
+func TestWaitForUnitActiveState(t *testing.T) {
+	conn, _ := newFakeConn(t)
+	defer conn.Close()
+
+	state, err := conn.WaitForUnitActiveState(context.Background(), "foo.service", "active", "failed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state != "active" {
+		t.Errorf("got state %q, want active", state)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := conn.WaitForUnitActiveState(ctx, "foo.service", "failed"); err != context.DeadlineExceeded {
+		t.Errorf("got error %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestWaitForUnitActiveStateSignal(t *testing.T) {
+	conn, backends := newFakeConn(t)
+	defer conn.Close()
+
+	done := make(chan string)
+	go func() {
+		state, _ := conn.WaitForUnitActiveState(context.Background(), "foo.service", "deactivating")
+		done <- state
+	}()
+
+	// keep signalling until the watch is set up
+	signal := &BackendSignal{
+		Path: unitPath("foo.service"),
+		Name: "org.freedesktop.DBus.Properties.PropertiesChanged",
+		Body: []interface{}{
+			"org.freedesktop.systemd1.Unit",
+			map[string]dbus.Variant{"ActiveState": dbus.MakeVariant("deactivating")},
+			[]string{},
+		},
+	}
+	for {
+		backends[1].emitSignal(signal)
+		select {
+		case state := <-done:
+			if state != "deactivating" {
+				t.Errorf("got state %q, want deactivating", state)
+			}
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,283 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lz4 implements a decoder for the LZ4 block and frame formats, which
+// journald and systemd-coredump may compress data with.
+//
+// The formats are described in
+// https://github.com/lz4/lz4/blob/dev/doc/lz4_Block_format.md and
+// https://github.com/lz4/lz4/blob/dev/doc/lz4_Frame_format.md
+package lz4
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	frameMagic     = 0x184d2204
+	skipMagic      = 0x184d2a50 // to 0x184d2a5f
+	windowSize     = 64 << 10
+	maxBlockSize   = 4 << 20
+	incompressible = 1 << 31 // set in the size of uncompressed blocks
+	flgVersionMask = 0xc0
+	flgVersion     = 0x40
+	flgBlockSum    = 1 << 4
+	flgContentSize = 1 << 3
+	flgContentSum  = 1 << 2
+	flgDictID      = 1 << 0
+)
+
+var errCorrupt = errors.New("corrupt LZ4 data")
+
+// Decompress decompresses src, an LZ4 block, which decompresses to size
+// bytes.
+func Decompress(src []byte, size int) ([]byte, error) {
+	// LZ4 cannot compress by more than a factor of 255
+	if size < 0 || size > 255*len(src)+16 {
+		return nil, errCorrupt
+	}
+	dst, err := decodeBlock(make([]byte, 0, size), src, size)
+	if err != nil {
+		return nil, err
+	}
+	if len(dst) != size {
+		return nil, errCorrupt
+	}
+	return dst, nil
+}
+
+// Reader decompresses a stream of LZ4 frames, as systemd-coredump writes.
+// Blocks may refer to the data of the previous ones, so the last 64 KiB of
+// output are kept.
+type Reader struct {
+	r       *bufio.Reader
+	flags   byte
+	inFrame bool
+
+	window []byte // history for back-references, then the current block
+	out    []byte // decompressed data not yet read
+	src    []byte
+}
+
+// NewReader returns a Reader decompressing the frames read from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+func (z *Reader) Read(p []byte) (int, error) {
+	for len(z.out) == 0 {
+		if err := z.nextBlock(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, z.out)
+	z.out = z.out[n:]
+	return n, nil
+}
+
+// nextBlock decompresses the next block, reading frame headers as needed.
+// It returns io.EOF at the end of the last frame.
+func (z *Reader) nextBlock() error {
+	if !z.inFrame {
+		if err := z.readFrameHeader(); err != nil {
+			return err
+		}
+	}
+
+	var b [4]byte
+	if _, err := io.ReadFull(z.r, b[:]); err != nil {
+		return unexpectedEOF(err)
+	}
+	size := binary.LittleEndian.Uint32(b[:])
+	if size == 0 {
+		// end of the frame
+		skip := 0
+		if z.flags&flgContentSum != 0 {
+			skip = 4
+		}
+		if _, err := z.r.Discard(skip); err != nil {
+			return unexpectedEOF(err)
+		}
+		z.inFrame = false
+		return nil
+	}
+
+	raw := size&incompressible != 0
+	size &^= incompressible
+	if size > maxBlockSize {
+		return errCorrupt
+	}
+	if cap(z.src) < int(size) {
+		z.src = make([]byte, size)
+	}
+	src := z.src[:size]
+	if _, err := io.ReadFull(z.r, src); err != nil {
+		return unexpectedEOF(err)
+	}
+	if z.flags&flgBlockSum != 0 {
+		if _, err := z.r.Discard(4); err != nil {
+			return unexpectedEOF(err)
+		}
+	}
+
+	// keep the history only, then append the block to it
+	if len(z.window) > windowSize {
+		z.window = append(z.window[:0], z.window[len(z.window)-windowSize:]...)
+	}
+	start := len(z.window)
+	if raw {
+		z.window = append(z.window, src...)
+	} else {
+		var err error
+		if z.window, err = decodeBlock(z.window, src, start+maxBlockSize); err != nil {
+			return err
+		}
+	}
+	z.out = z.window[start:]
+	return nil
+}
+
+// readFrameHeader reads the header of the next frame, skipping skippable
+// frames. It returns io.EOF if there are no more frames.
+func (z *Reader) readFrameHeader() error {
+	for {
+		var b [4]byte
+		if _, err := io.ReadFull(z.r, b[:]); err != nil {
+			// io.EOF if there is nothing after the last frame
+			if err == io.ErrUnexpectedEOF {
+				return errCorrupt
+			}
+			return err
+		}
+		magic := binary.LittleEndian.Uint32(b[:])
+		if magic&0xfffffff0 == skipMagic {
+			if _, err := io.ReadFull(z.r, b[:]); err != nil {
+				return unexpectedEOF(err)
+			}
+			if _, err := z.r.Discard(int(binary.LittleEndian.Uint32(b[:]))); err != nil {
+				return unexpectedEOF(err)
+			}
+			continue
+		}
+		if magic != frameMagic {
+			return fmt.Errorf("not LZ4 data: magic %#x", magic)
+		}
+		break
+	}
+
+	var d [2]byte // FLG, BD
+	if _, err := io.ReadFull(z.r, d[:]); err != nil {
+		return unexpectedEOF(err)
+	}
+	if d[0]&flgVersionMask != flgVersion {
+		return fmt.Errorf("unsupported LZ4 frame version")
+	}
+	if d[0]&flgDictID != 0 {
+		return fmt.Errorf("unsupported LZ4 frame with a dictionary")
+	}
+	skip := 1 // header checksum
+	if d[0]&flgContentSize != 0 {
+		skip += 8
+	}
+	if _, err := z.r.Discard(skip); err != nil {
+		return unexpectedEOF(err)
+	}
+
+	z.flags = d[0]
+	z.inFrame = true
+	z.window = z.window[:0]
+	return nil
+}
+
+// decodeBlock appends the decompressed LZ4 block src to dst, whose content
+// matches may refer to. It fails if dst would grow beyond max bytes.
+func decodeBlock(dst, src []byte, max int) ([]byte, error) {
+	for i := 0; i < len(src); {
+		token := src[i]
+		i++
+
+		// literals
+		n, j, err := readLength(src, i, int(token>>4), max-len(dst))
+		if err != nil {
+			return nil, err
+		}
+		i = j
+		if n > len(src)-i {
+			return nil, errCorrupt
+		}
+		dst = append(dst, src[i:i+n]...)
+		i += n
+		if i == len(src) {
+			// the last sequence has literals only
+			break
+		}
+
+		// match
+		if i+2 > len(src) {
+			return nil, errCorrupt
+		}
+		offset := int(src[i]) | int(src[i+1])<<8
+		i += 2
+		if offset == 0 || offset > len(dst) {
+			return nil, errCorrupt
+		}
+		n, i, err = readLength(src, i, int(token&0xf), max-len(dst)-4)
+		if err != nil {
+			return nil, err
+		}
+		n += 4
+		for k := 0; k < n; k++ {
+			// byte by byte, as the match may overlap its copy
+			dst = append(dst, dst[len(dst)-offset])
+		}
+	}
+	return dst, nil
+}
+
+// readLength returns the length n from a token, extended by the bytes of src
+// starting at i if it is 15, and the index following them. It fails if the
+// length exceeds max.
+func readLength(src []byte, i int, n int, max int) (int, int, error) {
+	if n == 15 {
+		for {
+			if i >= len(src) {
+				return 0, 0, errCorrupt
+			}
+			b := src[i]
+			i++
+			n += int(b)
+			if n > max {
+				return 0, 0, errCorrupt
+			}
+			if b != 255 {
+				break
+			}
+		}
+	}
+	if n > max {
+		return 0, 0, errCorrupt
+	}
+	return n, i, nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,116 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lz4
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"testing"
+)
+
+func TestDecompress(t *testing.T) {
+	// "abc" as literals, then a match of 9 bytes at offset 3, then "!"
+	src := []byte{0x35, 'a', 'b', 'c', 3, 0, 0x10, '!'}
+	out, err := Decompress(src, 13)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "abcabcabcabc!" {
+		t.Errorf("got %q, want %q", out, "abcabcabcabc!")
+	}
+
+	for _, src := range [][]byte{
+		{0x35, 'a', 'b', 'c', 4, 0},        // offset before the start
+		{0x50, 'a'},                        // truncated literals
+		{0x35, 'a', 'b', 'c', 3},           // truncated offset
+		{0x3f, 'a', 'b', 'c', 3, 0, 20},    // match longer than the output
+		{0xf0, 255, 255, 255, 255, 255, 0}, // literals longer than the output
+	} {
+		if _, err := Decompress(src, 13); err == nil {
+			t.Errorf("Decompress(%v): expected an error", src)
+		}
+	}
+}
+
+// lz4Frame builds an LZ4 frame from the given blocks, flagged as raw if
+// their first byte is 'R'.
+func lz4Frame(flags byte, blocks ...[]byte) []byte {
+	var b bytes.Buffer
+	binary.Write(&b, binary.LittleEndian, uint32(frameMagic))
+	b.Write([]byte{flags, 0x40, 0}) // FLG, BD with 64 KiB blocks, HC
+	for _, block := range blocks {
+		size := uint32(len(block))
+		if block[0] == 'R' {
+			block = block[1:]
+			size = uint32(len(block)) | incompressible
+		}
+		binary.Write(&b, binary.LittleEndian, size)
+		b.Write(block)
+		if flags&flgBlockSum != 0 {
+			b.Write([]byte{1, 2, 3, 4})
+		}
+	}
+	b.Write([]byte{0, 0, 0, 0})
+	if flags&flgContentSum != 0 {
+		b.Write([]byte{1, 2, 3, 4})
+	}
+	return b.Bytes()
+}
+
+func TestReader(t *testing.T) {
+	var data bytes.Buffer
+	data.Write(lz4Frame(0x60,
+		// "abc" then a 9 byte match at offset 3
+		[]byte{0x35, 'a', 'b', 'c', 3, 0},
+		// a 5 byte match in the previous block, then "!"
+		[]byte{0x01, 6, 0, 0x10, '!'},
+		[]byte("Rxyz"),
+	))
+	// a skippable frame, then a frame with checksums
+	binary.Write(&data, binary.LittleEndian, uint32(skipMagic+3))
+	binary.Write(&data, binary.LittleEndian, uint32(2))
+	data.Write([]byte{0xff, 0xff})
+	data.Write(lz4Frame(0x40|flgBlockSum|flgContentSum,
+		// 15+3 literals, extended length
+		append([]byte{0xf0, 3}, []byte("0123456789abcdefgh")...),
+	))
+
+	got, err := ioutil.ReadAll(NewReader(&data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "abcabcabcabcabcab!xyz0123456789abcdefgh"; string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestReaderCorrupt(t *testing.T) {
+	// a match length beyond the maximum block size
+	long := append([]byte{0x1f, 'a', 1, 0}, bytes.Repeat([]byte{255}, maxBlockSize/255+1)...)
+
+	tests := map[string][]byte{
+		"magic":     {1, 2, 3, 4},
+		"truncated": lz4Frame(0x60, []byte{0x35, 'a', 'b', 'c', 3, 0})[:12],
+		"offset":    lz4Frame(0x60, []byte{0x11, 'a', 9, 0}),
+		"literals":  lz4Frame(0x60, []byte{0x50, 'a'}),
+		"length":    lz4Frame(0x60, append(long, 0)),
+	}
+	for name, data := range tests {
+		if _, err := ioutil.ReadAll(NewReader(bytes.NewReader(data))); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,363 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zstd
+
+const maxBlockSize = 128 << 10
+
+// Baselines and extra bits of the literal length and match length codes.
+var (
+	llBase = [36]uint32{
+		0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15,
+		16, 18, 20, 22, 24, 28, 32, 40, 48, 64, 128, 256, 512, 1024, 2048, 4096,
+		8192, 16384, 32768, 65536,
+	}
+	llBits = [36]uint8{
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		1, 1, 1, 1, 2, 2, 3, 3, 4, 6, 7, 8, 9, 10, 11, 12,
+		13, 14, 15, 16,
+	}
+	mlBase = [53]uint32{
+		3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18,
+		19, 20, 21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31, 32, 33, 34,
+		35, 37, 39, 41, 43, 47, 51, 59, 67, 83, 99, 131, 259, 515, 1027, 2051,
+		4099, 8195, 16387, 32771, 65539,
+	}
+	mlBits = [53]uint8{
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		1, 1, 1, 1, 2, 2, 3, 3, 4, 4, 5, 7, 8, 9, 10, 11,
+		12, 13, 14, 15, 16,
+	}
+)
+
+// The predefined distributions of the sequence codes.
+var (
+	llPredefined = mustBuildFSETable([]int16{
+		4, 3, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 1, 1, 1,
+		2, 2, 2, 2, 2, 2, 2, 2, 2, 3, 2, 1, 1, 1, 1, 1,
+		-1, -1, -1, -1,
+	}, 6)
+	mlPredefined = mustBuildFSETable([]int16{
+		1, 4, 3, 2, 2, 2, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, -1, -1,
+		-1, -1, -1, -1, -1,
+	}, 6)
+	ofPredefined = mustBuildFSETable([]int16{
+		1, 1, 1, 1, 1, 1, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, -1, -1, -1, -1, -1,
+	}, 5)
+)
+
+// blockDecoder holds the state carried from block to block in a frame.
+type blockDecoder struct {
+	huf        *hufTable
+	ll, of, ml *fseTable
+	reps       [3]int
+	lits       []byte
+}
+
+func (d *blockDecoder) reset() {
+	d.huf = nil
+	d.ll, d.of, d.ml = nil, nil, nil
+	d.reps = [3]int{1, 4, 8}
+}
+
+// decode appends the content of the compressed block src to hist, which
+// holds the content decompressed so far within the window.
+func (d *blockDecoder) decode(hist, src []byte) ([]byte, error) {
+	lits, n, err := d.readLiterals(src)
+	if err != nil {
+		return nil, err
+	}
+	return d.execSequences(hist, src[n:], lits)
+}
+
+// readLiterals reads the literals section at the start of src, returning the
+// literals and the size of the section.
+func (d *blockDecoder) readLiterals(src []byte) ([]byte, int, error) {
+	if len(src) == 0 {
+		return nil, 0, errCorrupt
+	}
+	typ, format := src[0]&3, src[0]>>2&3
+
+	if typ == 0 || typ == 1 {
+		// raw or RLE
+		var size, hs int
+		switch format {
+		case 0, 2:
+			size, hs = int(src[0]>>3), 1
+		case 1:
+			if len(src) < 2 {
+				return nil, 0, errCorrupt
+			}
+			size, hs = int(src[0]>>4)|int(src[1])<<4, 2
+		case 3:
+			if len(src) < 3 {
+				return nil, 0, errCorrupt
+			}
+			size, hs = int(src[0]>>4)|int(src[1])<<4|int(src[2])<<12, 3
+		}
+		if size > maxBlockSize {
+			return nil, 0, errCorrupt
+		}
+		if typ == 0 {
+			if hs+size > len(src) {
+				return nil, 0, errCorrupt
+			}
+			return src[hs : hs+size], hs + size, nil
+		}
+		if hs >= len(src) {
+			return nil, 0, errCorrupt
+		}
+		d.lits = d.lits[:0]
+		for i := 0; i < size; i++ {
+			d.lits = append(d.lits, src[hs])
+		}
+		return d.lits, hs + 1, nil
+	}
+
+	// Huffman compressed, with a new tree or the one of the previous block
+	streams, hs, sizeBits := 4, 3, uint(10)
+	switch format {
+	case 0:
+		streams = 1
+	case 2:
+		hs, sizeBits = 4, 14
+	case 3:
+		hs, sizeBits = 5, 18
+	}
+	if len(src) < hs {
+		return nil, 0, errCorrupt
+	}
+	var h uint64
+	for i := 0; i < hs; i++ {
+		h |= uint64(src[i]) << (8 * uint(i))
+	}
+	size := int(h>>4) & (1<<sizeBits - 1)
+	compressed := int(h>>(4+sizeBits)) & (1<<sizeBits - 1)
+	if size > maxBlockSize || hs+compressed > len(src) {
+		return nil, 0, errCorrupt
+	}
+	data := src[hs : hs+compressed]
+
+	if typ == 2 {
+		t, n, err := readHuffmanTable(data)
+		if err != nil {
+			return nil, 0, err
+		}
+		d.huf = t
+		data = data[n:]
+	} else if d.huf == nil {
+		return nil, 0, errCorrupt
+	}
+
+	var err error
+	d.lits = d.lits[:0]
+	if streams == 1 {
+		d.lits, err = d.huf.decode(d.lits, data, size)
+	} else {
+		d.lits, err = d.decode4(data, size)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	return d.lits, hs + compressed, nil
+}
+
+// decode4 decodes literals split into four Huffman-coded streams.
+func (d *blockDecoder) decode4(data []byte, size int) ([]byte, error) {
+	if len(data) < 6 {
+		return nil, errCorrupt
+	}
+	var sizes [4]int
+	rest := len(data) - 6
+	for i := 0; i < 3; i++ {
+		sizes[i] = int(data[2*i]) | int(data[2*i+1])<<8
+		rest -= sizes[i]
+	}
+	sizes[3] = rest
+	if rest < 0 {
+		return nil, errCorrupt
+	}
+
+	segment := (size + 3) / 4
+	if size-3*segment < 0 {
+		return nil, errCorrupt
+	}
+	data = data[6:]
+	lits := d.lits
+	for i, n := range sizes {
+		count := segment
+		if i == 3 {
+			count = size - 3*segment
+		}
+		var err error
+		if lits, err = d.huf.decode(lits, data[:n], count); err != nil {
+			return nil, err
+		}
+		data = data[n:]
+	}
+	return lits, nil
+}
+
+// execSequences reads the sequences section src and appends the content it
+// describes, made of lits and matches, to hist.
+func (d *blockDecoder) execSequences(hist, src, lits []byte) ([]byte, error) {
+	if len(src) == 0 {
+		return nil, errCorrupt
+	}
+	var nbSeq, i int
+	switch b := int(src[0]); {
+	case b < 128:
+		nbSeq, i = b, 1
+	case b < 255:
+		if len(src) < 2 {
+			return nil, errCorrupt
+		}
+		nbSeq, i = (b-128)<<8|int(src[1]), 2
+	default:
+		if len(src) < 3 {
+			return nil, errCorrupt
+		}
+		nbSeq, i = int(src[1])|int(src[2])<<8+0x7f00, 3
+	}
+	if nbSeq == 0 {
+		if i != len(src) {
+			return nil, errCorrupt
+		}
+		return append(hist, lits...), nil
+	}
+
+	if i >= len(src) {
+		return nil, errCorrupt
+	}
+	modes := src[i]
+	i++
+	if modes&3 != 0 {
+		return nil, errCorrupt
+	}
+	var err error
+	tables := []struct {
+		t          **fseTable
+		mode       uint8
+		predefined *fseTable
+		maxSymbol  int
+		maxLog     uint8
+	}{
+		{&d.ll, modes >> 6, llPredefined, 35, 9},
+		{&d.of, modes >> 4 & 3, ofPredefined, 31, 8},
+		{&d.ml, modes >> 2 & 3, mlPredefined, 52, 9},
+	}
+	for _, tt := range tables {
+		switch tt.mode {
+		case 0:
+			*tt.t = tt.predefined
+		case 1:
+			if i >= len(src) || int(src[i]) > tt.maxSymbol {
+				return nil, errCorrupt
+			}
+			*tt.t = rleTable(src[i])
+			i++
+		case 2:
+			var n int
+			if *tt.t, n, err = readFSETable(src[i:], tt.maxSymbol, tt.maxLog); err != nil {
+				return nil, err
+			}
+			i += n
+		case 3:
+			if *tt.t == nil {
+				return nil, errCorrupt
+			}
+		}
+	}
+
+	br, err := newReverseBits(src[i:])
+	if err != nil {
+		return nil, err
+	}
+	ll, of, ml := d.ll, d.of, d.ml
+	llState, ofState, mlState := br.read(ll.log), br.read(of.log), br.read(ml.log)
+
+	start := len(hist)
+	for k := 0; k < nbSeq; k++ {
+		llCode, ofCode, mlCode := ll.entries[llState].symbol, of.entries[ofState].symbol, ml.entries[mlState].symbol
+		if llCode > 35 || mlCode > 52 || ofCode > 31 {
+			return nil, errCorrupt
+		}
+		ofValue := int(1)<<ofCode + int(br.read(ofCode))
+		matchLen := int(mlBase[mlCode]) + int(br.read(mlBits[mlCode]))
+		litLen := int(llBase[llCode]) + int(br.read(llBits[llCode]))
+
+		var offset int
+		if ofValue > 3 {
+			offset = ofValue - 3
+			d.reps[2], d.reps[1], d.reps[0] = d.reps[1], d.reps[0], offset
+		} else {
+			rep := ofValue
+			if litLen == 0 {
+				rep++
+			}
+			switch rep {
+			case 1:
+				offset = d.reps[0]
+			case 2:
+				offset = d.reps[1]
+				d.reps[1], d.reps[0] = d.reps[0], offset
+			case 3:
+				offset = d.reps[2]
+				d.reps[2], d.reps[1], d.reps[0] = d.reps[1], d.reps[0], offset
+			case 4:
+				offset = d.reps[0] - 1
+				d.reps[2], d.reps[1], d.reps[0] = d.reps[1], d.reps[0], offset
+			}
+		}
+
+		if k != nbSeq-1 {
+			llState = ll.update(llState, br)
+			mlState = ml.update(mlState, br)
+			ofState = of.update(ofState, br)
+		}
+		if br.pos < 0 {
+			return nil, errCorrupt
+		}
+
+		if litLen > len(lits) {
+			return nil, errCorrupt
+		}
+		hist = append(hist, lits[:litLen]...)
+		lits = lits[litLen:]
+
+		if offset <= 0 || offset > len(hist) || len(hist)-start+matchLen > maxBlockSize {
+			return nil, errCorrupt
+		}
+		from := len(hist) - offset
+		for matchLen > 0 {
+			// in chunks of up to offset bytes, as the match may overlap
+			// its copy
+			n := matchLen
+			if n > offset {
+				n = offset
+			}
+			hist = append(hist, hist[from:from+n]...)
+			from += n
+			matchLen -= n
+		}
+	}
+	if br.pos != 0 {
+		return nil, errCorrupt
+	}
+	return append(hist, lits...), nil
+}
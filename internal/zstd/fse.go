@@ -0,0 +1,379 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zstd
+
+import (
+	"errors"
+	"math/bits"
+)
+
+var errCorrupt = errors.New("corrupt zstd data")
+
+// reverseBits reads a bitstream backwards, from its last bit to its first
+// one, as sequences and Huffman-coded literals are written.
+type reverseBits struct {
+	b   []byte
+	pos int // number of bits left; negative once more bits than available were read
+}
+
+func newReverseBits(b []byte) (*reverseBits, error) {
+	if len(b) == 0 || b[len(b)-1] == 0 {
+		// the last byte holds a 1 bit marking the start of the stream
+		return nil, errCorrupt
+	}
+	return &reverseBits{b: b, pos: (len(b)-1)*8 + bits.Len8(b[len(b)-1]) - 1}, nil
+}
+
+// peek returns the next n bits, n <= 56, the first one being the most
+// significant. Bits past the beginning of the stream read as zeros.
+func (r *reverseBits) peek(n uint8) uint64 {
+	if n == 0 {
+		return 0
+	}
+	start := r.pos - int(n)
+	lo := start >> 3
+	var v uint64
+	for i := (r.pos - 1) >> 3; i >= lo; i-- {
+		v <<= 8
+		if i >= 0 {
+			v |= uint64(r.b[i])
+		}
+	}
+	return (v >> uint(start-lo*8)) & (1<<n - 1)
+}
+
+func (r *reverseBits) read(n uint8) uint64 {
+	v := r.peek(n)
+	r.pos -= int(n)
+	return v
+}
+
+// forwardBits reads a bitstream from its first bit, the least significant
+// one of its first byte, as FSE table descriptions are written.
+type forwardBits struct {
+	b   []byte
+	pos int
+}
+
+func (r *forwardBits) peek(n uint8) uint32 {
+	var v uint32
+	for i := int(n) - 1; i >= 0; i-- {
+		p := r.pos + i
+		v <<= 1
+		if p>>3 < len(r.b) {
+			v |= uint32(r.b[p>>3]>>uint(p&7)) & 1
+		}
+	}
+	return v
+}
+
+func (r *forwardBits) read(n uint8) uint32 {
+	v := r.peek(n)
+	r.pos += int(n)
+	return v
+}
+
+type fseEntry struct {
+	symbol   uint8
+	nbBits   uint8
+	newState uint16
+}
+
+// fseTable is an FSE decoding table.
+type fseTable struct {
+	log     uint8
+	entries []fseEntry
+}
+
+// update returns the state following state, reading its bits from br.
+func (t *fseTable) update(state uint64, br *reverseBits) uint64 {
+	e := t.entries[state]
+	return uint64(e.newState) + br.read(e.nbBits)
+}
+
+// readFSETable reads an FSE table description from src. It returns the
+// table and the number of bytes read.
+func readFSETable(src []byte, maxSymbol int, maxLog uint8) (*fseTable, int, error) {
+	br := &forwardBits{b: src}
+	log := uint8(br.read(4)) + 5
+	if log > maxLog {
+		return nil, 0, errCorrupt
+	}
+
+	norm := make([]int16, 0, maxSymbol+1)
+	remaining := int32(1)<<log + 1
+	threshold := int32(1) << log
+	nbBits := log + 1
+	for remaining > 1 && len(norm) <= maxSymbol {
+		max := 2*threshold - 1 - remaining
+		count := int32(br.peek(nbBits - 1))
+		if count < max {
+			br.pos += int(nbBits - 1)
+		} else {
+			count = int32(br.read(nbBits))
+			if count >= threshold {
+				count -= max
+			}
+		}
+
+		count-- // -1 is a probability of "less than 1"
+		if count < 0 {
+			remaining += count
+		} else {
+			remaining -= count
+		}
+		norm = append(norm, int16(count))
+
+		if count == 0 {
+			// followed by the number of symbols with a zero probability
+			for {
+				n := br.read(2)
+				for i := uint32(0); i < n; i++ {
+					norm = append(norm, 0)
+				}
+				if n != 3 {
+					break
+				}
+			}
+			if len(norm) > maxSymbol+1 {
+				return nil, 0, errCorrupt
+			}
+		}
+
+		if remaining < 1 {
+			return nil, 0, errCorrupt
+		}
+		for remaining < threshold {
+			nbBits--
+			threshold >>= 1
+		}
+	}
+	if remaining != 1 || br.pos > len(src)*8 {
+		return nil, 0, errCorrupt
+	}
+
+	t, err := buildFSETable(norm, log)
+	if err != nil {
+		return nil, 0, err
+	}
+	return t, (br.pos + 7) / 8, nil
+}
+
+// buildFSETable builds the decoding table of the normalized symbol
+// probabilities norm.
+func buildFSETable(norm []int16, log uint8) (*fseTable, error) {
+	size := 1 << log
+	t := &fseTable{log: log, entries: make([]fseEntry, size)}
+	next := make([]uint16, len(norm))
+
+	// symbols with a "less than 1" probability go at the end
+	high := size - 1
+	for s, c := range norm {
+		if c == -1 {
+			t.entries[high].symbol = uint8(s)
+			high--
+			next[s] = 1
+		} else {
+			next[s] = uint16(c)
+		}
+	}
+
+	step := size>>1 + size>>3 + 3
+	mask := size - 1
+	pos := 0
+	for s, c := range norm {
+		for i := 0; i < int(c); i++ {
+			t.entries[pos].symbol = uint8(s)
+			for {
+				pos = (pos + step) & mask
+				if pos <= high {
+					break
+				}
+			}
+		}
+	}
+	if pos != 0 {
+		return nil, errCorrupt
+	}
+
+	for i := range t.entries {
+		e := &t.entries[i]
+		n := next[e.symbol]
+		next[e.symbol]++
+		e.nbBits = log - uint8(bits.Len16(n)-1)
+		e.newState = n<<e.nbBits - uint16(size)
+	}
+	return t, nil
+}
+
+// rleTable returns the table of a single symbol.
+func rleTable(symbol uint8) *fseTable {
+	return &fseTable{entries: []fseEntry{{symbol: symbol}}}
+}
+
+func mustBuildFSETable(norm []int16, log uint8) *fseTable {
+	t, err := buildFSETable(norm, log)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+const maxHuffmanBits = 11
+
+type hufEntry struct {
+	symbol uint8
+	nbBits uint8
+}
+
+// hufTable is a Huffman decoding table, indexed by the next maxBits bits.
+type hufTable struct {
+	maxBits uint8
+	entries []hufEntry
+}
+
+// readHuffmanTable reads a Huffman tree description from src. It returns
+// the table and the number of bytes read.
+func readHuffmanTable(src []byte) (*hufTable, int, error) {
+	if len(src) == 0 {
+		return nil, 0, errCorrupt
+	}
+
+	var weights []uint8
+	var n int
+	if h := int(src[0]); h < 128 {
+		// the weights are FSE compressed, in h bytes
+		n = 1 + h
+		if n > len(src) {
+			return nil, 0, errCorrupt
+		}
+		data := src[1:n]
+		t, used, err := readFSETable(data, 255, 6)
+		if err != nil {
+			return nil, 0, err
+		}
+		br, err := newReverseBits(data[used:])
+		if err != nil {
+			return nil, 0, err
+		}
+
+		// two interleaved states, until the bitstream is exhausted
+		s1, s2 := br.read(t.log), br.read(t.log)
+		for len(weights) <= 255 {
+			weights = append(weights, t.entries[s1].symbol)
+			s1 = t.update(s1, br)
+			if br.pos < 0 {
+				weights = append(weights, t.entries[s2].symbol)
+				break
+			}
+			weights = append(weights, t.entries[s2].symbol)
+			s2 = t.update(s2, br)
+			if br.pos < 0 {
+				weights = append(weights, t.entries[s1].symbol)
+				break
+			}
+		}
+	} else {
+		// h-127 weights of 4 bits
+		count := h - 127
+		n = 1 + (count+1)/2
+		if n > len(src) {
+			return nil, 0, errCorrupt
+		}
+		for i := 0; i < count; i++ {
+			b := src[1+i/2]
+			if i%2 == 0 {
+				b >>= 4
+			}
+			weights = append(weights, b&0xf)
+		}
+	}
+
+	if len(weights) > 255 {
+		return nil, 0, errCorrupt
+	}
+	t, err := buildHuffmanTable(weights)
+	if err != nil {
+		return nil, 0, err
+	}
+	return t, n, nil
+}
+
+// buildHuffmanTable builds the decoding table of the symbol weights, the
+// weight of the last symbol being implied.
+func buildHuffmanTable(weights []uint8) (*hufTable, error) {
+	var total uint32
+	for _, w := range weights {
+		if w > maxHuffmanBits {
+			return nil, errCorrupt
+		}
+		if w > 0 {
+			total += 1 << (w - 1)
+		}
+	}
+	if total == 0 {
+		return nil, errCorrupt
+	}
+	maxBits := uint8(bits.Len32(total))
+	rest := uint32(1)<<maxBits - total
+	if maxBits > maxHuffmanBits || rest&(rest-1) != 0 {
+		return nil, errCorrupt
+	}
+	weights = append(weights, uint8(bits.Len32(rest)))
+
+	// codes are assigned from the smallest weights, the longest codes
+	var start [maxHuffmanBits + 2]int
+	for _, w := range weights {
+		if w > 0 {
+			start[w] += 1 << (w - 1)
+		}
+	}
+	pos := 0
+	for w := 1; w <= int(maxBits); w++ {
+		n := start[w]
+		start[w] = pos
+		pos += n
+	}
+
+	t := &hufTable{maxBits: maxBits, entries: make([]hufEntry, 1<<maxBits)}
+	for s, w := range weights {
+		if w == 0 {
+			continue
+		}
+		e := hufEntry{symbol: uint8(s), nbBits: maxBits + 1 - w}
+		for i := 0; i < 1<<(w-1); i++ {
+			t.entries[start[w]+i] = e
+		}
+		start[w] += 1 << (w - 1)
+	}
+	return t, nil
+}
+
+// decode appends the n symbols of the Huffman-coded stream src to dst.
+func (t *hufTable) decode(dst, src []byte, n int) ([]byte, error) {
+	br, err := newReverseBits(src)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < n; i++ {
+		e := t.entries[br.peek(t.maxBits)]
+		br.pos -= int(e.nbBits)
+		dst = append(dst, e.symbol)
+	}
+	if br.pos != 0 {
+		return nil, errCorrupt
+	}
+	return dst, nil
+}
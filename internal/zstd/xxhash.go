@@ -0,0 +1,110 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zstd
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+const (
+	prime1 uint64 = 11400714785074694791
+	prime2 uint64 = 14029467366897019727
+	prime3 uint64 = 1609587929392839161
+	prime4 uint64 = 9650029242287828579
+	prime5 uint64 = 2870177450012600261
+)
+
+// xxh64 computes the XXH64 hash with a seed of 0, which zstd frames are
+// checksummed with.
+type xxh64 struct {
+	v     [4]uint64
+	total uint64
+	buf   [32]byte
+	n     int // bytes in buf
+}
+
+func (h *xxh64) reset() {
+	p1, p2 := prime1, prime2 // wrapping around
+	h.v = [4]uint64{p1 + p2, p2, 0, -p1}
+	h.total = 0
+	h.n = 0
+}
+
+func (h *xxh64) Write(p []byte) {
+	h.total += uint64(len(p))
+	if h.n > 0 {
+		c := copy(h.buf[h.n:], p)
+		h.n += c
+		p = p[c:]
+		if h.n < 32 {
+			return
+		}
+		h.stripe(h.buf[:])
+		h.n = 0
+	}
+	for ; len(p) >= 32; p = p[32:] {
+		h.stripe(p)
+	}
+	h.n = copy(h.buf[:], p)
+}
+
+func (h *xxh64) stripe(p []byte) {
+	for i := range h.v {
+		h.v[i] = xxhRound(h.v[i], binary.LittleEndian.Uint64(p[8*i:]))
+	}
+}
+
+func (h *xxh64) sum() uint64 {
+	var s uint64
+	if h.total >= 32 {
+		v := h.v
+		s = bits.RotateLeft64(v[0], 1) + bits.RotateLeft64(v[1], 7) +
+			bits.RotateLeft64(v[2], 12) + bits.RotateLeft64(v[3], 18)
+		for _, x := range v {
+			s = (s^xxhRound(0, x))*prime1 + prime4
+		}
+	} else {
+		s = prime5
+	}
+	s += h.total
+
+	p := h.buf[:h.n]
+	for ; len(p) >= 8; p = p[8:] {
+		s ^= xxhRound(0, binary.LittleEndian.Uint64(p))
+		s = bits.RotateLeft64(s, 27)*prime1 + prime4
+	}
+	if len(p) >= 4 {
+		s ^= uint64(binary.LittleEndian.Uint32(p)) * prime1
+		s = bits.RotateLeft64(s, 23)*prime2 + prime3
+		p = p[4:]
+	}
+	for _, b := range p {
+		s ^= uint64(b) * prime5
+		s = bits.RotateLeft64(s, 11) * prime1
+	}
+
+	s ^= s >> 33
+	s *= prime2
+	s ^= s >> 29
+	s *= prime3
+	s ^= s >> 32
+	return s
+}
+
+func xxhRound(acc, input uint64) uint64 {
+	acc += input * prime2
+	return bits.RotateLeft64(acc, 31) * prime1
+}
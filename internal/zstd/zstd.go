@@ -0,0 +1,297 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package zstd implements a decoder for the Zstandard format described in
+// RFC 8878, which journald and systemd-coredump may compress data with.
+// Dictionaries are not supported.
+package zstd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+const (
+	frameMagic     = 0xfd2fb528
+	skippableMask  = 0xfffffff0
+	skippableMagic = 0x184d2a50
+
+	// maxWindowSize bounds the memory used for the history of a frame,
+	// like the default limit of the reference decoder.
+	maxWindowSize = 1 << 27
+)
+
+// ErrTooLarge is returned by Decompress when the data decompresses to more
+// than the given limit.
+var ErrTooLarge = errors.New("zstd data too large")
+
+// Reader decompresses a stream of zstd frames.
+type Reader struct {
+	r *bufio.Reader
+
+	inFrame     bool
+	lastBlock   bool
+	checksum    bool
+	contentSize int64 // -1 if unknown
+	produced    int64
+	windowSize  int
+	digest      xxh64
+
+	d     blockDecoder
+	hist  []byte // the window, followed by the last decompressed block
+	out   []byte // decompressed data not yet read
+	block []byte
+}
+
+// NewReader returns a Reader decompressing the frames read from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Decompress returns the decompressed content of the frames in src, or
+// ErrTooLarge if it is larger than max bytes.
+func Decompress(src []byte, max int64) ([]byte, error) {
+	out, err := ioutil.ReadAll(io.LimitReader(NewReader(bytes.NewReader(src)), max+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(out)) > max {
+		return nil, ErrTooLarge
+	}
+	return out, nil
+}
+
+func (z *Reader) Read(p []byte) (int, error) {
+	for len(z.out) == 0 {
+		if err := z.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, z.out)
+	z.out = z.out[n:]
+	return n, nil
+}
+
+// next decompresses the next block, reading frame headers and footers as
+// needed. It returns io.EOF after the last frame.
+func (z *Reader) next() error {
+	if !z.inFrame {
+		return z.readFrameHeader()
+	}
+	if z.lastBlock {
+		return z.endFrame()
+	}
+
+	var h [3]byte
+	if _, err := io.ReadFull(z.r, h[:]); err != nil {
+		return unexpectedEOF(err)
+	}
+	header := uint32(h[0]) | uint32(h[1])<<8 | uint32(h[2])<<16
+	z.lastBlock = header&1 != 0
+	typ, size := header>>1&3, int(header>>3)
+
+	blockMax := maxBlockSize
+	if z.windowSize < blockMax {
+		blockMax = z.windowSize
+	}
+	if size > blockMax {
+		return errCorrupt
+	}
+
+	// keep the window only, moving it when it has grown twice as large
+	if len(z.hist) > 2*z.windowSize {
+		z.hist = append(z.hist[:0], z.hist[len(z.hist)-z.windowSize:]...)
+	}
+	start := len(z.hist)
+
+	switch typ {
+	case 0: // raw
+		if err := z.readBlock(size); err != nil {
+			return err
+		}
+		z.hist = append(z.hist, z.block...)
+	case 1: // RLE
+		b, err := z.r.ReadByte()
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		for i := 0; i < size; i++ {
+			z.hist = append(z.hist, b)
+		}
+	case 2: // compressed
+		if err := z.readBlock(size); err != nil {
+			return err
+		}
+		var err error
+		if z.hist, err = z.d.decode(z.hist, z.block); err != nil {
+			return err
+		}
+	default:
+		return errCorrupt
+	}
+
+	z.out = z.hist[start:]
+	z.produced += int64(len(z.out))
+	if z.contentSize >= 0 && z.produced > z.contentSize {
+		return errCorrupt
+	}
+	if z.checksum {
+		z.digest.Write(z.out)
+	}
+	return nil
+}
+
+func (z *Reader) readBlock(size int) error {
+	if cap(z.block) < size {
+		z.block = make([]byte, size)
+	}
+	z.block = z.block[:size]
+	_, err := io.ReadFull(z.r, z.block)
+	return unexpectedEOF(err)
+}
+
+// readFrameHeader reads the header of the next frame, skipping skippable
+// frames. It returns io.EOF if there are no more frames.
+func (z *Reader) readFrameHeader() error {
+	var b [8]byte
+	for {
+		if _, err := io.ReadFull(z.r, b[:4]); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				return errCorrupt
+			}
+			return err
+		}
+		magic := binary.LittleEndian.Uint32(b[:4])
+		if magic&skippableMask != skippableMagic {
+			if magic != frameMagic {
+				return fmt.Errorf("not zstd data: magic %#x", magic)
+			}
+			break
+		}
+		if _, err := io.ReadFull(z.r, b[:4]); err != nil {
+			return unexpectedEOF(err)
+		}
+		if _, err := z.r.Discard(int(binary.LittleEndian.Uint32(b[:4]))); err != nil {
+			return unexpectedEOF(err)
+		}
+	}
+
+	desc, err := z.r.ReadByte()
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+	singleSegment := desc&(1<<5) != 0
+	if desc&(1<<3) != 0 {
+		return errCorrupt
+	}
+
+	windowSize := 0
+	if !singleSegment {
+		w, err := z.r.ReadByte()
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		log := 10 + uint(w>>3)
+		if log > 41 {
+			return errCorrupt
+		}
+		base := uint64(1) << log
+		size := base + base/8*uint64(w&7)
+		if size > maxWindowSize {
+			return fmt.Errorf("zstd window size %d too large", size)
+		}
+		windowSize = int(size)
+	}
+
+	if n := [4]int{0, 1, 2, 4}[desc&3]; n > 0 {
+		if _, err := io.ReadFull(z.r, b[:n]); err != nil {
+			return unexpectedEOF(err)
+		}
+		var id uint32
+		for i := n - 1; i >= 0; i-- {
+			id = id<<8 | uint32(b[i])
+		}
+		if id != 0 {
+			return errors.New("zstd dictionaries are not supported")
+		}
+	}
+
+	z.contentSize = -1
+	n := [4]int{0, 2, 4, 8}[desc>>6]
+	if n == 0 && singleSegment {
+		n = 1
+	}
+	if n > 0 {
+		if _, err := io.ReadFull(z.r, b[:n]); err != nil {
+			return unexpectedEOF(err)
+		}
+		var size uint64
+		for i := n - 1; i >= 0; i-- {
+			size = size<<8 | uint64(b[i])
+		}
+		if n == 2 {
+			size += 256
+		}
+		if size > 1<<62 {
+			return errCorrupt
+		}
+		z.contentSize = int64(size)
+	}
+	if singleSegment {
+		if z.contentSize > maxWindowSize {
+			return fmt.Errorf("zstd window size %d too large", z.contentSize)
+		}
+		windowSize = int(z.contentSize)
+	}
+
+	z.inFrame, z.lastBlock = true, false
+	z.checksum = desc&(1<<2) != 0
+	z.produced = 0
+	z.windowSize = windowSize
+	z.digest.reset()
+	z.d.reset()
+	z.hist = z.hist[:0]
+	return nil
+}
+
+// endFrame reads the checksum at the end of a frame, if any.
+func (z *Reader) endFrame() error {
+	z.inFrame = false
+	if z.contentSize >= 0 && z.produced != z.contentSize {
+		return errCorrupt
+	}
+	if !z.checksum {
+		return nil
+	}
+	var b [4]byte
+	if _, err := io.ReadFull(z.r, b[:]); err != nil {
+		return unexpectedEOF(err)
+	}
+	if binary.LittleEndian.Uint32(b[:]) != uint32(z.digest.sum()) {
+		return errors.New("zstd checksum mismatch")
+	}
+	return nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,117 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zstd
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"testing"
+)
+
+func mustHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// Frames written by the zstd command line tool at level 19, with checksums.
+var (
+	// 300 zero bytes, as a single RLE block.
+	zeros = mustHex("28b52ffd04584d00001000000100272ac00290ea003a")
+	// sessionText, with single-stream Huffman literals and FSE sequences.
+	sessions = mustHex("28b52ffd646405550400f205141680ab0e38c7328610ea93a6e0b84bb6eda482b3a9ac6047eb57cd6c91f3959fd82ef766a745eb57cd6c91f3958f2ccc910c6544181038314a95703eaa445d6de232279c9a40471ece138862788f30022aa81100b7d0fe77802b6e39112c11ec109e21304678f3033fde495876cc9be25834a53c3c12af0749f415fe9ad3dac1008fb222d645d4687d8e9c")
+	// numberText, with four-stream Huffman literals.
+	numbers = mustHex("28b52ffd64b900d50700969b3d0fb0e701dd0d0c75695696506a27723a3800380038004ec43c3df50c32ef54f5b302f0d39fe6a07a08c3de1b42a17dde627710f8ef21c452aa9a8ca104165d08495a359b528ed0ee63cce9e7a20a1b8caee06e220430aa42bbcb1868f4fd6f4388192156dda79432dadce03ac69cc0adfa55e594bac0f68f13d0023eb33ded591aeff4840031b62a7154b56abe3f1c5d0b98d10c1c611d9af30e8f76d3cc00d18e406e9935b38e526fef0080310219f45e5562b47be016210823acca7b6c5e0006d2f38a16aa8b8440113d4250a43d3434d94d24a02f7411e2905257cf668c23810b793e849ca34dbd7399528e10f5e71f338ebeba1600912cc3b6")
+)
+
+func sessionText() []byte {
+	var b bytes.Buffer
+	users := []string{"root", "core", "alice"}
+	for i := 0; i < 40; i++ {
+		fmt.Fprintf(&b, "MESSAGE=Started session %d of user %s.\n", i*7%23, users[i%3])
+	}
+	return b.Bytes()
+}
+
+func numberText() []byte {
+	var b bytes.Buffer
+	for i := 0; i < 40; i++ {
+		fmt.Fprintf(&b, "%d %x\n", i*i*31, i*7919)
+	}
+	return b.Bytes()
+}
+
+func TestDecompress(t *testing.T) {
+	// a skippable frame holding 3 bytes of user data
+	skippable := []byte{0x50, 0x2a, 0x4d, 0x18, 3, 0, 0, 0, 1, 2, 3}
+
+	for _, tt := range []struct {
+		name string
+		src  []byte
+		want []byte
+	}{
+		{"rle", zeros, make([]byte, 300)},
+		{"sessions", sessions, sessionText()},
+		{"numbers", numbers, numberText()},
+		{"concatenated", append(append(append([]byte{}, zeros...), skippable...), sessions...), append(make([]byte, 300), sessionText()...)},
+	} {
+		out, err := Decompress(tt.src, 1<<20)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !bytes.Equal(out, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, out, tt.want)
+		}
+	}
+}
+
+func TestDecompressInvalid(t *testing.T) {
+	corrupt := append([]byte{}, sessions...)
+	corrupt[len(corrupt)-1] ^= 0xff // content checksum
+	dict := []byte{0x28, 0xb5, 0x2f, 0xfd, 0x01, 0x00, 0x01, 0x01, 0x00, 0x00}
+
+	for _, tt := range []struct {
+		name string
+		src  []byte
+	}{
+		{"checksum", corrupt},
+		{"truncated", sessions[:len(sessions)/2]},
+		{"magic", []byte("not zstd data")},
+		{"dictionary", dict},
+	} {
+		if _, err := Decompress(tt.src, 1<<20); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+
+	if _, err := Decompress(zeros, 299); err != ErrTooLarge {
+		t.Errorf("got %v, want %v", err, ErrTooLarge)
+	}
+}
+
+func TestReader(t *testing.T) {
+	out, err := ioutil.ReadAll(NewReader(bytes.NewReader(sessions)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, sessionText()) {
+		t.Errorf("got %q, want %q", out, sessionText())
+	}
+}
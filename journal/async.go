@@ -0,0 +1,164 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+const defaultQueueSize = 1024
+
+// ErrQueueFull is returned by AsyncWriter.Send when the message was dropped
+// because the queue is full.
+var ErrQueueFull = errors.New("journal queue full, message dropped")
+
+// ErrWriterClosed is returned by AsyncWriter.Send after the writer was
+// closed.
+var ErrWriterClosed = errors.New("journal writer closed")
+
+// AsyncWriterOptions configures an AsyncWriter.
+type AsyncWriterOptions struct {
+	// QueueSize is the number of messages queued before Send drops or
+	// blocks, 1024 if 0.
+	QueueSize int
+	// Block makes Send wait for room in the queue when it is full, slowing
+	// down the caller, instead of dropping the message.
+	Block bool
+	// Namespace is the journal namespace messages are sent to, see
+	// SendNamespace. If empty, messages are sent to the default journal.
+	Namespace string
+}
+
+// AsyncWriter sends messages to the journal from a background goroutine, so
+// that logging does not wait for the journal: Send only serializes the
+// message and queues it. When the queue is full, messages are dropped and
+// counted, or Send blocks if configured to. The journal protocol carries a
+// message per datagram, so queued messages are still sent one by one. An
+// AsyncWriter is safe for concurrent use.
+type AsyncWriter struct {
+	// The counters are accessed atomically, and must stay first so that
+	// they are 64-bit aligned on 32-bit platforms, see the sync/atomic
+	// documentation.
+	dropped uint64
+	failed  uint64
+
+	queue chan []byte
+	block bool
+	write func(data []byte) error
+
+	lock      sync.RWMutex // held for writing while closing
+	closed    bool
+	drained   chan struct{}
+	closeOnce sync.Once
+}
+
+// NewAsyncWriter starts an AsyncWriter configured by opts, which may be nil.
+// Close it to send the queued messages and stop its goroutine.
+func NewAsyncWriter(opts *AsyncWriterOptions) (*AsyncWriter, error) {
+	if opts == nil {
+		opts = &AsyncWriterOptions{}
+	}
+	socket := journalSocket
+	if opts.Namespace != "" {
+		if err := validNamespace(opts.Namespace); err != nil {
+			return nil, err
+		}
+		socket = namespaceSocket(opts.Namespace, "socket")
+	}
+	size := opts.QueueSize
+	if size <= 0 {
+		size = defaultQueueSize
+	}
+
+	w := &AsyncWriter{
+		queue:   make(chan []byte, size),
+		block:   opts.Block,
+		write:   func(data []byte) error { return sendData(socket, data) },
+		drained: make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+func (w *AsyncWriter) run() {
+	defer close(w.drained)
+	for data := range w.queue {
+		if err := w.write(data); err != nil {
+			atomic.AddUint64(&w.failed, 1)
+		}
+	}
+}
+
+// Send queues a message like journal.Send. It returns ErrQueueFull if the
+// message was dropped, and does not report errors sending queued messages,
+// which are counted by Failed instead.
+func (w *AsyncWriter) Send(message string, priority Priority, vars map[string]string) error {
+	data := encodeEntry(message, priority, vars)
+
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	if w.closed {
+		return ErrWriterClosed
+	}
+
+	if w.block {
+		w.queue <- data
+		return nil
+	}
+	select {
+	case w.queue <- data:
+		return nil
+	default:
+		atomic.AddUint64(&w.dropped, 1)
+		return ErrQueueFull
+	}
+}
+
+// Print queues a message using Send.
+func (w *AsyncWriter) Print(priority Priority, format string, a ...interface{}) error {
+	return w.Send(fmt.Sprintf(format, a...), priority, nil)
+}
+
+// Dropped returns the number of messages dropped because the queue was
+// full.
+func (w *AsyncWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
+
+// Failed returns the number of queued messages that could not be sent to
+// the journal.
+func (w *AsyncWriter) Failed() uint64 {
+	return atomic.LoadUint64(&w.failed)
+}
+
+// Queued returns the number of messages waiting to be sent.
+func (w *AsyncWriter) Queued() int {
+	return len(w.queue)
+}
+
+// Close stops accepting messages, and waits until the queued ones are sent.
+func (w *AsyncWriter) Close() error {
+	w.closeOnce.Do(func() {
+		w.lock.Lock()
+		w.closed = true
+		close(w.queue)
+		w.lock.Unlock()
+	})
+	<-w.drained
+	return nil
+}
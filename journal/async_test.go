@@ -0,0 +1,117 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestAsyncWriter(t *testing.T) {
+	w, err := NewAsyncWriter(&AsyncWriterOptions{QueueSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	release := make(chan struct{})
+	var lock sync.Mutex
+	var sent []string
+	w.write = func(data []byte) error {
+		<-release
+		lock.Lock()
+		defer lock.Unlock()
+		sent = append(sent, string(data))
+		if strings.Contains(string(data), "MESSAGE=fail\n") {
+			return errors.New("failed")
+		}
+		return nil
+	}
+
+	// the first message is taken by the goroutine, or stays queued
+	var dropped int
+	for _, m := range []string{"fail", "two", "three", "four", "five"} {
+		switch err := w.Send(m, PriInfo, nil); err {
+		case nil:
+		case ErrQueueFull:
+			dropped++
+		default:
+			t.Fatal(err)
+		}
+	}
+	if dropped == 0 || uint64(dropped) != w.Dropped() {
+		t.Errorf("got %d dropped messages, Dropped returns %d", dropped, w.Dropped())
+	}
+
+	close(release)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent)+dropped != 5 {
+		t.Errorf("got %d sent messages and %d dropped", len(sent), dropped)
+	}
+	if !strings.Contains(sent[0], "PRIORITY=6\nMESSAGE=fail\n") {
+		t.Errorf("unexpected first message %q", sent[0])
+	}
+	if w.Failed() != 1 {
+		t.Errorf("got %d failed messages", w.Failed())
+	}
+
+	if err := w.Send("late", PriInfo, nil); err != ErrWriterClosed {
+		t.Errorf("expected ErrWriterClosed, got %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+}
+
+func TestAsyncWriterBlock(t *testing.T) {
+	w, err := NewAsyncWriter(&AsyncWriterOptions{QueueSize: 1, Block: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lock sync.Mutex
+	var n int
+	w.write = func(data []byte) error {
+		lock.Lock()
+		defer lock.Unlock()
+		n++
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := w.Print(PriDebug, "message %d", i); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	w.Close()
+
+	if n != 10 || w.Dropped() != 0 {
+		t.Errorf("got %d sent messages, %d dropped", n, w.Dropped())
+	}
+}
+
+func TestAsyncWriterNamespace(t *testing.T) {
+	if _, err := NewAsyncWriter(&AsyncWriterOptions{Namespace: "a/b"}); err == nil {
+		t.Error("expected an error for an invalid namespace")
+	}
+}
@@ -0,0 +1,391 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// MessageID identifies a kind of journal message, as described for the
+// MESSAGE_ID field in systemd.journal-fields(7). It is a 128-bit ID
+// written as 32 lowercase hexadecimal digits, such as generated with
+// `systemd-id128 new`, and should be declared as a constant:
+//
+//	const MsgBackupDone journal.MessageID = "6b09d8d3b3d84c84b2e0d94a0ee8a3b5"
+type MessageID string
+
+// ParseMessageID parses s, a 128-bit ID written with or without dashes as
+// a UUID, such as "6b09d8d3-b3d8-4c84-b2e0-d94a0ee8a3b5".
+func ParseMessageID(s string) (MessageID, error) {
+	id := MessageID(strings.ToLower(strings.Replace(s, "-", "", -1)))
+	if err := id.validate(); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+func (id MessageID) validate() error {
+	if len(id) != 32 {
+		return fmt.Errorf("invalid message ID %q: must be 32 hexadecimal digits", string(id))
+	}
+	for _, c := range id {
+		if !(('0' <= c && c <= '9') || ('a' <= c && c <= 'f')) {
+			return fmt.Errorf("invalid message ID %q: must be 32 lowercase hexadecimal digits", string(id))
+		}
+	}
+	return nil
+}
+
+// SendMessage is like Send, but sets the MESSAGE_ID field to id, so that
+// the message can be looked up with `journalctl MESSAGE_ID=...` and
+// explained with `journalctl -x` if a catalog entry is installed for it.
+func SendMessage(id MessageID, message string, priority Priority, vars map[string]string) error {
+	if err := id.validate(); err != nil {
+		return err
+	}
+
+	v := make(map[string]string, len(vars)+1)
+	for k, val := range vars {
+		v[k] = val
+	}
+	v["MESSAGE_ID"] = string(id)
+	return Send(message, priority, v)
+}
+
+// CatalogEntry describes a kind of message in a journal message catalog,
+// which `journalctl -x` uses to explain messages. See
+// https://www.freedesktop.org/wiki/Software/systemd/catalog/ for the meaning
+// of the fields.
+type CatalogEntry struct {
+	ID            MessageID
+	Language      string // e.g. "de", empty for the default language
+	Subject       string // a one line summary
+	DefinedBy     string // the project defining the message
+	Support       string // a URL to get help, if any
+	Documentation string // a man page or URL, if any
+	// Body explains the message and what to do about it. It may refer to
+	// fields of the message as @FIELD@, for example @UNIT@.
+	Body string
+}
+
+// WriteCatalog writes entries to w in the catalog file format understood by
+// journalctl --update-catalog. It is meant to be called by a program run
+// with go generate, so that the catalog shipped with a service, usually
+// installed in /usr/lib/systemd/catalog/<name>.catalog, is kept in sync
+// with the message IDs it declares:
+//
+//	journal.WriteCatalog(os.Stdout, []journal.CatalogEntry{{
+//		ID:        MsgBackupDone,
+//		Subject:   "Backup of @VOLUME@ finished",
+//		DefinedBy: "backupd",
+//		Body:      "The backup of volume @VOLUME@ finished successfully.",
+//	}})
+func WriteCatalog(w io.Writer, entries []CatalogEntry) error {
+	bw := bufio.NewWriter(w)
+	for i, e := range entries {
+		if err := e.validate(); err != nil {
+			return err
+		}
+
+		if i > 0 {
+			bw.WriteString("\n")
+		}
+		bw.WriteString("-- " + string(e.ID))
+		if e.Language != "" {
+			bw.WriteString(" " + e.Language)
+		}
+		bw.WriteString("\n")
+		e.writeText(bw)
+	}
+	return bw.Flush()
+}
+
+// writeText writes the header fields and the body of e.
+func (e *CatalogEntry) writeText(w io.Writer) {
+	for _, f := range []struct{ name, value string }{
+		{"Subject", e.Subject},
+		{"Defined-By", e.DefinedBy},
+		{"Support", e.Support},
+		{"Documentation", e.Documentation},
+	} {
+		if f.value != "" {
+			fmt.Fprintf(w, "%s: %s\n", f.name, f.value)
+		}
+	}
+
+	if body := strings.TrimRight(e.Body, "\n"); body != "" {
+		io.WriteString(w, "\n"+body+"\n")
+	}
+}
+
+func (e *CatalogEntry) validate() error {
+	if err := e.ID.validate(); err != nil {
+		return err
+	}
+	if e.Subject == "" {
+		return fmt.Errorf("catalog entry %s has no subject", e.ID)
+	}
+	for _, v := range []string{e.Language, e.Subject, e.DefinedBy, e.Support, e.Documentation} {
+		if strings.ContainsRune(v, '\n') {
+			return fmt.Errorf("catalog entry %s has a header field with a newline", e.ID)
+		}
+	}
+	for _, line := range strings.Split(e.Body, "\n") {
+		if strings.HasPrefix(line, "-- ") {
+			return fmt.Errorf("catalog entry %s has a body line starting with \"-- \"", e.ID)
+		}
+	}
+	return nil
+}
+
+// ErrNoCatalogEntry is returned when looking up a message ID that has no
+// catalog entry.
+var ErrNoCatalogEntry = errors.New("no catalog entry for message ID")
+
+// catalogDirs are the directories catalog files are installed in, in order
+// of precedence.
+var catalogDirs = []string{"/usr/local/lib/systemd/catalog", "/usr/lib/systemd/catalog"}
+
+// ReadCatalog parses the entries of a catalog file, as written by
+// WriteCatalog. Header fields other than those of CatalogEntry are ignored.
+func ReadCatalog(r io.Reader) ([]CatalogEntry, error) {
+	var entries []CatalogEntry
+	var cur *CatalogEntry
+	var text []string
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "-- ") {
+			if cur != nil {
+				entries = append(entries, parseCatalogText(*cur, text))
+			}
+			fields := strings.Fields(line[3:])
+			if len(fields) == 0 || len(fields) > 2 {
+				return nil, fmt.Errorf("line %d: invalid catalog entry header %q", n, line)
+			}
+			id, err := ParseMessageID(fields[0])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", n, err)
+			}
+			cur = &CatalogEntry{ID: id}
+			if len(fields) == 2 {
+				cur.Language = fields[1]
+			}
+			text = nil
+			continue
+		}
+
+		if cur == nil {
+			if strings.TrimSpace(line) != "" {
+				return nil, fmt.Errorf("line %d: text outside of a catalog entry", n)
+			}
+			continue
+		}
+		text = append(text, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if cur != nil {
+		entries = append(entries, parseCatalogText(*cur, text))
+	}
+	return entries, nil
+}
+
+// parseCatalogText fills e from the lines of its text: header fields up to
+// the first empty line, then the body.
+func parseCatalogText(e CatalogEntry, lines []string) CatalogEntry {
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 {
+		i := strings.Index(lines[0], ": ")
+		if i < 0 {
+			break
+		}
+		name, value := lines[0][:i], lines[0][i+2:]
+		switch name {
+		case "Subject":
+			e.Subject = value
+		case "Defined-By":
+			e.DefinedBy = value
+		case "Support":
+			e.Support = value
+		case "Documentation":
+			e.Documentation = value
+		}
+		lines = lines[1:]
+	}
+	e.Body = strings.TrimSpace(strings.Join(lines, "\n"))
+	return e
+}
+
+type catalogKey struct {
+	id       MessageID
+	language string
+}
+
+// Catalog holds the catalog entries journalctl -x uses to explain
+// messages.
+type Catalog struct {
+	// Language selects the entries translated to a language, such as
+	// "de"; if empty, or if there is no translation for a message, the
+	// entries in the default language are used.
+	Language string
+
+	entries map[catalogKey]CatalogEntry
+}
+
+// NewCatalog returns a Catalog holding entries. An entry overrides the
+// previous ones with the same ID and language.
+func NewCatalog(entries []CatalogEntry) *Catalog {
+	c := &Catalog{entries: make(map[catalogKey]CatalogEntry, len(entries))}
+	for _, e := range entries {
+		c.entries[catalogKey{e.ID, e.Language}] = e
+	}
+	return c
+}
+
+// LoadCatalog reads the *.catalog files in dirs, or in the directories
+// journalctl --update-catalog reads, /usr/local/lib/systemd/catalog and
+// /usr/lib/systemd/catalog, if none is given. As with journalctl, files are
+// read in the order of their names, and a file masks those of the same
+// name in the directories after it. Entries without a language take the
+// one in their file name, if named like name.de.catalog.
+func LoadCatalog(dirs ...string) (*Catalog, error) {
+	if len(dirs) == 0 {
+		dirs = catalogDirs
+	}
+
+	files := make(map[string]string)
+	var names []string
+	for _, dir := range dirs {
+		paths, err := filepath.Glob(filepath.Join(dir, "*.catalog"))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			name := filepath.Base(path)
+			if _, ok := files[name]; !ok {
+				files[name] = path
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	var entries []CatalogEntry
+	for _, name := range names {
+		f, err := os.Open(files[name])
+		if err != nil {
+			return nil, err
+		}
+		fileEntries, err := ReadCatalog(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", files[name], err)
+		}
+
+		language := filepath.Ext(strings.TrimSuffix(name, ".catalog"))
+		for _, e := range fileEntries {
+			if e.Language == "" && language != "" {
+				e.Language = language[1:]
+			}
+			entries = append(entries, e)
+		}
+	}
+	return NewCatalog(entries), nil
+}
+
+// Lookup returns the entry for id, in c.Language if translated.
+func (c *Catalog) Lookup(id MessageID) (CatalogEntry, bool) {
+	if c.Language != "" {
+		if e, ok := c.entries[catalogKey{id, c.Language}]; ok {
+			return e, true
+		}
+	}
+	e, ok := c.entries[catalogKey{id, ""}]
+	return e, ok
+}
+
+// GetCatalog returns the explanation of the journal entry with fields, like
+// journalctl -x shows and sd_journal_get_catalog(3) returns: the text of the
+// catalog entry for its MESSAGE_ID, with the @FIELD@ references replaced by
+// the values of the fields. References to fields that the entry lacks are
+// left as they are.
+func (c *Catalog) GetCatalog(fields map[string]string) (string, error) {
+	id, ok := fields["MESSAGE_ID"]
+	if !ok {
+		return "", ErrNoCatalogEntry
+	}
+	text, err := c.GetCatalogForMessageID(MessageID(id))
+	if err != nil {
+		return "", err
+	}
+	return replaceCatalogFields(text, fields), nil
+}
+
+// GetCatalogForMessageID returns the text of the catalog entry for id, with
+// its @FIELD@ references unreplaced, as
+// sd_journal_get_catalog_for_message_id(3) does.
+func (c *Catalog) GetCatalogForMessageID(id MessageID) (string, error) {
+	e, ok := c.Lookup(id)
+	if !ok {
+		return "", ErrNoCatalogEntry
+	}
+	var b strings.Builder
+	e.writeText(&b)
+	return b.String(), nil
+}
+
+// replaceCatalogFields replaces the @FIELD@ references in text with the
+// values of fields.
+func replaceCatalogFields(text string, fields map[string]string) string {
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(text, '@')
+		if i < 0 {
+			break
+		}
+		k := strings.IndexByte(text[i+1:], '@')
+		if k < 0 {
+			break
+		}
+		name := text[i+1 : i+1+k]
+		if v, ok := fields[name]; ok {
+			b.WriteString(text[:i])
+			b.WriteString(v)
+			text = text[i+k+2:]
+			continue
+		}
+		// not a reference, the closing @ may open the next one
+		b.WriteString(text[:i+1])
+		text = text[i+1:]
+	}
+	b.WriteString(text)
+	return b.String()
+}
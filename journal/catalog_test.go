@@ -0,0 +1,201 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseMessageID(t *testing.T) {
+	const expected MessageID = "6b09d8d3b3d84c84b2e0d94a0ee8a3b5"
+	for _, s := range []string{"6b09d8d3b3d84c84b2e0d94a0ee8a3b5", "6B09D8D3-B3D8-4C84-B2E0-D94A0EE8A3B5"} {
+		id, err := ParseMessageID(s)
+		if err != nil {
+			t.Errorf("ParseMessageID(%q): %v", s, err)
+		} else if id != expected {
+			t.Errorf("ParseMessageID(%q) = %q, want %q", s, id, expected)
+		}
+	}
+
+	for _, s := range []string{"", "6b09d8d3", "zb09d8d3b3d84c84b2e0d94a0ee8a3b5"} {
+		if _, err := ParseMessageID(s); err == nil {
+			t.Errorf("ParseMessageID(%q): expected an error", s)
+		}
+	}
+}
+
+func TestWriteCatalog(t *testing.T) {
+	entries := []CatalogEntry{
+		{
+			ID:        "6b09d8d3b3d84c84b2e0d94a0ee8a3b5",
+			Subject:   "Backup of @VOLUME@ finished",
+			DefinedBy: "backupd",
+			Support:   "https://example.com/support",
+			Body:      "The backup of volume @VOLUME@ finished successfully.\n",
+		},
+		{
+			ID:       "6b09d8d3b3d84c84b2e0d94a0ee8a3b5",
+			Language: "de",
+			Subject:  "Sicherung von @VOLUME@ abgeschlossen",
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCatalog(&buf, entries); err != nil {
+		t.Fatal(err)
+	}
+	expected := `-- 6b09d8d3b3d84c84b2e0d94a0ee8a3b5
+Subject: Backup of @VOLUME@ finished
+Defined-By: backupd
+Support: https://example.com/support
+
+The backup of volume @VOLUME@ finished successfully.
+
+-- 6b09d8d3b3d84c84b2e0d94a0ee8a3b5 de
+Subject: Sicherung von @VOLUME@ abgeschlossen
+`
+	if buf.String() != expected {
+		t.Errorf("got catalog\n%s\nwant\n%s", buf.String(), expected)
+	}
+
+	invalid := []CatalogEntry{
+		{ID: "bad", Subject: "x"},
+		{ID: "6b09d8d3b3d84c84b2e0d94a0ee8a3b5"},
+		{ID: "6b09d8d3b3d84c84b2e0d94a0ee8a3b5", Subject: "x", Body: "a\n-- b"},
+	}
+	for _, e := range invalid {
+		if err := WriteCatalog(&buf, []CatalogEntry{e}); err == nil {
+			t.Errorf("WriteCatalog(%+v): expected an error", e)
+		}
+	}
+}
+
+const testCatalog = `# comments are ignored
+-- 6b09d8d3b3d84c84b2e0d94a0ee8a3b5
+Subject: Backup of @VOLUME@ finished
+Defined-By: backupd
+X-Unknown: ignored
+
+The backup of volume @VOLUME@ finished
+in @DURATION@ seconds, user@example.com.
+
+-- 6B09D8D3-B3D8-4C84-B2E0-D94A0EE8A3B5 de
+Subject: Sicherung von @VOLUME@ abgeschlossen
+`
+
+func TestReadCatalog(t *testing.T) {
+	entries, err := ReadCatalog(strings.NewReader(testCatalog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []CatalogEntry{
+		{
+			ID:        "6b09d8d3b3d84c84b2e0d94a0ee8a3b5",
+			Subject:   "Backup of @VOLUME@ finished",
+			DefinedBy: "backupd",
+			Body:      "The backup of volume @VOLUME@ finished\nin @DURATION@ seconds, user@example.com.",
+		},
+		{
+			ID:       "6b09d8d3b3d84c84b2e0d94a0ee8a3b5",
+			Language: "de",
+			Subject:  "Sicherung von @VOLUME@ abgeschlossen",
+		},
+	}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("got entries %+v, want %+v", entries, expected)
+	}
+
+	// read back what WriteCatalog writes
+	var buf bytes.Buffer
+	if err := WriteCatalog(&buf, expected); err != nil {
+		t.Fatal(err)
+	}
+	if entries, err := ReadCatalog(&buf); err != nil || !reflect.DeepEqual(entries, expected) {
+		t.Errorf("got entries %+v, %v after writing them", entries, err)
+	}
+
+	for _, invalid := range []string{"text\n-- 6b09d8d3b3d84c84b2e0d94a0ee8a3b5\n", "-- bad\n", "-- 6b09d8d3b3d84c84b2e0d94a0ee8a3b5 de x\n"} {
+		if _, err := ReadCatalog(strings.NewReader(invalid)); err == nil {
+			t.Errorf("ReadCatalog(%q): expected an error", invalid)
+		}
+	}
+}
+
+func TestCatalogLookup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "catalog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	local, system := filepath.Join(dir, "local"), filepath.Join(dir, "system")
+	for path, data := range map[string]string{
+		filepath.Join(system, "backupd.catalog"):    "-- 6b09d8d3b3d84c84b2e0d94a0ee8a3b5\nSubject: masked\n",
+		filepath.Join(local, "backupd.catalog"):     testCatalog,
+		filepath.Join(system, "backupd.fr.catalog"): "-- 6b09d8d3b3d84c84b2e0d94a0ee8a3b5\nSubject: Sauvegarde de @VOLUME@ terminée\n",
+		filepath.Join(system, "README"):             "not a catalog",
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c, err := LoadCatalog(local, system)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields := map[string]string{"MESSAGE_ID": "6b09d8d3b3d84c84b2e0d94a0ee8a3b5", "VOLUME": "/home"}
+	text, err := c.GetCatalog(fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `Subject: Backup of /home finished
+Defined-By: backupd
+
+The backup of volume /home finished
+in @DURATION@ seconds, user@example.com.
+`
+	if text != expected {
+		t.Errorf("got text\n%s\nwant\n%s", text, expected)
+	}
+
+	for language, subject := range map[string]string{
+		"de": "Sicherung von @VOLUME@ abgeschlossen",
+		"fr": "Sauvegarde de @VOLUME@ terminée",
+		"nl": "Backup of @VOLUME@ finished",
+	} {
+		c.Language = language
+		if e, ok := c.Lookup("6b09d8d3b3d84c84b2e0d94a0ee8a3b5"); !ok || e.Subject != subject {
+			t.Errorf("%s: got subject %q, %v", language, e.Subject, ok)
+		}
+	}
+
+	if _, err := c.GetCatalogForMessageID("00000000000000000000000000000000"); err != ErrNoCatalogEntry {
+		t.Errorf("expected ErrNoCatalogEntry, got %v", err)
+	}
+	if _, err := c.GetCatalog(map[string]string{"MESSAGE": "x"}); err != ErrNoCatalogEntry {
+		t.Errorf("expected ErrNoCatalogEntry, got %v", err)
+	}
+}
@@ -92,24 +92,38 @@ func Enabled() bool {
 // (http://www.freedesktop.org/software/systemd/man/systemd.journal-fields.html)
 // for more details.  vars may be nil.
 func Send(message string, priority Priority, vars map[string]string) error {
-	conn := (*net.UnixConn)(atomic.LoadPointer(&unixConnPtr))
-	if conn == nil {
-		return errors.New("could not initialize socket to journald")
-	}
+	return send(journalSocket, message, priority, vars)
+}
 
-	socketAddr := &net.UnixAddr{
-		Name: journalSocket,
-		Net:  "unixgram",
-	}
+// send sends a message to the journal listening on socket.
+func send(socket string, message string, priority Priority, vars map[string]string) error {
+	return sendData(socket, encodeEntry(message, priority, vars))
+}
 
+// encodeEntry serializes a message in the native journal protocol.
+func encodeEntry(message string, priority Priority, vars map[string]string) []byte {
 	data := new(bytes.Buffer)
 	appendVariable(data, "PRIORITY", strconv.Itoa(int(priority)))
 	appendVariable(data, "MESSAGE", message)
 	for k, v := range vars {
 		appendVariable(data, k, v)
 	}
+	return data.Bytes()
+}
 
-	_, _, err := conn.WriteMsgUnix(data.Bytes(), nil, socketAddr)
+// sendData sends a serialized message to the journal listening on socket.
+func sendData(socket string, data []byte) error {
+	conn := (*net.UnixConn)(atomic.LoadPointer(&unixConnPtr))
+	if conn == nil {
+		return errors.New("could not initialize socket to journald")
+	}
+
+	socketAddr := &net.UnixAddr{
+		Name: socket,
+		Net:  "unixgram",
+	}
+
+	_, _, err := conn.WriteMsgUnix(data, nil, socketAddr)
 	if err == nil {
 		return nil
 	}
@@ -117,16 +131,13 @@ func Send(message string, priority Priority, vars map[string]string) error {
 		return err
 	}
 
-	// Large log entry, send it via tempfile and ancillary-fd.
-	file, err := tempFd()
+	// Large log entry, send it via a sealed memfd, or a tempfile if memfds
+	// are not available, and ancillary-fd, as sd_journal_sendv does.
+	file, err := entryFile(data)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
-	_, err = io.Copy(file, data)
-	if err != nil {
-		return err
-	}
 	rights := syscall.UnixRights(int(file.Fd()))
 	_, _, err = conn.WriteMsgUnix([]byte{}, rights, socketAddr)
 	if err != nil {
@@ -195,6 +206,29 @@ func isSocketSpaceError(err error) bool {
 	return sysErr.Err == syscall.EMSGSIZE || sysErr.Err == syscall.ENOBUFS
 }
 
+// entryFile returns a file holding the serialized entry data, either a
+// sealed memfd or, failing that, a temporary file.
+func entryFile(data []byte) (*os.File, error) {
+	if file, err := memfd("journal-entry"); err == nil {
+		if _, err = file.Write(data); err == nil {
+			if err = sealFile(file); err == nil {
+				return file, nil
+			}
+		}
+		file.Close()
+	}
+
+	file, err := tempFd()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
 // tempFd creates a temporary, unlinked file under `/dev/shm`.
 func tempFd() (*os.File, error) {
 	file, err := ioutil.TempFile("/dev/shm/", "journal.XXXXX")
@@ -0,0 +1,47 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func TestEntryFile(t *testing.T) {
+	data := []byte("MESSAGE=hello\n")
+	file, err := entryFile(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadAll(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != string(data) {
+		t.Errorf("got content %q, want %q", content, data)
+	}
+
+	if _, err := memfd("test"); err != nil {
+		t.Skipf("memfd not available: %v", err)
+	}
+	if _, err := file.Write(data); err == nil {
+		t.Error("expected the sealed memfd to reject writes")
+	}
+}
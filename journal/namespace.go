@@ -0,0 +1,68 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"fmt"
+	"os"
+)
+
+// This can be overridden at build-time, like journalSocket.
+var journalRuntimeDir = "/run/systemd"
+
+// maxNamespaceLen is the length of the longest namespace name, which must
+// fit in a file name after a machine ID and a dot.
+const maxNamespaceLen = 255 - 32 - 1
+
+// SendNamespace is like Send, but sends the message to the journal
+// namespace namespace, served by systemd-journald@namespace.service, as
+// the messages of units with LogNamespace=namespace are. See
+// systemd-journald.service(8).
+func SendNamespace(namespace string, message string, priority Priority, vars map[string]string) error {
+	if err := validNamespace(namespace); err != nil {
+		return err
+	}
+	return send(namespaceSocket(namespace, "socket"), message, priority, vars)
+}
+
+// StreamFileNamespace is like StreamFile, but connects to the journal
+// namespace namespace.
+func StreamFileNamespace(namespace string, identifier string, priority Priority, levelPrefix bool) (*os.File, error) {
+	if err := validNamespace(namespace); err != nil {
+		return nil, err
+	}
+	return streamFile(namespaceSocket(namespace, "stdout"), identifier, priority, levelPrefix)
+}
+
+// namespaceSocket returns the path of the socket name of the journald
+// instance serving namespace.
+func namespaceSocket(namespace, name string) string {
+	return journalRuntimeDir + "/journal." + namespace + "/" + name
+}
+
+// validNamespace checks that namespace is a valid journal namespace name:
+// made of letters, digits and the characters ":-_.", as unit instance names
+// are, and usable as a file name.
+func validNamespace(namespace string) error {
+	if namespace == "" || namespace == "." || namespace == ".." || len(namespace) > maxNamespaceLen {
+		return fmt.Errorf("invalid journal namespace %q", namespace)
+	}
+	for _, c := range namespace {
+		if !(('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') || c == ':' || c == '-' || c == '_' || c == '.') {
+			return fmt.Errorf("invalid journal namespace %q", namespace)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidNamespace(t *testing.T) {
+	for _, ns := range []string{"foo", "tenant-1", "a_b.c:d"} {
+		if err := validNamespace(ns); err != nil {
+			t.Errorf("%q: %v", ns, err)
+		}
+	}
+	for _, ns := range []string{"", ".", "..", "a/b", "a*", "a b", strings.Repeat("a", maxNamespaceLen+1)} {
+		if err := validNamespace(ns); err == nil {
+			t.Errorf("%q: expected an error", ns)
+		}
+	}
+}
+
+func TestSendNamespace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.Mkdir(filepath.Join(dir, "journal.tenant"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: filepath.Join(dir, "journal.tenant", "socket"), Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	defer func(dir string) { journalRuntimeDir = dir }(journalRuntimeDir)
+	journalRuntimeDir = dir
+
+	if err := SendNamespace("tenant", "hello", PriInfo, map[string]string{"FOO": "bar"}); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 1024)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{"PRIORITY=6\n", "MESSAGE=hello\n", "FOO=bar\n"} {
+		if !strings.Contains(string(buf[:n]), field) {
+			t.Errorf("%q not in datagram %q", field, buf[:n])
+		}
+	}
+
+	if err := SendNamespace("../tenant", "hello", PriInfo, nil); err == nil {
+		t.Error("expected an error for an invalid namespace")
+	}
+	if _, err := StreamFileNamespace("", "worker", PriInfo, false); err == nil {
+		t.Error("expected an error for an empty namespace")
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.21
+// +build go1.21
+
+package journal
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// HandlerOptions configures a Handler.
+type HandlerOptions struct {
+	// Level is the minimum level of the records sent to the journal,
+	// slog.LevelInfo if nil.
+	Level slog.Leveler
+	// Namespace is the journal namespace records are sent to, see
+	// SendNamespace. If empty, records are sent to the default journal.
+	Namespace string
+}
+
+// Handler is a slog.Handler sending records to the local systemd journal
+// with Send. Record levels are mapped to journal priorities, attributes to
+// journal fields with uppercased names, joined with underscores to the
+// names of their groups, and the CODE_FILE, CODE_LINE and CODE_FUNC fields
+// are set from the source of the record:
+//
+//	slog.SetDefault(slog.New(journal.NewHandler(nil)))
+//	slog.Info("request handled", "status", 200, "path", r.URL.Path)
+//
+// logs the message with the fields STATUS=200 and PATH set.
+type Handler struct {
+	level     slog.Leveler
+	namespace string
+	fields    map[string]string // fields added with WithAttrs
+	prefix    string            // prefix of field names, from WithGroup
+}
+
+// NewHandler returns a Handler configured by opts, which may be nil.
+func NewHandler(opts *HandlerOptions) *Handler {
+	h := &Handler{level: slog.LevelInfo}
+	if opts != nil && opts.Level != nil {
+		h.level = opts.Level
+	}
+	if opts != nil {
+		h.namespace = opts.Namespace
+	}
+	return h
+}
+
+// Enabled reports whether records of level are sent to the journal.
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle sends r to the journal.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	if h.namespace != "" {
+		return SendNamespace(h.namespace, r.Message, levelPriority(r.Level), h.vars(r))
+	}
+	return Send(r.Message, levelPriority(r.Level), h.vars(r))
+}
+
+// vars returns the journal fields for r.
+func (h *Handler) vars(r slog.Record) map[string]string {
+	vars := make(map[string]string, len(h.fields)+r.NumAttrs()+3)
+	for k, v := range h.fields {
+		vars[k] = v
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		addAttr(vars, h.prefix, a)
+		return true
+	})
+
+	if r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		vars["CODE_FILE"] = frame.File
+		vars["CODE_LINE"] = strconv.Itoa(frame.Line)
+		vars["CODE_FUNC"] = frame.Function
+	}
+	return vars
+}
+
+// WithAttrs returns a Handler adding attrs to all records.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.fields = make(map[string]string, len(h.fields)+len(attrs))
+	for k, v := range h.fields {
+		h2.fields[k] = v
+	}
+	for _, a := range attrs {
+		addAttr(h2.fields, h.prefix, a)
+	}
+	return &h2
+}
+
+// WithGroup returns a Handler prefixing the field names of the attributes
+// added later with name.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix = h.prefix + fieldName(name) + "_"
+	return &h2
+}
+
+// levelPriority maps level to a journal priority.
+func levelPriority(level slog.Level) Priority {
+	switch {
+	case level < slog.LevelInfo:
+		return PriDebug
+	case level < slog.LevelWarn:
+		return PriInfo
+	case level < slog.LevelError:
+		return PriWarning
+	default:
+		return PriErr
+	}
+}
+
+// addAttr adds the field for a to vars, or the fields for its attributes if
+// a is a group.
+func addAttr(vars map[string]string, prefix string, a slog.Attr) {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += fieldName(a.Key) + "_"
+		}
+		for _, ga := range v.Group() {
+			addAttr(vars, prefix, ga)
+		}
+		return
+	}
+
+	name := strings.TrimLeft(prefix+fieldName(a.Key), "_")
+	if name == "" {
+		return
+	}
+	vars[name] = v.String()
+}
+
+// fieldName converts key to a valid journal field name: letters are
+// uppercased, and other characters but digits and underscores are replaced
+// with underscores.
+func fieldName(key string) string {
+	return strings.Map(func(c rune) rune {
+		switch {
+		case 'a' <= c && c <= 'z':
+			return c - 'a' + 'A'
+		case 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '_':
+			return c
+		default:
+			return '_'
+		}
+	}, key)
+}
@@ -0,0 +1,83 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.21
+// +build go1.21
+
+package journal
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandlerVars(t *testing.T) {
+	var h slog.Handler = NewHandler(nil)
+	h = h.WithAttrs([]slog.Attr{slog.String("service", "api")})
+	h = h.WithGroup("req")
+
+	pc, _, _, _ := runtime.Caller(0)
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "handled", pc)
+	r.AddAttrs(
+		slog.Int("status", 200),
+		slog.String("user-agent", "curl"),
+		slog.Group("tls", slog.String("version", "1.3")),
+	)
+
+	vars := h.(*Handler).vars(r)
+	if !strings.HasSuffix(vars["CODE_FILE"], "slog_test.go") || vars["CODE_LINE"] == "" || !strings.HasSuffix(vars["CODE_FUNC"], "TestHandlerVars") {
+		t.Errorf("unexpected source fields in %v", vars)
+	}
+	delete(vars, "CODE_FILE")
+	delete(vars, "CODE_LINE")
+	delete(vars, "CODE_FUNC")
+
+	expected := map[string]string{
+		"SERVICE":         "api",
+		"REQ_STATUS":      "200",
+		"REQ_USER_AGENT":  "curl",
+		"REQ_TLS_VERSION": "1.3",
+	}
+	if !reflect.DeepEqual(vars, expected) {
+		t.Errorf("got fields %v, want %v", vars, expected)
+	}
+}
+
+func TestHandlerLevels(t *testing.T) {
+	h := NewHandler(&HandlerOptions{Level: slog.LevelWarn})
+	if h.Enabled(context.Background(), slog.LevelInfo) {
+		t.Error("info records should be disabled")
+	}
+	if !h.Enabled(context.Background(), slog.LevelError) {
+		t.Error("error records should be enabled")
+	}
+
+	tests := map[slog.Level]Priority{
+		slog.LevelDebug:     PriDebug,
+		slog.LevelInfo:      PriInfo,
+		slog.LevelWarn:      PriWarning,
+		slog.LevelError:     PriErr,
+		slog.LevelError + 4: PriErr,
+	}
+	for level, expected := range tests {
+		if p := levelPriority(level); p != expected {
+			t.Errorf("levelPriority(%v) = %d, want %d", level, p, expected)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// This can be overridden at build-time, like journalSocket.
+var journalStdoutSocket = "/run/systemd/journal/stdout"
+
+// StreamFile connects to the stream socket of the local systemd journal, as
+// sd_journal_stream_fd(3) does, and returns the connection as a file. Each
+// line written to it is logged as a message with identifier as
+// SYSLOG_IDENTIFIER and priority; if levelPrefix is set, lines starting
+// with a syslog priority prefix such as "<3>" are logged with that priority
+// instead. The file is meant to be used as the standard output or error of
+// a child process, whose messages are then attributed to the unit of the
+// calling process:
+//
+//	stdout, err := journal.StreamFile("worker", journal.PriInfo, true)
+//	...
+//	defer stdout.Close()
+//	cmd := exec.Command("/usr/bin/worker")
+//	cmd.Stdout = stdout
+//	cmd.Stderr = stdout
+func StreamFile(identifier string, priority Priority, levelPrefix bool) (*os.File, error) {
+	return streamFile(journalStdoutSocket, identifier, priority, levelPrefix)
+}
+
+// streamFile connects to the journal stream socket at socket.
+func streamFile(socket string, identifier string, priority Priority, levelPrefix bool) (*os.File, error) {
+	if strings.ContainsRune(identifier, '\n') {
+		return nil, errors.New("identifier contains a newline")
+	}
+
+	conn, err := net.DialUnix("unix", nil, &net.UnixAddr{Name: socket, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// journald never sends anything on the stream
+	if err := conn.CloseRead(); err != nil {
+		return nil, err
+	}
+
+	// identifier, unit ID, priority, level prefix, forward to syslog, kmsg
+	// and console, one per line
+	header := fmt.Sprintf("%s\n\n%d\n%s\n0\n0\n0\n", identifier, priority, boolHeader(levelPrefix))
+	if _, err := conn.Write([]byte(header)); err != nil {
+		return nil, err
+	}
+
+	return conn.File()
+}
+
+func boolHeader(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
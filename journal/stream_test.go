@@ -0,0 +1,69 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStreamFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	l, err := net.Listen("unix", filepath.Join(dir, "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	defer func(socket string) { journalStdoutSocket = socket }(journalStdoutSocket)
+	journalStdoutSocket = filepath.Join(dir, "stdout")
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			received <- err.Error()
+			return
+		}
+		defer conn.Close()
+		data, _ := ioutil.ReadAll(conn)
+		received <- string(data)
+	}()
+
+	file, err := StreamFile("worker", PriWarning, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.WriteString("hello\n"); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	if data, expected := <-received, "worker\n\n4\n1\n0\n0\n0\nhello\n"; data != expected {
+		t.Errorf("got stream %q, want %q", data, expected)
+	}
+
+	if _, err := StreamFile("bad\nidentifier", PriInfo, false); err == nil {
+		t.Error("expected an error for an identifier with a newline")
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package syslog is a replacement for the standard log/syslog package which
+// sends messages to the local systemd journal with the native protocol of
+// the "journal" package. Severities are kept as journal priorities,
+// facilities as SYSLOG_FACILITY and tags as SYSLOG_IDENTIFIER, so that
+// programs using log/syslog can be moved to the journal by changing their
+// import path:
+//
+//	import "github.com/coreos/go-systemd/v22/journal/syslog"
+//
+//	w, err := syslog.New(syslog.LOG_WARNING|syslog.LOG_DAEMON, "myprog")
+//	...
+//	w.Info("started")
+package syslog
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	stdsyslog "log/syslog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/coreos/go-systemd/v22/journal"
+)
+
+// Priority is a combination of a syslog facility and severity, as in
+// log/syslog.
+type Priority = stdsyslog.Priority
+
+// Severities, the journal priorities.
+const (
+	LOG_EMERG   = stdsyslog.LOG_EMERG
+	LOG_ALERT   = stdsyslog.LOG_ALERT
+	LOG_CRIT    = stdsyslog.LOG_CRIT
+	LOG_ERR     = stdsyslog.LOG_ERR
+	LOG_WARNING = stdsyslog.LOG_WARNING
+	LOG_NOTICE  = stdsyslog.LOG_NOTICE
+	LOG_INFO    = stdsyslog.LOG_INFO
+	LOG_DEBUG   = stdsyslog.LOG_DEBUG
+)
+
+// Facilities.
+const (
+	LOG_KERN     = stdsyslog.LOG_KERN
+	LOG_USER     = stdsyslog.LOG_USER
+	LOG_MAIL     = stdsyslog.LOG_MAIL
+	LOG_DAEMON   = stdsyslog.LOG_DAEMON
+	LOG_AUTH     = stdsyslog.LOG_AUTH
+	LOG_SYSLOG   = stdsyslog.LOG_SYSLOG
+	LOG_LPR      = stdsyslog.LOG_LPR
+	LOG_NEWS     = stdsyslog.LOG_NEWS
+	LOG_UUCP     = stdsyslog.LOG_UUCP
+	LOG_CRON     = stdsyslog.LOG_CRON
+	LOG_AUTHPRIV = stdsyslog.LOG_AUTHPRIV
+	LOG_FTP      = stdsyslog.LOG_FTP
+	LOG_LOCAL0   = stdsyslog.LOG_LOCAL0
+	LOG_LOCAL1   = stdsyslog.LOG_LOCAL1
+	LOG_LOCAL2   = stdsyslog.LOG_LOCAL2
+	LOG_LOCAL3   = stdsyslog.LOG_LOCAL3
+	LOG_LOCAL4   = stdsyslog.LOG_LOCAL4
+	LOG_LOCAL5   = stdsyslog.LOG_LOCAL5
+	LOG_LOCAL6   = stdsyslog.LOG_LOCAL6
+	LOG_LOCAL7   = stdsyslog.LOG_LOCAL7
+)
+
+const (
+	severityMask = 0x07
+	facilityMask = 0xf8
+)
+
+// Writer sends messages to the journal, like the log/syslog Writer. A
+// Writer is safe for concurrent use.
+type Writer struct {
+	priority Priority
+	tag      string
+	send     func(message string, priority journal.Priority, vars map[string]string) error
+}
+
+// New returns a Writer sending messages with the facility and severity of
+// priority and tag as SYSLOG_IDENTIFIER. If tag is empty, the name of the
+// program is used. It returns an error if the journal is not available.
+func New(priority Priority, tag string) (*Writer, error) {
+	if priority < 0 || priority > LOG_LOCAL7|LOG_DEBUG {
+		return nil, errors.New("invalid syslog priority")
+	}
+	if !journal.Enabled() {
+		return nil, errors.New("systemd journal not available")
+	}
+	if tag == "" {
+		tag = filepath.Base(os.Args[0])
+	}
+	return &Writer{priority: priority, tag: tag, send: journal.Send}, nil
+}
+
+// Dial is New for compatibility with log/syslog: network and raddr must be
+// empty, as messages can only be sent to the local journal.
+func Dial(network, raddr string, priority Priority, tag string) (*Writer, error) {
+	if network != "" || raddr != "" {
+		return nil, fmt.Errorf("cannot send to remote syslog server %s %s, only to the local journal", network, raddr)
+	}
+	return New(priority, tag)
+}
+
+// NewLogger returns a log.Logger writing to a Writer created with New, with
+// logFlag as its flags.
+func NewLogger(p Priority, logFlag int) (*log.Logger, error) {
+	w, err := New(p, "")
+	if err != nil {
+		return nil, err
+	}
+	return log.New(w, "", logFlag), nil
+}
+
+// Write sends b as a message with the severity of the Writer.
+func (w *Writer) Write(b []byte) (int, error) {
+	return len(b), w.write(w.priority, string(b))
+}
+
+// Close does nothing, as messages are sent with the connection shared by
+// the journal package.
+func (w *Writer) Close() error {
+	return nil
+}
+
+// Emerg logs a message with severity LOG_EMERG, ignoring the severity of
+// the Writer.
+func (w *Writer) Emerg(m string) error {
+	return w.write(LOG_EMERG, m)
+}
+
+// Alert logs a message with severity LOG_ALERT, ignoring the severity of
+// the Writer.
+func (w *Writer) Alert(m string) error {
+	return w.write(LOG_ALERT, m)
+}
+
+// Crit logs a message with severity LOG_CRIT, ignoring the severity of the
+// Writer.
+func (w *Writer) Crit(m string) error {
+	return w.write(LOG_CRIT, m)
+}
+
+// Err logs a message with severity LOG_ERR, ignoring the severity of the
+// Writer.
+func (w *Writer) Err(m string) error {
+	return w.write(LOG_ERR, m)
+}
+
+// Warning logs a message with severity LOG_WARNING, ignoring the severity
+// of the Writer.
+func (w *Writer) Warning(m string) error {
+	return w.write(LOG_WARNING, m)
+}
+
+// Notice logs a message with severity LOG_NOTICE, ignoring the severity of
+// the Writer.
+func (w *Writer) Notice(m string) error {
+	return w.write(LOG_NOTICE, m)
+}
+
+// Info logs a message with severity LOG_INFO, ignoring the severity of the
+// Writer.
+func (w *Writer) Info(m string) error {
+	return w.write(LOG_INFO, m)
+}
+
+// Debug logs a message with severity LOG_DEBUG, ignoring the severity of
+// the Writer.
+func (w *Writer) Debug(m string) error {
+	return w.write(LOG_DEBUG, m)
+}
+
+// write sends msg with the severity of p and the facility of the Writer.
+func (w *Writer) write(p Priority, msg string) error {
+	vars := map[string]string{
+		"SYSLOG_IDENTIFIER": w.tag,
+		"SYSLOG_FACILITY":   strconv.Itoa(int(w.priority&facilityMask) >> 3),
+		"SYSLOG_PID":        strconv.Itoa(os.Getpid()),
+	}
+	return w.send(strings.TrimSuffix(msg, "\n"), journal.Priority(p&severityMask), vars)
+}
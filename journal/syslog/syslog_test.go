@@ -0,0 +1,76 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syslog
+
+import (
+	"log"
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/coreos/go-systemd/v22/journal"
+)
+
+type sent struct {
+	message  string
+	priority journal.Priority
+	vars     map[string]string
+}
+
+func testWriter(p Priority, tag string) (*Writer, *[]sent) {
+	var messages []sent
+	w := &Writer{priority: p, tag: tag}
+	w.send = func(message string, priority journal.Priority, vars map[string]string) error {
+		messages = append(messages, sent{message, priority, vars})
+		return nil
+	}
+	return w, &messages
+}
+
+func TestWriter(t *testing.T) {
+	w, messages := testWriter(LOG_WARNING|LOG_DAEMON, "myprog")
+
+	if n, err := w.Write([]byte("written\n")); err != nil || n != 8 {
+		t.Errorf("Write: got %d, %v", n, err)
+	}
+	w.Err("failed")
+	w.Debug("details")
+	log.New(w, "", 0).Printf("from %s", "log")
+
+	vars := map[string]string{
+		"SYSLOG_IDENTIFIER": "myprog",
+		"SYSLOG_FACILITY":   "3",
+		"SYSLOG_PID":        strconv.Itoa(os.Getpid()),
+	}
+	expected := []sent{
+		{"written", journal.PriWarning, vars},
+		{"failed", journal.PriErr, vars},
+		{"details", journal.PriDebug, vars},
+		{"from log", journal.PriWarning, vars},
+	}
+	if !reflect.DeepEqual(*messages, expected) {
+		t.Errorf("got messages %+v, want %+v", *messages, expected)
+	}
+}
+
+func TestDial(t *testing.T) {
+	if _, err := Dial("udp", "localhost:514", LOG_INFO, "myprog"); err == nil {
+		t.Error("expected an error for a remote server")
+	}
+	if _, err := New(-1, "myprog"); err == nil {
+		t.Error("expected an error for an invalid priority")
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journalfile
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/big"
+)
+
+// FSPRG is the forward-secure pseudorandom generator journald derives the
+// keys of the sealing tags from, see src/libsystemd/sd-journal/fsprg.c in
+// the systemd sources. Its parameters are derived from the seed of the
+// verification key, with the recommended security parameter only.
+const (
+	fsprgSecpar  = 1536
+	fsprgSeedLen = 96 / 8
+
+	fsprgGenP = 1
+	fsprgGenQ = 2
+	fsprgGenX = 3
+)
+
+// fsprg holds the secret parameters generated from a seed, from which the
+// state of any epoch can be computed.
+type fsprg struct {
+	p, q, n *big.Int
+	x       *big.Int // the state of epoch 0
+}
+
+func newFSPRG(seed []byte) *fsprg {
+	g := &fsprg{
+		p: genPrime3Mod4(fsprgSecpar/2, seed, fsprgGenP),
+		q: genPrime3Mod4(fsprgSecpar/2, seed, fsprgGenQ),
+	}
+	g.n = new(big.Int).Mul(g.p, g.q)
+
+	// a quadratic residue modulo n
+	buf := detRandomize(fsprgSecpar/8, seed, fsprgGenX)
+	buf[0] &= 0x7f
+	x := new(big.Int).SetBytes(buf)
+	g.x = x.Mul(x, x).Mod(x, g.n)
+	return g
+}
+
+// key returns the HMAC key of epoch, x^(2^epoch) mod n computed with the
+// Chinese remainder theorem.
+func (g *fsprg) key(epoch uint64) []byte {
+	two := big.NewInt(2)
+	e := new(big.Int).SetUint64(epoch)
+	one := big.NewInt(1)
+
+	xp := new(big.Int).Mod(g.x, g.p)
+	kp := new(big.Int).Exp(two, e, new(big.Int).Sub(g.p, one))
+	xp.Exp(xp, kp, g.p)
+
+	xq := new(big.Int).Mod(g.x, g.q)
+	kq := new(big.Int).Exp(two, e, new(big.Int).Sub(g.q, one))
+	xq.Exp(xq, kq, g.q)
+
+	// x = p * ((xq - xp) / p mod q) + xp
+	a := new(big.Int).Sub(xq, xp)
+	a.Mod(a, g.q)
+	a.Mul(a, new(big.Int).ModInverse(g.p, g.q))
+	a.Mod(a, g.q)
+	x := a.Mul(a, g.p).Add(a, xp)
+
+	// the serialized state, without its secpar header: n, x and epoch
+	state := make([]byte, 2*fsprgSecpar/8+8)
+	mpiExport(state[:fsprgSecpar/8], g.n)
+	mpiExport(state[fsprgSecpar/8:2*fsprgSecpar/8], x)
+	binary.BigEndian.PutUint64(state[2*fsprgSecpar/8:], epoch)
+
+	return detRandomize(256/8, state, 0)
+}
+
+// genPrime3Mod4 deterministically generates a prime of bits bits, equal to
+// 3 modulo 4, from seed and idx.
+func genPrime3Mod4(bits int, seed []byte, idx uint32) *big.Int {
+	buf := detRandomize(bits/8, seed, idx)
+	buf[0] |= 0xc0
+	buf[len(buf)-1] |= 0x03
+
+	p := new(big.Int).SetBytes(buf)
+	four := big.NewInt(4)
+	for !p.ProbablyPrime(20) {
+		p.Add(p, four)
+	}
+	return p
+}
+
+// detRandomize deterministically generates n pseudorandom bytes from seed
+// and idx, by hashing them with a counter.
+func detRandomize(n int, seed []byte, idx uint32) []byte {
+	out := make([]byte, 0, n+sha256.Size)
+	var b [4]byte
+	for ctr := uint32(0); len(out) < n; ctr++ {
+		h := sha256.New()
+		h.Write(seed)
+		binary.BigEndian.PutUint32(b[:], idx)
+		h.Write(b[:])
+		binary.BigEndian.PutUint32(b[:], ctr)
+		h.Write(b[:])
+		out = h.Sum(out)
+	}
+	return out[:n]
+}
+
+// mpiExport writes x to buf in big-endian order, padded with zeros.
+func mpiExport(buf []byte, x *big.Int) {
+	b := x.Bytes()
+	for i := range buf[:len(buf)-len(b)] {
+		buf[i] = 0
+	}
+	copy(buf[len(buf)-len(b):], b)
+}
@@ -0,0 +1,417 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package journalfile reads systemd journal files directly, without the
+// sd-journal C library. It is meant for programs that cannot use cgo, such
+// as static binaries; its API follows the one of the "sdjournal" package.
+//
+// Data compressed with LZ4 or zstd is supported. Data compressed with XZ,
+// which journald uses depending on how systemd was built, cannot be read;
+// reading entries containing such data fails with ErrUnsupportedCompression.
+//
+// Files sealed with Forward Secure Sealing can be verified with Verify.
+//
+// The format is described in
+// https://systemd.io/JOURNAL_FILE_FORMAT/
+package journalfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/coreos/go-systemd/v22/internal/lz4"
+	"github.com/coreos/go-systemd/v22/internal/zstd"
+)
+
+var (
+	// ErrUnsupportedCompression is returned when reading data compressed
+	// with an algorithm other than LZ4.
+	ErrUnsupportedCompression = errors.New("unsupported journal compression")
+	// ErrNoEntry is returned when reading entry data while the read
+	// pointer is not at an entry.
+	ErrNoEntry = errors.New("no journal entry at the read pointer")
+)
+
+const (
+	headerMinSize = 208 // up to and including tail_entry_monotonic
+	objectHeader  = 16
+
+	incompatibleCompressedXZ   = 1 << 0
+	incompatibleCompressedLZ4  = 1 << 1
+	incompatibleKeyedHash      = 1 << 2
+	incompatibleCompressedZSTD = 1 << 3
+	incompatibleCompact        = 1 << 4
+	incompatibleSupported      = incompatibleCompressedXZ | incompatibleCompressedLZ4 |
+		incompatibleKeyedHash | incompatibleCompressedZSTD | incompatibleCompact
+
+	objectData       = 1
+	objectField      = 2
+	objectEntry      = 3
+	objectEntryArray = 6
+
+	objectCompressedXZ   = 1 << 0
+	objectCompressedLZ4  = 1 << 1
+	objectCompressedZSTD = 1 << 2
+
+	// maxObjectSize bounds the objects read, to protect against corrupt
+	// sizes.
+	maxObjectSize = 1 << 30
+)
+
+var signature = []byte("LPKSHHRH")
+
+// JournalEntry represents all fields of a journal entry plus address fields,
+// like sdjournal.JournalEntry.
+type JournalEntry struct {
+	Fields             map[string]string
+	Cursor             string
+	RealtimeTimestamp  uint64
+	MonotonicTimestamp uint64
+}
+
+// Journal is a journal file open for reading. A Journal is not safe for
+// concurrent use by multiple goroutines.
+type Journal struct {
+	f       *os.File
+	compact bool
+
+	seqnumID string
+	entries  []uint64 // offsets of the entry objects, in seqnum order
+
+	fieldHashTable     uint64 // offset of the field hash table items
+	fieldHashTableSize uint64 // size of the field hash table items, in bytes
+	nObjects           uint64
+
+	// pos is the index in entries of the entry at the read pointer; -1
+	// before the first entry and len(entries) after the last one
+	pos int
+}
+
+// Open opens the journal file at path, such as
+// /var/log/journal/<machine-id>/system.journal. The read pointer is
+// positioned before the first entry.
+func Open(path string) (*Journal, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	j := &Journal{f: f, pos: -1}
+	if err := j.readHeader(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to read journal file %q: %v", path, err)
+	}
+	return j, nil
+}
+
+// Close closes the journal file.
+func (j *Journal) Close() error {
+	return j.f.Close()
+}
+
+func (j *Journal) readHeader() error {
+	h := make([]byte, headerMinSize)
+	if _, err := j.f.ReadAt(h, 0); err != nil {
+		return err
+	}
+	if !bytes.Equal(h[:8], signature) {
+		return errors.New("not a journal file")
+	}
+
+	incompatible := le32(h[12:])
+	if incompatible&^incompatibleSupported != 0 {
+		return fmt.Errorf("unsupported incompatible flags %#x", incompatible)
+	}
+	j.compact = incompatible&incompatibleCompact != 0
+	j.seqnumID = hex.EncodeToString(h[72:88])
+	j.fieldHashTable = le64(h[120:])
+	j.fieldHashTableSize = le64(h[128:])
+	j.nObjects = le64(h[144:])
+
+	nEntries := le64(h[152:])
+	arrayOffset := le64(h[176:])
+	return j.readEntryArrays(arrayOffset, nEntries)
+}
+
+// readEntryArrays collects the offsets of the n entries listed in the chain of
+// entry array objects starting at offset.
+func (j *Journal) readEntryArrays(offset uint64, n uint64) error {
+	itemSize := uint64(8)
+	if j.compact {
+		itemSize = 4
+	}
+
+	for offset != 0 && uint64(len(j.entries)) < n {
+		o, err := j.readObject(offset, objectEntryArray)
+		if err != nil {
+			return err
+		}
+		if len(o) < objectHeader+8 {
+			return errors.New("truncated entry array object")
+		}
+
+		items := o[objectHeader+8:]
+		for i := uint64(0); i+itemSize <= uint64(len(items)) && uint64(len(j.entries)) < n; i += itemSize {
+			var p uint64
+			if j.compact {
+				p = uint64(le32(items[i:]))
+			} else {
+				p = le64(items[i:])
+			}
+			if p == 0 {
+				// unused items at the end of the last array
+				break
+			}
+			j.entries = append(j.entries, p)
+		}
+		offset = le64(o[objectHeader:])
+	}
+	return nil
+}
+
+// readObject reads the object at offset, checking that it has type typ.
+func (j *Journal) readObject(offset uint64, typ byte) ([]byte, error) {
+	h := make([]byte, objectHeader)
+	if _, err := j.f.ReadAt(h, int64(offset)); err != nil {
+		return nil, err
+	}
+	if h[0] != typ {
+		return nil, fmt.Errorf("object at %d has type %d, want %d", offset, h[0], typ)
+	}
+	size := le64(h[8:])
+	if size < objectHeader || size > maxObjectSize {
+		return nil, fmt.Errorf("object at %d has invalid size %d", offset, size)
+	}
+
+	o := make([]byte, size)
+	if _, err := j.f.ReadAt(o, int64(offset)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return o, nil
+}
+
+// Next advances the read pointer by one entry. It returns 0 if there are no
+// more entries, and 1 otherwise.
+func (j *Journal) Next() (uint64, error) {
+	if j.pos >= len(j.entries)-1 {
+		j.pos = len(j.entries)
+		return 0, nil
+	}
+	j.pos++
+	return 1, nil
+}
+
+// Previous sets back the read pointer by one entry. It returns 0 if there
+// are no more entries, and 1 otherwise.
+func (j *Journal) Previous() (uint64, error) {
+	if j.pos <= 0 {
+		j.pos = -1
+		return 0, nil
+	}
+	j.pos--
+	return 1, nil
+}
+
+// SeekHead seeks to the beginning of the journal: Next moves to the oldest
+// entry.
+func (j *Journal) SeekHead() error {
+	j.pos = -1
+	return nil
+}
+
+// SeekTail seeks to the end of the journal: Previous moves to the most
+// recent entry.
+func (j *Journal) SeekTail() error {
+	j.pos = len(j.entries)
+	return nil
+}
+
+// entry holds the decoded entry object at the read pointer.
+type entry struct {
+	seqnum    uint64
+	realtime  uint64
+	monotonic uint64
+	bootID    string
+	xorHash   uint64
+	data      []uint64 // offsets of the data objects
+}
+
+func (j *Journal) readEntry() (*entry, error) {
+	if j.pos < 0 || j.pos >= len(j.entries) {
+		return nil, ErrNoEntry
+	}
+
+	offset := j.entries[j.pos]
+	o, err := j.readObject(offset, objectEntry)
+	if err != nil {
+		return nil, err
+	}
+	if len(o) < 64 {
+		return nil, fmt.Errorf("truncated entry object at %d", offset)
+	}
+
+	e := &entry{
+		seqnum:    le64(o[16:]),
+		realtime:  le64(o[24:]),
+		monotonic: le64(o[32:]),
+		bootID:    hex.EncodeToString(o[40:56]),
+		xorHash:   le64(o[56:]),
+	}
+	items := o[64:]
+	if j.compact {
+		for i := 0; i+4 <= len(items); i += 4 {
+			e.data = append(e.data, uint64(le32(items[i:])))
+		}
+	} else {
+		for i := 0; i+16 <= len(items); i += 16 {
+			e.data = append(e.data, le64(items[i:]))
+		}
+	}
+	return e, nil
+}
+
+// readData returns the payload of the data object at offset, a FIELD=value
+// pair, decompressing it if needed.
+func (j *Journal) readData(offset uint64) ([]byte, error) {
+	o, err := j.readObject(offset, objectData)
+	if err != nil {
+		return nil, err
+	}
+
+	start := 64
+	if j.compact {
+		start = 72
+	}
+	if len(o) < start {
+		return nil, fmt.Errorf("truncated data object at %d", offset)
+	}
+	payload := o[start:]
+
+	switch flags := o[1]; {
+	case flags&objectCompressedLZ4 != 0:
+		if len(payload) < 8 {
+			return nil, fmt.Errorf("truncated data object at %d", offset)
+		}
+		size := le64(payload)
+		if size > maxObjectSize {
+			return nil, fmt.Errorf("data object at %d has invalid size %d", offset, size)
+		}
+		return lz4.Decompress(payload[8:], int(size))
+	case flags&objectCompressedZSTD != 0:
+		data, err := zstd.Decompress(payload, maxObjectSize)
+		if err != nil {
+			return nil, fmt.Errorf("data object at %d: %v", offset, err)
+		}
+		return data, nil
+	case flags&objectCompressedXZ != 0:
+		return nil, ErrUnsupportedCompression
+	default:
+		return payload, nil
+	}
+}
+
+// GetDataBytes returns the FIELD=value data of the entry at the read pointer
+// for field, as sdjournal.Journal.GetDataBytes does.
+func (j *Journal) GetDataBytes(field string) ([]byte, error) {
+	e, err := j.readEntry()
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := []byte(field + "=")
+	for _, offset := range e.data {
+		d, err := j.readData(offset)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.HasPrefix(d, prefix) {
+			return d, nil
+		}
+	}
+	return nil, fmt.Errorf("field %q not found in journal entry", field)
+}
+
+// GetData returns the FIELD=value data of the entry at the read pointer for
+// field.
+func (j *Journal) GetData(field string) (string, error) {
+	d, err := j.GetDataBytes(field)
+	if err != nil {
+		return "", err
+	}
+	return string(d), nil
+}
+
+// GetDataValue is like GetData, but returns the value of the field only.
+func (j *Journal) GetDataValue(field string) (string, error) {
+	d, err := j.GetDataBytes(field)
+	if err != nil {
+		return "", err
+	}
+	return string(d[len(field)+1:]), nil
+}
+
+// GetEntry returns a full representation of the entry at the read pointer.
+func (j *Journal) GetEntry() (*JournalEntry, error) {
+	e, err := j.readEntry()
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &JournalEntry{
+		Fields:             make(map[string]string, len(e.data)),
+		RealtimeTimestamp:  e.realtime,
+		MonotonicTimestamp: e.monotonic,
+	}
+	for _, offset := range e.data {
+		d, err := j.readData(offset)
+		if err != nil {
+			return nil, err
+		}
+		kv := strings.SplitN(string(d), "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("malformed journal data at %d", offset)
+		}
+		entry.Fields[kv[0]] = kv[1]
+	}
+	entry.Cursor = fmt.Sprintf("s=%s;i=%x;b=%s;m=%x;t=%x;x=%x",
+		j.seqnumID, e.seqnum, e.bootID, e.monotonic, e.realtime, e.xorHash)
+	return entry, nil
+}
+
+// GetRealtimeUsec returns the realtime (wallclock) timestamp of the entry at
+// the read pointer, in microseconds since the epoch.
+func (j *Journal) GetRealtimeUsec() (uint64, error) {
+	e, err := j.readEntry()
+	if err != nil {
+		return 0, err
+	}
+	return e.realtime, nil
+}
+
+func le32(b []byte) uint32 {
+	return binary.LittleEndian.Uint32(b)
+}
+
+func le64(b []byte) uint64 {
+	return binary.LittleEndian.Uint64(b)
+}
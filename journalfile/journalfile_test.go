@@ -0,0 +1,262 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journalfile
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// fileBuilder builds journal files for tests.
+type fileBuilder struct {
+	buf     []byte
+	compact bool
+
+	seqnumID byte // first byte of the sequence number ID of the file
+	bootID   byte // first byte of the boot ID of the entries added
+}
+
+func newFileBuilder(compact bool) *fileBuilder {
+	return &fileBuilder{buf: make([]byte, 272), compact: compact}
+}
+
+// add appends an object and returns its offset.
+func (b *fileBuilder) add(typ byte, flags byte, body []byte) uint64 {
+	for len(b.buf)%8 != 0 {
+		b.buf = append(b.buf, 0)
+	}
+	offset := uint64(len(b.buf))
+	h := make([]byte, objectHeader)
+	h[0], h[1] = typ, flags
+	binary.LittleEndian.PutUint64(h[8:], uint64(objectHeader+len(body)))
+	b.buf = append(b.buf, h...)
+	b.buf = append(b.buf, body...)
+	return offset
+}
+
+func (b *fileBuilder) addData(flags byte, payload []byte) uint64 {
+	n := 48
+	if b.compact {
+		n = 56
+	}
+	return b.add(objectData, flags, append(make([]byte, n), payload...))
+}
+
+func (b *fileBuilder) addEntry(seqnum, realtime uint64, data ...uint64) uint64 {
+	body := make([]byte, 48)
+	binary.LittleEndian.PutUint64(body[0:], seqnum)
+	binary.LittleEndian.PutUint64(body[8:], realtime)
+	binary.LittleEndian.PutUint64(body[16:], realtime/2)
+	body[24] = b.bootID
+	for _, d := range data {
+		if b.compact {
+			body = appendUint32(body, uint32(d))
+		} else {
+			body = appendUint64(body, d)
+			body = appendUint64(body, 0)
+		}
+	}
+	return b.add(objectEntry, 0, body)
+}
+
+func (b *fileBuilder) addEntryArray(next uint64, entries ...uint64) uint64 {
+	body := make([]byte, 8)
+	binary.LittleEndian.PutUint64(body, next)
+	// a spare item, as journald preallocates arrays
+	for _, e := range append(entries, 0) {
+		if b.compact {
+			body = appendUint32(body, uint32(e))
+		} else {
+			body = appendUint64(body, e)
+		}
+	}
+	return b.add(objectEntryArray, 0, body)
+}
+
+// write writes the file to dir, with the given number of entries and offset
+// of the first entry array.
+func (b *fileBuilder) write(t *testing.T, dir string, nEntries uint64, arrayOffset uint64) string {
+	copy(b.buf, signature)
+	if b.compact {
+		binary.LittleEndian.PutUint32(b.buf[12:], incompatibleCompact)
+	}
+	b.buf[72] = b.seqnumID
+	binary.LittleEndian.PutUint64(b.buf[88:], 272)
+	binary.LittleEndian.PutUint64(b.buf[152:], nEntries)
+	binary.LittleEndian.PutUint64(b.buf[176:], arrayOffset)
+
+	path := filepath.Join(dir, "system.journal")
+	if err := ioutil.WriteFile(path, b.buf, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "journalfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+// lz4Literals returns an LZ4 block holding data as literals, prefixed with
+// its size as journald stores it.
+func lz4Literals(data []byte) []byte {
+	out := make([]byte, 8)
+	binary.LittleEndian.PutUint64(out, uint64(len(data)))
+	if len(data) < 15 {
+		out = append(out, byte(len(data))<<4)
+	} else {
+		out = append(out, 15<<4)
+		n := len(data) - 15
+		for ; n >= 255; n -= 255 {
+			out = append(out, 255)
+		}
+		out = append(out, byte(n))
+	}
+	return append(out, data...)
+}
+
+// zstdMessage is "MESSAGE=second" as written by the zstd command line tool.
+var zstdMessage = []byte{
+	0x28, 0xb5, 0x2f, 0xfd, 0x04, 0x68, 0x71, 0x00, 0x00, 0x4d, 0x45, 0x53,
+	0x53, 0x41, 0x47, 0x45, 0x3d, 0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x3a,
+	0x6f, 0xdd, 0xf5,
+}
+
+func testJournal(t *testing.T, compact bool) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	b := newFileBuilder(compact)
+	msg1 := b.addData(0, []byte("MESSAGE=first"))
+	unit := b.addData(objectCompressedLZ4, lz4Literals([]byte("_SYSTEMD_UNIT=foo.service")))
+	msg2 := b.addData(objectCompressedZSTD, zstdMessage)
+	e1 := b.addEntry(1, 1000, msg1, unit)
+	e2 := b.addEntry(2, 2000, msg2, unit)
+	e3 := b.addEntry(3, 3000, msg1)
+	// entries are split across two chained arrays
+	second := b.addEntryArray(0, e3)
+	first := b.addEntryArray(second, e1, e2)
+
+	j, err := Open(b.write(t, dir, 3, first))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+
+	var messages []string
+	for {
+		n, err := j.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n == 0 {
+			break
+		}
+		m, err := j.GetDataValue("MESSAGE")
+		if err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, m)
+	}
+	if expected := []string{"first", "second", "first"}; !reflect.DeepEqual(messages, expected) {
+		t.Errorf("got messages %v, want %v", messages, expected)
+	}
+
+	if err := j.SeekTail(); err != nil {
+		t.Fatal(err)
+	}
+	j.Previous()
+	j.Previous()
+	entry, err := j.GetEntry()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"MESSAGE": "second", "_SYSTEMD_UNIT": "foo.service"}
+	if !reflect.DeepEqual(entry.Fields, expected) {
+		t.Errorf("got fields %v, want %v", entry.Fields, expected)
+	}
+	if entry.RealtimeTimestamp != 2000 || entry.MonotonicTimestamp != 1000 {
+		t.Errorf("unexpected timestamps in %+v", entry)
+	}
+
+	if err := j.SeekHead(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.GetEntry(); err != ErrNoEntry {
+		t.Errorf("got error %v before the first entry, want %v", err, ErrNoEntry)
+	}
+}
+
+func TestJournal(t *testing.T) {
+	testJournal(t, false)
+}
+
+func TestJournalCompact(t *testing.T) {
+	testJournal(t, true)
+}
+
+func TestJournalUnsupportedCompression(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	b := newFileBuilder(false)
+	data := b.addData(objectCompressedXZ, []byte("garbage"))
+	e := b.addEntry(1, 1000, data)
+	array := b.addEntryArray(0, e)
+
+	j, err := Open(b.write(t, dir, 1, array))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+
+	j.Next()
+	if _, err := j.GetEntry(); err != ErrUnsupportedCompression {
+		t.Errorf("got error %v, want %v", err, ErrUnsupportedCompression)
+	}
+}
+
+func TestOpenInvalid(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	b := newFileBuilder(false)
+	path := b.write(t, dir, 0, 0)
+	if err := ioutil.WriteFile(path, make([]byte, 272), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path); err == nil {
+		t.Error("expected an error for a file without signature")
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journalfile
+
+import (
+	"errors"
+)
+
+// Merged reads several journal files as one journal, with their entries
+// interleaved like sd-journal does when opening a directory. Entries are
+// read as the read pointer moves. A Merged is not safe for concurrent use by
+// multiple goroutines.
+type Merged struct {
+	files []*mergedFile
+
+	// cur is the index in files of the file holding the entry at the read
+	// pointer, or -1 before the first entry and after the last one
+	cur int
+}
+
+// mergedFile is a file of a Merged.
+type mergedFile struct {
+	*Journal
+
+	// n is the number of entries of the file at or before the read
+	// pointer: the entry at the read pointer is n-1 if the file holds it,
+	// and the next entry is n
+	n int
+
+	// last caches the most recently read entry, at index lastPos
+	last    *entry
+	lastPos int
+}
+
+// OpenMerged opens the journal files at paths as one journal. The read
+// pointer is positioned before the first entry.
+func OpenMerged(paths ...string) (*Merged, error) {
+	if len(paths) == 0 {
+		return nil, errors.New("no journal files to open")
+	}
+
+	m := &Merged{cur: -1}
+	for _, path := range paths {
+		j, err := Open(path)
+		if err != nil {
+			m.Close()
+			return nil, err
+		}
+		m.files = append(m.files, &mergedFile{Journal: j, lastPos: -1})
+	}
+	return m, nil
+}
+
+// OpenDirs opens the journal files in dirs, as listed by DirFiles, as one
+// journal, for example the system journal together with the journals of
+// mounted images.
+func OpenDirs(dirs ...string) (*Merged, error) {
+	var paths []string
+	for _, dir := range dirs {
+		files, err := DirFiles(dir)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, files...)
+	}
+	return OpenMerged(paths...)
+}
+
+// Close closes the journal files.
+func (m *Merged) Close() error {
+	var err error
+	for _, j := range m.files {
+		if cerr := j.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// entryAt returns the entry of f at index pos.
+func (f *mergedFile) entryAt(pos int) (*entry, error) {
+	if f.last == nil || f.lastPos != pos {
+		f.pos = pos
+		e, err := f.readEntry()
+		if err != nil {
+			return nil, err
+		}
+		f.last, f.lastPos = e, pos
+	}
+	return f.last, nil
+}
+
+// compareEntries orders entry a of file fa and entry b of file fb as
+// sd-journal does: by sequence number if the files share their sequence
+// number ID, then by monotonic time if the entries are of the same boot,
+// then by realtime.
+func compareEntries(fa *mergedFile, a *entry, fb *mergedFile, b *entry) int {
+	if fa.seqnumID == fb.seqnumID {
+		if c := compareUint64(a.seqnum, b.seqnum); c != 0 {
+			return c
+		}
+	}
+	if a.bootID == b.bootID {
+		if c := compareUint64(a.monotonic, b.monotonic); c != 0 {
+			return c
+		}
+	}
+	if c := compareUint64(a.realtime, b.realtime); c != 0 {
+		return c
+	}
+	return compareUint64(a.xorHash, b.xorHash)
+}
+
+func compareUint64(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Next advances the read pointer by one entry. It returns 0 if there are no
+// more entries, and 1 otherwise.
+func (m *Merged) Next() (uint64, error) {
+	// the next entry is the first one of those following the read pointer
+	// in each file, the earlier file winning ties
+	next, nextEntry := -1, (*entry)(nil)
+	for i, f := range m.files {
+		if f.n >= len(f.entries) {
+			continue
+		}
+		e, err := f.entryAt(f.n)
+		if err != nil {
+			return 0, err
+		}
+		if next < 0 || compareEntries(f, e, m.files[next], nextEntry) < 0 {
+			next, nextEntry = i, e
+		}
+	}
+
+	m.cur = next
+	if next < 0 {
+		return 0, nil
+	}
+	m.files[next].n++
+	return 1, nil
+}
+
+// Previous sets back the read pointer by one entry. It returns 0 if there
+// are no more entries, and 1 otherwise.
+func (m *Merged) Previous() (uint64, error) {
+	if m.cur >= 0 {
+		m.files[m.cur].n--
+	}
+
+	// the previous entry is the last one of those preceding the read
+	// pointer in each file, the later file winning ties
+	prev, prevEntry := -1, (*entry)(nil)
+	for i, f := range m.files {
+		if f.n == 0 {
+			continue
+		}
+		e, err := f.entryAt(f.n - 1)
+		if err != nil {
+			return 0, err
+		}
+		if prev < 0 || compareEntries(f, e, m.files[prev], prevEntry) >= 0 {
+			prev, prevEntry = i, e
+		}
+	}
+
+	m.cur = prev
+	if prev < 0 {
+		return 0, nil
+	}
+	return 1, nil
+}
+
+// SeekHead seeks to the beginning of the journal: Next moves to the oldest
+// entry.
+func (m *Merged) SeekHead() error {
+	for _, f := range m.files {
+		f.n = 0
+	}
+	m.cur = -1
+	return nil
+}
+
+// SeekTail seeks to the end of the journal: Previous moves to the most
+// recent entry.
+func (m *Merged) SeekTail() error {
+	for _, f := range m.files {
+		f.n = len(f.entries)
+	}
+	m.cur = -1
+	return nil
+}
+
+// current returns the file of the entry at the read pointer, with its read
+// pointer at that entry.
+func (m *Merged) current() (*mergedFile, error) {
+	if m.cur < 0 {
+		return nil, ErrNoEntry
+	}
+	f := m.files[m.cur]
+	f.pos = f.n - 1
+	return f, nil
+}
+
+// GetData returns the FIELD=value data of the entry at the read pointer for
+// field.
+func (m *Merged) GetData(field string) (string, error) {
+	f, err := m.current()
+	if err != nil {
+		return "", err
+	}
+	return f.GetData(field)
+}
+
+// GetDataValue is like GetData, but returns the value of the field only.
+func (m *Merged) GetDataValue(field string) (string, error) {
+	f, err := m.current()
+	if err != nil {
+		return "", err
+	}
+	return f.GetDataValue(field)
+}
+
+// GetEntry returns a full representation of the entry at the read pointer.
+func (m *Merged) GetEntry() (*JournalEntry, error) {
+	f, err := m.current()
+	if err != nil {
+		return nil, err
+	}
+	return f.GetEntry()
+}
+
+// GetRealtimeUsec returns the realtime (wallclock) timestamp of the entry at
+// the read pointer, in microseconds since the epoch.
+func (m *Merged) GetRealtimeUsec() (uint64, error) {
+	f, err := m.current()
+	if err != nil {
+		return 0, err
+	}
+	e, err := f.entryAt(f.pos)
+	if err != nil {
+		return 0, err
+	}
+	return e.realtime, nil
+}
@@ -0,0 +1,173 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journalfile
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeMessages writes a journal file to dir with an entry per message, at
+// the given times.
+func writeMessages(t *testing.T, dir string, messages []string, times []uint64) string {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	b := newFileBuilder(false)
+	var entries []uint64
+	for i, m := range messages {
+		data := b.addData(0, []byte("MESSAGE="+m))
+		entries = append(entries, b.addEntry(uint64(i+1), times[i], data))
+	}
+	array := b.addEntryArray(0, entries...)
+	return b.write(t, dir, uint64(len(entries)), array)
+}
+
+func TestOpenDirs(t *testing.T) {
+	root, cleanup := tempDir(t)
+	defer cleanup()
+
+	system := filepath.Join(root, "system")
+	image := filepath.Join(root, "image")
+	writeMessages(t, filepath.Join(system, "8cf7ed9d451ea194b77a9f02ee95f549"), []string{"a", "c", "e"}, []uint64{1000, 3000, 5000})
+	writeMessages(t, image, []string{"b", "d", "f"}, []uint64{2000, 4000, 5000})
+	// another namespace is not opened
+	writeMessages(t, filepath.Join(system, "8cf7ed9d451ea194b77a9f02ee95f549.tenant"), []string{"x"}, []uint64{1500})
+
+	m, err := OpenDirs(system, image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	var messages []string
+	for {
+		n, err := m.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n == 0 {
+			break
+		}
+		msg, err := m.GetDataValue("MESSAGE")
+		if err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, msg)
+	}
+	if expected := []string{"a", "b", "c", "d", "e", "f"}; !reflect.DeepEqual(messages, expected) {
+		t.Errorf("got messages %v, want %v", messages, expected)
+	}
+
+	if err := m.SeekTail(); err != nil {
+		t.Fatal(err)
+	}
+	m.Previous()
+	m.Previous()
+	entry, err := m.GetEntry()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Fields["MESSAGE"] != "e" || entry.RealtimeTimestamp != 5000 {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+	if usec, err := m.GetRealtimeUsec(); err != nil || usec != 5000 {
+		t.Errorf("got time %d, %v", usec, err)
+	}
+
+	if err := m.SeekHead(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.GetEntry(); err != ErrNoEntry {
+		t.Errorf("got error %v before the first entry, want %v", err, ErrNoEntry)
+	}
+
+	if _, err := OpenDirs(filepath.Join(root, "missing")); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
+
+func TestOpenMergedOrder(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	// write writes a journal file to dir/name with an entry per message
+	// and its sequence number and time
+	write := func(name string, seqnumID, bootID byte, messages []string, seqnums, times []uint64) string {
+		b := newFileBuilder(false)
+		b.seqnumID, b.bootID = seqnumID, bootID
+		var entries []uint64
+		for i, m := range messages {
+			data := b.addData(0, []byte("MESSAGE="+m))
+			entries = append(entries, b.addEntry(seqnums[i], times[i], data))
+		}
+		if err := os.Mkdir(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		return b.write(t, filepath.Join(dir, name), uint64(len(entries)), b.addEntryArray(0, entries...))
+	}
+	// the clock was set back between b and c: the files sharing their
+	// sequence numbers are merged by sequence number, the last one by time
+	paths := []string{
+		write("a", 1, 1, []string{"a", "c"}, []uint64{1, 3}, []uint64{1000, 500}),
+		write("b", 1, 1, []string{"b", "d"}, []uint64{2, 4}, []uint64{2000, 600}),
+		write("c", 2, 2, []string{"x"}, []uint64{1}, []uint64{3000}),
+	}
+
+	m, err := OpenMerged(paths...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	read := func(move func() (uint64, error)) []string {
+		var messages []string
+		for {
+			n, err := move()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n == 0 {
+				return messages
+			}
+			msg, err := m.GetDataValue("MESSAGE")
+			if err != nil {
+				t.Fatal(err)
+			}
+			messages = append(messages, msg)
+		}
+	}
+	if got, expected := read(m.Next), []string{"a", "b", "c", "d", "x"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("got messages %v, want %v", got, expected)
+	}
+	if got, expected := read(m.Previous), []string{"x", "d", "c", "b", "a"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("got messages %v backwards, want %v", got, expected)
+	}
+
+	// change direction in the middle
+	m.Next()
+	m.Next()
+	m.Next()
+	m.Previous()
+	if usec, err := m.GetRealtimeUsec(); err != nil || usec != 2000 {
+		t.Errorf("got time %d, %v, want 2000", usec, err)
+	}
+	m.Next()
+	if msg, err := m.GetDataValue("MESSAGE"); err != nil || msg != "c" {
+		t.Errorf("got message %q, %v, want c", msg, err)
+	}
+}
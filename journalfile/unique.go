@@ -0,0 +1,99 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journalfile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+const fieldHashItemSize = 16 // head_hash_offset, tail_hash_offset
+
+// GetUniqueValues returns the values field takes in the journal file, as
+// sdjournal.Journal.GetUniqueValues does. Like sd_journal_query_unique, it
+// follows the chain of data objects of the field instead of reading every
+// entry.
+func (j *Journal) GetUniqueValues(field string) ([]string, error) {
+	offset, err := j.findField(field)
+	if err != nil || offset == 0 {
+		return nil, err
+	}
+	o, err := j.readObject(offset, objectField)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := []byte(field + "=")
+	var values []string
+	next := le64(o[32:])
+	for n := uint64(0); next != 0; n++ {
+		if n > j.nObjects {
+			return nil, errors.New("loop in the data objects of a field")
+		}
+		d, err := j.readObject(next, objectData)
+		if err != nil {
+			return nil, err
+		}
+		if len(d) < 40 {
+			return nil, fmt.Errorf("truncated data object at %d", next)
+		}
+
+		payload, err := j.readData(next)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.HasPrefix(payload, prefix) {
+			values = append(values, string(payload[len(prefix):]))
+		}
+		next = le64(d[32:])
+	}
+	return values, nil
+}
+
+// findField returns the offset of the field object for field, or 0 if the
+// file has none. Rather than hashing field, which depends on the hash
+// function the file uses, it looks through all the buckets of the field hash
+// table, which is small.
+func (j *Journal) findField(field string) (uint64, error) {
+	if j.fieldHashTable == 0 || j.fieldHashTableSize > maxObjectSize {
+		return 0, nil
+	}
+	items := make([]byte, j.fieldHashTableSize)
+	if _, err := j.f.ReadAt(items, int64(j.fieldHashTable)); err != nil {
+		return 0, fmt.Errorf("failed to read field hash table: %v", err)
+	}
+
+	for i := 0; i+fieldHashItemSize <= len(items); i += fieldHashItemSize {
+		next := le64(items[i:])
+		for n := uint64(0); next != 0; n++ {
+			if n > j.nObjects {
+				return 0, errors.New("loop in the field hash table")
+			}
+			o, err := j.readObject(next, objectField)
+			if err != nil {
+				return 0, err
+			}
+			if len(o) < 40 {
+				return 0, fmt.Errorf("truncated field object at %d", next)
+			}
+			if string(o[40:]) == field {
+				return next, nil
+			}
+			next = le64(o[24:])
+		}
+	}
+	return 0, nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journalfile
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// addField appends a field object whose data objects are data, linking them
+// with their next_field_offset.
+func (b *fileBuilder) addField(nextHash uint64, name string, data ...uint64) uint64 {
+	for i := 0; i+1 < len(data); i++ {
+		binary.LittleEndian.PutUint64(b.buf[data[i]+32:], data[i+1])
+	}
+	body := make([]byte, 24)
+	binary.LittleEndian.PutUint64(body[8:], nextHash)
+	if len(data) > 0 {
+		binary.LittleEndian.PutUint64(body[16:], data[0])
+	}
+	return b.add(objectField, 0, append(body, name...))
+}
+
+func testUniqueValues(t *testing.T, compact bool) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	b := newFileBuilder(compact)
+	foo := b.addData(0, []byte("_SYSTEMD_UNIT=foo.service"))
+	bar := b.addData(objectCompressedLZ4, lz4Literals([]byte("_SYSTEMD_UNIT=bar.service")))
+	msg := b.addData(0, []byte("MESSAGE=hello"))
+	unitField := b.addField(0, "_SYSTEMD_UNIT", foo, bar)
+	// two fields in the same bucket
+	msgField := b.addField(unitField, "MESSAGE", msg)
+	idField := b.addField(0, "SYSLOG_IDENTIFIER")
+
+	table := make([]byte, 3*fieldHashItemSize)
+	binary.LittleEndian.PutUint64(table[fieldHashItemSize:], msgField)
+	binary.LittleEndian.PutUint64(table[2*fieldHashItemSize:], idField)
+	tableOffset := b.add(5, 0, table) + objectHeader
+
+	binary.LittleEndian.PutUint64(b.buf[120:], tableOffset)
+	binary.LittleEndian.PutUint64(b.buf[128:], uint64(len(table)))
+	binary.LittleEndian.PutUint64(b.buf[144:], 7)
+	j, err := Open(b.write(t, dir, 0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+
+	for field, expected := range map[string][]string{
+		"_SYSTEMD_UNIT":     {"foo.service", "bar.service"},
+		"MESSAGE":           {"hello"},
+		"SYSLOG_IDENTIFIER": nil,
+		"PRIORITY":          nil,
+	} {
+		values, err := j.GetUniqueValues(field)
+		if err != nil {
+			t.Errorf("%s: %v", field, err)
+		} else if !reflect.DeepEqual(values, expected) {
+			t.Errorf("%s: got %q, want %q", field, values, expected)
+		}
+	}
+
+	// a loop in the chain of data objects
+	binary.LittleEndian.PutUint64(b.buf[bar+32:], foo)
+	j2, err := Open(b.write(t, dir, 0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j2.Close()
+	if _, err := j2.GetUniqueValues("_SYSTEMD_UNIT"); err == nil {
+		t.Error("expected an error for a loop")
+	}
+}
+
+func TestUniqueValues(t *testing.T) {
+	testUniqueValues(t, false)
+}
+
+func TestUniqueValuesCompact(t *testing.T) {
+	testUniqueValues(t, true)
+}
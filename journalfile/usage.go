@@ -0,0 +1,132 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journalfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Directories journald stores journal files in, persistently and in
+// memory.
+const (
+	PersistentDir = "/var/log/journal"
+	RuntimeDir    = "/run/log/journal"
+)
+
+// isJournalFile reports whether name is the name of a journal file, either
+// in use or renamed by journald after finding it corrupted.
+func isJournalFile(name string) bool {
+	return strings.HasSuffix(name, ".journal") || strings.HasSuffix(name, ".journal~")
+}
+
+// DirUsage returns the disk space used by the journal files in dir and its
+// subdirectories, in bytes. Like sd_journal_get_usage, it counts the blocks
+// allocated to the files rather than their sizes.
+func DirUsage(dir string) (uint64, error) {
+	var usage uint64
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() && isJournalFile(info.Name()) {
+			usage += fileUsage(info)
+		}
+		return nil
+	})
+	return usage, err
+}
+
+// NamespaceUsage returns the disk space used by the journal files in root,
+// such as PersistentDir or RuntimeDir, in bytes, per journal namespace. The
+// files of the default namespace are found in a directory named after the
+// machine ID, and those of other namespaces in directories named after the
+// machine ID, a dot and the namespace; the default namespace is reported as
+// "". Directories of all machine IDs are counted.
+func NamespaceUsage(root string) (map[string]uint64, error) {
+	infos, err := ioutil.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := make(map[string]uint64)
+	for _, info := range infos {
+		if !info.IsDir() {
+			continue
+		}
+		namespace, ok := dirNamespace(info.Name())
+		if !ok {
+			continue
+		}
+		u, err := DirUsage(filepath.Join(root, info.Name()))
+		if err != nil {
+			return nil, err
+		}
+		usage[namespace] += u
+	}
+	return usage, nil
+}
+
+// dirNamespace returns the namespace of the journal directory name, and
+// whether name is one.
+func dirNamespace(name string) (string, bool) {
+	if len(name) < 32 {
+		return "", false
+	}
+	for _, c := range name[:32] {
+		if !(('0' <= c && c <= '9') || ('a' <= c && c <= 'f')) {
+			return "", false
+		}
+	}
+	switch {
+	case len(name) == 32:
+		return "", true
+	case name[32] == '.' && len(name) > 33:
+		return name[33:], true
+	}
+	return "", false
+}
+
+// DirFiles returns the paths of the journal files in dir and in its
+// subdirectories named after machine IDs, which sd_journal_open_directory(3)
+// opens, sorted by name. dir may be PersistentDir, RuntimeDir, the
+// /var/log/journal/<machine-id> directory of a mounted image, or any other
+// directory holding journal files.
+func DirFiles(dir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, info := range infos {
+		path := filepath.Join(dir, info.Name())
+		switch {
+		case info.Mode().IsRegular() && isJournalFile(info.Name()):
+			files = append(files, path)
+		case info.IsDir():
+			if namespace, ok := dirNamespace(info.Name()); ok && namespace == "" {
+				sub, err := DirFiles(path)
+				if err != nil {
+					return nil, err
+				}
+				files = append(files, sub...)
+			}
+		}
+	}
+	return files, nil
+}
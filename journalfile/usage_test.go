@@ -0,0 +1,88 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journalfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNamespaceUsage(t *testing.T) {
+	root, cleanup := tempDir(t)
+	defer cleanup()
+
+	const machineID = "8cf7ed9d451ea194b77a9f02ee95f549"
+	files := map[string]int{
+		machineID + "/system.journal":                      4096,
+		machineID + "/user-1000@0005a1b2c3d4e5f6.journal~": 8192,
+		machineID + "/README":                              100000,
+		machineID + ".tenant/system.journal":               4096,
+		"remote/remote-host.journal":                       4096,
+	}
+	for name, size := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	usage, err := NamespaceUsage(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(usage) != 2 {
+		t.Errorf("got namespaces %v", usage)
+	}
+	// blocks are allocated for at least the size of the files
+	if usage[""] < 12288 {
+		t.Errorf("got %d bytes for the default namespace", usage[""])
+	}
+	if usage["tenant"] < 4096 || usage["tenant"] >= usage[""] {
+		t.Errorf("got %d bytes for namespace tenant", usage["tenant"])
+	}
+
+	total, err := DirUsage(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != usage[""]+usage["tenant"]+usage["tenant"] {
+		t.Errorf("got %d bytes in total, namespaces %v", total, usage)
+	}
+
+	if _, err := DirUsage(filepath.Join(root, "missing")); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
+
+func TestDirNamespace(t *testing.T) {
+	for name, want := range map[string]string{
+		"8cf7ed9d451ea194b77a9f02ee95f549":     "",
+		"8cf7ed9d451ea194b77a9f02ee95f549.foo": "foo",
+	} {
+		if got, ok := dirNamespace(name); !ok || got != want {
+			t.Errorf("%q: got %q, %v", name, got, ok)
+		}
+	}
+	for _, name := range []string{"remote", "8cf7ed9d451ea194b77a9f02ee95f549.", "8cf7ed9d451ea194b77a9f02ee95f549x", "8CF7ED9D451EA194B77A9F02EE95F549"} {
+		if _, ok := dirNamespace(name); ok {
+			t.Errorf("%q: unexpectedly a journal directory", name)
+		}
+	}
+}
@@ -0,0 +1,410 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journalfile
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	compatibleSealed = 1 << 0
+
+	objectUnused         = 0
+	objectDataHashTable  = 4
+	objectFieldHashTable = 5
+	objectTag            = 7
+
+	tagLength = 256 / 8
+)
+
+// ErrNoVerificationKey is set in the results of Verify for sealed files
+// checked without a verification key: their structure was checked, but not
+// their tags.
+var ErrNoVerificationKey = errors.New("journal file is sealed, but no verification key was given")
+
+// VerificationKey is the key checking the tags sealing journal files with
+// Forward Secure Sealing, as printed by "journalctl --setup-keys".
+type VerificationKey struct {
+	Seed         []byte
+	StartUsec    uint64 // start of the first epoch, in microseconds since the epoch
+	IntervalUsec uint64 // length of each epoch, in microseconds
+
+	prg *fsprg
+}
+
+// ParseVerificationKey parses a verification key such as
+// "a1b2c3-d4e5f6-a1b2c3-d4e5f6/1e61ae-35a4e900", as passed to
+// "journalctl --verify-key".
+func ParseVerificationKey(s string) (*VerificationKey, error) {
+	i := strings.IndexByte(s, '/')
+	if i < 0 {
+		return nil, errors.New("invalid verification key: missing '/'")
+	}
+
+	seed := make([]byte, 0, fsprgSeedLen)
+	hex := strings.Replace(s[:i], "-", "", -1)
+	for len(hex) >= 2 && len(seed) < fsprgSeedLen {
+		b, err := strconv.ParseUint(hex[:2], 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid verification key seed: %v", err)
+		}
+		seed = append(seed, byte(b))
+		hex = hex[2:]
+	}
+	if len(seed) != fsprgSeedLen || hex != "" {
+		return nil, fmt.Errorf("invalid verification key seed %q", s[:i])
+	}
+
+	parts := strings.SplitN(s[i+1:], "-", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid verification key epoch %q", s[i+1:])
+	}
+	start, err := strconv.ParseUint(parts[0], 16, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid verification key epoch: %v", err)
+	}
+	interval, err := strconv.ParseUint(parts[1], 16, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid verification key interval: %v", err)
+	}
+	if interval == 0 {
+		return nil, errors.New("invalid verification key: zero interval")
+	}
+
+	return &VerificationKey{
+		Seed:         seed,
+		StartUsec:    start * interval,
+		IntervalUsec: interval,
+		prg:          newFSPRG(seed),
+	}, nil
+}
+
+// VerifyError describes a problem found in a journal file by Verify.
+type VerifyError struct {
+	Offset uint64 // offset of the object at fault, 0 for the header
+	Reason string
+}
+
+func (e *VerifyError) Error() string {
+	return fmt.Sprintf("journal file corrupted at %#x: %s", e.Offset, e.Reason)
+}
+
+// SealedRange is the time span sealed by a tag, that is one epoch of the
+// verification key.
+type SealedRange struct {
+	Epoch     uint64
+	StartUsec uint64
+	EndUsec   uint64
+}
+
+// VerifyResult is the outcome of the verification of a journal file.
+type VerifyResult struct {
+	Path string
+	// Sealed is set if the file uses Forward Secure Sealing.
+	Sealed bool
+	// Err is nil if no problem was found, a *VerifyError if the file is
+	// corrupted or has been tampered with, or ErrNoVerificationKey.
+	Err error
+
+	FirstUsec uint64 // realtime timestamp of the first entry
+	LastUsec  uint64 // realtime timestamp of the last entry
+	// ValidatedUsec is the realtime timestamp of the last entry sealed by
+	// a verified tag, 0 if there is none.
+	ValidatedUsec uint64
+	// Unsealed is the number of entries after the last verified tag, which
+	// could have been modified without being detected.
+	Unsealed int
+	// Ranges lists the epochs of the verified tags, in file order.
+	Ranges []SealedRange
+}
+
+// Verify checks the journal file at path, like "journalctl --verify". The
+// objects are walked and checked against the header; if the file is sealed
+// and key is not nil, the tags are checked as well, so that entries modified
+// after they were sealed are detected. Unlike journalctl, the hash tables
+// and entry arrays are not checked. An error is returned only if the file
+// cannot be read; problems found in the file are reported in the result.
+func Verify(path string, key *VerificationKey) (*VerifyResult, error) {
+	j, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer j.Close()
+
+	h := make([]byte, headerMinSize)
+	if _, err := j.f.ReadAt(h, 0); err != nil {
+		return nil, err
+	}
+	headerSize := le64(h[88:])
+	if headerSize > maxObjectSize {
+		return nil, fmt.Errorf("invalid journal header size %d", headerSize)
+	}
+	if headerSize > headerMinSize {
+		h = make([]byte, headerSize)
+		if _, err := j.f.ReadAt(h, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	v := &verifier{j: j, header: h, key: key}
+	res := &VerifyResult{
+		Path:      path,
+		Sealed:    le32(h[8:])&compatibleSealed != 0,
+		FirstUsec: le64(h[184:]),
+		LastUsec:  le64(h[192:]),
+	}
+	if err := v.run(res); err != nil {
+		if _, ok := err.(*VerifyError); !ok {
+			return nil, err
+		}
+		res.Err = err
+	} else if res.Sealed && key == nil {
+		res.Err = ErrNoVerificationKey
+	}
+	return res, nil
+}
+
+// VerifyDir verifies the journal files in dir and its subdirectories, as
+// listed by DirFiles.
+func VerifyDir(dir string, key *VerificationKey) ([]*VerifyResult, error) {
+	paths, err := DirFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*VerifyResult
+	for _, path := range paths {
+		res, err := Verify(path, key)
+		if err != nil {
+			return results, fmt.Errorf("failed to verify journal file %q: %v", path, err)
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+type verifier struct {
+	j      *Journal
+	header []byte
+	key    *VerificationKey
+}
+
+func corrupt(offset uint64, format string, args ...interface{}) error {
+	return &VerifyError{Offset: offset, Reason: fmt.Sprintf(format, args...)}
+}
+
+// run walks the objects of the file, checking them and the tags.
+func (v *verifier) run(res *VerifyResult) error {
+	sealed := res.Sealed && v.key != nil
+	tailObject := le64(v.header[136:])
+
+	var (
+		nObjects, nEntries, nTags uint64
+		lastEpoch                 uint64
+		lastTag                   uint64 // offset following the last tag
+		lastTagRealtime           uint64
+		entryRealtime             uint64
+		entryRealtimeSet          bool
+	)
+
+	p := le64(v.header[88:])
+	for p != 0 && p <= tailObject {
+		o, err := v.readObjectHeader(p)
+		if err != nil {
+			return err
+		}
+		typ, size := o[0], le64(o[8:])
+		nObjects++
+
+		switch typ {
+		case objectData, objectField, objectDataHashTable, objectFieldHashTable, objectEntryArray:
+		case objectEntry:
+			if res.Sealed && nTags == 0 {
+				return corrupt(p, "first entry before first tag")
+			}
+			o, err = v.j.readObject(p, objectEntry)
+			if err != nil {
+				return err
+			}
+			if len(o) < 64 {
+				return corrupt(p, "truncated entry object")
+			}
+			realtime := le64(o[24:])
+			if realtime < lastTagRealtime {
+				return corrupt(p, "older entry after newer tag")
+			}
+			entryRealtime, entryRealtimeSet = realtime, true
+			nEntries++
+			res.Unsealed++
+		case objectTag:
+			if !res.Sealed {
+				return corrupt(p, "tag object in file without sealing")
+			}
+			o, err = v.j.readObject(p, objectTag)
+			if err != nil {
+				return err
+			}
+			if len(o) < objectHeader+16+tagLength {
+				return corrupt(p, "truncated tag object")
+			}
+			seqnum, epoch := le64(o[16:]), le64(o[24:])
+			if seqnum != nTags+1 {
+				return corrupt(p, "tag sequence number out of synchronization")
+			}
+			if epoch < lastEpoch {
+				return corrupt(p, "epoch sequence out of synchronization")
+			}
+
+			if sealed {
+				rt := v.key.StartUsec + epoch*v.key.IntervalUsec
+				rtEnd := rt + v.key.IntervalUsec
+				if entryRealtimeSet && entryRealtime >= rtEnd {
+					return corrupt(p, "tag/entry realtime timestamp out of synchronization")
+				}
+
+				mac := hmac.New(sha256.New, v.key.prg.key(epoch))
+				from := lastTag
+				if lastTag == 0 {
+					v.hashHeader(mac)
+					from = le64(v.header[88:])
+				}
+				if err := v.hashObjects(mac, from, p); err != nil {
+					return err
+				}
+				if !hmac.Equal(o[32:32+tagLength], mac.Sum(nil)) {
+					return corrupt(p, "tag failed verification")
+				}
+
+				lastTagRealtime = rt
+				res.ValidatedUsec = entryRealtime
+				res.Unsealed = 0
+				res.Ranges = append(res.Ranges, SealedRange{Epoch: epoch, StartUsec: rt, EndUsec: rtEnd})
+			}
+			lastTag = p + align64(size)
+			lastEpoch = epoch
+			nTags++
+		default:
+			return corrupt(p, "unknown object type %d", typ)
+		}
+
+		next := p + align64(size)
+		if next <= p {
+			return corrupt(p, "invalid object size %d", size)
+		}
+		p = next
+	}
+
+	if n := le64(v.header[144:]); n != nObjects {
+		return corrupt(0, "object number mismatch: header says %d, found %d", n, nObjects)
+	}
+	if n := le64(v.header[152:]); n != nEntries {
+		return corrupt(0, "entry number mismatch: header says %d, found %d", n, nEntries)
+	}
+	if len(v.header) >= 232 {
+		if n := le64(v.header[224:]); n != nTags {
+			return corrupt(0, "tag number mismatch: header says %d, found %d", n, nTags)
+		}
+	}
+	if !sealed {
+		res.Unsealed = 0
+	}
+	return nil
+}
+
+// readObjectHeader reads the header of the object at p and checks its size.
+func (v *verifier) readObjectHeader(p uint64) ([]byte, error) {
+	if p%8 != 0 {
+		return nil, corrupt(p, "object not aligned")
+	}
+	o := make([]byte, objectHeader)
+	if _, err := v.j.f.ReadAt(o, int64(p)); err != nil {
+		if err == io.EOF {
+			return nil, corrupt(p, "object past the end of the file")
+		}
+		return nil, err
+	}
+	if size := le64(o[8:]); size < objectHeader || size > maxObjectSize {
+		return nil, corrupt(p, "invalid object size %d", size)
+	}
+	return o, nil
+}
+
+// hashHeader adds the immutable parts of the file header to mac.
+func (v *verifier) hashHeader(mac hash.Hash) {
+	h := v.header
+	mac.Write(h[0:16])    // signature and flags
+	mac.Write(h[24:56])   // file and machine IDs
+	mac.Write(h[72:96])   // seqnum ID and header size
+	mac.Write(h[104:136]) // hash table offsets and sizes
+}
+
+// hashObjects adds the immutable parts of the objects from offset from up to
+// and including the tag at offset to to mac.
+func (v *verifier) hashObjects(mac hash.Hash, from, to uint64) error {
+	for q := from; q <= to; {
+		o, err := v.readObjectHeader(q)
+		if err != nil {
+			return err
+		}
+		typ, size := o[0], le64(o[8:])
+		if typ == objectData || typ == objectField || typ == objectEntry || typ == objectTag {
+			if o, err = v.j.readObject(q, typ); err != nil {
+				return err
+			}
+		}
+
+		mac.Write(o[:objectHeader])
+		switch typ {
+		case objectData:
+			start := 64
+			if v.j.compact {
+				start = 72
+			}
+			if len(o) < start {
+				return corrupt(q, "truncated data object")
+			}
+			mac.Write(o[16:24]) // hash
+			mac.Write(o[start:])
+		case objectField:
+			if len(o) < 40 {
+				return corrupt(q, "truncated field object")
+			}
+			mac.Write(o[16:24]) // hash
+			mac.Write(o[40:])
+		case objectEntry:
+			mac.Write(o[16:])
+		case objectTag:
+			mac.Write(o[16:32]) // seqnum and epoch, but not the tag
+		case objectDataHashTable, objectFieldHashTable, objectEntryArray:
+			// mutable
+		default:
+			return corrupt(q, "unknown object type %d", typ)
+		}
+
+		q += align64(size)
+	}
+	return nil
+}
+
+func align64(n uint64) uint64 {
+	return (n + 7) &^ 7
+}
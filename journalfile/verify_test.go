@@ -0,0 +1,242 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journalfile
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"io/ioutil"
+	"testing"
+)
+
+// the verification key used in the systemd tests
+const testVerificationKey = "c262bd-85187f-0b1b04-877cc5/1c7af8-35a4e900"
+
+func TestParseVerificationKey(t *testing.T) {
+	key, err := ParseVerificationKey(testVerificationKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(key.Seed) != "c262bd85187f0b1b04877cc5" {
+		t.Errorf("got seed %x", key.Seed)
+	}
+	if key.IntervalUsec != 900000000 || key.StartUsec != 0x1c7af8*900000000 {
+		t.Errorf("got start %d and interval %d", key.StartUsec, key.IntervalUsec)
+	}
+
+	for _, s := range []string{
+		"",
+		"c262bd-85187f-0b1b04-877cc5",
+		"c262bd-85187f-0b1b04/1c7af8-35a4e900",
+		"c262bd-85187f-0b1b04-877cc5ff/1c7af8-35a4e900",
+		"x262bd-85187f-0b1b04-877cc5/1c7af8-35a4e900",
+		"c262bd-85187f-0b1b04-877cc5/1c7af8",
+		"c262bd-85187f-0b1b04-877cc5/1c7af8-0",
+	} {
+		if _, err := ParseVerificationKey(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
+
+func TestFSPRGKey(t *testing.T) {
+	key, err := ParseVerificationKey(testVerificationKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// computed with FSPRG_Seek and FSPRG_GetKey from systemd
+	for epoch, expected := range map[uint64]string{
+		0:         "ee998a1df99c9105ff7a9ed50bf6b6a20dd22f31712152521327fda74b26b7da",
+		1:         "126f2d3fd0605be9607325bb05a936bf72d33dbc1b9eb6cea82b849f3fc33c33",
+		2:         "fb3463f6e917d2160604844ecc5e0a94f855d32f1d43e6080e7d271e2c40d45c",
+		1000:      "c1df1cc1388eb3f24f4f8ef61c0f97f1d050d138f9fa76aa068d09b60cb0e2fe",
+		123456789: "9cd8ce8f0ddf39d308eb5d666719661a7ac66ddb3d9696d0157c9d0f3e23f8a9",
+	} {
+		if k := hex.EncodeToString(key.prg.key(epoch)); k != expected {
+			t.Errorf("epoch %d: got key %s, want %s", epoch, k, expected)
+		}
+	}
+}
+
+func (b *fileBuilder) addTag(seqnum, epoch uint64) uint64 {
+	body := make([]byte, 16+tagLength)
+	binary.LittleEndian.PutUint64(body[0:], seqnum)
+	binary.LittleEndian.PutUint64(body[8:], epoch)
+	return b.add(objectTag, 0, body)
+}
+
+// writeSealed writes a sealed file like write, then computes its tags.
+func (b *fileBuilder) writeSealed(t *testing.T, dir string, key *VerificationKey, nObjects, nEntries, nTags, tail, arrayOffset uint64) string {
+	binary.LittleEndian.PutUint32(b.buf[8:], compatibleSealed)
+	binary.LittleEndian.PutUint64(b.buf[136:], tail)
+	binary.LittleEndian.PutUint64(b.buf[144:], nObjects)
+	binary.LittleEndian.PutUint64(b.buf[224:], nTags)
+	path := b.write(t, dir, nEntries, arrayOffset)
+
+	j, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	v := &verifier{j: j, header: b.buf[:272], key: key}
+
+	var from uint64
+	for p := uint64(272); p <= tail; p += align64(le64(b.buf[p+8:])) {
+		if b.buf[p] != objectTag {
+			continue
+		}
+		mac := hmac.New(sha256.New, key.prg.key(le64(b.buf[p+24:])))
+		if from == 0 {
+			v.hashHeader(mac)
+			from = 272
+		}
+		if err := v.hashObjects(mac, from, p); err != nil {
+			t.Fatal(err)
+		}
+		copy(b.buf[p+32:], mac.Sum(nil))
+		from = p + align64(le64(b.buf[p+8:]))
+	}
+	if err := ioutil.WriteFile(path, b.buf, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestVerify(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	key, err := ParseVerificationKey(testVerificationKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := key.StartUsec
+
+	// two epochs sealed, then an entry not sealed yet
+	build := func() (*fileBuilder, uint64, uint64) {
+		b := newFileBuilder(false)
+		b.addTag(1, 0)
+		msg1 := b.addData(0, []byte("MESSAGE=first"))
+		e1 := b.addEntry(1, start+1000, msg1)
+		b.addTag(2, 0)
+		msg2 := b.addData(0, []byte("MESSAGE=second"))
+		e2 := b.addEntry(2, start+key.IntervalUsec+1000, msg2)
+		b.addTag(3, 1)
+		e3 := b.addEntry(3, start+key.IntervalUsec+2000, msg2)
+		tail := b.addEntryArray(0, e1, e2, e3)
+		binary.LittleEndian.PutUint64(b.buf[184:], start+1000)
+		binary.LittleEndian.PutUint64(b.buf[192:], start+key.IntervalUsec+2000)
+		return b, tail, msg2
+	}
+
+	b, tail, msg2 := build()
+	path := b.writeSealed(t, dir, key, 9, 3, 3, tail, tail)
+	res, err := Verify(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Err != nil {
+		t.Fatalf("unexpected verification failure: %v", res.Err)
+	}
+	if !res.Sealed || res.FirstUsec != start+1000 || res.LastUsec != start+key.IntervalUsec+2000 {
+		t.Errorf("unexpected result %+v", res)
+	}
+	if res.ValidatedUsec != start+key.IntervalUsec+1000 || res.Unsealed != 1 {
+		t.Errorf("got entries validated up to %d with %d unsealed, want %d and 1",
+			res.ValidatedUsec, res.Unsealed, start+key.IntervalUsec+1000)
+	}
+	expected := []SealedRange{
+		{0, start, start + key.IntervalUsec},
+		{0, start, start + key.IntervalUsec},
+		{1, start + key.IntervalUsec, start + 2*key.IntervalUsec},
+	}
+	if len(res.Ranges) != len(expected) {
+		t.Fatalf("got ranges %v, want %v", res.Ranges, expected)
+	}
+	for i := range expected {
+		if res.Ranges[i] != expected[i] {
+			t.Errorf("got range %v, want %v", res.Ranges[i], expected[i])
+		}
+	}
+
+	// without the key, only the structure is checked
+	res, err = Verify(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Err != ErrNoVerificationKey || len(res.Ranges) != 0 {
+		t.Errorf("got %v and ranges %v without a key", res.Err, res.Ranges)
+	}
+
+	// tamper with a sealed message
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[msg2+64] = 'S'
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	res, err = Verify(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verr, ok := res.Err.(*VerifyError); !ok || verr.Reason != "tag failed verification" {
+		t.Errorf("got %v, want a tag verification failure", res.Err)
+	}
+
+	// a file truncated after a tag
+	b, tail, _ = build()
+	path = b.writeSealed(t, dir, key, 10, 3, 3, tail, tail)
+	if res, err := Verify(path, key); err != nil || res.Err == nil {
+		t.Errorf("got %v, %v with a wrong number of objects, want a verification failure", res, err)
+	}
+
+	results, err := VerifyDir(dir, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Path != path || results[0].Err == nil {
+		t.Errorf("unexpected results %+v", results)
+	}
+}
+
+func TestVerifyEntryBeforeTag(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	key, err := ParseVerificationKey(testVerificationKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := newFileBuilder(false)
+	msg := b.addData(0, []byte("MESSAGE=first"))
+	e := b.addEntry(1, key.StartUsec, msg)
+	b.addTag(1, 0)
+	tail := b.addEntryArray(0, e)
+	path := b.writeSealed(t, dir, key, 4, 1, 1, tail, tail)
+
+	res, err := Verify(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verr, ok := res.Err.(*VerifyError); !ok || verr.Offset != e {
+		t.Errorf("got %v, want an error at the entry at %#x", res.Err, e)
+	}
+}
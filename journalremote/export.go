@@ -0,0 +1,252 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package journalremote exchanges journal entries with other processes and
+// hosts: it encodes and decodes the Journal Export Format, which
+// systemd-journal-remote, systemd-journal-gatewayd and journalctl -o export
+// use, pulls entries from systemd-journal-gatewayd, uploads entries to
+// systemd-journal-remote and exports them to OpenTelemetry collectors over
+// HTTP.
+//
+// The format is described in
+// https://systemd.io/JOURNAL_EXPORT_FORMATS/
+package journalremote
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// maxFieldSize bounds the size of the binary fields decoded, as journald
+// does.
+const maxFieldSize = 768 << 20
+
+// Field is a field of a journal entry.
+type Field struct {
+	Name  string
+	Value []byte
+}
+
+// Entry is a journal entry. Its fields are kept in order, and a field may
+// appear more than once, so that entries are exchanged without loss. Besides
+// fields such as MESSAGE, entries usually carry the address fields
+// __CURSOR, __REALTIME_TIMESTAMP and __MONOTONIC_TIMESTAMP.
+type Entry struct {
+	Fields []Field
+}
+
+// NewEntry returns an entry holding fields, sorted by name, preceded by the
+// address fields __CURSOR, __REALTIME_TIMESTAMP and __MONOTONIC_TIMESTAMP if
+// cursor or the timestamps are set. It converts the entries returned by the
+// sdjournal and journalfile packages:
+//
+//	e := journalremote.NewEntry(entry.Fields, entry.Cursor, entry.RealtimeTimestamp, entry.MonotonicTimestamp)
+func NewEntry(fields map[string]string, cursor string, realtimeUsec, monotonicUsec uint64) *Entry {
+	e := &Entry{Fields: make([]Field, 0, len(fields)+3)}
+	if cursor != "" {
+		e.Add("__CURSOR", cursor)
+	}
+	if realtimeUsec != 0 {
+		e.Add("__REALTIME_TIMESTAMP", strconv.FormatUint(realtimeUsec, 10))
+	}
+	if monotonicUsec != 0 {
+		e.Add("__MONOTONIC_TIMESTAMP", strconv.FormatUint(monotonicUsec, 10))
+	}
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		e.Add(name, fields[name])
+	}
+	return e
+}
+
+// Add appends the field name with value to e.
+func (e *Entry) Add(name string, value string) {
+	e.Fields = append(e.Fields, Field{Name: name, Value: []byte(value)})
+}
+
+// Get returns the value of the first field name of e, and whether there is
+// one.
+func (e *Entry) Get(name string) (string, bool) {
+	for _, f := range e.Fields {
+		if f.Name == name {
+			return string(f.Value), true
+		}
+	}
+	return "", false
+}
+
+// Encoder writes journal entries in the export format.
+type Encoder struct {
+	w   *bufio.Writer
+	err error
+}
+
+// NewEncoder returns an Encoder writing to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: bufio.NewWriter(w)}
+}
+
+// Encode writes e, followed by the empty line ending it.
+func (enc *Encoder) Encode(e *Entry) error {
+	if enc.err != nil {
+		return enc.err
+	}
+	for _, f := range e.Fields {
+		if err := validFieldName(f.Name); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range e.Fields {
+		if isText(f.Value) {
+			enc.w.WriteString(f.Name)
+			enc.w.WriteByte('=')
+			enc.w.Write(f.Value)
+			enc.w.WriteByte('\n')
+		} else {
+			var size [8]byte
+			binary.LittleEndian.PutUint64(size[:], uint64(len(f.Value)))
+			enc.w.WriteString(f.Name)
+			enc.w.WriteByte('\n')
+			enc.w.Write(size[:])
+			enc.w.Write(f.Value)
+			enc.w.WriteByte('\n')
+		}
+	}
+	enc.w.WriteByte('\n')
+	enc.err = enc.w.Flush()
+	return enc.err
+}
+
+// isText reports whether value can be written as text: it is valid UTF-8
+// without control characters other than tabs.
+func isText(value []byte) bool {
+	if !utf8.Valid(value) {
+		return false
+	}
+	for _, r := range string(value) {
+		if (r < ' ' && r != '\t') || (0x7f <= r && r <= 0x9f) {
+			return false
+		}
+	}
+	return true
+}
+
+// validFieldName checks that name consists of uppercase letters, digits and
+// underscores, and does not start with a digit.
+func validFieldName(name string) error {
+	if name == "" {
+		return errors.New("empty field name")
+	}
+	for i, c := range name {
+		if !(('A' <= c && c <= 'Z') || c == '_' || (i > 0 && '0' <= c && c <= '9')) {
+			return fmt.Errorf("invalid field name %q", name)
+		}
+	}
+	return nil
+}
+
+// Decoder reads journal entries in the export format.
+type Decoder struct {
+	r *bufio.Reader
+}
+
+// NewDecoder returns a Decoder reading from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r)}
+}
+
+// Decode reads the next entry. It returns io.EOF if there are no more
+// entries, and io.ErrUnexpectedEOF if the input ends within an entry.
+func (dec *Decoder) Decode() (*Entry, error) {
+	e := &Entry{}
+	for {
+		line, err := dec.r.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) == 0 && len(e.Fields) == 0 {
+				return nil, io.EOF
+			}
+			if len(line) == 0 {
+				// the last entry may lack its terminating empty line
+				return e, nil
+			}
+			return nil, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		line = line[:len(line)-1]
+
+		if len(line) == 0 {
+			if len(e.Fields) == 0 {
+				// extra empty lines between entries
+				continue
+			}
+			return e, nil
+		}
+
+		if i := bytes.IndexByte(line, '='); i >= 0 {
+			e.Fields = append(e.Fields, Field{Name: string(line[:i]), Value: line[i+1:]})
+			continue
+		}
+
+		value, err := dec.readBinary()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read field %q: %v", line, err)
+		}
+		e.Fields = append(e.Fields, Field{Name: string(line), Value: value})
+	}
+}
+
+// readBinary reads the size and value of a binary field.
+func (dec *Decoder) readBinary() ([]byte, error) {
+	var size [8]byte
+	if _, err := io.ReadFull(dec.r, size[:]); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	n := binary.LittleEndian.Uint64(size[:])
+	if n > maxFieldSize {
+		return nil, fmt.Errorf("field size %d exceeds the maximum", n)
+	}
+
+	var value bytes.Buffer
+	if _, err := io.CopyN(&value, dec.r, int64(n)); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	if c, err := dec.r.ReadByte(); err != nil {
+		return nil, unexpectedEOF(err)
+	} else if c != '\n' {
+		return nil, errors.New("missing newline after binary field")
+	}
+	return value.Bytes(), nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
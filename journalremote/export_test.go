@@ -0,0 +1,131 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journalremote
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	entries := []*Entry{
+		{Fields: []Field{
+			{"__CURSOR", []byte("s=739ad463348b4ceca5a9e69c95a3c93f;i=4ece7")},
+			{"__REALTIME_TIMESTAMP", []byte("1342540861416409")},
+			{"MESSAGE", []byte("hello\tworld")},
+			{"MESSAGE", []byte("twice")},
+		}},
+		{Fields: []Field{
+			{"MESSAGE", []byte("first line\nsecond line")},
+			{"BINARY", []byte{0, 1, 2, 0xff}},
+			{"EMPTY", []byte{}},
+		}},
+	}
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dec := NewDecoder(&buf)
+	for i, want := range entries {
+		got, err := dec.Decode()
+		if err != nil {
+			t.Fatalf("entry %d: %v", i, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("entry %d: got %q, want %q", i, got.Fields, want.Fields)
+		}
+	}
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestEncodeFraming(t *testing.T) {
+	var buf bytes.Buffer
+	e := &Entry{}
+	e.Add("MESSAGE", "a\nb")
+	e.Add("PRIORITY", "6")
+	if err := NewEncoder(&buf).Encode(e); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "MESSAGE\n\x03\x00\x00\x00\x00\x00\x00\x00a\nb\nPRIORITY=6\n\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestEncodeInvalidName(t *testing.T) {
+	for _, name := range []string{"", "message", "1ABC", "A=B", "A\nB"} {
+		e := &Entry{}
+		e.Add(name, "x")
+		var buf bytes.Buffer
+		if err := NewEncoder(&buf).Encode(e); err == nil {
+			t.Errorf("%q: expected an error", name)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("%q: wrote %q", name, buf.String())
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	dec := NewDecoder(strings.NewReader("\n\nA=1\nB==2\n\nC=3\n"))
+	e, err := dec.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := e.Get("B"); v != "=2" {
+		t.Errorf("B: got %q", v)
+	}
+
+	// the last entry may lack its terminating empty line
+	e, err = dec.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := e.Get("C"); !ok || v != "3" {
+		t.Errorf("C: got %q, %v", v, ok)
+	}
+	if _, ok := e.Get("A"); ok {
+		t.Error("A found in the second entry")
+	}
+}
+
+func TestDecodeTruncated(t *testing.T) {
+	for _, input := range []string{
+		"A=1",
+		"A\n\x05\x00",
+		"A\n\x05\x00\x00\x00\x00\x00\x00\x00abc",
+		"A\n\x01\x00\x00\x00\x00\x00\x00\x00a",
+	} {
+		if _, err := NewDecoder(strings.NewReader(input)).Decode(); err == nil {
+			t.Errorf("%q: expected an error", input)
+		}
+	}
+
+	input := "A\n\x01\x00\x00\x00\x00\x00\x00\x00ab\n"
+	if _, err := NewDecoder(strings.NewReader(input)).Decode(); err == nil {
+		t.Errorf("%q: expected an error", input)
+	}
+}
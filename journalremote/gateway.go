@@ -0,0 +1,249 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journalremote
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// exportMediaType is the media type of the Journal Export Format.
+const exportMediaType = "application/vnd.fdo.journal"
+
+// Gateway is a client for the HTTP API of systemd-journal-gatewayd, see
+// systemd-journal-gatewayd.service(8).
+type Gateway struct {
+	url    *url.URL
+	client *http.Client
+}
+
+// NewGateway returns a Gateway for the server at rawurl, such as
+// http://host:19531. Requests are sent with client, or with
+// http.DefaultClient if client is nil; configure its transport for TLS.
+func NewGateway(rawurl string, client *http.Client) (*Gateway, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Gateway{url: u, client: client}, nil
+}
+
+// EntriesRequest selects the entries returned by Gateway.Entries.
+type EntriesRequest struct {
+	// Cursor is the cursor of the entry to start at. If empty, entries are
+	// returned from the start of the journal.
+	Cursor string
+	// Skip is the number of entries to skip from Cursor; it may be negative
+	// to go back.
+	Skip int64
+	// Count is the number of entries to return; 0 returns all of them.
+	Count uint64
+	// Matches holds FIELD=value matches the entries must satisfy.
+	Matches []string
+	// Boot limits the entries to the current boot of the host.
+	Boot bool
+	// Follow keeps the response open after the last entry, sending new
+	// entries as they are written, until the context is done or the stream
+	// is closed.
+	Follow bool
+}
+
+// rangeHeader returns the value of the Range header for r, or "" if none is
+// needed.
+func (r *EntriesRequest) rangeHeader() string {
+	if r.Cursor == "" && r.Skip == 0 && r.Count == 0 {
+		return ""
+	}
+	h := "entries=" + r.Cursor
+	if r.Skip != 0 {
+		h += ":" + strconv.FormatInt(r.Skip, 10) + ":"
+		if r.Count > 0 {
+			h += strconv.FormatUint(r.Count, 10)
+		}
+	} else if r.Count > 0 {
+		h += ":" + strconv.FormatUint(r.Count, 10)
+	}
+	return h
+}
+
+// query returns the query string for r.
+func (r *EntriesRequest) query() string {
+	var args []string
+	if r.Follow {
+		args = append(args, "follow")
+	}
+	if r.Boot {
+		args = append(args, "boot")
+	}
+	for _, m := range r.Matches {
+		name, value := m, ""
+		if i := strings.IndexByte(m, '='); i >= 0 {
+			name, value = m[:i], m[i+1:]
+		}
+		args = append(args, url.QueryEscape(name)+"="+url.QueryEscape(value))
+	}
+	return strings.Join(args, "&")
+}
+
+// EntryStream reads the entries sent by the gateway.
+type EntryStream struct {
+	body io.ReadCloser
+	dec  *Decoder
+}
+
+// Next returns the next entry, or io.EOF after the last one.
+func (s *EntryStream) Next() (*Entry, error) {
+	return s.dec.Decode()
+}
+
+// Close closes the stream, which also ends a followed stream.
+func (s *EntryStream) Close() error {
+	return s.body.Close()
+}
+
+// Entries requests the entries selected by req, in the Journal Export
+// Format. The caller must close the returned stream.
+func (g *Gateway) Entries(ctx context.Context, req *EntriesRequest) (*EntryStream, error) {
+	if req == nil {
+		req = &EntriesRequest{}
+	}
+	for _, m := range req.Matches {
+		i := strings.IndexByte(m, '=')
+		if i < 0 {
+			return nil, fmt.Errorf("invalid match %q", m)
+		}
+		if err := validFieldName(m[:i]); err != nil {
+			return nil, err
+		}
+	}
+
+	u := g.endpoint("entries")
+	u.RawQuery = req.query()
+	header := http.Header{"Accept": {exportMediaType}}
+	if r := req.rangeHeader(); r != "" {
+		header.Set("Range", r)
+	}
+
+	resp, err := g.get(ctx, u, header)
+	if err != nil {
+		return nil, err
+	}
+	return &EntryStream{body: resp.Body, dec: NewDecoder(resp.Body)}, nil
+}
+
+// MachineInfo describes the host of a gateway, as returned by
+// Gateway.Machine.
+type MachineInfo struct {
+	MachineID      string `json:"machine_id"`
+	BootID         string `json:"boot_id"`
+	Hostname       string `json:"hostname"`
+	OSPrettyName   string `json:"os_pretty_name"`
+	Virtualization string `json:"virtualization"`
+	// Usage is the disk space used by the journal, in bytes.
+	Usage uint64 `json:"usage,string"`
+	// CutoffFromRealtime and CutoffToRealtime are the timestamps of the
+	// first and last entries, in microseconds since the epoch.
+	CutoffFromRealtime uint64 `json:"cutoff_from_realtime,string"`
+	CutoffToRealtime   uint64 `json:"cutoff_to_realtime,string"`
+}
+
+// Machine returns information about the host of the gateway.
+func (g *Gateway) Machine(ctx context.Context) (*MachineInfo, error) {
+	resp, err := g.get(ctx, g.endpoint("machine"), http.Header{"Accept": {"application/json"}})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	info := &MachineInfo{}
+	if err := json.NewDecoder(resp.Body).Decode(info); err != nil {
+		return nil, fmt.Errorf("failed to decode machine information: %v", err)
+	}
+	return info, nil
+}
+
+// FieldValues returns the distinct values of the field name in the journal
+// of the host.
+func (g *Gateway) FieldValues(ctx context.Context, name string) ([]string, error) {
+	if err := validFieldName(name); err != nil {
+		return nil, err
+	}
+	resp, err := g.get(ctx, g.endpoint("fields", name), http.Header{"Accept": {"text/plain"}})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var values []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if v := scanner.Text(); v != "" {
+			values = append(values, v)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// Boots returns the IDs of the boots in the journal of the host.
+func (g *Gateway) Boots(ctx context.Context) ([]string, error) {
+	return g.FieldValues(ctx, "_BOOT_ID")
+}
+
+// endpoint returns the URL of the endpoint made of elem, relative to the
+// gateway URL.
+func (g *Gateway) endpoint(elem ...string) *url.URL {
+	u := *g.url
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + strings.Join(elem, "/")
+	u.RawPath = ""
+	u.RawQuery = ""
+	return &u
+}
+
+// get sends a GET request to u and returns the response if its status is
+// 200 OK.
+func (g *Gateway) get(ctx context.Context, u *url.URL, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header
+	resp, err := g.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("GET %s: %s: %s", u.Path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
@@ -0,0 +1,211 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journalremote
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRangeHeader(t *testing.T) {
+	for _, tt := range []struct {
+		req  EntriesRequest
+		want string
+	}{
+		{EntriesRequest{}, ""},
+		{EntriesRequest{Cursor: "s=1;i=2"}, "entries=s=1;i=2"},
+		{EntriesRequest{Count: 10}, "entries=:10"},
+		{EntriesRequest{Cursor: "c", Count: 10}, "entries=c:10"},
+		{EntriesRequest{Cursor: "c", Skip: -5, Count: 10}, "entries=c:-5:10"},
+		{EntriesRequest{Cursor: "c", Skip: 1}, "entries=c:1:"},
+	} {
+		if got := tt.req.rangeHeader(); got != tt.want {
+			t.Errorf("%+v: got %q, want %q", tt.req, got, tt.want)
+		}
+	}
+}
+
+func TestGatewayEntries(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/entries" {
+			http.NotFound(w, r)
+			return
+		}
+		if got := r.Header.Get("Accept"); got != exportMediaType {
+			t.Errorf("Accept: got %q", got)
+		}
+		if got := r.Header.Get("Range"); got != "entries=c:2" {
+			t.Errorf("Range: got %q", got)
+		}
+		if got := r.URL.RawQuery; got != "boot&_SYSTEMD_UNIT=foo%40bar.service" {
+			t.Errorf("query: got %q", got)
+		}
+
+		enc := NewEncoder(w)
+		for i := 0; i < 2; i++ {
+			e := &Entry{}
+			e.Add("__CURSOR", fmt.Sprintf("c%d", i))
+			e.Add("MESSAGE", "line\nline")
+			enc.Encode(e)
+		}
+	}))
+	defer srv.Close()
+
+	g, err := NewGateway(srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := g.Entries(context.Background(), &EntriesRequest{
+		Cursor:  "c",
+		Count:   2,
+		Boot:    true,
+		Matches: []string{"_SYSTEMD_UNIT=foo@bar.service"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for i := 0; i < 2; i++ {
+		e, err := s.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c, _ := e.Get("__CURSOR"); c != fmt.Sprintf("c%d", i) {
+			t.Errorf("entry %d: got cursor %q", i, c)
+		}
+		if m, _ := e.Get("MESSAGE"); m != "line\nline" {
+			t.Errorf("entry %d: got message %q", i, m)
+		}
+	}
+	if _, err := s.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+
+	if _, err := g.Entries(context.Background(), &EntriesRequest{Matches: []string{"foo"}}); err == nil {
+		t.Error("expected an error for an invalid match")
+	}
+}
+
+func TestGatewayFollow(t *testing.T) {
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.RawQuery != "follow" {
+			t.Errorf("query: got %q", r.URL.RawQuery)
+		}
+		e := &Entry{}
+		e.Add("MESSAGE", "first")
+		NewEncoder(w).Encode(e)
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	g, err := NewGateway(srv.URL+"/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s, err := g.Entries(ctx, &EntriesRequest{Follow: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if e, err := s.Next(); err != nil {
+		t.Fatal(err)
+	} else if m, _ := e.Get("MESSAGE"); m != "first" {
+		t.Errorf("got message %q", m)
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := s.Next()
+		errc <- err
+	}()
+	cancel()
+	select {
+	case err := <-errc:
+		if err == nil {
+			t.Error("expected an error after cancelling")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Next did not return after cancelling")
+	}
+}
+
+func TestGatewayMachineAndBoots(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/prefix/machine":
+			fmt.Fprint(w, `{ "machine_id" : "8cf7ed9d451ea194b77a9f02ee95f549", "boot_id" : "3d3c9efaf556496a9b04259ee35df7f7", "hostname" : "fedora", "os_pretty_name" : "Fedora 19 (Rawhide)", "virtualization" : "kvm", "usage" : "91136", "cutoff_from_realtime" : "1342540861416409", "cutoff_to_realtime" : "1342541084749943" }`)
+		case "/prefix/fields/_BOOT_ID":
+			fmt.Fprint(w, "3d3c9efaf556496a9b04259ee35df7f7\n1b4c8e4b2d6c4a7e9bf3a5e1d0c8f7a6\n")
+		default:
+			http.Error(w, "No such file", http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	g, err := NewGateway(srv.URL+"/prefix", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := g.Machine(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := MachineInfo{
+		MachineID:          "8cf7ed9d451ea194b77a9f02ee95f549",
+		BootID:             "3d3c9efaf556496a9b04259ee35df7f7",
+		Hostname:           "fedora",
+		OSPrettyName:       "Fedora 19 (Rawhide)",
+		Virtualization:     "kvm",
+		Usage:              91136,
+		CutoffFromRealtime: 1342540861416409,
+		CutoffToRealtime:   1342541084749943,
+	}
+	if *info != want {
+		t.Errorf("got %+v, want %+v", *info, want)
+	}
+
+	boots, err := g.Boots(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(boots) != 2 || boots[0] != "3d3c9efaf556496a9b04259ee35df7f7" {
+		t.Errorf("got boots %q", boots)
+	}
+
+	if _, err := g.FieldValues(context.Background(), "MESSAGE"); err == nil {
+		t.Error("expected an error for a 404 response")
+	}
+}
+
+func TestNewGatewayInvalidURL(t *testing.T) {
+	if _, err := NewGateway("ftp://host", nil); err == nil {
+		t.Error("expected an error")
+	}
+}
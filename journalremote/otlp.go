@@ -0,0 +1,304 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journalremote
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// otlpScopeName is the instrumentation scope of the exported records.
+const otlpScopeName = "github.com/coreos/go-systemd/v22/journalremote"
+
+// otlpSeverities maps journal priorities to OpenTelemetry severity numbers.
+var otlpSeverities = []int{
+	21, // emerg: FATAL
+	19, // alert: ERROR3
+	18, // crit: ERROR2
+	17, // err: ERROR
+	13, // warning: WARN
+	10, // notice: INFO2
+	9,  // info: INFO
+	5,  // debug: DEBUG
+}
+
+var priorityNames = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+// Fields which are not exported as log record attributes, as they are
+// exported as resource attributes or other parts of the record.
+var otlpRecordFields = map[string]bool{
+	"MESSAGE":                    true,
+	"PRIORITY":                   true,
+	"TRACE_ID":                   true,
+	"SPAN_ID":                    true,
+	"_SYSTEMD_UNIT":              true,
+	"_HOSTNAME":                  true,
+	"_MACHINE_ID":                true,
+	"__REALTIME_TIMESTAMP":       true,
+	"_SOURCE_REALTIME_TIMESTAMP": true,
+}
+
+// OTLP/JSON messages, see
+// https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/logs/v1/logs.proto
+type otlpLogsData struct {
+	ResourceLogs []*otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeLogs struct {
+	Scope      otlpScope       `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano         string         `json:"timeUnixNano,omitempty"`
+	ObservedTimeUnixNano string         `json:"observedTimeUnixNano,omitempty"`
+	SeverityNumber       int            `json:"severityNumber,omitempty"`
+	SeverityText         string         `json:"severityText,omitempty"`
+	Body                 *otlpAnyValue  `json:"body,omitempty"`
+	Attributes           []otlpKeyValue `json:"attributes,omitempty"`
+	TraceID              string         `json:"traceId,omitempty"`
+	SpanID               string         `json:"spanId,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	BytesValue  []byte  `json:"bytesValue,omitempty"`
+}
+
+func otlpValue(value []byte) otlpAnyValue {
+	if !utf8.Valid(value) {
+		return otlpAnyValue{BytesValue: value}
+	}
+	s := string(value)
+	return otlpAnyValue{StringValue: &s}
+}
+
+func otlpString(key, value string) otlpKeyValue {
+	return otlpKeyValue{Key: key, Value: otlpAnyValue{StringValue: &value}}
+}
+
+// MarshalOTLP converts entries to OpenTelemetry log records, encoded as an
+// OTLP/JSON ExportLogsServiceRequest:
+//
+//   - the records of the entries of each unit, host and machine are grouped
+//     in a resource, with the attributes systemd.unit and service.name
+//     derived from _SYSTEMD_UNIT, host.name from _HOSTNAME and host.id from
+//     _MACHINE_ID;
+//   - MESSAGE is the body of the record and PRIORITY its severity;
+//   - the time of the record is _SOURCE_REALTIME_TIMESTAMP, or
+//     __REALTIME_TIMESTAMP which is its observed time;
+//   - TRACE_ID and SPAN_ID, if set to valid IDs, link the record to a trace;
+//   - the other fields are attributes of the record, named after them. Only
+//     the first of repeated fields is kept.
+func MarshalOTLP(entries []*Entry) ([]byte, error) {
+	type resourceKey struct{ unit, hostname, machineID string }
+	resources := make(map[resourceKey]*otlpResourceLogs)
+	data := otlpLogsData{ResourceLogs: []*otlpResourceLogs{}}
+
+	for _, e := range entries {
+		unit, _ := e.Get("_SYSTEMD_UNIT")
+		hostname, _ := e.Get("_HOSTNAME")
+		machineID, _ := e.Get("_MACHINE_ID")
+		key := resourceKey{unit, hostname, machineID}
+
+		rl, ok := resources[key]
+		if !ok {
+			rl = &otlpResourceLogs{
+				Resource:  otlpResource{Attributes: otlpResourceAttributes(unit, hostname, machineID)},
+				ScopeLogs: []otlpScopeLogs{{Scope: otlpScope{Name: otlpScopeName}}},
+			}
+			resources[key] = rl
+			data.ResourceLogs = append(data.ResourceLogs, rl)
+		}
+		rl.ScopeLogs[0].LogRecords = append(rl.ScopeLogs[0].LogRecords, otlpRecord(e))
+	}
+	return json.Marshal(data)
+}
+
+func otlpResourceAttributes(unit, hostname, machineID string) []otlpKeyValue {
+	attrs := []otlpKeyValue{}
+	if unit != "" {
+		attrs = append(attrs, otlpString("service.name", strings.TrimSuffix(unit, ".service")))
+		attrs = append(attrs, otlpString("systemd.unit", unit))
+	}
+	if hostname != "" {
+		attrs = append(attrs, otlpString("host.name", hostname))
+	}
+	if machineID != "" {
+		attrs = append(attrs, otlpString("host.id", machineID))
+	}
+	return attrs
+}
+
+func otlpRecord(e *Entry) otlpLogRecord {
+	var r otlpLogRecord
+	if usec, ok := e.Get("__REALTIME_TIMESTAMP"); ok {
+		r.ObservedTimeUnixNano = usecToNsec(usec)
+	}
+	if usec, ok := e.Get("_SOURCE_REALTIME_TIMESTAMP"); ok {
+		r.TimeUnixNano = usecToNsec(usec)
+	}
+	if r.TimeUnixNano == "" {
+		r.TimeUnixNano = r.ObservedTimeUnixNano
+	}
+
+	if p, ok := e.Get("PRIORITY"); ok {
+		if i, err := strconv.Atoi(p); err == nil && i >= 0 && i < len(otlpSeverities) {
+			r.SeverityNumber = otlpSeverities[i]
+			r.SeverityText = priorityNames[i]
+		}
+	}
+	if id, ok := e.Get("TRACE_ID"); ok && validHexID(id, 16) {
+		r.TraceID = strings.ToLower(id)
+	}
+	if id, ok := e.Get("SPAN_ID"); ok && validHexID(id, 8) {
+		r.SpanID = strings.ToLower(id)
+	}
+
+	seen := make(map[string]bool)
+	for _, f := range e.Fields {
+		if f.Name == "MESSAGE" && r.Body == nil {
+			body := otlpValue(f.Value)
+			r.Body = &body
+			continue
+		}
+		if otlpRecordFields[f.Name] || seen[f.Name] {
+			continue
+		}
+		seen[f.Name] = true
+		r.Attributes = append(r.Attributes, otlpKeyValue{Key: f.Name, Value: otlpValue(f.Value)})
+	}
+	return r
+}
+
+// usecToNsec converts a journal timestamp to the nanoseconds of OTLP, or ""
+// if it is invalid.
+func usecToNsec(usec string) string {
+	u, err := strconv.ParseUint(usec, 10, 64)
+	if err != nil || u == 0 {
+		return ""
+	}
+	return strconv.FormatUint(u, 10) + "000"
+}
+
+func validHexID(id string, size int) bool {
+	b, err := hex.DecodeString(id)
+	return err == nil && len(b) == size && strings.Trim(id, "0") != ""
+}
+
+// OTLPExporterConfig configures an OTLPExporter.
+type OTLPExporterConfig struct {
+	// URL is the OTLP/HTTP endpoint of the collector, such as
+	// http://localhost:4318; records are posted to its /v1/logs path,
+	// unless the URL already ends with it.
+	URL string
+	// Client sends the requests; http.DefaultClient if nil.
+	Client *http.Client
+	// Header holds headers added to the requests, for example for
+	// authentication.
+	Header http.Header
+}
+
+// OTLPExporter sends journal entries to an OpenTelemetry collector as log
+// records, with the OTLP/HTTP protocol and the JSON encoding. It is safe for
+// concurrent use.
+type OTLPExporter struct {
+	url    string
+	client *http.Client
+	header http.Header
+}
+
+// NewOTLPExporter returns an OTLPExporter configured by config.
+func NewOTLPExporter(config OTLPExporterConfig) (*OTLPExporter, error) {
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+	if !strings.HasSuffix(u.Path, "/v1/logs") {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/v1/logs"
+		u.RawPath = ""
+	}
+
+	x := &OTLPExporter{url: u.String(), client: config.Client, header: config.Header}
+	if x.client == nil {
+		x.client = http.DefaultClient
+	}
+	return x, nil
+}
+
+// Export sends entries, converted with MarshalOTLP, in a single request.
+func (x *OTLPExporter) Export(ctx context.Context, entries []*Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	data, err := MarshalOTLP(entries)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", x.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	for name, values := range x.header {
+		for _, v := range values {
+			req.Header.Add(name, v)
+		}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := x.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("OTLP export failed: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	return nil
+}
@@ -0,0 +1,175 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journalremote
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func testOTLPEntries() []*Entry {
+	first := NewEntry(map[string]string{
+		"MESSAGE":       "started",
+		"PRIORITY":      "6",
+		"_PID":          "1234",
+		"_SYSTEMD_UNIT": "foo.service",
+		"_HOSTNAME":     "web1",
+		"_MACHINE_ID":   "8cf7ed9d451ea194b77a9f02ee95f549",
+		"TRACE_ID":      "4bf92f3577b34da6a3ce929d0e0e4736",
+		"SPAN_ID":       "00f067aa0ba902b7",
+	}, "s=1;i=1", 1600000000000001, 5)
+	second := NewEntry(map[string]string{
+		"MESSAGE":                    "failed",
+		"PRIORITY":                   "3",
+		"_SYSTEMD_UNIT":              "foo.service",
+		"_HOSTNAME":                  "web1",
+		"_MACHINE_ID":                "8cf7ed9d451ea194b77a9f02ee95f549",
+		"_SOURCE_REALTIME_TIMESTAMP": "1600000000000002",
+		"TRACE_ID":                   "not-an-id",
+	}, "", 1600000000000003, 0)
+	second.Fields = append(second.Fields, Field{Name: "BINARY", Value: []byte{0xff}})
+	kernel := NewEntry(map[string]string{"MESSAGE": "kernel", "_HOSTNAME": "web1"}, "", 1600000000000004, 0)
+	return []*Entry{first, kernel, second}
+}
+
+func TestMarshalOTLP(t *testing.T) {
+	data, err := MarshalOTLP(testOTLPEntries())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got interface{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	var want interface{}
+	if err := json.Unmarshal([]byte(`{"resourceLogs": [
+		{
+			"resource": {"attributes": [
+				{"key": "service.name", "value": {"stringValue": "foo"}},
+				{"key": "systemd.unit", "value": {"stringValue": "foo.service"}},
+				{"key": "host.name", "value": {"stringValue": "web1"}},
+				{"key": "host.id", "value": {"stringValue": "8cf7ed9d451ea194b77a9f02ee95f549"}}
+			]},
+			"scopeLogs": [{
+				"scope": {"name": "github.com/coreos/go-systemd/v22/journalremote"},
+				"logRecords": [
+					{
+						"timeUnixNano": "1600000000000001000",
+						"observedTimeUnixNano": "1600000000000001000",
+						"severityNumber": 9,
+						"severityText": "info",
+						"body": {"stringValue": "started"},
+						"attributes": [
+							{"key": "__CURSOR", "value": {"stringValue": "s=1;i=1"}},
+							{"key": "__MONOTONIC_TIMESTAMP", "value": {"stringValue": "5"}},
+							{"key": "_PID", "value": {"stringValue": "1234"}}
+						],
+						"traceId": "4bf92f3577b34da6a3ce929d0e0e4736",
+						"spanId": "00f067aa0ba902b7"
+					},
+					{
+						"timeUnixNano": "1600000000000002000",
+						"observedTimeUnixNano": "1600000000000003000",
+						"severityNumber": 17,
+						"severityText": "err",
+						"body": {"stringValue": "failed"},
+						"attributes": [
+							{"key": "BINARY", "value": {"bytesValue": "/w=="}}
+						]
+					}
+				]
+			}]
+		},
+		{
+			"resource": {"attributes": [
+				{"key": "host.name", "value": {"stringValue": "web1"}}
+			]},
+			"scopeLogs": [{
+				"scope": {"name": "github.com/coreos/go-systemd/v22/journalremote"},
+				"logRecords": [{
+					"timeUnixNano": "1600000000000004000",
+					"observedTimeUnixNano": "1600000000000004000",
+					"body": {"stringValue": "kernel"}
+				}]
+			}]
+		}
+	]}`), &want); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%s", data)
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/otel/v1/logs" {
+			t.Errorf("got path %q", r.URL.Path)
+		}
+		if got := r.Header.Get("Content-Type"); got != "application/json" {
+			t.Errorf("Content-Type: got %q", got)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer token" {
+			t.Errorf("Authorization: got %q", got)
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		if !json.Valid(body) {
+			t.Errorf("invalid JSON %q", body)
+		}
+		if requests > 1 {
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	x, err := NewOTLPExporter(OTLPExporterConfig{
+		URL:    srv.URL + "/otel",
+		Header: http.Header{"Authorization": {"Bearer token"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := x.Export(ctx, testOTLPEntries()); err != nil {
+		t.Fatal(err)
+	}
+	if err := x.Export(ctx, testOTLPEntries()); err == nil {
+		t.Error("expected an error for a 503 response")
+	}
+	if err := x.Export(ctx, nil); err != nil || requests != 2 {
+		t.Errorf("exporting nothing: got %v after %d requests", err, requests)
+	}
+}
+
+func TestNewEntry(t *testing.T) {
+	e := NewEntry(map[string]string{"B": "2", "A": "1"}, "c", 10, 0)
+	expected := []Field{
+		{"__CURSOR", []byte("c")},
+		{"__REALTIME_TIMESTAMP", []byte("10")},
+		{"A", []byte("1")},
+		{"B", []byte("2")},
+	}
+	if !reflect.DeepEqual(e.Fields, expected) {
+		t.Errorf("got fields %q", e.Fields)
+	}
+}
@@ -0,0 +1,313 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journalremote
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrBufferFull is returned by Uploader.Add when MaxPending entries are
+// buffered already; the entry is dropped.
+var ErrBufferFull = errors.New("upload buffer full")
+
+const (
+	defaultBatchSize     = 1000
+	defaultMaxRetries    = 5
+	defaultRetryInterval = time.Second
+	maxRetryInterval     = time.Minute
+)
+
+// UploaderConfig configures an Uploader.
+type UploaderConfig struct {
+	// URL is the address of systemd-journal-remote, such as
+	// https://host:19532. Entries are posted to its /upload endpoint.
+	URL string
+	// TLSConfig configures TLS, such as the client certificate to
+	// authenticate with. Ignored if Client is set.
+	TLSConfig *tls.Config
+	// Client sends the requests; if nil, a client using TLSConfig is used.
+	Client *http.Client
+	// BatchSize is the number of entries Add buffers before uploading
+	// them. Defaults to 1000.
+	BatchSize int
+	// MaxPending is the number of entries Add buffers at most, while
+	// uploads fail. Defaults to 10 times BatchSize.
+	MaxPending int
+	// MaxRetries is the number of times a failed upload is retried by
+	// Flush and Upload. Defaults to 5; set a negative value to never retry.
+	MaxRetries int
+	// RetryInterval is the delay before the first retry; it is doubled for
+	// each retry, up to a minute. Defaults to a second.
+	RetryInterval time.Duration
+}
+
+// Uploader sends journal entries to systemd-journal-remote, as
+// systemd-journal-upload does. It is safe for concurrent use.
+type Uploader struct {
+	url           string
+	client        *http.Client
+	batchSize     int
+	maxPending    int
+	maxRetries    int
+	retryInterval time.Duration
+
+	// sending holds a token while an upload is in progress, so that
+	// uploads do not interleave; lock is not held while uploading
+	sending chan struct{}
+
+	lock    sync.Mutex
+	pending []pendingEntry
+	cursor  string
+}
+
+// pendingEntry is an entry buffered by Add, in the export format.
+type pendingEntry struct {
+	data   []byte
+	cursor string // the __CURSOR field, if any
+}
+
+// NewUploader returns an Uploader configured by config.
+func NewUploader(config UploaderConfig) (*Uploader, error) {
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/upload"
+	u.RawPath = ""
+
+	up := &Uploader{
+		url:           u.String(),
+		client:        config.Client,
+		batchSize:     config.BatchSize,
+		maxPending:    config.MaxPending,
+		maxRetries:    config.MaxRetries,
+		retryInterval: config.RetryInterval,
+		sending:       make(chan struct{}, 1),
+	}
+	if up.client == nil {
+		up.client = &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: config.TLSConfig,
+			},
+		}
+	}
+	if up.batchSize <= 0 {
+		up.batchSize = defaultBatchSize
+	}
+	if up.maxPending <= 0 {
+		up.maxPending = 10 * up.batchSize
+	}
+	if up.maxRetries == 0 {
+		up.maxRetries = defaultMaxRetries
+	} else if up.maxRetries < 0 {
+		up.maxRetries = 0
+	}
+	if up.retryInterval <= 0 {
+		up.retryInterval = defaultRetryInterval
+	}
+	return up, nil
+}
+
+// Add buffers e, uploading the buffered entries once there are BatchSize of
+// them. Such uploads are attempted once, unless another upload is in
+// progress: if they fail, the error is returned and the entries stay
+// buffered for the next attempt. Once MaxPending entries are buffered, Add
+// drops e and returns ErrBufferFull. An entry which cannot be encoded, for
+// example because of an invalid field name, is not buffered. Call Flush to
+// upload the rest, for example periodically and before exiting.
+func (up *Uploader) Add(ctx context.Context, e *Entry) error {
+	p, err := encodePending(e)
+	if err != nil {
+		return err
+	}
+
+	up.lock.Lock()
+	if len(up.pending) >= up.maxPending {
+		up.lock.Unlock()
+		return ErrBufferFull
+	}
+	up.pending = append(up.pending, p)
+	full := len(up.pending) >= up.batchSize
+	up.lock.Unlock()
+	if !full {
+		return nil
+	}
+
+	select {
+	case up.sending <- struct{}{}:
+	default:
+		// the entries are uploaded along with the next batch
+		return nil
+	}
+	defer func() { <-up.sending }()
+	return up.flush(ctx, 0)
+}
+
+// Flush uploads the buffered entries, retrying as configured. If the upload
+// fails, they stay buffered for the next attempt.
+func (up *Uploader) Flush(ctx context.Context) error {
+	select {
+	case up.sending <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-up.sending }()
+	return up.flush(ctx, up.maxRetries)
+}
+
+// flush uploads the buffered entries. up.sending must be held.
+func (up *Uploader) flush(ctx context.Context, retries int) error {
+	up.lock.Lock()
+	batch := up.pending
+	up.lock.Unlock()
+	if len(batch) == 0 {
+		return nil
+	}
+
+	var data []byte
+	for _, p := range batch {
+		data = append(data, p.data...)
+	}
+	if err := up.send(ctx, data, retries); err != nil {
+		return err
+	}
+
+	up.lock.Lock()
+	defer up.lock.Unlock()
+	// Add may have buffered more entries meanwhile
+	up.pending = append([]pendingEntry(nil), up.pending[len(batch):]...)
+	for i := len(batch) - 1; i >= 0; i-- {
+		if batch[i].cursor != "" {
+			up.cursor = batch[i].cursor
+			break
+		}
+	}
+	return nil
+}
+
+// encodePending encodes e for the buffer of Add.
+func encodePending(e *Entry) (pendingEntry, error) {
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(e); err != nil {
+		return pendingEntry{}, err
+	}
+	c, _ := e.Get("__CURSOR")
+	return pendingEntry{data: buf.Bytes(), cursor: c}, nil
+}
+
+// Upload uploads entries in a single request, bypassing the buffer.
+func (up *Uploader) Upload(ctx context.Context, entries []*Entry) error {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+
+	select {
+	case up.sending <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-up.sending }()
+	if err := up.send(ctx, buf.Bytes(), up.maxRetries); err != nil {
+		return err
+	}
+
+	up.lock.Lock()
+	defer up.lock.Unlock()
+	for i := len(entries) - 1; i >= 0; i-- {
+		if c, ok := entries[i].Get("__CURSOR"); ok {
+			up.cursor = c
+			break
+		}
+	}
+	return nil
+}
+
+// Cursor returns the __CURSOR field of the last entry uploaded which had
+// one, so that uploading can resume after it.
+func (up *Uploader) Cursor() string {
+	up.lock.Lock()
+	defer up.lock.Unlock()
+	return up.cursor
+}
+
+// send posts data, retrying up to retries times on connection errors and
+// server errors.
+func (up *Uploader) send(ctx context.Context, data []byte, retries int) error {
+	interval := up.retryInterval
+	for attempt := 0; ; attempt++ {
+		retry, err := up.post(ctx, data)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= retries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+		interval *= 2
+		if interval > maxRetryInterval {
+			interval = maxRetryInterval
+		}
+	}
+}
+
+// post sends data to the server, and reports whether a failure is worth
+// retrying.
+func (up *Uploader) post(ctx context.Context, data []byte) (bool, error) {
+	// Hiding the length of the body makes it sent with chunked transfer
+	// encoding, as systemd-journal-upload does.
+	body := ioutil.NopCloser(bytes.NewReader(data))
+	req, err := http.NewRequest("POST", up.url, body)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", exportMediaType)
+	resp, err := up.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 {
+		io.Copy(ioutil.Discard, resp.Body)
+		return false, nil
+	}
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("upload failed: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+}
@@ -0,0 +1,253 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journalremote
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// remote is a fake systemd-journal-remote, which fails the first fail
+// requests with status.
+type remote struct {
+	t      *testing.T
+	status int
+	fail   int
+
+	lock     sync.Mutex
+	requests int
+	entries  []*Entry
+}
+
+func (r *remote) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" || req.URL.Path != "/upload" {
+		r.t.Errorf("unexpected request %s %s", req.Method, req.URL.Path)
+	}
+	if got := req.Header.Get("Content-Type"); got != exportMediaType {
+		r.t.Errorf("Content-Type: got %q", got)
+	}
+	if len(req.TransferEncoding) != 1 || req.TransferEncoding[0] != "chunked" {
+		r.t.Errorf("Transfer-Encoding: got %q", req.TransferEncoding)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.requests++
+	if r.fail > 0 {
+		r.fail--
+		http.Error(w, "nope", r.status)
+		return
+	}
+
+	dec := NewDecoder(req.Body)
+	for {
+		e, err := dec.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			r.t.Error(err)
+			break
+		}
+		r.entries = append(r.entries, e)
+	}
+	fmt.Fprintln(w, "OK.")
+}
+
+func testEntry(i int) *Entry {
+	e := &Entry{}
+	e.Add("__CURSOR", fmt.Sprintf("c%d", i))
+	e.Add("MESSAGE", fmt.Sprintf("entry %d", i))
+	return e
+}
+
+func TestUploaderBatching(t *testing.T) {
+	r := &remote{t: t}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	up, err := NewUploader(UploaderConfig{URL: srv.URL, BatchSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		if err := up.Add(ctx, testEntry(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if r.requests != 2 || len(r.entries) != 4 {
+		t.Errorf("got %d requests with %d entries before flushing", r.requests, len(r.entries))
+	}
+	if c := up.Cursor(); c != "c3" {
+		t.Errorf("got cursor %q", c)
+	}
+
+	if err := up.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if r.requests != 3 || len(r.entries) != 5 {
+		t.Errorf("got %d requests with %d entries after flushing", r.requests, len(r.entries))
+	}
+	for i, e := range r.entries {
+		if m, _ := e.Get("MESSAGE"); m != fmt.Sprintf("entry %d", i) {
+			t.Errorf("entry %d: got message %q", i, m)
+		}
+	}
+	if c := up.Cursor(); c != "c4" {
+		t.Errorf("got cursor %q", c)
+	}
+
+	if err := up.Flush(ctx); err != nil || r.requests != 3 {
+		t.Errorf("flushing nothing: got %v after %d requests", err, r.requests)
+	}
+}
+
+func TestUploaderRetry(t *testing.T) {
+	r := &remote{t: t, status: http.StatusServiceUnavailable, fail: 2}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	up, err := NewUploader(UploaderConfig{URL: srv.URL, RetryInterval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := up.Upload(context.Background(), []*Entry{testEntry(0)}); err != nil {
+		t.Fatal(err)
+	}
+	if r.requests != 3 || len(r.entries) != 1 {
+		t.Errorf("got %d requests with %d entries", r.requests, len(r.entries))
+	}
+}
+
+func TestUploaderNoRetry(t *testing.T) {
+	r := &remote{t: t, status: http.StatusBadRequest, fail: 1}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	up, err := NewUploader(UploaderConfig{URL: srv.URL, RetryInterval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := up.Add(ctx, testEntry(0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := up.Flush(ctx); err == nil {
+		t.Fatal("expected an error")
+	}
+	if r.requests != 1 {
+		t.Errorf("got %d requests", r.requests)
+	}
+
+	// the entry stays buffered for the next attempt
+	if err := up.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.entries) != 1 {
+		t.Errorf("got %d entries", len(r.entries))
+	}
+}
+
+func TestUploaderRetriesExhausted(t *testing.T) {
+	r := &remote{t: t, status: http.StatusInternalServerError, fail: 10}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	up, err := NewUploader(UploaderConfig{URL: srv.URL, MaxRetries: 2, RetryInterval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := up.Upload(context.Background(), []*Entry{testEntry(0)}); err == nil {
+		t.Fatal("expected an error")
+	}
+	if r.requests != 3 {
+		t.Errorf("got %d requests", r.requests)
+	}
+}
+
+func TestUploaderInvalidEntry(t *testing.T) {
+	r := &remote{t: t}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	up, err := NewUploader(UploaderConfig{URL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	bad := &Entry{}
+	bad.Add("message", "lowercase")
+	if err := up.Add(ctx, bad); err == nil {
+		t.Error("expected an error for an invalid field name")
+	}
+
+	// the invalid entry is not buffered
+	if err := up.Add(ctx, testEntry(0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := up.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.entries) != 1 {
+		t.Errorf("got %d entries", len(r.entries))
+	}
+}
+
+func TestUploaderOutage(t *testing.T) {
+	r := &remote{t: t, status: http.StatusServiceUnavailable, fail: 100}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	up, err := NewUploader(UploaderConfig{URL: srv.URL, BatchSize: 2, MaxPending: 3, RetryInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := up.Add(ctx, testEntry(0)); err != nil {
+		t.Fatal(err)
+	}
+	// uploads from Add are not retried
+	for i := 1; i < 3; i++ {
+		if err := up.Add(ctx, testEntry(i)); err == nil {
+			t.Errorf("entry %d: expected an upload error", i)
+		}
+	}
+	if r.requests != 2 {
+		t.Errorf("got %d requests", r.requests)
+	}
+	if err := up.Add(ctx, testEntry(3)); err != ErrBufferFull {
+		t.Errorf("got error %v, want ErrBufferFull", err)
+	}
+
+	r.lock.Lock()
+	r.fail = 0
+	r.lock.Unlock()
+	if err := up.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.entries) != 3 {
+		t.Errorf("got %d entries", len(r.entries))
+	}
+	if c := up.Cursor(); c != "c2" {
+		t.Errorf("got cursor %q", c)
+	}
+}
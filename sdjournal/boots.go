@@ -0,0 +1,108 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdjournal
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Boot describes a boot of which the journal has entries.
+type Boot struct {
+	// Index is the position of the boot relative to the last one, as
+	// used by journalctl --boot: 0 for the last boot, -1 for the one
+	// before, and so on.
+	Index int
+	ID    string    // the _BOOT_ID of the entries
+	First time.Time // the time of the first entry of the boot
+	Last  time.Time // the time of the last entry of the boot
+}
+
+// ListBoots returns the boots of which j has entries, from the first to the
+// last, like `journalctl --list-boots`. The ID of the boot before the last
+// one, which `journalctl -b -1` shows, is boots[len(boots)-2].ID, for
+// example, and can be set as the BootID of a Filter.
+//
+// ListBoots flushes the matches of j and leaves its read pointer at an
+// unspecified position.
+func (j *Journal) ListBoots() ([]Boot, error) {
+	ids, err := j.GetUniqueValues(SD_JOURNAL_FIELD_BOOT_ID)
+	if err != nil {
+		return nil, err
+	}
+
+	j.FlushMatches()
+	defer j.FlushMatches()
+
+	boots := make([]Boot, 0, len(ids))
+	for _, id := range ids {
+		boot, ok, err := j.boot(id)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			boots = append(boots, boot)
+		}
+	}
+
+	indexBoots(boots)
+	return boots, nil
+}
+
+// boot returns the boot id, and whether j has entries of it.
+func (j *Journal) boot(id string) (Boot, bool, error) {
+	j.FlushMatches()
+	if err := j.AddMatch(SD_JOURNAL_FIELD_BOOT_ID + "=" + id); err != nil {
+		return Boot{}, false, err
+	}
+
+	first, ok, err := j.bootEdge(j.SeekHead, j.Next)
+	if err != nil || !ok {
+		return Boot{}, ok, err
+	}
+	last, ok, err := j.bootEdge(j.SeekTail, j.Previous)
+	if err != nil || !ok {
+		return Boot{}, ok, err
+	}
+	return Boot{ID: id, First: first, Last: last}, true, nil
+}
+
+// bootEdge returns the time of the entry found by seek and step, and whether
+// there is one.
+func (j *Journal) bootEdge(seek func() error, step func() (uint64, error)) (time.Time, bool, error) {
+	if err := seek(); err != nil {
+		return time.Time{}, false, err
+	}
+	if n, err := step(); err != nil || n == 0 {
+		return time.Time{}, false, err
+	}
+	usec, err := j.GetRealtimeUsec()
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to get the time of a boot: %v", err)
+	}
+	return time.Unix(0, int64(usec)*int64(time.Microsecond)), true, nil
+}
+
+// indexBoots sorts boots by the time of their first entry and sets their
+// Index.
+func indexBoots(boots []Boot) {
+	sort.Slice(boots, func(i, k int) bool {
+		return boots[i].First.Before(boots[k].First)
+	})
+	for i := range boots {
+		boots[i].Index = i - (len(boots) - 1)
+	}
+}
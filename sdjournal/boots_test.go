@@ -0,0 +1,65 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdjournal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIndexBoots(t *testing.T) {
+	now := time.Now()
+	boots := []Boot{
+		{ID: "b", First: now.Add(-time.Hour), Last: now.Add(-time.Minute)},
+		{ID: "c", First: now, Last: now},
+		{ID: "a", First: now.Add(-2 * time.Hour), Last: now.Add(-90 * time.Minute)},
+	}
+	indexBoots(boots)
+
+	for i, want := range []struct {
+		id    string
+		index int
+	}{{"a", -2}, {"b", -1}, {"c", 0}} {
+		if boots[i].ID != want.id || boots[i].Index != want.index {
+			t.Errorf("boot %d: got %s at index %d, want %s at index %d", i, boots[i].ID, boots[i].Index, want.id, want.index)
+		}
+	}
+}
+
+func TestListBoots(t *testing.T) {
+	j, err := NewJournal()
+	if err != nil {
+		t.Fatalf("Error opening journal: %s", err)
+	}
+	defer j.Close()
+
+	boots, err := j.ListBoots()
+	if err != nil {
+		t.Fatalf("Error listing boots: %s", err)
+	}
+	if len(boots) == 0 {
+		t.Skip("no boots in the journal")
+	}
+
+	last := boots[len(boots)-1]
+	if last.Index != 0 {
+		t.Errorf("got index %d for the last boot", last.Index)
+	}
+	for _, b := range boots {
+		if b.ID == "" || b.Last.Before(b.First) {
+			t.Errorf("invalid boot %+v", b)
+		}
+	}
+}
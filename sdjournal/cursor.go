@@ -0,0 +1,129 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdjournal
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GetCursor returns the cursor of the last entry read by the JournalReader.
+// Passed to SeekCursor, possibly by another process after a restart, it
+// makes reading resume with the entry following this one.
+func (r *JournalReader) GetCursor() (string, error) {
+	return r.journal.GetCursor()
+}
+
+// TestCursor checks whether the last entry read by the JournalReader is the
+// one of cursor, returning ErrNoTestCursor if it is not.
+func (r *JournalReader) TestCursor(cursor string) error {
+	return r.journal.TestCursor(cursor)
+}
+
+// SeekCursor positions the JournalReader so that the next entry read is the
+// one following the entry of cursor, as returned by GetCursor. If that entry
+// no longer exists, for example because the journal was rotated since,
+// reading resumes with the closest entry after it.
+func (r *JournalReader) SeekCursor(cursor string) error {
+	r.msgReader = nil
+	if err := r.journal.SeekCursor(cursor); err != nil {
+		return err
+	}
+
+	n, err := r.journal.Next()
+	if err != nil || n == 0 {
+		return err
+	}
+	err = r.journal.TestCursor(cursor)
+	if err == ErrNoTestCursor {
+		// not the entry of cursor, but the one following it: step back so
+		// that it is read next
+		_, err = r.journal.Previous()
+	}
+	return err
+}
+
+// CursorStore saves the position of a journal reader, so that reading can
+// resume where it left off after a restart:
+//
+//	store := sdjournal.NewFileCursorStore("/var/lib/shipper/cursor")
+//	cursor, err := store.Load()
+//	...
+//	if cursor != "" {
+//		err = r.SeekCursor(cursor)
+//	}
+//	for {
+//		// read and ship entries, then checkpoint
+//		cursor, err = r.GetCursor()
+//		...
+//		err = store.Save(cursor)
+//	}
+type CursorStore interface {
+	// Load returns the saved cursor, or an empty string if there is none.
+	Load() (string, error)
+	// Save saves cursor, replacing the saved one.
+	Save(cursor string) error
+}
+
+// FileCursorStore is a CursorStore keeping the cursor in a file.
+type FileCursorStore struct {
+	path string
+}
+
+var _ CursorStore = (*FileCursorStore)(nil)
+
+// NewFileCursorStore returns a CursorStore keeping the cursor in the file at
+// path. The file is replaced atomically on Save, so that a crash never
+// leaves a partially written cursor behind.
+func NewFileCursorStore(path string) *FileCursorStore {
+	return &FileCursorStore{path: path}
+}
+
+// Load returns the cursor saved in the file, or an empty string if the file
+// does not exist.
+func (s *FileCursorStore) Load() (string, error) {
+	data, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// Save writes cursor to the file.
+func (s *FileCursorStore) Save(cursor string) error {
+	f, err := ioutil.TempFile(filepath.Dir(s.path), "."+filepath.Base(s.path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.WriteString(cursor + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.path)
+}
@@ -0,0 +1,59 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdjournal
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileCursorStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sdjournal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := NewFileCursorStore(filepath.Join(dir, "cursor"))
+	cursor, err := store.Load()
+	if err != nil {
+		t.Fatalf("Error loading missing cursor: %s", err)
+	}
+	if cursor != "" {
+		t.Fatalf("Got cursor %q without a saved one", cursor)
+	}
+
+	for _, c := range []string{"s=1;i=2", "s=1;i=3"} {
+		if err := store.Save(c); err != nil {
+			t.Fatalf("Error saving cursor: %s", err)
+		}
+		if cursor, err = store.Load(); err != nil {
+			t.Fatalf("Error loading cursor: %s", err)
+		}
+		if cursor != c {
+			t.Errorf("Got cursor %q, want %q", cursor, c)
+		}
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("Got %d files, want only the cursor file", len(files))
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdjournal
+
+import (
+	"strconv"
+	"time"
+)
+
+// Time returns the realtime (wallclock) timestamp of the entry.
+func (e *JournalEntry) Time() time.Time {
+	return time.Unix(0, int64(e.RealtimeTimestamp)*int64(time.Microsecond))
+}
+
+// Monotonic returns the monotonic timestamp of the entry, the time since
+// its boot started.
+func (e *JournalEntry) Monotonic() time.Duration {
+	return time.Duration(e.MonotonicTimestamp) * time.Microsecond
+}
+
+// Message returns the MESSAGE field of the entry.
+func (e *JournalEntry) Message() string {
+	return e.Fields[SD_JOURNAL_FIELD_MESSAGE]
+}
+
+// Identifier returns the SYSLOG_IDENTIFIER field of the entry.
+func (e *JournalEntry) Identifier() string {
+	return e.Fields[SD_JOURNAL_FIELD_SYSLOG_IDENTIFIER]
+}
+
+// Unit returns the _SYSTEMD_UNIT field of the entry, the system unit of the
+// process that logged it.
+func (e *JournalEntry) Unit() string {
+	return e.Fields[SD_JOURNAL_FIELD_SYSTEMD_UNIT]
+}
+
+// UserUnit returns the _SYSTEMD_USER_UNIT field of the entry, the user unit
+// of the process that logged it.
+func (e *JournalEntry) UserUnit() string {
+	return e.Fields[SD_JOURNAL_FIELD_SYSTEMD_USER_UNIT]
+}
+
+// BootID returns the _BOOT_ID field of the entry.
+func (e *JournalEntry) BootID() string {
+	return e.Fields[SD_JOURNAL_FIELD_BOOT_ID]
+}
+
+// Priority returns the PRIORITY field of the entry, from 0 (emerg) to 7
+// (debug), and whether it is set to a valid priority.
+func (e *JournalEntry) Priority() (int, bool) {
+	p, ok := e.intField(SD_JOURNAL_FIELD_PRIORITY)
+	if !ok || p < 0 || p >= len(priorityNames) {
+		return 0, false
+	}
+	return p, true
+}
+
+// PID returns the _PID field of the entry, and whether it is set.
+func (e *JournalEntry) PID() (int, bool) {
+	return e.intField(SD_JOURNAL_FIELD_PID)
+}
+
+// UID returns the _UID field of the entry, and whether it is set.
+func (e *JournalEntry) UID() (int, bool) {
+	return e.intField(SD_JOURNAL_FIELD_UID)
+}
+
+func (e *JournalEntry) intField(name string) (int, bool) {
+	v, ok := e.Fields[name]
+	if !ok {
+		return 0, false
+	}
+	i, err := strconv.Atoi(v)
+	return i, err == nil
+}
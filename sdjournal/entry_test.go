@@ -0,0 +1,75 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdjournal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJournalEntryAccessors(t *testing.T) {
+	entry := &JournalEntry{
+		Fields: map[string]string{
+			"MESSAGE":            "hello",
+			"SYSLOG_IDENTIFIER":  "worker",
+			"PRIORITY":           "3",
+			"_PID":               "1234",
+			"_UID":               "0",
+			"_SYSTEMD_UNIT":      "user@1000.service",
+			"_SYSTEMD_USER_UNIT": "worker.service",
+			"_BOOT_ID":           "3d3c9efaf556496a9b04259ee35df7f7",
+		},
+		RealtimeTimestamp:  1600000000123456,
+		MonotonicTimestamp: 2500000,
+	}
+
+	if got, want := entry.Time(), time.Unix(1600000000, 123456000); !got.Equal(want) {
+		t.Errorf("Time: got %v, want %v", got, want)
+	}
+	if got := entry.Monotonic(); got != 2500*time.Millisecond {
+		t.Errorf("Monotonic: got %v", got)
+	}
+	for name, tt := range map[string]struct{ got, want string }{
+		"Message":    {entry.Message(), "hello"},
+		"Identifier": {entry.Identifier(), "worker"},
+		"Unit":       {entry.Unit(), "user@1000.service"},
+		"UserUnit":   {entry.UserUnit(), "worker.service"},
+		"BootID":     {entry.BootID(), "3d3c9efaf556496a9b04259ee35df7f7"},
+	} {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %q, want %q", name, tt.got, tt.want)
+		}
+	}
+	if p, ok := entry.Priority(); !ok || p != 3 {
+		t.Errorf("Priority: got %d, %v", p, ok)
+	}
+	if pid, ok := entry.PID(); !ok || pid != 1234 {
+		t.Errorf("PID: got %d, %v", pid, ok)
+	}
+	if uid, ok := entry.UID(); !ok || uid != 0 {
+		t.Errorf("UID: got %d, %v", uid, ok)
+	}
+
+	entry.Fields = map[string]string{"PRIORITY": "9", "_PID": "abc"}
+	if _, ok := entry.Priority(); ok {
+		t.Error("Priority: expected an invalid priority")
+	}
+	if _, ok := entry.PID(); ok {
+		t.Error("PID: expected an invalid PID")
+	}
+	if _, ok := entry.UID(); ok {
+		t.Error("UID: expected no UID")
+	}
+}
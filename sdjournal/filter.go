@@ -0,0 +1,126 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdjournal
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+var priorityNames = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+// Filter selects journal entries like the options of journalctl of the same
+// names. Entries of any of Units or UserUnits are selected, and of those,
+// the entries matching all other fields that are set.
+type Filter struct {
+	Units       []string // _SYSTEMD_UNIT values, like --unit
+	UserUnits   []string // _SYSTEMD_USER_UNIT values, like --user-unit
+	Identifiers []string // SYSLOG_IDENTIFIER values, like --identifier
+	// MaxPriority selects the entries with this priority or a more
+	// important one, like --priority. It is a priority name, such as
+	// "warning", or number, such as "4".
+	MaxPriority string
+	BootID      string    // _BOOT_ID value, like --boot
+	Since       time.Time // the earliest entry time, like --since
+	Until       time.Time // the latest entry time, like --until
+}
+
+// matcher builds the match expression of a journal, as Journal does.
+type matcher interface {
+	AddMatch(match string) error
+	AddDisjunction() error
+	AddConjunction() error
+}
+
+// AddMatches adds the matches for the fields of f to j. Since and Until are
+// not matches: the read pointer must be positioned with SeekRealtimeUsec,
+// and reading stopped after Until, by the caller. NewJournalReader does both
+// for JournalReaderConfig.Filter.
+func (f *Filter) AddMatches(j *Journal) error {
+	return f.addMatches(j)
+}
+
+func (f *Filter) addMatches(j matcher) error {
+	maxPriority := -1
+	if f.MaxPriority != "" {
+		p, err := parsePriority(f.MaxPriority)
+		if err != nil {
+			return err
+		}
+		maxPriority = p
+	}
+
+	// Matches on the same field are ORed, matches on different fields
+	// ANDed, disjunctions OR the groups before and after them, and
+	// conjunctions AND the groups of disjunctions before and after them:
+	// (unit matches) OR (user unit matches), AND the other matches.
+	if len(f.Units) > 0 || len(f.UserUnits) > 0 {
+		if err := addMatches(j, SD_JOURNAL_FIELD_SYSTEMD_UNIT, f.Units...); err != nil {
+			return err
+		}
+		if len(f.Units) > 0 && len(f.UserUnits) > 0 {
+			if err := j.AddDisjunction(); err != nil {
+				return err
+			}
+		}
+		if err := addMatches(j, SD_JOURNAL_FIELD_SYSTEMD_USER_UNIT, f.UserUnits...); err != nil {
+			return err
+		}
+		if err := j.AddConjunction(); err != nil {
+			return err
+		}
+	}
+
+	for p := 0; p <= maxPriority; p++ {
+		if err := addMatches(j, SD_JOURNAL_FIELD_PRIORITY, strconv.Itoa(p)); err != nil {
+			return err
+		}
+	}
+	if f.BootID != "" {
+		if err := addMatches(j, SD_JOURNAL_FIELD_BOOT_ID, f.BootID); err != nil {
+			return err
+		}
+	}
+	return addMatches(j, SD_JOURNAL_FIELD_SYSLOG_IDENTIFIER, f.Identifiers...)
+}
+
+func addMatches(j matcher, field string, values ...string) error {
+	for _, v := range values {
+		m := Match{Field: field, Value: v}
+		if err := j.AddMatch(m.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parsePriority parses a syslog priority name or number.
+func parsePriority(s string) (int, error) {
+	for i, name := range priorityNames {
+		if s == name {
+			return i, nil
+		}
+	}
+	if p, err := strconv.Atoi(s); err == nil && p >= 0 && p < len(priorityNames) {
+		return p, nil
+	}
+	return 0, fmt.Errorf("invalid priority %q", s)
+}
+
+// timeUsec returns t in microseconds since the epoch.
+func timeUsec(t time.Time) uint64 {
+	return uint64(t.UnixNano() / 1000)
+}
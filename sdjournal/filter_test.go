@@ -0,0 +1,133 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdjournal
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/journal"
+)
+
+func TestParsePriority(t *testing.T) {
+	for s, expected := range map[string]int{"emerg": 0, "warning": 4, "4": 4, "debug": 7} {
+		p, err := parsePriority(s)
+		if err != nil {
+			t.Errorf("parsePriority(%q): %v", s, err)
+		} else if p != expected {
+			t.Errorf("parsePriority(%q) = %d, want %d", s, p, expected)
+		}
+	}
+
+	for _, s := range []string{"", "8", "-1", "warn"} {
+		if _, err := parsePriority(s); err == nil {
+			t.Errorf("parsePriority(%q): expected an error", s)
+		}
+	}
+}
+
+// matchRecorder records a match expression, with "+" for a disjunction and
+// "*" for a conjunction.
+type matchRecorder struct {
+	terms []string
+}
+
+func (m *matchRecorder) AddMatch(match string) error {
+	m.terms = append(m.terms, match)
+	return nil
+}
+
+func (m *matchRecorder) AddDisjunction() error {
+	m.terms = append(m.terms, "+")
+	return nil
+}
+
+func (m *matchRecorder) AddConjunction() error {
+	m.terms = append(m.terms, "*")
+	return nil
+}
+
+func TestReaderMatches(t *testing.T) {
+	config := JournalReaderConfig{
+		Matches: []Match{{Field: SD_JOURNAL_FIELD_SYSLOG_IDENTIFIER, Value: "app"}},
+		Filter: &Filter{
+			Units:       []string{"a.service"},
+			UserUnits:   []string{"b.service"},
+			MaxPriority: "crit",
+		},
+	}
+	var m matchRecorder
+	if err := addReaderMatches(&m, &config); err != nil {
+		t.Fatal(err)
+	}
+	expected := "SYSLOG_IDENTIFIER=app * " +
+		"_SYSTEMD_UNIT=a.service + _SYSTEMD_USER_UNIT=b.service * " +
+		"PRIORITY=0 PRIORITY=1 PRIORITY=2"
+	if got := strings.Join(m.terms, " "); got != expected {
+		t.Errorf("got match expression %q, want %q", got, expected)
+	}
+
+	config.Matches = nil
+	m.terms = nil
+	if err := addReaderMatches(&m, &config); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.terms) == 0 || m.terms[0] == "*" {
+		t.Errorf("got match expression %q, want no leading conjunction", strings.Join(m.terms, " "))
+	}
+}
+
+func TestJournalReaderFilter(t *testing.T) {
+	if !journal.Enabled() {
+		t.Skip("systemd journal not available locally")
+	}
+
+	id := "go-systemd-test-" + time.Now().Format("150405.000000")
+	vars := map[string]string{"SYSLOG_IDENTIFIER": id}
+	if err := journal.Send("important", journal.PriErr, vars); err != nil {
+		t.Fatalf("Error writing to journal: %s", err)
+	}
+	if err := journal.Send("chatty", journal.PriDebug, vars); err != nil {
+		t.Fatalf("Error writing to journal: %s", err)
+	}
+
+	// wait for the entries to be written
+	time.Sleep(time.Second)
+
+	r, err := NewJournalReader(JournalReaderConfig{
+		Filter: &Filter{
+			Identifiers: []string{id},
+			MaxPriority: "warning",
+			Since:       time.Now().Add(-time.Minute),
+		},
+		Formatter: func(entry *JournalEntry) (string, error) {
+			return entry.Fields["MESSAGE"] + "\n", nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Error opening journal: %s", err)
+	}
+	defer r.Close()
+
+	out, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Error reading journal: %s", err)
+	}
+	if lines := strings.Fields(string(out)); len(lines) != 1 || lines[0] != "important" {
+		t.Errorf("Got messages %q, want only %q", lines, "important")
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdjournal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// FormatterByName returns the formatter for the journalctl output mode
+// name: "json", "json-pretty", "short-iso", "verbose" or "cat".
+func FormatterByName(name string) (func(entry *JournalEntry) (string, error), error) {
+	switch name {
+	case "json":
+		return JSONFormatter, nil
+	case "json-pretty":
+		return JSONPrettyFormatter, nil
+	case "short-iso":
+		return ShortISOFormatter, nil
+	case "verbose":
+		return VerboseFormatter, nil
+	case "cat":
+		return CatFormatter, nil
+	}
+	return nil, fmt.Errorf("unknown output mode %q", name)
+}
+
+// The formatters below render journal entries like journalctl --output, for
+// use as JournalReaderConfig.Formatter. Times are shown in the local time
+// zone.
+
+// JSONFormatter renders an entry as a JSON object on a single line, like
+// journalctl -o json.
+func JSONFormatter(entry *JournalEntry) (string, error) {
+	return jsonFormat(entry, false)
+}
+
+// JSONPrettyFormatter renders an entry as an indented JSON object, like
+// journalctl -o json-pretty.
+func JSONPrettyFormatter(entry *JournalEntry) (string, error) {
+	return jsonFormat(entry, true)
+}
+
+func jsonFormat(entry *JournalEntry, pretty bool) (string, error) {
+	obj := make(map[string]interface{}, len(entry.Fields)+3)
+	for k, v := range entry.Fields {
+		if utf8.ValidString(v) {
+			obj[k] = v
+		} else {
+			// journalctl renders binary values as arrays of bytes
+			b := make([]int, len(v))
+			for i := 0; i < len(v); i++ {
+				b[i] = int(v[i])
+			}
+			obj[k] = b
+		}
+	}
+	obj[SD_JOURNAL_FIELD_CURSOR] = entry.Cursor
+	obj[SD_JOURNAL_FIELD_REALTIME_TIMESTAMP] = strconv.FormatUint(entry.RealtimeTimestamp, 10)
+	obj[SD_JOURNAL_FIELD_MONOTONIC_TIMESTAMP] = strconv.FormatUint(entry.MonotonicTimestamp, 10)
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if pretty {
+		enc.SetIndent("", "\t")
+	}
+	if err := enc.Encode(obj); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// ShortISOFormatter renders an entry like a syslog line with an ISO 8601
+// timestamp, like journalctl -o short-iso.
+func ShortISOFormatter(entry *JournalEntry) (string, error) {
+	msg, ok := entry.Fields[SD_JOURNAL_FIELD_MESSAGE]
+	if !ok {
+		return "", fmt.Errorf("no MESSAGE field present in journal entry")
+	}
+
+	identifier := entry.Fields[SD_JOURNAL_FIELD_SYSLOG_IDENTIFIER]
+	if identifier == "" {
+		identifier = entry.Fields[SD_JOURNAL_FIELD_COMM]
+	}
+	pid := entry.Fields[SD_JOURNAL_FIELD_SYSLOG_PID]
+	if pid == "" {
+		pid = entry.Fields[SD_JOURNAL_FIELD_PID]
+	}
+	if pid != "" {
+		identifier += "[" + pid + "]"
+	}
+
+	timestamp := entry.Time().Format("2006-01-02T15:04:05-07:00")
+	return fmt.Sprintf("%s %s %s: %s\n", timestamp, entry.Fields[SD_JOURNAL_FIELD_HOSTNAME], identifier, msg), nil
+}
+
+// VerboseFormatter renders an entry with all its fields, like journalctl -o
+// verbose.
+func VerboseFormatter(entry *JournalEntry) (string, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s [%s]\n", entry.Time().Format("Mon 2006-01-02 15:04:05.000000 MST"), entry.Cursor)
+
+	keys := make([]string, 0, len(entry.Fields))
+	for k := range entry.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&buf, "    %s=%s\n", k, entry.Fields[k])
+	}
+	return buf.String(), nil
+}
+
+// CatFormatter renders the message of an entry only, like journalctl -o cat.
+func CatFormatter(entry *JournalEntry) (string, error) {
+	msg, ok := entry.Fields[SD_JOURNAL_FIELD_MESSAGE]
+	if !ok {
+		return "", fmt.Errorf("no MESSAGE field present in journal entry")
+	}
+	return msg + "\n", nil
+}
@@ -0,0 +1,64 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdjournal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatters(t *testing.T) {
+	entry := &JournalEntry{
+		Fields: map[string]string{
+			"MESSAGE":           "hello \"world\"",
+			"SYSLOG_IDENTIFIER": "app",
+			"_PID":              "42",
+			"_HOSTNAME":         "host",
+			"BINARY":            "\xff",
+		},
+		Cursor:             "s=1;i=2",
+		RealtimeTimestamp:  1600000000123456,
+		MonotonicTimestamp: 789,
+	}
+	ts := time.Unix(1600000000, 123456000)
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"json", `{"BINARY":[255],"MESSAGE":"hello \"world\"","SYSLOG_IDENTIFIER":"app","_HOSTNAME":"host","_PID":"42","__CURSOR":"s=1;i=2","__MONOTONIC_TIMESTAMP":"789","__REALTIME_TIMESTAMP":"1600000000123456"}` + "\n"},
+		{"short-iso", ts.Format("2006-01-02T15:04:05-07:00") + " host app[42]: hello \"world\"\n"},
+		{"verbose", ts.Format("Mon 2006-01-02 15:04:05.000000 MST") + " [s=1;i=2]\n" +
+			"    BINARY=\xff\n    MESSAGE=hello \"world\"\n    SYSLOG_IDENTIFIER=app\n    _HOSTNAME=host\n    _PID=42\n"},
+		{"cat", "hello \"world\"\n"},
+	}
+	for _, tt := range tests {
+		f, err := FormatterByName(tt.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := f(entry)
+		if err != nil {
+			t.Fatalf("Error formatting %s: %s", tt.name, err)
+		}
+		if out != tt.expected {
+			t.Errorf("Got %s output %q, want %q", tt.name, out, tt.expected)
+		}
+	}
+
+	if _, err := FormatterByName("export"); err == nil {
+		t.Error("Expected an error for an unknown output mode")
+	}
+}
@@ -48,6 +48,15 @@ package sdjournal
 // }
 //
 // int
+// my_sd_journal_open_namespace(void *f, sd_journal **ret, const char *namespace, int flags)
+// {
+//   int (*sd_journal_open_namespace)(sd_journal **, const char *, int);
+//
+//   sd_journal_open_namespace = f;
+//   return sd_journal_open_namespace(ret, namespace, flags);
+// }
+//
+// int
 // my_sd_journal_open_files(void *f, sd_journal **ret, const char **paths, int flags)
 // {
 //   int (*sd_journal_open_files)(sd_journal **, const char **, int);
@@ -300,9 +309,24 @@ package sdjournal
 //   return sd_journal_get_catalog(j, ret);
 // }
 //
+// int
+// my_sd_journal_get_catalog_for_message_id(void *f, const unsigned char *id, char **ret)
+// {
+//   int(*sd_journal_get_catalog_for_message_id)(sd_id128_t, char **);
+//   sd_id128_t message_id;
+//   int i;
+//
+//   for (i = 0; i < 16; i++)
+//     message_id.bytes[i] = id[i];
+//
+//   sd_journal_get_catalog_for_message_id = f;
+//   return sd_journal_get_catalog_for_message_id(message_id, ret);
+// }
+//
 import "C"
 import (
 	"bytes"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"strings"
@@ -310,6 +334,8 @@ import (
 	"syscall"
 	"time"
 	"unsafe"
+
+	"github.com/coreos/go-systemd/v22/journalfile"
 )
 
 // Journal entry field strings which correspond to:
@@ -404,6 +430,22 @@ func (m *Match) String() string {
 
 // NewJournal returns a new Journal instance pointing to the local journal
 func NewJournal() (j *Journal, err error) {
+	return newJournal(C.SD_JOURNAL_LOCAL_ONLY)
+}
+
+// NewSystemJournal returns a new Journal instance pointing to the local
+// journal of system services and the kernel only.
+func NewSystemJournal() (j *Journal, err error) {
+	return newJournal(C.SD_JOURNAL_LOCAL_ONLY | C.SD_JOURNAL_SYSTEM)
+}
+
+// NewUserJournal returns a new Journal instance pointing to the local
+// journal of the current user only.
+func NewUserJournal() (j *Journal, err error) {
+	return newJournal(C.SD_JOURNAL_LOCAL_ONLY | C.SD_JOURNAL_CURRENT_USER)
+}
+
+func newJournal(flags C.int) (j *Journal, err error) {
 	j = &Journal{}
 
 	sd_journal_open, err := getFunction("sd_journal_open")
@@ -411,7 +453,7 @@ func NewJournal() (j *Journal, err error) {
 		return nil, err
 	}
 
-	r := C.my_sd_journal_open(sd_journal_open, &j.cjournal, C.SD_JOURNAL_LOCAL_ONLY)
+	r := C.my_sd_journal_open(sd_journal_open, &j.cjournal, flags)
 
 	if r < 0 {
 		return nil, fmt.Errorf("failed to open journal: %s", syscall.Errno(-r).Error())
@@ -441,6 +483,48 @@ func NewJournalFromDir(path string) (j *Journal, err error) {
 	return j, nil
 }
 
+// NewJournalFromDirs returns a new Journal instance pointing to the journals
+// residing in the given directories, such as the system journal and the
+// /var/log/journal/<machine-id> directories of mounted images, with their
+// entries interleaved by time. The journal files directly in each directory
+// and in its subdirectories named after machine IDs are opened.
+func NewJournalFromDirs(paths ...string) (j *Journal, err error) {
+	var files []string
+	for _, path := range paths {
+		dirFiles, err := journalfile.DirFiles(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open journal in directory %q: %v", path, err)
+		}
+		files = append(files, dirFiles...)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no journal files in directories %q", paths)
+	}
+	return NewJournalFromFiles(files...)
+}
+
+// NewJournalFromNamespace returns a new Journal instance pointing to the
+// local journal of the namespace namespace, which units with
+// LogNamespace=namespace log to. It requires systemd v245 or newer.
+func NewJournalFromNamespace(namespace string) (j *Journal, err error) {
+	j = &Journal{}
+
+	sd_journal_open_namespace, err := getFunction("sd_journal_open_namespace")
+	if err != nil {
+		return nil, err
+	}
+
+	ns := C.CString(namespace)
+	defer C.free(unsafe.Pointer(ns))
+
+	r := C.my_sd_journal_open_namespace(sd_journal_open_namespace, &j.cjournal, ns, C.SD_JOURNAL_LOCAL_ONLY)
+	if r < 0 {
+		return nil, fmt.Errorf("failed to open journal namespace %q: %s", namespace, syscall.Errno(-r).Error())
+	}
+
+	return j, nil
+}
+
 // NewJournalFromFiles returns a new Journal instance pointing to a journals residing
 // in a given files.
 func NewJournalFromFiles(paths ...string) (j *Journal, err error) {
@@ -1017,7 +1101,10 @@ func (j *Journal) Wait(timeout time.Duration) int {
 	return int(r)
 }
 
-// GetUsage returns the journal disk space usage, in bytes.
+// GetUsage returns the journal disk space usage, in bytes. It covers the
+// files j was opened with, so open j with NewJournalFromDir or
+// NewJournalFromNamespace to get the usage of a directory or namespace. See
+// also journalfile.DirUsage, which does not require cgo.
 func (j *Journal) GetUsage() (uint64, error) {
 	var out C.uint64_t
 
@@ -1118,3 +1205,29 @@ func (j *Journal) GetCatalog() (string, error) {
 
 	return catalog, nil
 }
+
+// GetCatalogForMessageID retrieves the message catalog entry for the
+// MESSAGE_ID id, which is 32 hexadecimal digits, optionally written as a
+// UUID. Unlike GetCatalog, the @FIELD@ references of the entry are not
+// replaced.
+func GetCatalogForMessageID(id string) (string, error) {
+	b, err := hex.DecodeString(strings.Replace(id, "-", "", -1))
+	if err != nil || len(b) != 16 {
+		return "", fmt.Errorf("invalid message ID %q", id)
+	}
+
+	sd_journal_get_catalog_for_message_id, err := getFunction("sd_journal_get_catalog_for_message_id")
+	if err != nil {
+		return "", err
+	}
+
+	var c *C.char
+	r := C.my_sd_journal_get_catalog_for_message_id(sd_journal_get_catalog_for_message_id, (*C.uchar)(unsafe.Pointer(&b[0])), &c)
+	defer C.free(unsafe.Pointer(c))
+
+	if r < 0 {
+		return "", fmt.Errorf("failed to retrieve catalog entry for message ID %s: %s", id, syscall.Errno(-r).Error())
+	}
+
+	return C.GoString(c), nil
+}
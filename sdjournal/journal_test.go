@@ -17,6 +17,7 @@ package sdjournal
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -28,6 +29,7 @@ import (
 	"time"
 
 	"github.com/coreos/go-systemd/v22/journal"
+	"github.com/coreos/go-systemd/v22/journalfile"
 )
 
 func TestJournalFollow(t *testing.T) {
@@ -84,6 +86,37 @@ func TestJournalFollow(t *testing.T) {
 	}
 }
 
+func TestJournalFollowContext(t *testing.T) {
+	r, err := NewJournalReader(JournalReaderConfig{
+		NumFromTail: 1,
+	})
+
+	if err != nil {
+		t.Fatalf("Error opening journal: %s", err)
+	}
+
+	if r == nil {
+		t.Fatal("Got a nil reader")
+	}
+
+	defer r.Close()
+
+	id := time.Now().String()
+	if err := journal.Print(journal.PriInfo, "test message %s", id); err != nil {
+		t.Fatalf("Error writing to journal: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var buf bytes.Buffer
+	if err = r.FollowContext(ctx, &buf); err != context.DeadlineExceeded {
+		t.Fatalf("Error during follow: %s", err)
+	}
+	if !strings.Contains(buf.String(), id) {
+		t.Errorf("Followed output doesn't contain the test message %q", id)
+	}
+}
+
 func TestJournalWait(t *testing.T) {
 	id := time.Now().String()
 	j, err := NewJournal()
@@ -147,6 +180,69 @@ func TestJournalGetUsage(t *testing.T) {
 	}
 }
 
+func TestNewSystemAndUserJournal(t *testing.T) {
+	for name, open := range map[string]func() (*Journal, error){
+		"system": NewSystemJournal,
+		"user":   NewUserJournal,
+	} {
+		j, err := open()
+		if err != nil {
+			t.Fatalf("Error opening %s journal: %s", name, err)
+		}
+
+		if j == nil {
+			t.Fatalf("Got a nil %s journal", name)
+		}
+
+		if _, err := j.Next(); err != nil {
+			t.Errorf("Error reading %s journal: %s", name, err)
+		}
+		j.Close()
+	}
+}
+
+func TestNewJournalFromDirs(t *testing.T) {
+	var dirs []string
+	for _, dir := range []string{journalfile.PersistentDir, journalfile.RuntimeDir} {
+		if files, err := journalfile.DirFiles(dir); err == nil && len(files) > 0 {
+			dirs = append(dirs, dir)
+		}
+	}
+	if len(dirs) == 0 {
+		t.Skip("no journal directories found")
+	}
+
+	j, err := NewJournalFromDirs(dirs...)
+	if err != nil {
+		t.Fatalf("Error opening journal directories %q: %s", dirs, err)
+	}
+	defer j.Close()
+
+	if _, err := j.Next(); err != nil {
+		t.Errorf("Error reading journal directories: %s", err)
+	}
+
+	if _, err := NewJournalFromDirs("/nonexistent"); err == nil {
+		t.Error("Expected an error for a missing directory")
+	}
+}
+
+func TestNewJournalFromNamespace(t *testing.T) {
+	if _, err := getFunction("sd_journal_open_namespace"); err != nil {
+		t.Skip("journal namespaces not supported by libsystemd")
+	}
+
+	j, err := NewJournalFromNamespace("go-systemd-test")
+	if err != nil {
+		t.Fatalf("Error opening journal namespace: %s", err)
+	}
+	defer j.Close()
+
+	if _, err := j.Next(); err != nil {
+		t.Errorf("Error reading journal namespace: %s", err)
+	}
+}
+
 func TestJournalCursorGetSeekAndTest(t *testing.T) {
 	j, err := NewJournal()
 	if err != nil {
@@ -470,6 +566,24 @@ func TestJournalGetCatalog(t *testing.T) {
 	}
 }
 
+func TestGetCatalogForMessageID(t *testing.T) {
+	// SD_MESSAGE_UNIT_STARTED, documented in systemd's own catalog
+	catalog, err := GetCatalogForMessageID("39f53479d3a045ac8e11786248231fbf")
+	if err != nil {
+		t.Fatalf("Failed to retrieve catalog entry: %s", err)
+	}
+
+	for _, w := range []string{"Subject: ", "Defined-By: systemd", "@UNIT@"} {
+		if !strings.Contains(catalog, w) {
+			t.Fatalf("Failed to find \"%s\" in \n%s", w, catalog)
+		}
+	}
+
+	if _, err := GetCatalogForMessageID("not-an-id"); err == nil {
+		t.Fatal("Expected an error for an invalid message ID")
+	}
+}
+
 func contains(s []string, v string) bool {
 	for _, entry := range s {
 		if entry == v {
@@ -16,6 +16,7 @@
 package sdjournal
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -44,10 +45,24 @@ type JournalReaderConfig struct {
 	// the array is empty, entries will not be filtered.
 	Matches []Match
 
+	// If not nil, show only journal entries selected by Filter, in addition
+	// to Matches. If none of the options above is set, reading begins at
+	// Filter.Since.
+	Filter *Filter
+
 	// If not empty, the journal instance will point to a journal residing
 	// in this directory. The supplied path may be relative or absolute.
 	Path string
 
+	// If not empty and Path is not set, the journal instance will point to
+	// the journals residing in these directories, merged as with
+	// NewJournalFromDirs.
+	Paths []string
+
+	// If not empty and neither Path nor Paths is set, the journal instance
+	// will point to the journal of this namespace.
+	Namespace string
+
 	// If not nil, Formatter will be used to translate the resulting entries
 	// into strings. If not set, the default format (timestamp and message field)
 	// will be used. If Formatter returns an error, Read will stop and return the error.
@@ -60,6 +75,7 @@ type JournalReader struct {
 	journal   *Journal
 	msgReader *strings.Reader
 	formatter func(entry *JournalEntry) (string, error)
+	until     uint64 // realtime after which entries are not read, if not 0
 }
 
 // NewJournalReader creates a new JournalReader with configuration options that are similar to the
@@ -78,6 +94,10 @@ func NewJournalReader(config JournalReaderConfig) (*JournalReader, error) {
 	var err error
 	if config.Path != "" {
 		r.journal, err = NewJournalFromDir(config.Path)
+	} else if len(config.Paths) > 0 {
+		r.journal, err = NewJournalFromDirs(config.Paths...)
+	} else if config.Namespace != "" {
+		r.journal, err = NewJournalFromNamespace(config.Namespace)
 	} else {
 		r.journal, err = NewJournal()
 	}
@@ -86,10 +106,11 @@ func NewJournalReader(config JournalReaderConfig) (*JournalReader, error) {
 	}
 
 	// Add any supplied matches
-	for _, m := range config.Matches {
-		if err = r.journal.AddMatch(m.String()); err != nil {
-			return nil, err
-		}
+	if err = addReaderMatches(r.journal, &config); err != nil {
+		return nil, err
+	}
+	if f := config.Filter; f != nil && !f.Until.IsZero() {
+		r.until = timeUsec(f.Until)
 	}
 
 	// Set the start position based on options
@@ -124,11 +145,35 @@ func NewJournalReader(config JournalReaderConfig) (*JournalReader, error) {
 		if err := r.journal.SeekCursor(config.Cursor); err != nil {
 			return nil, err
 		}
+	} else if config.Filter != nil && !config.Filter.Since.IsZero() {
+		if err := r.journal.SeekRealtimeUsec(timeUsec(config.Filter.Since)); err != nil {
+			return nil, err
+		}
 	}
 
 	return r, nil
 }
 
+// addReaderMatches adds config.Matches to j, and the matches of
+// config.Filter ANDed with them.
+func addReaderMatches(j matcher, config *JournalReaderConfig) error {
+	for _, m := range config.Matches {
+		if err := j.AddMatch(m.String()); err != nil {
+			return err
+		}
+	}
+	f := config.Filter
+	if f == nil {
+		return nil
+	}
+	if len(config.Matches) > 0 {
+		if err := j.AddConjunction(); err != nil {
+			return err
+		}
+	}
+	return f.addMatches(j)
+}
+
 // Read reads entries from the journal. Read follows the Reader interface so
 // it must be able to read a specific amount of bytes. Journald on the other
 // hand only allows us to read full entries of arbitrary size (without byte
@@ -155,6 +200,13 @@ func (r *JournalReader) Read(b []byte) (int, error) {
 		if err != nil {
 			return 0, err
 		}
+		if r.until != 0 && entry.RealtimeTimestamp > r.until {
+			// Step back so that later reads stop here as well
+			if _, err := r.journal.Previous(); err != nil {
+				return 0, err
+			}
+			return 0, io.EOF
+		}
 
 		// Build a message
 		msg, err := r.formatter(entry)
@@ -197,6 +249,43 @@ func (r *JournalReader) Rewind() error {
 // Follow synchronously follows the JournalReader, writing each new journal entry to writer. The
 // follow will continue until a single time.Time is received on the until channel.
 func (r *JournalReader) Follow(until <-chan time.Time, writer io.Writer) error {
+	done := make(chan struct{})
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-until:
+			close(done)
+		case <-stop:
+		}
+	}()
+	return r.follow(done, writer)
+}
+
+// FollowContext is like Follow, but follows the JournalReader until ctx is
+// done, and then returns ctx.Err(). For example, the logs of a unit can be
+// streamed to an HTTP client until it goes away:
+//
+//	r, err := sdjournal.NewJournalReader(sdjournal.JournalReaderConfig{
+//		NumFromTail: 10,
+//		Matches: []sdjournal.Match{{
+//			Field: sdjournal.SD_JOURNAL_FIELD_SYSTEMD_UNIT,
+//			Value: "foo.service",
+//		}},
+//	})
+//	...
+//	defer r.Close()
+//	err = r.FollowContext(req.Context(), w)
+func (r *JournalReader) FollowContext(ctx context.Context, writer io.Writer) error {
+	err := r.follow(ctx.Done(), writer)
+	if err == ErrExpired {
+		return ctx.Err()
+	}
+	return err
+}
+
+// follow writes new journal entries to writer until done is closed.
+func (r *JournalReader) follow(done <-chan struct{}, writer io.Writer) error {
 
 	// Process journal entries and events. Entries are flushed until the tail or
 	// timeout is reached, and then we wait for new events or the timeout.
@@ -213,7 +302,7 @@ process:
 		}
 
 		select {
-		case <-until:
+		case <-done:
 			return ErrExpired
 		default:
 		}
@@ -236,7 +325,7 @@ process:
 			}()
 
 			select {
-			case <-until:
+			case <-done:
 				return ErrExpired
 			case e := <-waitCh:
 				switch e {
@@ -0,0 +1,156 @@
+// Copyright 2015 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdjournal
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	sd_dbus "github.com/coreos/go-systemd/v22/dbus"
+)
+
+// unitOutputWait is how long StartUnitWithOutput waits for new journal
+// entries before checking whether the start job finished.
+const unitOutputWait = 100 * time.Millisecond
+
+// StartUnitWithOutput starts the unit name with conn, like StartUnitContext
+// with mode, and sends the journal entries of the unit logged while the start
+// job runs to entries, like running `systemctl start` with `journalctl -fu`
+// alongside. It returns the result of the job once it finished and all
+// entries logged until then have been sent.
+//
+// Entries are those logged by the processes of the unit and those logged by
+// systemd about it, excluding entries for previous invocations of the unit.
+// entries is left open.
+func StartUnitWithOutput(ctx context.Context, conn *sd_dbus.Conn, name string, mode string, entries chan<- *JournalEntry) (string, error) {
+	j, err := NewJournal()
+	if err != nil {
+		return "", err
+	}
+	defer j.Close()
+
+	// _SYSTEMD_UNIT=name OR (UNIT=name AND _PID=1), as journalctl -u does
+	matches := []string{
+		SD_JOURNAL_FIELD_SYSTEMD_UNIT + "=" + name,
+		"",
+		"UNIT=" + name,
+		SD_JOURNAL_FIELD_PID + "=1",
+	}
+	for _, m := range matches {
+		if m == "" {
+			err = j.AddDisjunction()
+		} else {
+			err = j.AddMatch(m)
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// Only entries logged from now on are sent: position the read pointer
+	// at the last entry so that Next returns the following ones.
+	if err := j.SeekTail(); err != nil {
+		return "", err
+	}
+	if _, err := j.Previous(); err != nil {
+		return "", err
+	}
+
+	resultCh := make(chan string, 1)
+	if _, err := conn.StartUnitContext(ctx, name, mode, resultCh); err != nil {
+		return "", err
+	}
+
+	o := &unitOutput{conn: conn, name: name, journal: j}
+	for {
+		if err := o.send(ctx, entries); err != nil {
+			return "", err
+		}
+
+		select {
+		case result := <-resultCh:
+			// send the entries logged until the job finished
+			return result, o.send(ctx, entries)
+		case <-ctx.Done():
+			return "", ctx.Err()
+		default:
+		}
+
+		if e := j.Wait(unitOutputWait); e < 0 {
+			return "", fmt.Errorf("received error event: %d", e)
+		}
+	}
+}
+
+// unitOutput sends the journal entries of a unit being started.
+type unitOutput struct {
+	conn    *sd_dbus.Conn
+	name    string
+	journal *Journal
+
+	invocationID string // the last known invocation of the unit
+}
+
+// send sends the entries from the read pointer to the tail of the journal.
+func (o *unitOutput) send(ctx context.Context, entries chan<- *JournalEntry) error {
+	for {
+		n, err := o.journal.Next()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return nil
+		}
+
+		entry, err := o.journal.GetEntry()
+		if err != nil {
+			return err
+		}
+		if !o.current(ctx, entry) {
+			continue
+		}
+
+		select {
+		case entries <- entry:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// current reports whether entry belongs to the current invocation of the
+// unit. Entries without an invocation ID are assumed to.
+func (o *unitOutput) current(ctx context.Context, entry *JournalEntry) bool {
+	id := entry.Fields["_SYSTEMD_INVOCATION_ID"]
+	if id == "" {
+		// set by systemd on its own messages about the unit
+		id = entry.Fields["INVOCATION_ID"]
+	}
+	if id == "" || id == o.invocationID {
+		return true
+	}
+
+	// the unit may have been invoked after the ID was last read
+	prop, err := o.conn.GetUnitPropertyContext(ctx, o.name, "InvocationID")
+	if err != nil {
+		return true
+	}
+	if b, ok := prop.Value.Value().([]byte); ok {
+		o.invocationID = hex.EncodeToString(b)
+	}
+	return id == o.invocationID
+}